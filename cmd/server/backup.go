@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dukerupert/dd/internal/backup"
+	"github.com/dukerupert/dd/internal/config"
+	"github.com/dukerupert/dd/internal/replica"
+)
+
+// runBackup writes a backup archive of the database and media. It's safe to
+// run while the server is up.
+//
+//	server backup [-o file]
+func runBackup(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := fs.String("o", backup.FileName(time.Now()), "archive to write")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Written beside the output and renamed, so a failed backup leaves no
+	// half-written archive behind
+	tmp, err := os.CreateTemp(filepath.Dir(*output), ".dd-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	manifest, err := backup.Write(ctx, db, cfg.Media.Dir, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), *output); err != nil {
+		return err
+	}
+
+	slog.Info("Backup written",
+		slog.String("path", *output),
+		slog.Int("files", len(manifest.Files)),
+		slog.Int64("version", manifest.Version),
+	)
+	return nil
+}
+
+// runRestore verifies a backup archive and restores it over the database and
+// media. Stop the server first. With -verify it only checks the archive.
+// With -to-time it restores the database alone, as it was then, from the
+// replica instead.
+//
+//	server restore [-verify] file
+//	server restore -to-time 2026-10-16T18:00:00Z
+func runRestore(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	verify := fs.Bool("verify", false, "check the archive without restoring it")
+	toTime := fs.String("to-time", "", "restore the database from the replica as of this RFC 3339 time, or now")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *toTime != "" {
+		if fs.NArg() != 0 || *verify {
+			return errors.New("usage: restore -to-time time")
+		}
+		return restoreToTime(ctx, cfg, *toTime)
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: restore [-verify] file | restore -to-time time")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var manifest backup.Manifest
+	if *verify {
+		manifest, err = backup.Verify(f)
+	} else {
+		manifest, err = backup.Restore(ctx, f, cfg.Database.Path, cfg.Media.Dir)
+	}
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+
+	message := "Backup restored"
+	if *verify {
+		message = "Backup verified"
+	}
+	slog.Info(message,
+		slog.String("path", fs.Arg(0)),
+		slog.Time("created_at", manifest.CreatedAt),
+		slog.Int("files", len(manifest.Files)),
+		slog.Int64("version", manifest.Version),
+	)
+	return nil
+}
+
+// restoreToTime restores the database from the replica as it was at value
+func restoreToTime(ctx context.Context, cfg *config.Config, value string) error {
+	if cfg.Replica.Dir == "" {
+		return errors.New("REPLICA_DIR isn't set")
+	}
+	at := time.Now()
+	if value != "now" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("-to-time must be an RFC 3339 time like 2026-10-16T18:00:00Z, or now: %w", err)
+		}
+	}
+
+	restored, err := replica.Restore(ctx, cfg.Replica.Dir, cfg.Database.Path, at)
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	slog.Info("Database restored from the replica",
+		slog.String("generation", restored.Generation),
+		slog.Time("snapshot", restored.Snapshot),
+		slog.Int("segments", restored.Segments),
+		slog.Time("as_of", restored.At),
+	)
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"log/slog"
@@ -14,6 +15,7 @@ import (
 	"github.com/dukerupert/dd/internal/config"
 	"github.com/dukerupert/dd/internal/handler"
 	"github.com/dukerupert/dd/internal/renderer"
+	"github.com/dukerupert/dd/internal/replica"
 	"github.com/dukerupert/dd/internal/router"
 	"github.com/dukerupert/dd/internal/store"
 	"github.com/dukerupert/dd/templates"
@@ -34,29 +36,54 @@ func run() error {
 	slog.SetDefault(logger)
 	logger.Info("logger initialized", slog.String("level", cfg.Logging.Level.String()))
 
-	// Open database
-	db, err := sql.Open("sqlite", cfg.Database.Path)
+	// Subcommands; with none, serve
+	ctx := context.Background()
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+	case "backup":
+		return runBackup(ctx, cfg, flag.Args()[1:])
+	case "restore":
+		return runRestore(ctx, cfg, flag.Args()[1:])
+	default:
+		return fmt.Errorf("unknown command %q: use backup or restore, or nothing to serve", cmd)
+	}
+
+	// Open database; replication needs it in WAL mode, checkpointed only by
+	// the replicator
+	dsn := cfg.Database.Path + "?_pragma=foreign_keys(1)"
+	if cfg.Replica.Dir != "" {
+		dsn = replica.DSN(cfg.Database.Path)
+	}
+	db, err := openDB(dsn)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	// CRITICAL: Enable foreign key constraints
-	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
-
 	// Run migrations
 	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
 	if err != nil {
 		return err
 	}
 
-	ctx := context.Background()
 	if _, err := provider.Up(ctx); err != nil {
 		return err
 	}
 
+	// Start replication
+	if cfg.Replica.Dir != "" {
+		rep := replica.New(db, logger, cfg.Database.Path, cfg.Replica.Dir, replica.Options{
+			SyncInterval:     cfg.Replica.SyncInterval,
+			SnapshotInterval: cfg.Replica.SnapshotInterval,
+			Retention:        cfg.Replica.Retention,
+		})
+		go func() {
+			if err := rep.Run(ctx); err != nil {
+				slog.Error("Replication stopped", slog.String("error", err.Error()))
+			}
+		}()
+	}
+
 	// Create queries
 	queries := store.New(db)
 
@@ -67,7 +94,13 @@ func run() error {
 	}
 
 	// Create handler
-	h := handler.New(logger, queries, templateRenderer, cfg)
+	h := handler.New(logger, db, queries, templateRenderer, cfg)
+
+	// Start scheduled backups
+	if backups := h.Backups(); backups != nil {
+		slog.Info("Scheduled backups on", slog.String("dir", cfg.Backup.Dir), slog.Duration("interval", cfg.Backup.Interval))
+		go backups.Run(ctx)
+	}
 
 	// Create router
 	srv := router.New(h, queries, cfg.Session.CookieName)
@@ -77,6 +110,13 @@ func run() error {
 	return http.ListenAndServe(net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port)), srv)
 }
 
+// openDB opens the SQLite database at dsn, a path with parameters. Pragmas
+// such as foreign_keys only hold for the connection that runs them, so they
+// go in the DSN, which every pooled connection is opened with.
+func openDB(dsn string) (*sql.DB, error) {
+	return sql.Open("sqlite", dsn)
+}
+
 func main() {
 	if err := run(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,90 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Full-text index over records and artists. Each row is one searchable
+-- entity; entity_type/entity_id point back at the source row.
+CREATE VIRTUAL TABLE search_index USING fts5(
+    entity_type UNINDEXED, -- 'record' or 'artist'
+    entity_id UNINDEXED,
+    title,
+    album_title,
+    catalog_number,
+    notes,
+    artist_name,
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+-- Backfill existing data
+INSERT INTO search_index (entity_type, entity_id, title, album_title, catalog_number, notes, artist_name)
+SELECT 'record', r.id, r.title, r.album_title, r.catalog_number, r.notes, a.name
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id;
+
+INSERT INTO search_index (entity_type, entity_id, artist_name)
+SELECT 'artist', id, name FROM artists;
+
+-- Keep the index in sync with records
+CREATE TRIGGER search_index_records_insert
+    AFTER INSERT ON records
+    FOR EACH ROW
+BEGIN
+    INSERT INTO search_index (entity_type, entity_id, title, album_title, catalog_number, notes, artist_name)
+    VALUES ('record', NEW.id, NEW.title, NEW.album_title, NEW.catalog_number, NEW.notes,
+            (SELECT name FROM artists WHERE id = NEW.artist_id));
+END;
+
+CREATE TRIGGER search_index_records_update
+    AFTER UPDATE OF title, album_title, catalog_number, notes, artist_id ON records
+    FOR EACH ROW
+BEGIN
+    DELETE FROM search_index WHERE entity_type = 'record' AND entity_id = OLD.id;
+    INSERT INTO search_index (entity_type, entity_id, title, album_title, catalog_number, notes, artist_name)
+    VALUES ('record', NEW.id, NEW.title, NEW.album_title, NEW.catalog_number, NEW.notes,
+            (SELECT name FROM artists WHERE id = NEW.artist_id));
+END;
+
+CREATE TRIGGER search_index_records_delete
+    AFTER DELETE ON records
+    FOR EACH ROW
+BEGIN
+    DELETE FROM search_index WHERE entity_type = 'record' AND entity_id = OLD.id;
+END;
+
+-- Keep the index in sync with artists, including the artist name copied
+-- onto each of their records
+CREATE TRIGGER search_index_artists_insert
+    AFTER INSERT ON artists
+    FOR EACH ROW
+BEGIN
+    INSERT INTO search_index (entity_type, entity_id, artist_name)
+    VALUES ('artist', NEW.id, NEW.name);
+END;
+
+CREATE TRIGGER search_index_artists_update
+    AFTER UPDATE OF name ON artists
+    FOR EACH ROW
+BEGIN
+    UPDATE search_index SET artist_name = NEW.name
+    WHERE (entity_type = 'artist' AND entity_id = NEW.id)
+       OR (entity_type = 'record' AND entity_id IN (SELECT id FROM records WHERE artist_id = NEW.id));
+END;
+
+CREATE TRIGGER search_index_artists_delete
+    AFTER DELETE ON artists
+    FOR EACH ROW
+BEGIN
+    DELETE FROM search_index WHERE entity_type = 'artist' AND entity_id = OLD.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS search_index_artists_delete;
+DROP TRIGGER IF EXISTS search_index_artists_update;
+DROP TRIGGER IF EXISTS search_index_artists_insert;
+DROP TRIGGER IF EXISTS search_index_records_delete;
+DROP TRIGGER IF EXISTS search_index_records_update;
+DROP TRIGGER IF EXISTS search_index_records_insert;
+
+DROP TABLE IF EXISTS search_index;
+-- +goose StatementEnd
@@ -0,0 +1,83 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per listen. records.play_count and records.last_played_at are
+-- kept in sync with this table by the triggers below.
+CREATE TABLE plays (
+    id INTEGER PRIMARY KEY,
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    user_id TEXT REFERENCES users(id) ON DELETE SET NULL, -- NULL for anonymous plays
+    played_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    side TEXT, -- e.g. 'A', 'B'
+    notes TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_plays_record_id_played_at ON plays(record_id, played_at);
+CREATE INDEX idx_plays_played_at ON plays(played_at);
+
+-- Backfill one play per counted listen so existing counts survive. Only the
+-- last listen time was ever stored, so every backfilled play gets that time.
+WITH RECURSIVE backfill(record_id, played_at, remaining) AS (
+    SELECT id, COALESCE(last_played_at, updated_at, created_at), play_count
+    FROM records
+    WHERE play_count > 0
+    UNION ALL
+    SELECT record_id, played_at, remaining - 1
+    FROM backfill
+    WHERE remaining > 1
+)
+INSERT INTO plays (record_id, played_at, notes)
+SELECT record_id, played_at, 'Imported from play count'
+FROM backfill;
+
+CREATE TRIGGER update_plays_updated_at
+    AFTER UPDATE ON plays
+    FOR EACH ROW
+BEGIN
+    UPDATE plays SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER plays_insert_sync_record
+    AFTER INSERT ON plays
+    FOR EACH ROW
+BEGIN
+    UPDATE records
+    SET play_count = (SELECT COUNT(*) FROM plays WHERE record_id = NEW.record_id),
+        last_played_at = (SELECT MAX(played_at) FROM plays WHERE record_id = NEW.record_id)
+    WHERE id = NEW.record_id;
+END;
+
+CREATE TRIGGER plays_update_sync_record
+    AFTER UPDATE OF record_id, played_at ON plays
+    FOR EACH ROW
+BEGIN
+    UPDATE records
+    SET play_count = (SELECT COUNT(*) FROM plays WHERE record_id = records.id),
+        last_played_at = (SELECT MAX(played_at) FROM plays WHERE record_id = records.id)
+    WHERE id IN (OLD.record_id, NEW.record_id);
+END;
+
+CREATE TRIGGER plays_delete_sync_record
+    AFTER DELETE ON plays
+    FOR EACH ROW
+BEGIN
+    UPDATE records
+    SET play_count = (SELECT COUNT(*) FROM plays WHERE record_id = OLD.record_id),
+        last_played_at = (SELECT MAX(played_at) FROM plays WHERE record_id = OLD.record_id)
+    WHERE id = OLD.record_id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS plays_delete_sync_record;
+DROP TRIGGER IF EXISTS plays_update_sync_record;
+DROP TRIGGER IF EXISTS plays_insert_sync_record;
+DROP TRIGGER IF EXISTS update_plays_updated_at;
+
+DROP INDEX IF EXISTS idx_plays_played_at;
+DROP INDEX IF EXISTS idx_plays_record_id_played_at;
+
+DROP TABLE IF EXISTS plays;
+-- +goose StatementEnd
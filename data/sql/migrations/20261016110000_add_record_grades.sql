@@ -0,0 +1,109 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Goldmine grading scale. rank orders the grades so filters can compare them;
+-- higher is better.
+CREATE TABLE grades (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    rank INTEGER NOT NULL UNIQUE
+);
+
+INSERT INTO grades (code, name, rank) VALUES
+('M', 'Mint', 8),
+('NM', 'Near Mint', 7),
+('VG+', 'Very Good Plus', 6),
+('VG', 'Very Good', 5),
+('G+', 'Good Plus', 4),
+('G', 'Good', 3),
+('F', 'Fair', 2),
+('P', 'Poor', 1);
+
+-- Media (vinyl) and sleeve are graded separately
+ALTER TABLE records ADD COLUMN media_grade TEXT
+    CHECK (media_grade IN ('M', 'NM', 'VG+', 'VG', 'G+', 'G', 'F', 'P'));
+ALTER TABLE records ADD COLUMN sleeve_grade TEXT
+    CHECK (sleeve_grade IN ('M', 'NM', 'VG+', 'VG', 'G+', 'G', 'F', 'P'));
+
+-- Every regrade, newest last. The previous grades are kept so the timeline
+-- can show what changed.
+CREATE TABLE condition_history (
+    id INTEGER PRIMARY KEY,
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    media_grade TEXT,
+    sleeve_grade TEXT,
+    previous_media_grade TEXT,
+    previous_sleeve_grade TEXT,
+    note TEXT,
+    user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    graded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_condition_history_record_id ON condition_history(record_id, graded_at);
+
+-- Carry the old free-text condition over as the media grade. Suspend the
+-- updated_at trigger so migrated rows keep their timestamps.
+DROP TRIGGER IF EXISTS update_records_updated_at;
+
+UPDATE records
+SET media_grade = CASE condition
+    WHEN 'Mint' THEN 'M'
+    WHEN 'Near Mint' THEN 'NM'
+    WHEN 'Very Good' THEN 'VG'
+    WHEN 'Good' THEN 'G'
+    WHEN 'Fair' THEN 'F'
+    WHEN 'Poor' THEN 'P'
+END;
+
+-- Anything that doesn't map is preserved in the notes rather than lost
+UPDATE records
+SET notes = COALESCE(notes || char(10), '') || 'Condition: ' || condition
+WHERE media_grade IS NULL AND condition IS NOT NULL AND condition <> '';
+
+INSERT INTO condition_history (record_id, media_grade, note, graded_at)
+SELECT id, media_grade, 'Graded before condition history was kept', COALESCE(updated_at, created_at, CURRENT_TIMESTAMP)
+FROM records
+WHERE media_grade IS NOT NULL;
+
+CREATE TRIGGER update_records_updated_at
+    AFTER UPDATE ON records
+    FOR EACH ROW
+BEGIN
+    UPDATE records SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+ALTER TABLE records DROP COLUMN condition;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE records ADD COLUMN condition TEXT;
+
+DROP TRIGGER IF EXISTS update_records_updated_at;
+
+UPDATE records
+SET condition = CASE media_grade
+    WHEN 'M' THEN 'Mint'
+    WHEN 'NM' THEN 'Near Mint'
+    WHEN 'VG+' THEN 'Very Good'
+    WHEN 'VG' THEN 'Very Good'
+    WHEN 'G+' THEN 'Good'
+    WHEN 'G' THEN 'Good'
+    WHEN 'F' THEN 'Fair'
+    WHEN 'P' THEN 'Poor'
+END;
+
+CREATE TRIGGER update_records_updated_at
+    AFTER UPDATE ON records
+    FOR EACH ROW
+BEGIN
+    UPDATE records SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+DROP INDEX IF EXISTS idx_condition_history_record_id;
+DROP TABLE IF EXISTS condition_history;
+
+ALTER TABLE records DROP COLUMN sleeve_grade;
+ALTER TABLE records DROP COLUMN media_grade;
+
+DROP TABLE IF EXISTS grades;
+-- +goose StatementEnd
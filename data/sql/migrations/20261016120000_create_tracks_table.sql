@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- What's on the disc. position is the label printed on the sleeve (A1, B3);
+-- side is kept separately so running times can be totalled per side.
+CREATE TABLE tracks (
+    id INTEGER PRIMARY KEY,
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    side TEXT, -- e.g. 'A', 'B'; NULL when the format has no sides
+    position TEXT NOT NULL,
+    title TEXT NOT NULL,
+    duration_seconds INTEGER CHECK (duration_seconds >= 0),
+    artist_id INTEGER REFERENCES artists(id) ON DELETE SET NULL, -- per-track credit, if different from the record
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (record_id, position)
+);
+
+CREATE INDEX idx_tracks_artist_id ON tracks(artist_id);
+
+CREATE TRIGGER update_tracks_updated_at
+    AFTER UPDATE ON tracks
+    FOR EACH ROW
+BEGIN
+    UPDATE tracks SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_tracks_updated_at;
+DROP INDEX IF EXISTS idx_tracks_artist_id;
+DROP TABLE IF EXISTS tracks;
+-- +goose StatementEnd
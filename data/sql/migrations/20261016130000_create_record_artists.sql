@@ -0,0 +1,211 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A record can credit any number of artists. position is the display order of
+-- the credits on the record.
+CREATE TABLE record_artists (
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    artist_id INTEGER NOT NULL REFERENCES artists(id) ON DELETE CASCADE,
+    role TEXT NOT NULL DEFAULT 'primary'
+        CHECK (role IN ('primary', 'featuring', 'producer', 'remixer')),
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (record_id, artist_id, role)
+);
+
+CREATE INDEX idx_record_artists_artist_id ON record_artists(artist_id, role);
+
+-- Every existing artist_id becomes the record's primary credit
+INSERT INTO record_artists (record_id, artist_id, role, position)
+SELECT id, artist_id, 'primary', 0 FROM records WHERE artist_id IS NOT NULL;
+
+-- records.artist_id stays as the lead artist: the first primary credit. It is
+-- derived from record_artists, the same way play_count is derived from plays.
+CREATE TRIGGER record_artists_insert_lead
+    AFTER INSERT ON record_artists
+    FOR EACH ROW
+BEGIN
+    UPDATE records
+    SET artist_id = (SELECT artist_id FROM record_artists
+                     WHERE record_id = NEW.record_id AND role = 'primary'
+                     ORDER BY position, rowid LIMIT 1)
+    WHERE id = NEW.record_id
+      AND artist_id IS NOT (SELECT artist_id FROM record_artists
+                            WHERE record_id = NEW.record_id AND role = 'primary'
+                            ORDER BY position, rowid LIMIT 1);
+END;
+
+CREATE TRIGGER record_artists_update_lead
+    AFTER UPDATE ON record_artists
+    FOR EACH ROW
+BEGIN
+    UPDATE records
+    SET artist_id = (SELECT artist_id FROM record_artists
+                     WHERE record_id = NEW.record_id AND role = 'primary'
+                     ORDER BY position, rowid LIMIT 1)
+    WHERE id = NEW.record_id
+      AND artist_id IS NOT (SELECT artist_id FROM record_artists
+                            WHERE record_id = NEW.record_id AND role = 'primary'
+                            ORDER BY position, rowid LIMIT 1);
+END;
+
+CREATE TRIGGER record_artists_delete_lead
+    AFTER DELETE ON record_artists
+    FOR EACH ROW
+BEGIN
+    UPDATE records
+    SET artist_id = (SELECT artist_id FROM record_artists
+                     WHERE record_id = OLD.record_id AND role = 'primary'
+                     ORDER BY position, rowid LIMIT 1)
+    WHERE id = OLD.record_id
+      AND artist_id IS NOT (SELECT artist_id FROM record_artists
+                            WHERE record_id = OLD.record_id AND role = 'primary'
+                            ORDER BY position, rowid LIMIT 1);
+END;
+
+-- Writes that still set records.artist_id directly are turned into a lead
+-- primary credit, replacing the previous lead. Updates made by the triggers
+-- above already match the first primary credit and are skipped.
+CREATE TRIGGER records_insert_artist_credit
+    AFTER INSERT ON records
+    FOR EACH ROW
+    WHEN NEW.artist_id IS NOT NULL
+BEGIN
+    INSERT OR IGNORE INTO record_artists (record_id, artist_id, role, position)
+    VALUES (NEW.id, NEW.artist_id, 'primary', 0);
+END;
+
+CREATE TRIGGER records_update_artist_credit
+    AFTER UPDATE OF artist_id ON records
+    FOR EACH ROW
+    WHEN NEW.artist_id IS NOT OLD.artist_id
+     AND NEW.artist_id IS NOT (SELECT artist_id FROM record_artists
+                               WHERE record_id = NEW.id AND role = 'primary'
+                               ORDER BY position, rowid LIMIT 1)
+BEGIN
+    DELETE FROM record_artists
+    WHERE record_id = NEW.id AND role = 'primary' AND artist_id IS OLD.artist_id;
+
+    INSERT INTO record_artists (record_id, artist_id, role, position)
+    SELECT NEW.id, NEW.artist_id, 'primary',
+           COALESCE((SELECT MIN(position) - 1 FROM record_artists
+                     WHERE record_id = NEW.id AND role = 'primary'), 0)
+    WHERE NEW.artist_id IS NOT NULL
+    ON CONFLICT (record_id, artist_id, role) DO UPDATE SET position = excluded.position;
+END;
+
+-- A record is found by the name of any artist it credits
+DROP TRIGGER IF EXISTS search_index_records_insert;
+DROP TRIGGER IF EXISTS search_index_records_update;
+DROP TRIGGER IF EXISTS search_index_artists_update;
+
+CREATE TRIGGER search_index_records_insert
+    AFTER INSERT ON records
+    FOR EACH ROW
+BEGIN
+    INSERT INTO search_index (entity_type, entity_id, title, album_title, catalog_number, notes, artist_name)
+    VALUES ('record', NEW.id, NEW.title, NEW.album_title, NEW.catalog_number, NEW.notes,
+            (SELECT group_concat(a.name, ' ') FROM record_artists ra
+             JOIN artists a ON ra.artist_id = a.id WHERE ra.record_id = NEW.id));
+END;
+
+CREATE TRIGGER search_index_records_update
+    AFTER UPDATE OF title, album_title, catalog_number, notes, artist_id ON records
+    FOR EACH ROW
+BEGIN
+    DELETE FROM search_index WHERE entity_type = 'record' AND entity_id = OLD.id;
+    INSERT INTO search_index (entity_type, entity_id, title, album_title, catalog_number, notes, artist_name)
+    VALUES ('record', NEW.id, NEW.title, NEW.album_title, NEW.catalog_number, NEW.notes,
+            (SELECT group_concat(a.name, ' ') FROM record_artists ra
+             JOIN artists a ON ra.artist_id = a.id WHERE ra.record_id = NEW.id));
+END;
+
+CREATE TRIGGER search_index_record_artists_insert
+    AFTER INSERT ON record_artists
+    FOR EACH ROW
+BEGIN
+    UPDATE search_index
+    SET artist_name = (SELECT group_concat(a.name, ' ') FROM record_artists ra
+                       JOIN artists a ON ra.artist_id = a.id WHERE ra.record_id = NEW.record_id)
+    WHERE entity_type = 'record' AND entity_id = NEW.record_id;
+END;
+
+CREATE TRIGGER search_index_record_artists_delete
+    AFTER DELETE ON record_artists
+    FOR EACH ROW
+BEGIN
+    UPDATE search_index
+    SET artist_name = (SELECT group_concat(a.name, ' ') FROM record_artists ra
+                       JOIN artists a ON ra.artist_id = a.id WHERE ra.record_id = OLD.record_id)
+    WHERE entity_type = 'record' AND entity_id = OLD.record_id;
+END;
+
+CREATE TRIGGER search_index_artists_update
+    AFTER UPDATE OF name ON artists
+    FOR EACH ROW
+BEGIN
+    UPDATE search_index SET artist_name = NEW.name
+    WHERE entity_type = 'artist' AND entity_id = NEW.id;
+
+    UPDATE search_index
+    SET artist_name = (SELECT group_concat(a.name, ' ') FROM record_artists ra
+                       JOIN artists a ON ra.artist_id = a.id WHERE ra.record_id = search_index.entity_id)
+    WHERE entity_type = 'record'
+      AND entity_id IN (SELECT record_id FROM record_artists WHERE artist_id = NEW.id);
+END;
+
+UPDATE search_index
+SET artist_name = (SELECT group_concat(a.name, ' ') FROM record_artists ra
+                   JOIN artists a ON ra.artist_id = a.id WHERE ra.record_id = search_index.entity_id)
+WHERE entity_type = 'record';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS search_index_artists_update;
+DROP TRIGGER IF EXISTS search_index_record_artists_delete;
+DROP TRIGGER IF EXISTS search_index_record_artists_insert;
+DROP TRIGGER IF EXISTS search_index_records_update;
+DROP TRIGGER IF EXISTS search_index_records_insert;
+
+CREATE TRIGGER search_index_records_insert
+    AFTER INSERT ON records
+    FOR EACH ROW
+BEGIN
+    INSERT INTO search_index (entity_type, entity_id, title, album_title, catalog_number, notes, artist_name)
+    VALUES ('record', NEW.id, NEW.title, NEW.album_title, NEW.catalog_number, NEW.notes,
+            (SELECT name FROM artists WHERE id = NEW.artist_id));
+END;
+
+CREATE TRIGGER search_index_records_update
+    AFTER UPDATE OF title, album_title, catalog_number, notes, artist_id ON records
+    FOR EACH ROW
+BEGIN
+    DELETE FROM search_index WHERE entity_type = 'record' AND entity_id = OLD.id;
+    INSERT INTO search_index (entity_type, entity_id, title, album_title, catalog_number, notes, artist_name)
+    VALUES ('record', NEW.id, NEW.title, NEW.album_title, NEW.catalog_number, NEW.notes,
+            (SELECT name FROM artists WHERE id = NEW.artist_id));
+END;
+
+CREATE TRIGGER search_index_artists_update
+    AFTER UPDATE OF name ON artists
+    FOR EACH ROW
+BEGIN
+    UPDATE search_index SET artist_name = NEW.name
+    WHERE (entity_type = 'artist' AND entity_id = NEW.id)
+       OR (entity_type = 'record' AND entity_id IN (SELECT id FROM records WHERE artist_id = NEW.id));
+END;
+
+UPDATE search_index
+SET artist_name = (SELECT a.name FROM records r JOIN artists a ON r.artist_id = a.id
+                   WHERE r.id = search_index.entity_id)
+WHERE entity_type = 'record';
+
+DROP TRIGGER IF EXISTS records_update_artist_credit;
+DROP TRIGGER IF EXISTS records_insert_artist_credit;
+DROP TRIGGER IF EXISTS record_artists_delete_lead;
+DROP TRIGGER IF EXISTS record_artists_update_lead;
+DROP TRIGGER IF EXISTS record_artists_insert_lead;
+
+DROP INDEX IF EXISTS idx_record_artists_artist_id;
+DROP TABLE IF EXISTS record_artists;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Genres, styles, moods and free-form tags. Names are unique within a kind
+-- regardless of case: there can't be two "jazz" genres, but "Jazz" the genre
+-- and "jazz" the custom tag can both exist.
+CREATE TABLE tags (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL COLLATE NOCASE CHECK (length(name) > 0),
+    kind TEXT NOT NULL DEFAULT 'custom'
+        CHECK (kind IN ('genre', 'style', 'mood', 'custom')),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (kind, name)
+);
+
+CREATE TABLE record_tags (
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (record_id, tag_id)
+);
+
+CREATE INDEX idx_record_tags_tag_id ON record_tags(tag_id);
+
+CREATE TRIGGER update_tags_updated_at
+    AFTER UPDATE ON tags
+    FOR EACH ROW
+BEGIN
+    UPDATE tags SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_tags_updated_at;
+DROP INDEX IF EXISTS idx_record_tags_tag_id;
+DROP TABLE IF EXISTS record_tags;
+DROP TABLE IF EXISTS tags;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Cover art for a record: at most one front, back and label image. The files
+-- live on disk under the media directory; checksum is the SHA-256 of the
+-- original and doubles as its ETag.
+CREATE TABLE record_images (
+    id INTEGER PRIMARY KEY,
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('front', 'back', 'label')),
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    checksum TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (record_id, kind)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS record_images;
+-- +goose StatementEnd
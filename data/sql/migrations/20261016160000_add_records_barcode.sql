@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A UPC-A or EAN-13 barcode, stored as 13 digits (UPC-A gets a leading zero).
+-- Not unique: a collection can hold more than one copy of a pressing.
+ALTER TABLE records ADD COLUMN barcode TEXT;
+
+CREATE INDEX idx_records_barcode ON records(barcode) WHERE barcode IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_records_barcode;
+ALTER TABLE records DROP COLUMN barcode;
+-- +goose StatementEnd
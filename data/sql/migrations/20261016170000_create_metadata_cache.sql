@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Releases fetched from an online metadata provider, kept so the same release
+-- is never fetched twice. data is the release as JSON; release_id is the
+-- provider's own id.
+CREATE TABLE metadata_cache (
+    provider TEXT NOT NULL,
+    release_id TEXT NOT NULL,
+    data TEXT NOT NULL,
+    fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, release_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS metadata_cache;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The wishlist. artist_name is free text, since a want is often by an artist
+-- who isn't in the collection yet; it's resolved to an artist when the want is
+-- acquired. Prices are in cents. Priority runs from 1 (high) to 3 (low).
+-- Acquiring a want creates a record and keeps the want, linked to it.
+CREATE TABLE wants (
+    id INTEGER PRIMARY KEY,
+    artist_name TEXT NOT NULL,
+    title TEXT NOT NULL,
+    pressing TEXT,
+    catalog_number TEXT,
+    barcode TEXT,
+    max_price_cents INTEGER CHECK (max_price_cents >= 0),
+    priority INTEGER NOT NULL DEFAULT 2 CHECK (priority BETWEEN 1 AND 3),
+    notes TEXT,
+    record_id INTEGER REFERENCES records(id) ON DELETE SET NULL,
+    acquired_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_wants_acquired_at ON wants(acquired_at);
+
+CREATE TRIGGER update_wants_updated_at
+    AFTER UPDATE ON wants
+    FOR EACH ROW
+BEGIN
+    UPDATE wants SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_wants_updated_at;
+DROP INDEX IF EXISTS idx_wants_acquired_at;
+DROP TABLE IF EXISTS wants;
+-- +goose StatementEnd
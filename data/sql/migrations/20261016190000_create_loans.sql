@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The people records are lent to. Names are unique, ignoring case, so lending
+-- to "sam" finds Sam.
+CREATE TABLE borrowers (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL COLLATE NOCASE CHECK(length(name) > 0),
+    contact TEXT,
+    notes TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_borrower_name UNIQUE (name)
+);
+
+-- A record lent to a borrower. A loan is open until returned_at is set, and a
+-- record can only be on one open loan at a time. due_at is optional; a loan is
+-- overdue once it's passed. Borrowers with loans can't be deleted.
+CREATE TABLE loans (
+    id INTEGER PRIMARY KEY,
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    borrower_id INTEGER NOT NULL REFERENCES borrowers(id) ON DELETE RESTRICT,
+    lent_at DATETIME NOT NULL,
+    due_at DATETIME,
+    returned_at DATETIME,
+    notes TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_loans_open_record ON loans(record_id) WHERE returned_at IS NULL;
+CREATE INDEX idx_loans_borrower_id ON loans(borrower_id);
+CREATE INDEX idx_loans_due_at ON loans(due_at) WHERE returned_at IS NULL;
+
+CREATE TRIGGER update_borrowers_updated_at
+    AFTER UPDATE ON borrowers
+    FOR EACH ROW
+BEGIN
+    UPDATE borrowers SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER update_loans_updated_at
+    AFTER UPDATE ON loans
+    FOR EACH ROW
+BEGIN
+    UPDATE loans SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+-- Lending a record moves it to the "On loan" location, which isn't a real
+-- place; returning it moves it home. There's only ever one, added the first
+-- time a record is lent.
+ALTER TABLE locations ADD COLUMN is_loan BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX idx_locations_is_loan ON locations(is_loan) WHERE is_loan = 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM locations WHERE is_loan = 1;
+DROP INDEX IF EXISTS idx_locations_is_loan;
+ALTER TABLE locations DROP COLUMN is_loan;
+
+DROP TRIGGER IF EXISTS update_loans_updated_at;
+DROP TRIGGER IF EXISTS update_borrowers_updated_at;
+DROP INDEX IF EXISTS idx_loans_due_at;
+DROP INDEX IF EXISTS idx_loans_borrower_id;
+DROP INDEX IF EXISTS idx_loans_open_record;
+DROP TABLE IF EXISTS loans;
+DROP TABLE IF EXISTS borrowers;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+-- What was paid for a record, and where. A record has at most one purchase;
+-- amounts are in cents of an ISO 4217 currency.
+CREATE TABLE record_purchases (
+    record_id INTEGER PRIMARY KEY REFERENCES records(id) ON DELETE CASCADE,
+    price_cents INTEGER CHECK(price_cents >= 0),
+    currency TEXT NOT NULL DEFAULT 'USD',
+    purchased_at DATETIME,
+    seller TEXT,
+    shop TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Estimates of what a record is worth over time, e.g. from a price guide.
+-- The latest estimate is the record's current value.
+CREATE TABLE record_values (
+    id INTEGER PRIMARY KEY,
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    valued_at DATETIME NOT NULL,
+    amount_cents INTEGER NOT NULL CHECK(amount_cents >= 0),
+    currency TEXT NOT NULL DEFAULT 'USD',
+    source TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_record_values_record_id ON record_values(record_id, valued_at);
+
+CREATE TRIGGER update_record_purchases_updated_at
+    AFTER UPDATE ON record_purchases
+    FOR EACH ROW
+BEGIN
+    UPDATE record_purchases SET updated_at = CURRENT_TIMESTAMP WHERE record_id = NEW.record_id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_record_purchases_updated_at;
+DROP INDEX IF EXISTS idx_record_values_record_id;
+DROP TABLE IF EXISTS record_values;
+DROP TABLE IF EXISTS record_purchases;
+-- +goose StatementEnd
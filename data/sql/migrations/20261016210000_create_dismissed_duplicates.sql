@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Pairs of records the duplicate finder flagged that someone reviewed and
+-- said are different records, so they aren't suggested again. Each pair is
+-- stored once with the lower id first.
+CREATE TABLE dismissed_duplicates (
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    duplicate_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    dismissed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (record_id, duplicate_id),
+    CHECK(record_id < duplicate_id)
+);
+
+CREATE INDEX idx_dismissed_duplicates_duplicate_id ON dismissed_duplicates(duplicate_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_dismissed_duplicates_duplicate_id;
+DROP TABLE IF EXISTS dismissed_duplicates;
+-- +goose StatementEnd
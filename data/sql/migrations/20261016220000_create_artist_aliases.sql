@@ -0,0 +1,65 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Other names an artist is known by, e.g. "Beatles" and "Beatles, The" for
+-- "The Beatles". An alias belongs to one artist and matches ignoring case.
+CREATE TABLE artist_aliases (
+    id INTEGER PRIMARY KEY,
+    artist_id INTEGER NOT NULL REFERENCES artists(id) ON DELETE CASCADE,
+    name TEXT NOT NULL COLLATE NOCASE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_artist_alias UNIQUE (name)
+);
+
+CREATE INDEX idx_artist_aliases_artist_id ON artist_aliases(artist_id);
+
+-- An artist is found by any of their aliases. Artist rows in the search
+-- index don't use notes, so it holds the aliases.
+CREATE TRIGGER search_index_artist_aliases_insert
+    AFTER INSERT ON artist_aliases
+    FOR EACH ROW
+BEGIN
+    UPDATE search_index
+    SET notes = (SELECT group_concat(name, ' ') FROM artist_aliases WHERE artist_id = NEW.artist_id)
+    WHERE entity_type = 'artist' AND entity_id = NEW.artist_id;
+END;
+
+CREATE TRIGGER search_index_artist_aliases_update
+    AFTER UPDATE ON artist_aliases
+    FOR EACH ROW
+BEGIN
+    UPDATE search_index
+    SET notes = (SELECT group_concat(name, ' ') FROM artist_aliases WHERE artist_id = search_index.entity_id)
+    WHERE entity_type = 'artist' AND entity_id IN (OLD.artist_id, NEW.artist_id);
+END;
+
+CREATE TRIGGER search_index_artist_aliases_delete
+    AFTER DELETE ON artist_aliases
+    FOR EACH ROW
+BEGIN
+    UPDATE search_index
+    SET notes = (SELECT group_concat(name, ' ') FROM artist_aliases WHERE artist_id = OLD.artist_id)
+    WHERE entity_type = 'artist' AND entity_id = OLD.artist_id;
+END;
+
+-- Credits moved between artists or records, e.g. by a merge, keep the
+-- records' artist names in the index current
+CREATE TRIGGER search_index_record_artists_update
+    AFTER UPDATE ON record_artists
+    FOR EACH ROW
+BEGIN
+    UPDATE search_index
+    SET artist_name = (SELECT group_concat(a.name, ' ') FROM record_artists ra
+                       JOIN artists a ON ra.artist_id = a.id WHERE ra.record_id = search_index.entity_id)
+    WHERE entity_type = 'record' AND entity_id IN (OLD.record_id, NEW.record_id);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS search_index_record_artists_update;
+DROP TRIGGER IF EXISTS search_index_artist_aliases_delete;
+DROP TRIGGER IF EXISTS search_index_artist_aliases_update;
+DROP TRIGGER IF EXISTS search_index_artist_aliases_insert;
+DROP INDEX IF EXISTS idx_artist_aliases_artist_id;
+DROP TABLE IF EXISTS artist_aliases;
+-- +goose StatementEnd
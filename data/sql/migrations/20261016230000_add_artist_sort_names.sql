@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The name artists are alphabetized by: "Rolling Stones, The" for "The
+-- Rolling Stones" and "Olafur Arnalds" for "Ólafur Arnalds". It's derived
+-- from the name unless sort_name_manual says it was set by hand. Existing
+-- artists are filled in by the next migration, which needs Go to fold
+-- diacritics; new ones start with their name until the app derives it.
+ALTER TABLE artists ADD COLUMN sort_name TEXT NOT NULL DEFAULT '' COLLATE NOCASE;
+ALTER TABLE artists ADD COLUMN sort_name_manual BOOLEAN NOT NULL DEFAULT 0;
+
+UPDATE artists SET sort_name = name;
+
+CREATE INDEX idx_artists_sort_name ON artists(sort_name);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_artists_sort_name;
+ALTER TABLE artists DROP COLUMN sort_name_manual;
+ALTER TABLE artists DROP COLUMN sort_name;
+-- +goose StatementEnd
@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode"
+
+	"github.com/pressly/goose/v3"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Deriving sort names folds diacritics, which SQLite can't do, so existing
+// artists are filled in from Go. Later artists get theirs from the app.
+func init() {
+	goose.AddMigrationContext(upBackfillArtistSortNames, downBackfillArtistSortNames)
+}
+
+func upBackfillArtistSortNames(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, name FROM artists WHERE NOT sort_name_manual`)
+	if err != nil {
+		return err
+	}
+	names := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		names[id] = name
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, name := range names {
+		if _, err := tx.ExecContext(ctx, `UPDATE artists SET sort_name = ? WHERE id = ?`, backfillSortName(name), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downBackfillArtistSortNames(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `UPDATE artists SET sort_name = name WHERE NOT sort_name_manual`)
+	return err
+}
+
+// The derivation below is a copy of store.ArtistSortName as it was when this
+// migration was written. It's frozen so that changing the app's rules later
+// doesn't change what this migration does to a fresh database.
+
+var backfillArticles = map[string]bool{
+	"the": true, "a": true, "an": true,
+	"le": true, "la": true, "les": true,
+	"el": true, "los": true, "las": true,
+	"der": true, "die": true, "das": true,
+	"il": true, "gli": true,
+	"het": true,
+	"os":  true,
+}
+
+var backfillElisions = []string{"l'", "l’"}
+
+var backfillLetters = strings.NewReplacer(
+	"ß", "ss", "Æ", "AE", "æ", "ae", "Œ", "OE", "œ", "oe",
+	"Ø", "O", "ø", "o", "Ł", "L", "ł", "l", "Đ", "D", "đ", "d",
+	"Þ", "Th", "þ", "th", "ı", "i",
+)
+
+func backfillSortName(name string) string {
+	decomposed := norm.NFD.String(backfillLetters.Replace(name))
+	folded := norm.NFC.String(strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, decomposed))
+	name = strings.Join(strings.Fields(folded), " ")
+
+	first, rest, ok := strings.Cut(name, " ")
+	if ok && backfillArticles[strings.ToLower(first)] {
+		return rest + ", " + first
+	}
+
+	lower := strings.ToLower(name)
+	for _, elision := range backfillElisions {
+		if strings.HasPrefix(lower, elision) && len(name) > len(elision) {
+			return name[len(elision):] + ", " + name[:len(elision)]
+		}
+	}
+	return name
+}
@@ -0,0 +1,64 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Locations nest: "Living room / Kallax / Row 2 / Cube 3" is a cube in a row
+-- of a shelf unit in a room. Deleting a location moves the locations inside
+-- it up a level rather than orphaning them; records there are left without a
+-- location, as before.
+ALTER TABLE locations ADD COLUMN parent_id INTEGER REFERENCES locations(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_locations_parent_id ON locations(parent_id);
+
+-- Every location paired with itself and each location under it, however
+-- deep, for rolling counts up and filtering by a location and what's in it
+CREATE VIEW location_closure (ancestor_id, location_id) AS
+WITH RECURSIVE closure (ancestor_id, location_id) AS (
+    SELECT id, id FROM locations
+    UNION ALL
+    SELECT closure.ancestor_id, l.id
+    FROM locations l
+    JOIN closure ON l.parent_id = closure.location_id
+)
+SELECT ancestor_id, location_id FROM closure;
+
+-- Each location's full path from the top, e.g. "Living room / Kallax"
+CREATE VIEW location_paths (id, path, depth) AS
+WITH RECURSIVE paths (id, path, depth) AS (
+    SELECT id, name, 0 FROM locations WHERE parent_id IS NULL
+    UNION ALL
+    SELECT l.id, paths.path || ' / ' || l.name, paths.depth + 1
+    FROM locations l
+    JOIN paths ON l.parent_id = paths.id
+)
+SELECT id, path, depth FROM paths;
+
+-- A location can't be inside itself, directly or further down. The app
+-- checks first; this keeps the tree a tree whatever writes to it.
+CREATE TRIGGER locations_prevent_cycle
+    BEFORE UPDATE OF parent_id ON locations
+    FOR EACH ROW
+    WHEN NEW.parent_id IS NOT NULL
+BEGIN
+    SELECT RAISE(ABORT, 'location cannot be inside itself')
+    WHERE EXISTS (
+        SELECT 1 FROM location_closure
+        WHERE ancestor_id = NEW.id AND location_id = NEW.parent_id
+    );
+END;
+
+CREATE TRIGGER locations_delete_reparent
+    BEFORE DELETE ON locations
+    FOR EACH ROW
+BEGIN
+    UPDATE locations SET parent_id = OLD.parent_id WHERE parent_id = OLD.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS locations_delete_reparent;
+DROP TRIGGER IF EXISTS locations_prevent_cycle;
+DROP VIEW IF EXISTS location_paths;
+DROP VIEW IF EXISTS location_closure;
+DROP INDEX IF EXISTS idx_locations_parent_id;
+ALTER TABLE locations DROP COLUMN parent_id;
+-- +goose StatementEnd
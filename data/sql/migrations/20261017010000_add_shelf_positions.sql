@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Records stand in order on their shelf: shelf_position orders the records
+-- directly in a location. Positions only have to sort, so gaps left by
+-- records moving out are fine. A location's capacity is how many records fit
+-- in it directly; NULL is no limit.
+ALTER TABLE records ADD COLUMN shelf_position INTEGER;
+ALTER TABLE locations ADD COLUMN capacity INTEGER CHECK (capacity IS NULL OR capacity > 0);
+
+CREATE INDEX idx_records_shelf ON records(current_location_id, shelf_position);
+
+-- Records already put away are shelved alphabetically by artist, then title.
+-- Suspend the updated_at trigger so shelving them doesn't count as an edit.
+DROP TRIGGER IF EXISTS update_records_updated_at;
+
+UPDATE records
+SET shelf_position = (
+    SELECT shelved.position
+    FROM (
+        SELECT r.id, ROW_NUMBER() OVER (
+            PARTITION BY r.current_location_id
+            ORDER BY COALESCE(a.sort_name, '') COLLATE NOCASE, r.title COLLATE NOCASE, r.id
+        ) AS position
+        FROM records r
+        LEFT JOIN artists a ON a.id = r.artist_id
+        WHERE r.current_location_id IS NOT NULL
+    ) shelved
+    WHERE shelved.id = records.id
+)
+WHERE current_location_id IS NOT NULL;
+
+CREATE TRIGGER update_records_updated_at
+    AFTER UPDATE ON records
+    FOR EACH ROW
+BEGIN
+    UPDATE records SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_records_shelf;
+ALTER TABLE locations DROP COLUMN capacity;
+ALTER TABLE records DROP COLUMN shelf_position;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every change of a record's current location: where it came from, where it
+-- went, who moved it and when. A location that's since been deleted reads as
+-- NULL, the same as nowhere. moved_at is NULL where it isn't known.
+CREATE TABLE record_movements (
+    id INTEGER PRIMARY KEY,
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    from_location_id INTEGER REFERENCES locations(id) ON DELETE SET NULL,
+    to_location_id INTEGER REFERENCES locations(id) ON DELETE SET NULL,
+    note TEXT,
+    user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    moved_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_record_movements_record_id ON record_movements(record_id, moved_at);
+
+-- Records already somewhere start their history there. When they were put
+-- there wasn't kept, so the best there is is when they were added.
+INSERT INTO record_movements (record_id, to_location_id, note, moved_at)
+SELECT id, current_location_id, 'Here before movement history was kept', created_at
+FROM records
+WHERE current_location_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_record_movements_record_id;
+DROP TABLE IF EXISTS record_movements;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- name: CreateArtistAlias :one
+INSERT INTO artist_aliases (artist_id, name)
+VALUES (?, ?)
+RETURNING id, artist_id, name, created_at;
+
+-- name: ListArtistAliases :many
+SELECT id, artist_id, name, created_at
+FROM artist_aliases
+WHERE artist_id = ?
+ORDER BY name;
+
+-- name: DeleteArtistAlias :execrows
+DELETE FROM artist_aliases
+WHERE id = ? AND artist_id = ?;
+
+-- name: MoveArtistAliases :exec
+UPDATE artist_aliases
+SET artist_id = sqlc.arg(into_id)
+WHERE artist_id = sqlc.arg(from_id);
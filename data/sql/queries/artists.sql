@@ -1,40 +1,52 @@
 -- name: CreateArtist :one
-INSERT INTO artists (name)
-VALUES (?)
-RETURNING id, name, created_at, updated_at;
+-- The sort name is derived from the name with ArtistSortName
+INSERT INTO artists (name, sort_name)
+VALUES (?, ?)
+RETURNING id, name, created_at, updated_at, sort_name, sort_name_manual;
 
 -- name: GetArtist :one
-SELECT id, name, created_at, updated_at
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
 WHERE id = ?;
 
 -- name: GetArtistByName :one
-SELECT id, name, created_at, updated_at
+-- The artist with the name, or else the artist with an alias of that name
+-- (ignoring case)
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
-WHERE name = ?;
+WHERE name = sqlc.arg(name)
+   OR id = (SELECT artist_id FROM artist_aliases WHERE artist_aliases.name = sqlc.arg(name))
+ORDER BY name = sqlc.arg(name) DESC
+LIMIT 1;
 
 -- name: ListArtists :many
-SELECT id, name, created_at, updated_at
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
-ORDER BY name ASC;
+ORDER BY sort_name ASC, name ASC;
 
 -- name: ListArtistsWithPagination :many
-SELECT id, name, created_at, updated_at
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
-ORDER BY name ASC
+ORDER BY sort_name ASC, name ASC
 LIMIT ? OFFSET ?;
 
 -- name: SearchArtistsByName :many
-SELECT id, name, created_at, updated_at
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
 WHERE name LIKE '%' || ? || '%'
-ORDER BY name ASC;
+ORDER BY sort_name ASC, name ASC;
 
 -- name: UpdateArtist :one
 UPDATE artists
 SET name = ?
 WHERE id = ?
-RETURNING id, name, created_at, updated_at;
+RETURNING id, name, created_at, updated_at, sort_name, sort_name_manual;
+
+-- name: UpdateArtistSortName :one
+UPDATE artists
+SET sort_name = ?, sort_name_manual = ?
+WHERE id = ?
+RETURNING id, name, created_at, updated_at, sort_name, sort_name_manual;
 
 -- name: DeleteArtist :exec
 DELETE FROM artists
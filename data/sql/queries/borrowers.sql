@@ -0,0 +1,31 @@
+-- name: CreateBorrower :one
+INSERT INTO borrowers (name, contact, notes)
+VALUES (?, ?, ?)
+RETURNING id, name, contact, notes, created_at, updated_at;
+
+-- name: GetBorrower :one
+SELECT id, name, contact, notes, created_at, updated_at
+FROM borrowers
+WHERE id = ?;
+
+-- name: GetBorrowerByName :one
+SELECT id, name, contact, notes, created_at, updated_at
+FROM borrowers
+WHERE name = ?;
+
+-- name: ListBorrowers :many
+-- Every borrower with how many records they have now
+SELECT b.id, b.name, b.contact, b.notes, b.created_at, b.updated_at,
+       (SELECT COUNT(*) FROM loans l WHERE l.borrower_id = b.id AND l.returned_at IS NULL) AS open_loans
+FROM borrowers b
+ORDER BY b.name ASC;
+
+-- name: UpdateBorrower :one
+UPDATE borrowers
+SET name = ?, contact = ?, notes = ?
+WHERE id = ?
+RETURNING id, name, contact, notes, created_at, updated_at;
+
+-- name: DeleteBorrower :execrows
+DELETE FROM borrowers
+WHERE id = ?;
@@ -0,0 +1,22 @@
+-- name: CreateConditionHistory :one
+INSERT INTO condition_history (
+    record_id, media_grade, sleeve_grade,
+    previous_media_grade, previous_sleeve_grade, note, user_id
+)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING id, record_id, media_grade, sleeve_grade,
+          previous_media_grade, previous_sleeve_grade, note, user_id, graded_at;
+
+-- name: ListConditionHistoryByRecord :many
+SELECT h.id, h.record_id, h.media_grade, h.sleeve_grade,
+       h.previous_media_grade, h.previous_sleeve_grade, h.note, h.user_id, h.graded_at,
+       u.username
+FROM condition_history h
+LEFT JOIN users u ON h.user_id = u.id
+WHERE h.record_id = ?
+ORDER BY h.graded_at DESC, h.id DESC;
+
+-- name: MoveConditionHistory :exec
+UPDATE condition_history
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id);
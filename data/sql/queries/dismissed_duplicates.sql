@@ -0,0 +1,9 @@
+-- name: DismissDuplicate :exec
+-- Dismissing a pair twice is a no-op
+INSERT OR IGNORE INTO dismissed_duplicates (record_id, duplicate_id)
+VALUES (?, ?);
+
+-- name: ListDismissedDuplicates :many
+SELECT record_id, duplicate_id, dismissed_at
+FROM dismissed_duplicates
+ORDER BY record_id, duplicate_id;
@@ -0,0 +1,4 @@
+-- name: ListGrades :many
+SELECT code, name, rank
+FROM grades
+ORDER BY rank DESC;
@@ -0,0 +1,56 @@
+-- name: CreateLoan :one
+INSERT INTO loans (record_id, borrower_id, lent_at, due_at, notes)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, record_id, borrower_id, lent_at, due_at, returned_at, notes, created_at, updated_at;
+
+-- name: GetOpenLoanByRecord :one
+SELECT l.id, l.record_id, l.borrower_id, l.lent_at, l.due_at, l.returned_at, l.notes,
+       l.created_at, l.updated_at, b.name AS borrower_name
+FROM loans l
+JOIN borrowers b ON l.borrower_id = b.id
+WHERE l.record_id = ? AND l.returned_at IS NULL;
+
+-- name: ListLoansByRecord :many
+-- A record's loans, newest first
+SELECT l.id, l.record_id, l.borrower_id, l.lent_at, l.due_at, l.returned_at, l.notes,
+       l.created_at, l.updated_at, b.name AS borrower_name
+FROM loans l
+JOIN borrowers b ON l.borrower_id = b.id
+WHERE l.record_id = ?
+ORDER BY l.lent_at DESC, l.id DESC;
+
+-- name: ListOpenLoans :many
+-- Records out on loan, soonest due first; loans without a due date come last
+SELECT l.id, l.record_id, l.borrower_id, l.lent_at, l.due_at, l.notes,
+       b.name AS borrower_name, r.title, a.name AS artist_name
+FROM loans l
+JOIN borrowers b ON l.borrower_id = b.id
+JOIN records r ON l.record_id = r.id
+LEFT JOIN artists a ON r.artist_id = a.id
+WHERE l.returned_at IS NULL
+ORDER BY l.due_at IS NULL, l.due_at ASC, l.lent_at ASC;
+
+-- name: ListOverdueLoans :many
+-- Open loans due before the given time, most overdue first
+SELECT l.id, l.record_id, l.borrower_id, l.lent_at, l.due_at, l.notes,
+       b.name AS borrower_name, r.title, a.name AS artist_name
+FROM loans l
+JOIN borrowers b ON l.borrower_id = b.id
+JOIN records r ON l.record_id = r.id
+LEFT JOIN artists a ON r.artist_id = a.id
+WHERE l.returned_at IS NULL AND l.due_at < sqlc.arg(now)
+ORDER BY l.due_at ASC, l.lent_at ASC;
+
+-- name: CountLoansByBorrower :one
+SELECT COUNT(*) FROM loans WHERE borrower_id = ?;
+
+-- name: ReturnLoan :one
+UPDATE loans
+SET returned_at = ?
+WHERE id = ? AND returned_at IS NULL
+RETURNING id, record_id, borrower_id, lent_at, due_at, returned_at, notes, created_at, updated_at;
+
+-- name: MoveLoans :exec
+UPDATE loans
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id);
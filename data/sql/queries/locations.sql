@@ -1,52 +1,107 @@
 -- name: CreateLocation :one
-INSERT INTO locations (name, description, is_default)
-VALUES (?, ?, ?)
-RETURNING id, name, description, is_default, created_at, updated_at;
+INSERT INTO locations (name, description, is_default, parent_id, capacity)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity;
 
 -- name: GetLocation :one
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 WHERE id = ?;
 
 -- name: GetLocationByName :one
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 WHERE name = ?;
 
+-- name: GetChildLocationByName :one
+-- The location with the name directly inside the parent, or at the top level
+-- when parent_id is null
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
+FROM locations
+WHERE name = sqlc.arg(name) AND parent_id IS sqlc.narg(parent_id)
+ORDER BY id
+LIMIT 1;
+
+-- name: GetLocationPath :one
+-- A location's full path from the top, e.g. "Living room / Kallax"
+SELECT path FROM location_paths
+WHERE id = ?;
+
+-- name: IsLocationWithin :one
+-- Whether the location is the ancestor or somewhere inside it
+SELECT EXISTS (
+    SELECT 1 FROM location_closure
+    WHERE ancestor_id = sqlc.arg(ancestor_id) AND location_id = sqlc.arg(location_id)
+);
+
+-- name: GetLoanLocation :one
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
+FROM locations
+WHERE is_loan = 1;
+
+-- name: CreateLoanLocation :one
+-- Adds the "On loan" location that records are moved to while they're lent
+INSERT INTO locations (name, description, is_default, is_loan)
+VALUES ('On loan', 'Records lent out to borrowers', 0, 1)
+RETURNING id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity;
+
 -- name: GetDefaultLocation :one
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 WHERE is_default = 1
 LIMIT 1;
 
 -- name: ListLocations :many
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 ORDER BY name ASC;
 
+-- name: ListLocationsByName :many
+-- Up to two locations with the name, top-level ones first: enough to tell
+-- whether a bare name is ambiguous
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
+FROM locations
+WHERE name = ?
+ORDER BY parent_id IS NOT NULL, id
+LIMIT 2;
+
+-- name: ListLocationTree :many
+-- Every location with its full path, the records in it and the records in it
+-- or anywhere inside it. Children are put under their parents by the caller.
+SELECT l.id, l.name, l.description, l.is_default, l.is_loan, l.parent_id, l.capacity,
+       p.path,
+       COUNT(CASE WHEN r.current_location_id = l.id THEN 1 END) AS record_count,
+       COUNT(r.id) AS total_record_count
+FROM locations l
+JOIN location_paths p ON p.id = l.id
+LEFT JOIN location_closure c ON c.ancestor_id = l.id
+LEFT JOIN records r ON r.current_location_id = c.location_id
+GROUP BY l.id
+ORDER BY l.name COLLATE NOCASE, l.id;
+
 -- name: ListLocationsWithPagination :many
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 ORDER BY name ASC
 LIMIT ? OFFSET ?;
 
 -- name: SearchLocationsByName :many
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 WHERE name LIKE '%' || ? || '%'
 ORDER BY name ASC;
 
 -- name: UpdateLocation :one
 UPDATE locations
-SET name = ?, description = ?, is_default = ?
+SET name = ?, description = ?, is_default = ?, parent_id = ?, capacity = ?
 WHERE id = ?
-RETURNING id, name, description, is_default, created_at, updated_at;
+RETURNING id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity;
 
 -- name: UpdateLocationName :one
 UPDATE locations
 SET name = ?
 WHERE id = ?
-RETURNING id, name, description, is_default, created_at, updated_at;
+RETURNING id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity;
 
 -- name: SetDefaultLocation :exec
 UPDATE locations
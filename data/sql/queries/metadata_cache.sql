@@ -0,0 +1,10 @@
+-- name: GetCachedRelease :one
+SELECT * FROM metadata_cache
+WHERE provider = ? AND release_id = ?;
+
+-- name: UpsertCachedRelease :exec
+INSERT INTO metadata_cache (provider, release_id, data)
+VALUES (?, ?, ?)
+ON CONFLICT (provider, release_id) DO UPDATE SET
+    data = excluded.data,
+    fetched_at = CURRENT_TIMESTAMP;
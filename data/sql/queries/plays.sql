@@ -0,0 +1,36 @@
+-- name: CreatePlay :one
+INSERT INTO plays (record_id, user_id, played_at, side, notes)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, record_id, user_id, played_at, side, notes, created_at, updated_at;
+
+-- name: GetPlay :one
+SELECT id, record_id, user_id, played_at, side, notes, created_at, updated_at
+FROM plays
+WHERE id = ?;
+
+-- name: ListPlaysByRecord :many
+SELECT id, record_id, user_id, played_at, side, notes, created_at, updated_at
+FROM plays
+WHERE record_id = ?
+ORDER BY played_at DESC, id DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountPlaysByRecord :one
+SELECT COUNT(*) FROM plays WHERE record_id = ?;
+
+-- name: UpdatePlay :one
+UPDATE plays
+SET played_at = ?, side = ?, notes = ?
+WHERE id = ?
+RETURNING id, record_id, user_id, played_at, side, notes, created_at, updated_at;
+
+-- name: DeletePlay :exec
+DELETE FROM plays
+WHERE id = ?;
+
+-- name: MovePlays :exec
+-- Moves a record's play history onto another record; the play count
+-- triggers recount both
+UPDATE plays
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id);
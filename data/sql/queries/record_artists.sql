@@ -0,0 +1,63 @@
+-- name: CreateRecordArtist :one
+INSERT INTO record_artists (record_id, artist_id, role, position)
+VALUES (?, ?, ?, ?)
+RETURNING record_id, artist_id, role, position, created_at;
+
+-- name: GetNextRecordArtistPosition :one
+SELECT CAST(COALESCE(MAX(position) + 1, 0) AS INTEGER) AS position
+FROM record_artists
+WHERE record_id = ?;
+
+-- name: ListRecordArtists :many
+-- Credits in display order, with the artist's name
+SELECT ra.record_id, ra.artist_id, ra.role, ra.position, ra.created_at,
+       a.name as artist_name
+FROM record_artists ra
+JOIN artists a ON ra.artist_id = a.id
+WHERE ra.record_id = ?
+ORDER BY ra.position, ra.rowid;
+
+-- name: ListArtistCredits :many
+-- Every record crediting the artist, grouped by role and with the record's
+-- lead artist so "featuring" credits can say whose record it is
+SELECT ra.role, r.id, r.title, r.album_title, r.release_year,
+       r.media_grade, r.sleeve_grade,
+       la.id as lead_artist_id, la.name as lead_artist_name
+FROM record_artists ra
+JOIN records r ON ra.record_id = r.id
+LEFT JOIN artists la ON r.artist_id = la.id
+WHERE ra.artist_id = ?
+ORDER BY CASE ra.role
+             WHEN 'primary' THEN 0
+             WHEN 'featuring' THEN 1
+             WHEN 'producer' THEN 2
+             ELSE 3
+         END,
+         r.release_year, r.title;
+
+-- name: DeleteRecordArtist :execrows
+DELETE FROM record_artists
+WHERE record_id = ? AND artist_id = ? AND role = ?;
+
+-- name: DeleteRecordArtists :exec
+DELETE FROM record_artists
+WHERE record_id = ?;
+
+-- name: MergeRecordArtists :exec
+-- Moves a record's credits onto another record after its own, shifted by
+-- position_offset so the other record's lead artist stays first. Credits
+-- both records have are skipped and go when the old record is deleted.
+UPDATE OR IGNORE record_artists
+SET record_id = sqlc.arg(into_id), position = position + sqlc.arg(position_offset)
+WHERE record_id = sqlc.arg(from_id);
+
+-- name: MoveArtistCredits :exec
+-- Credits an artist's records to another artist. Records crediting both in
+-- the same role are skipped here; see DeleteArtistCredits.
+UPDATE OR IGNORE record_artists
+SET artist_id = sqlc.arg(into_id)
+WHERE artist_id = sqlc.arg(from_id);
+
+-- name: DeleteArtistCredits :exec
+DELETE FROM record_artists
+WHERE artist_id = ?;
@@ -0,0 +1,32 @@
+-- name: DeleteRecordImage :execrows
+DELETE FROM record_images
+WHERE record_id = ? AND kind = ?;
+
+-- name: GetRecordImage :one
+SELECT * FROM record_images
+WHERE record_id = ? AND kind = ?;
+
+-- name: ListRecordImages :many
+SELECT * FROM record_images
+WHERE record_id = ?
+ORDER BY CASE kind WHEN 'front' THEN 0 WHEN 'back' THEN 1 ELSE 2 END;
+
+-- name: UpsertRecordImage :one
+-- Replacing an image keeps its row and created_at
+INSERT INTO record_images (record_id, kind, content_type, width, height, size_bytes, checksum)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (record_id, kind) DO UPDATE SET
+    content_type = excluded.content_type,
+    width = excluded.width,
+    height = excluded.height,
+    size_bytes = excluded.size_bytes,
+    checksum = excluded.checksum,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: MergeRecordImages :exec
+-- Moves a record's images onto another record for the kinds it has no image
+-- of; the rest go when the old record is deleted
+UPDATE OR IGNORE record_images
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id);
@@ -0,0 +1,20 @@
+-- name: CreateRecordMovement :one
+INSERT INTO record_movements (record_id, from_location_id, to_location_id, note, user_id)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, record_id, from_location_id, to_location_id, note, user_id, moved_at;
+
+-- name: ListRecordMovements :many
+-- A record's movements, latest first, with the locations' full paths
+SELECT m.id, m.record_id, m.from_location_id, m.to_location_id, m.note, m.user_id, m.moved_at,
+       fl.path AS from_location_name, tl.path AS to_location_name, u.username
+FROM record_movements m
+LEFT JOIN location_paths fl ON m.from_location_id = fl.id
+LEFT JOIN location_paths tl ON m.to_location_id = tl.id
+LEFT JOIN users u ON m.user_id = u.id
+WHERE m.record_id = ?
+ORDER BY m.moved_at DESC, m.id DESC;
+
+-- name: MoveRecordMovements :exec
+UPDATE record_movements
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id);
@@ -0,0 +1,29 @@
+-- name: GetRecordPurchase :one
+SELECT record_id, price_cents, currency, purchased_at, seller, shop,
+       created_at, updated_at
+FROM record_purchases
+WHERE record_id = ?;
+
+-- name: UpsertRecordPurchase :one
+-- Sets a record's purchase, replacing any it had
+INSERT INTO record_purchases (record_id, price_cents, currency, purchased_at, seller, shop)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (record_id) DO UPDATE
+SET price_cents = excluded.price_cents,
+    currency = excluded.currency,
+    purchased_at = excluded.purchased_at,
+    seller = excluded.seller,
+    shop = excluded.shop
+RETURNING record_id, price_cents, currency, purchased_at, seller, shop,
+          created_at, updated_at;
+
+-- name: DeleteRecordPurchase :execrows
+DELETE FROM record_purchases
+WHERE record_id = ?;
+
+-- name: MergeRecordPurchase :exec
+-- Moves a record's purchase onto another record unless that record already
+-- has one; a purchase left behind goes when its record is deleted
+UPDATE OR IGNORE record_purchases
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id);
@@ -0,0 +1,20 @@
+-- name: CreateRecordValue :one
+INSERT INTO record_values (record_id, valued_at, amount_cents, currency, source)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, record_id, valued_at, amount_cents, currency, source, created_at;
+
+-- name: ListRecordValues :many
+-- A record's value estimates, newest first; the first is its current value
+SELECT id, record_id, valued_at, amount_cents, currency, source, created_at
+FROM record_values
+WHERE record_id = ?
+ORDER BY valued_at DESC, id DESC;
+
+-- name: DeleteRecordValue :execrows
+DELETE FROM record_values
+WHERE id = ? AND record_id = ?;
+
+-- name: MoveRecordValues :exec
+UPDATE record_values
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id);
@@ -2,61 +2,128 @@
 INSERT INTO records (
     title, artist_id, album_title, release_year, 
     current_location_id, home_location_id, catalog_number, 
-    condition, notes, play_count
+    media_grade, sleeve_grade, notes, play_count, barcode
 )
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING id, title, artist_id, album_title, release_year, 
           current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at;
+          notes, last_played_at, play_count, 
+          created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position;
 
 -- name: GetRecord :one
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 WHERE id = ?;
 
 -- name: GetRecordWithDetails :one
 SELECT r.id, r.title, r.album_title, r.release_year, 
-       r.catalog_number, r.condition, r.notes, 
+       r.catalog_number, r.media_grade, r.sleeve_grade, r.notes, 
        r.last_played_at, r.play_count, r.created_at, r.updated_at,
        a.id as artist_id, a.name as artist_name,
-       cl.id as current_location_id, cl.name as current_location_name,
-       hl.id as home_location_id, hl.name as home_location_name
+       cl.id as current_location_id, cl.path as current_location_name,
+       hl.id as home_location_id, hl.path as home_location_name,
+       r.barcode
 FROM records r
 LEFT JOIN artists a ON r.artist_id = a.id
-LEFT JOIN locations cl ON r.current_location_id = cl.id
-LEFT JOIN locations hl ON r.home_location_id = hl.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+LEFT JOIN location_paths hl ON r.home_location_id = hl.id
 WHERE r.id = ?;
 
 -- name: ListRecords :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 ORDER BY title ASC;
 
 -- name: ListRecordsWithDetails :many
 SELECT r.id, r.title, r.album_title, r.release_year, 
-       r.catalog_number, r.condition, r.notes, 
+       r.catalog_number, r.barcode, r.media_grade, r.sleeve_grade, r.notes, 
        r.last_played_at, r.play_count, r.created_at, r.updated_at,
        a.id as artist_id, a.name as artist_name,
-       cl.id as current_location_id, cl.name as current_location_name,
-       hl.id as home_location_id, hl.name as home_location_name
+       cl.id as current_location_id, cl.path as current_location_name,
+       hl.id as home_location_id, hl.path as home_location_name,
+       (SELECT COUNT(*) FROM tracks t WHERE t.record_id = r.id) AS track_count,
+       (SELECT CAST(COALESCE(SUM(t.duration_seconds), 0) AS INTEGER) FROM tracks t WHERE t.record_id = r.id) AS running_time,
+       fi.checksum AS cover_checksum
 FROM records r
 LEFT JOIN artists a ON r.artist_id = a.id
-LEFT JOIN locations cl ON r.current_location_id = cl.id
-LEFT JOIN locations hl ON r.home_location_id = hl.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+LEFT JOIN location_paths hl ON r.home_location_id = hl.id
+LEFT JOIN record_images fi ON fi.record_id = r.id AND fi.kind = 'front'
 ORDER BY r.title ASC;
 
+-- name: ListRecordsAwayFromHome :many
+-- Records with a home location that are somewhere else, or nowhere, grouped
+-- by home so they can be put away in one trip. MovedAt is when the record
+-- last moved, if that's been logged.
+SELECT r.id, r.title, a.name AS artist_name,
+       r.current_location_id, cl.path AS current_location_name,
+       CAST(COALESCE(c.is_loan, 0) AS BOOLEAN) AS on_loan,
+       r.home_location_id, hl.path AS home_location_name,
+       m.moved_at
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id
+LEFT JOIN locations c ON r.current_location_id = c.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+JOIN location_paths hl ON r.home_location_id = hl.id
+LEFT JOIN record_movements m ON m.id = (
+    SELECT id FROM record_movements
+    WHERE record_id = r.id
+    ORDER BY moved_at DESC, id DESC
+    LIMIT 1)
+WHERE r.current_location_id IS NOT r.home_location_id
+ORDER BY hl.path, COALESCE(a.sort_name, '') COLLATE NOCASE, r.title COLLATE NOCASE, r.id;
+
+-- name: ListRecordsByBarcode :many
+-- Every copy with the barcode, and where it is
+SELECT r.id, r.title, r.album_title, r.release_year, r.catalog_number,
+       r.media_grade, r.sleeve_grade,
+       a.name AS artist_name,
+       r.current_location_id, cl.path AS current_location_name
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+WHERE r.barcode = ?
+ORDER BY r.id;
+
+-- name: FindMatchingRecord :one
+-- The first record with the title (ignoring case), artist and catalog number,
+-- for imports to spot records already in the collection
+SELECT id FROM records
+WHERE title = sqlc.arg(title) COLLATE NOCASE
+  AND artist_id IS sqlc.narg(artist_id)
+  AND COALESCE(catalog_number, '') = sqlc.arg(catalog_number) COLLATE NOCASE
+ORDER BY id
+LIMIT 1;
+
+-- name: ListRecordIDsByTitle :many
+-- Up to two records with the title (ignoring case), for imports that have
+-- nothing else to match on to tell whether it's unambiguous
+SELECT id FROM records
+WHERE title = ? COLLATE NOCASE
+ORDER BY id
+LIMIT 2;
+
+-- name: ListDuplicateCandidates :many
+-- Every record with what the duplicate finder compares and the review page
+-- shows
+SELECT r.id, r.title, r.album_title, r.release_year, r.catalog_number,
+       r.barcode, r.media_grade, r.sleeve_grade, r.notes, r.play_count,
+       r.created_at, a.name AS artist_name
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id
+ORDER BY r.id;
+
 -- name: ListRecordsWithPagination :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 ORDER BY title ASC
 LIMIT ? OFFSET ?;
@@ -64,8 +131,8 @@ LIMIT ? OFFSET ?;
 -- name: SearchRecordsByTitle :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 WHERE title LIKE '%' || ? || '%'
 ORDER BY title ASC;
@@ -73,8 +140,8 @@ ORDER BY title ASC;
 -- name: SearchRecordsByAlbum :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 WHERE album_title LIKE '%' || ? || '%'
 ORDER BY album_title ASC;
@@ -82,17 +149,17 @@ ORDER BY album_title ASC;
 -- name: GetRecordsByArtist :many
 SELECT r.id, r.title, r.artist_id, r.album_title, r.release_year, 
        r.current_location_id, r.home_location_id, r.catalog_number, 
-       r.condition, r.notes, r.last_played_at, r.play_count, 
-       r.created_at, r.updated_at
+       r.notes, r.last_played_at, r.play_count, 
+       r.created_at, r.updated_at, r.media_grade, r.sleeve_grade, r.barcode, r.shelf_position
 FROM records r
-WHERE r.artist_id = ?
+WHERE r.id IN (SELECT record_id FROM record_artists WHERE artist_id = ?)
 ORDER BY r.title ASC;
 
 -- name: GetRecordsByLocation :many
 SELECT r.id, r.title, r.artist_id, r.album_title, r.release_year, 
        r.current_location_id, r.home_location_id, r.catalog_number, 
-       r.condition, r.notes, r.last_played_at, r.play_count, 
-       r.created_at, r.updated_at
+       r.notes, r.last_played_at, r.play_count, 
+       r.created_at, r.updated_at, r.media_grade, r.sleeve_grade, r.barcode, r.shelf_position
 FROM records r
 WHERE r.current_location_id = ?
 ORDER BY r.title ASC;
@@ -100,78 +167,75 @@ ORDER BY r.title ASC;
 -- name: GetRecordsByReleaseYear :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 WHERE release_year = ?
 ORDER BY title ASC;
 
--- name: GetRecordsByCondition :many
+-- name: GetRecordsByMediaGrade :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
-WHERE condition = ?
+WHERE media_grade = ?
 ORDER BY title ASC;
 
 -- name: GetRecentlyPlayedRecords :many
-SELECT id, title, artist_id, album_title, release_year, 
-       current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
-FROM records
-WHERE last_played_at IS NOT NULL
-ORDER BY last_played_at DESC
-LIMIT ?;
+SELECT r.id, r.title, r.artist_id, r.album_title, r.release_year, 
+       r.current_location_id, r.home_location_id, r.catalog_number, 
+       r.notes, r.last_played_at, r.play_count, 
+       r.created_at, r.updated_at, r.media_grade, r.sleeve_grade, r.barcode, r.shelf_position
+FROM records r
+JOIN plays p ON p.record_id = r.id
+WHERE p.played_at >= sqlc.arg(since) AND p.played_at < sqlc.arg(until)
+GROUP BY r.id
+ORDER BY MAX(p.played_at) DESC
+LIMIT sqlc.arg(limit);
 
 -- name: GetMostPlayedRecords :many
-SELECT id, title, artist_id, album_title, release_year, 
-       current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
-FROM records
-WHERE play_count > 0
-ORDER BY play_count DESC
-LIMIT ?;
+SELECT r.id, r.title, r.artist_id, r.album_title, r.release_year, 
+       r.current_location_id, r.home_location_id, r.catalog_number, 
+       r.notes, r.last_played_at, r.play_count, 
+       r.created_at, r.updated_at, r.media_grade, r.sleeve_grade, r.barcode, r.shelf_position,
+       COUNT(p.id) AS window_play_count
+FROM records r
+JOIN plays p ON p.record_id = r.id
+WHERE p.played_at >= sqlc.arg(since) AND p.played_at < sqlc.arg(until)
+GROUP BY r.id
+ORDER BY window_play_count DESC, MAX(p.played_at) DESC
+LIMIT sqlc.arg(limit);
 
 -- name: UpdateRecord :one
 UPDATE records
 SET title = ?, artist_id = ?, album_title = ?, release_year = ?,
     current_location_id = ?, home_location_id = ?, catalog_number = ?,
-    condition = ?, notes = ?
+    media_grade = ?, sleeve_grade = ?, notes = ?, barcode = ?
 WHERE id = ?
 RETURNING id, title, artist_id, album_title, release_year, 
           current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at;
+          notes, last_played_at, play_count, 
+          created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position;
 
 -- name: UpdateRecordLocation :one
+-- Moves a record. It has no shelf position in the new location until it's given one.
 UPDATE records
-SET current_location_id = ?
+SET current_location_id = ?, shelf_position = NULL
 WHERE id = ?
 RETURNING id, title, artist_id, album_title, release_year, 
           current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at;
+          notes, last_played_at, play_count, 
+          created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position;
 
 -- name: UpdateRecordCondition :one
 UPDATE records
-SET condition = ?
-WHERE id = ?
-RETURNING id, title, artist_id, album_title, release_year, 
-          current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at;
-
--- name: RecordPlayback :one
-UPDATE records
-SET last_played_at = CURRENT_TIMESTAMP, play_count = play_count + 1
+SET media_grade = ?, sleeve_grade = ?
 WHERE id = ?
 RETURNING id, title, artist_id, album_title, release_year, 
           current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at;
+          notes, last_played_at, play_count, 
+          created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position;
 
 -- name: DeleteRecord :exec
 DELETE FROM records
@@ -181,7 +245,7 @@ WHERE id = ?;
 SELECT COUNT(*) FROM records;
 
 -- name: CountRecordsByArtist :one
-SELECT COUNT(*) FROM records WHERE artist_id = ?;
+SELECT COUNT(DISTINCT record_id) FROM record_artists WHERE artist_id = ?;
 
 -- name: CountRecordsByLocation :one
 SELECT COUNT(*) FROM records WHERE current_location_id = ?;
\ No newline at end of file
@@ -0,0 +1,38 @@
+-- name: ListShelfRecords :many
+-- The records directly in a location in shelf order. Records without a
+-- position yet go at the end in the order they were added.
+SELECT r.id, r.title, r.shelf_position, a.name AS artist_name
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id
+WHERE r.current_location_id = ?
+ORDER BY r.shelf_position IS NULL, r.shelf_position, r.id;
+
+-- name: AlphabeticalShelfPosition :one
+-- Where a record goes on a location's shelf alphabetically, by artist sort
+-- name and then title: the position of the first record there that sorts
+-- after it, or just past the last one
+SELECT CAST(COALESCE(
+           MIN(r.shelf_position),
+           (SELECT MAX(s.shelf_position) + 1 FROM records s
+            WHERE s.current_location_id = sqlc.arg(location_id) AND s.id != sqlc.arg(record_id)),
+           1) AS INTEGER) AS position
+FROM records t
+LEFT JOIN artists ta ON t.artist_id = ta.id
+JOIN records r ON r.current_location_id = sqlc.arg(location_id)
+              AND r.id != t.id AND r.shelf_position IS NOT NULL
+LEFT JOIN artists a ON r.artist_id = a.id
+WHERE t.id = sqlc.arg(record_id)
+  AND (COALESCE(a.sort_name, '') > COALESCE(ta.sort_name, '') COLLATE NOCASE
+       OR (COALESCE(a.sort_name, '') = COALESCE(ta.sort_name, '') COLLATE NOCASE
+           AND r.title > t.title COLLATE NOCASE));
+
+-- name: MakeShelfRoom :exec
+-- Moves the records at or after a position on a location's shelf along one
+UPDATE records
+SET shelf_position = shelf_position + 1
+WHERE current_location_id = sqlc.arg(location_id) AND shelf_position >= sqlc.arg(position);
+
+-- name: SetRecordShelfPosition :exec
+UPDATE records
+SET shelf_position = ?
+WHERE id = ?;
@@ -0,0 +1,82 @@
+-- name: CreateTag :one
+INSERT INTO tags (name, kind)
+VALUES (?, ?)
+RETURNING id, name, kind, created_at, updated_at;
+
+-- name: GetTag :one
+SELECT id, name, kind, created_at, updated_at
+FROM tags
+WHERE id = ?;
+
+-- name: GetTagByName :one
+SELECT id, name, kind, created_at, updated_at
+FROM tags
+WHERE kind = ? AND name = ?;
+
+-- name: FindTagByName :one
+-- A tag with the name in any kind, genres first
+SELECT id, name, kind, created_at, updated_at
+FROM tags
+WHERE name = ?
+ORDER BY CASE kind
+             WHEN 'genre' THEN 0
+             WHEN 'style' THEN 1
+             WHEN 'mood' THEN 2
+             ELSE 3
+         END
+LIMIT 1;
+
+-- name: ListTags :many
+-- Every tag with the number of records carrying it
+SELECT t.id, t.name, t.kind, t.created_at, t.updated_at,
+       (SELECT COUNT(*) FROM record_tags rt WHERE rt.tag_id = t.id) AS record_count
+FROM tags t
+ORDER BY t.kind, t.name;
+
+-- name: SearchTagsByPrefix :many
+-- Autocomplete: tags whose name starts with the given text. "%" and "_" in
+-- the text are escaped so they match only themselves.
+SELECT id, name, kind, created_at, updated_at
+FROM tags
+WHERE name LIKE replace(replace(replace(sqlc.arg(prefix), '\', '\\'), '%', '\%'), '_', '\_') || '%' ESCAPE '\'
+ORDER BY name, kind
+LIMIT sqlc.arg(limit);
+
+-- name: UpdateTag :one
+UPDATE tags
+SET name = ?, kind = ?
+WHERE id = ?
+RETURNING id, name, kind, created_at, updated_at;
+
+-- name: DeleteTag :execrows
+DELETE FROM tags
+WHERE id = ?;
+
+-- name: ListTagsByRecord :many
+SELECT t.id, t.name, t.kind, t.created_at, t.updated_at
+FROM record_tags rt
+JOIN tags t ON rt.tag_id = t.id
+WHERE rt.record_id = ?
+ORDER BY t.kind, t.name;
+
+-- name: AddRecordTag :exec
+INSERT OR IGNORE INTO record_tags (record_id, tag_id)
+VALUES (?, ?);
+
+-- name: RemoveRecordTag :execrows
+DELETE FROM record_tags
+WHERE record_id = ? AND tag_id = ?;
+
+-- name: MoveRecordTags :exec
+-- Retag every record from one tag to another. Records that already carry
+-- both are skipped here and lose the old tag when it is deleted.
+UPDATE OR IGNORE record_tags
+SET tag_id = sqlc.arg(into_id)
+WHERE tag_id = sqlc.arg(from_id);
+
+-- name: MergeRecordTags :exec
+-- Moves a record's tags onto another record. Tags both records carry are
+-- skipped here and go when the old record is deleted.
+UPDATE OR IGNORE record_tags
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id);
@@ -0,0 +1,50 @@
+-- name: CreateTrack :one
+INSERT INTO tracks (record_id, side, position, title, duration_seconds, artist_id)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, record_id, side, position, title, duration_seconds, artist_id, created_at, updated_at;
+
+-- name: GetTrack :one
+SELECT id, record_id, side, position, title, duration_seconds, artist_id, created_at, updated_at
+FROM tracks
+WHERE id = ?;
+
+-- name: ListTracksByRecord :many
+-- Positions sort naturally within a side: A2 before A10
+SELECT t.id, t.record_id, t.side, t.position, t.title, t.duration_seconds, t.artist_id,
+       t.created_at, t.updated_at,
+       a.name as artist_name
+FROM tracks t
+LEFT JOIN artists a ON t.artist_id = a.id
+WHERE t.record_id = ?
+ORDER BY t.side, length(t.position), t.position, t.id;
+
+-- name: ListSideRunningTimesByRecord :many
+SELECT side, COUNT(*) AS track_count,
+       CAST(COALESCE(SUM(duration_seconds), 0) AS INTEGER) AS running_time
+FROM tracks
+WHERE record_id = ?
+GROUP BY side
+ORDER BY side;
+
+-- name: UpdateTrack :one
+UPDATE tracks
+SET side = ?, position = ?, title = ?, duration_seconds = ?, artist_id = ?
+WHERE id = ? AND record_id = ?
+RETURNING id, record_id, side, position, title, duration_seconds, artist_id, created_at, updated_at;
+
+-- name: DeleteTrack :execrows
+DELETE FROM tracks
+WHERE id = ? AND record_id = ?;
+
+-- name: MoveTracks :exec
+-- Moves a record's tracklist onto another record that has none; two
+-- tracklists are never mixed
+UPDATE tracks
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id)
+  AND NOT EXISTS (SELECT 1 FROM tracks WHERE record_id = sqlc.arg(into_id));
+
+-- name: MoveTrackArtists :exec
+UPDATE tracks
+SET artist_id = sqlc.arg(into_id)
+WHERE artist_id = sqlc.arg(from_id);
@@ -0,0 +1,77 @@
+-- name: CreateWant :one
+INSERT INTO wants (
+    artist_name, title, pressing, catalog_number, barcode,
+    max_price_cents, priority, notes
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, artist_name, title, pressing, catalog_number, barcode,
+          max_price_cents, priority, notes, record_id, acquired_at,
+          created_at, updated_at;
+
+-- name: GetWant :one
+SELECT id, artist_name, title, pressing, catalog_number, barcode,
+       max_price_cents, priority, notes, record_id, acquired_at,
+       created_at, updated_at
+FROM wants
+WHERE id = ?;
+
+-- name: ListWants :many
+-- The open wants, most wanted first
+SELECT id, artist_name, title, pressing, catalog_number, barcode,
+       max_price_cents, priority, notes, record_id, acquired_at,
+       created_at, updated_at
+FROM wants
+WHERE acquired_at IS NULL
+ORDER BY priority, artist_name COLLATE NOCASE, title COLLATE NOCASE;
+
+-- name: ListAcquiredWants :many
+-- The wants that have been found, most recent first
+SELECT id, artist_name, title, pressing, catalog_number, barcode,
+       max_price_cents, priority, notes, record_id, acquired_at,
+       created_at, updated_at
+FROM wants
+WHERE acquired_at IS NOT NULL
+ORDER BY acquired_at DESC, id DESC;
+
+-- name: ListWantMatches :many
+-- Records already in the collection that an open want matches: by barcode,
+-- or by artist and title (or album title), ignoring case
+SELECT w.id AS want_id, r.id AS record_id, r.title, a.name AS artist_name,
+       r.catalog_number, r.barcode, cl.path AS current_location_name
+FROM wants w
+JOIN records r
+  ON r.barcode = w.barcode
+  OR ((r.title = w.title COLLATE NOCASE OR r.album_title = w.title COLLATE NOCASE)
+      AND r.artist_id IN (SELECT id FROM artists WHERE name = w.artist_name COLLATE NOCASE))
+LEFT JOIN artists a ON r.artist_id = a.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+WHERE w.acquired_at IS NULL
+ORDER BY w.id, r.id;
+
+-- name: UpdateWant :one
+UPDATE wants
+SET artist_name = ?, title = ?, pressing = ?, catalog_number = ?, barcode = ?,
+    max_price_cents = ?, priority = ?, notes = ?
+WHERE id = ?
+RETURNING id, artist_name, title, pressing, catalog_number, barcode,
+          max_price_cents, priority, notes, record_id, acquired_at,
+          created_at, updated_at;
+
+-- name: MarkWantAcquired :one
+-- Links an open want to the record it became
+UPDATE wants
+SET record_id = ?, acquired_at = CURRENT_TIMESTAMP
+WHERE id = ? AND acquired_at IS NULL
+RETURNING id, artist_name, title, pressing, catalog_number, barcode,
+          max_price_cents, priority, notes, record_id, acquired_at,
+          created_at, updated_at;
+
+-- name: DeleteWant :execrows
+DELETE FROM wants
+WHERE id = ?;
+
+-- name: MoveWants :exec
+-- Points wants acquired as one record at another
+UPDATE wants
+SET record_id = sqlc.arg(into_id)
+WHERE record_id = sqlc.arg(from_id);
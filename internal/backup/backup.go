@@ -0,0 +1,248 @@
+// Package backup takes consistent snapshots of the database while the server
+// is running, bundles them with the uploaded media into a tar.gz archive, and
+// restores them.
+//
+// An archive holds the database as database.sqlite and the media directory
+// under media/, then a manifest.json listing every other file with its size
+// and SHA-256, and the database's migration version.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/dd/data/sql/migrations"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+	_ "modernc.org/sqlite"
+)
+
+// Names inside an archive
+const (
+	ManifestName = "manifest.json"
+	DatabaseName = "database.sqlite"
+	MediaPrefix  = "media/"
+)
+
+// ErrIntegrity is returned when a snapshot fails SQLite's integrity check
+var ErrIntegrity = errors.New("database failed its integrity check")
+
+// fileTime is the timestamp in archive file names
+const fileTime = "20060102T150405Z"
+
+// FileName is the name an archive taken at t is saved under
+func FileName(t time.Time) string {
+	return "dd-backup-" + t.UTC().Format(fileTime) + ".tar.gz"
+}
+
+// parseFileName returns when the archive with the name was taken, or false
+// if it isn't one
+func parseFileName(name string) (time.Time, bool) {
+	stamp, ok := strings.CutPrefix(name, "dd-backup-")
+	if !ok {
+		return time.Time{}, false
+	}
+	if stamp, ok = strings.CutSuffix(stamp, ".tar.gz"); !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(fileTime, stamp)
+	return t, err == nil
+}
+
+// Manifest describes an archive
+type Manifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	// Version is the goose migration version of the database
+	Version int64  `json:"version"`
+	Files   []File `json:"files"`
+}
+
+// File is a file in an archive
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Snapshot writes a consistent copy of the database to path, which must not
+// exist. It uses VACUUM INTO, so other connections carry on meanwhile and the
+// copy comes out compacted.
+func Snapshot(ctx context.Context, db *sql.DB, path string) error {
+	_, err := db.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
+
+// IntegrityCheck runs PRAGMA integrity_check on the database file at path
+func IntegrityCheck(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrIntegrity, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// SchemaVersions returns the migration version of the database file at path,
+// and the latest migration this build knows
+func SchemaVersions(ctx context.Context, path string) (current, latest int64, err error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer db.Close()
+
+	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
+	if err != nil {
+		return 0, 0, err
+	}
+	return provider.GetVersions(ctx)
+}
+
+// Write snapshots the database, checks the snapshot's integrity and writes it
+// to w as an archive, with every file under mediaDir. A missing media
+// directory is backed up as empty.
+func Write(ctx context.Context, db *sql.DB, mediaDir string, w io.Writer) (Manifest, error) {
+	dir, err := os.MkdirTemp("", "dd-backup-*")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	snapshot := filepath.Join(dir, DatabaseName)
+	if err := Snapshot(ctx, db, snapshot); err != nil {
+		return Manifest{}, fmt.Errorf("snapshot database: %w", err)
+	}
+	if err := IntegrityCheck(ctx, snapshot); err != nil {
+		return Manifest{}, err
+	}
+	version, _, err := SchemaVersions(ctx, snapshot)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read migration version: %w", err)
+	}
+
+	manifest := Manifest{CreatedAt: time.Now().UTC(), Version: version, Files: []File{}}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	add := func(name, path string) error {
+		file, err := addFile(tw, name, path)
+		if err != nil {
+			return fmt.Errorf("add %s: %w", name, err)
+		}
+		manifest.Files = append(manifest.Files, file)
+		return nil
+	}
+
+	if err := add(DatabaseName, snapshot); err != nil {
+		return Manifest{}, err
+	}
+	err = filepath.WalkDir(mediaDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == mediaDir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(mediaDir, path)
+		if err != nil {
+			return err
+		}
+		return add(MediaPrefix+filepath.ToSlash(rel), path)
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    ManifestName,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: manifest.CreatedAt,
+	}); err != nil {
+		return Manifest{}, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return Manifest{}, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, gz.Close()
+}
+
+// addFile writes the file at path to the archive as name and returns its
+// manifest entry. The size is taken when the file is opened, so a file
+// that grows meanwhile is cut off there rather than breaking the archive.
+func addFile(tw *tar.Writer, name, path string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return File{}, err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return File{}, err
+	}
+
+	hash := sha256.New()
+	if _, err := io.CopyN(tw, io.TeeReader(f, hash), info.Size()); err != nil {
+		return File{}, err
+	}
+	return File{Path: name, Size: info.Size(), SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
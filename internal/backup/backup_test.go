@@ -0,0 +1,219 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dukerupert/dd/data/sql/migrations"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+// setupDB creates a migrated database file with an artist in it
+func setupDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
+	if err != nil {
+		t.Fatalf("Failed to create migration provider: %v", err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO artists (name) VALUES ('Nirvana')"); err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+	return db
+}
+
+// rewrite copies an archive, passing each file through fn, which can change
+// its name or contents, or drop it by returning false
+func rewrite(t *testing.T, archive []byte, fn func(name string, data []byte) (string, []byte, bool)) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		name, data, keep := fn(hdr.Name, data)
+		if !keep {
+			continue
+		}
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data))})
+		tw.Write(data)
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+// TestWriteRestore tests that an archive restores the database and media it
+// was taken from
+func TestWriteRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db := setupDB(t, filepath.Join(dir, "live.db"))
+
+	mediaDir := filepath.Join(dir, "media")
+	cover := filepath.Join(mediaDir, "records", "1", "front")
+	if err := os.MkdirAll(filepath.Dir(cover), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cover, []byte("not really a JPEG"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Write(ctx, db, mediaDir, &buf)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if manifest.Version == 0 || len(manifest.Files) != 2 || manifest.Files[1].Path != "media/records/1/front" {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	if _, err := Verify(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// Restore over another database and media directory
+	target := filepath.Join(dir, "restored", "sqlite.db")
+	targetMedia := filepath.Join(dir, "restored", "media")
+	os.MkdirAll(filepath.Join(targetMedia, "stale"), 0o755)
+	os.WriteFile(target, []byte("old"), 0o644)
+	os.WriteFile(target+"-wal", []byte("old wal"), 0o644)
+
+	if _, err := Restore(ctx, bytes.NewReader(buf.Bytes()), target, targetMedia); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	restored, err := sql.Open("sqlite", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	var name string
+	if err := restored.QueryRow("SELECT name FROM artists").Scan(&name); err != nil || name != "Nirvana" {
+		t.Errorf("restored artist = %q, %v", name, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(targetMedia, "records", "1", "front")); err != nil || string(data) != "not really a JPEG" {
+		t.Errorf("restored cover = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(targetMedia, "stale")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("restore kept media that isn't in the backup")
+	}
+	for _, path := range []string{target + ".before-restore", target + "-wal.before-restore", targetMedia + ".before-restore"} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s wasn't set aside: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(target + "-wal"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("the old WAL was left beside the restored database")
+	}
+}
+
+// TestRestore_Rejected tests archives that must not be restored
+func TestRestore_Rejected(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db := setupDB(t, filepath.Join(dir, "live.db"))
+	mediaDir := filepath.Join(dir, "media")
+	os.MkdirAll(mediaDir, 0o755)
+	os.WriteFile(filepath.Join(mediaDir, "cover"), []byte("cover"), 0o644)
+
+	var buf bytes.Buffer
+	if _, err := Write(ctx, db, mediaDir, &buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	archive := buf.Bytes()
+
+	tests := []struct {
+		name string
+		fn   func(name string, data []byte) (string, []byte, bool)
+		want error
+	}{
+		{"tampered media", func(name string, data []byte) (string, []byte, bool) {
+			if name == "media/cover" {
+				data = []byte("COVER")
+			}
+			return name, data, true
+		}, ErrCorrupt},
+		{"missing database", func(name string, data []byte) (string, []byte, bool) {
+			return name, data, name != DatabaseName
+		}, ErrCorrupt},
+		{"no manifest", func(name string, data []byte) (string, []byte, bool) {
+			return name, data, name != ManifestName
+		}, ErrCorrupt},
+		{"path outside media", func(name string, data []byte) (string, []byte, bool) {
+			if name == "media/cover" {
+				name = "media/../../cover"
+			}
+			return name, data, true
+		}, ErrCorrupt},
+		{"manifest version differs", func(name string, data []byte) (string, []byte, bool) {
+			if name == ManifestName {
+				var m Manifest
+				json.Unmarshal(data, &m)
+				m.Version--
+				data, _ = json.Marshal(m)
+			}
+			return name, data, true
+		}, ErrVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "sqlite.db")
+			os.WriteFile(target, []byte("old"), 0o644)
+
+			_, err := Restore(ctx, bytes.NewReader(rewrite(t, archive, tt.fn)), target, filepath.Join(filepath.Dir(target), "media"))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Restore() error = %v, want %v", err, tt.want)
+			}
+			if data, _ := os.ReadFile(target); string(data) != "old" {
+				t.Error("a rejected restore replaced the database")
+			}
+		})
+	}
+
+	if _, err := Restore(ctx, bytes.NewReader([]byte("not an archive")), filepath.Join(dir, "x.db"), filepath.Join(dir, "x")); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Restore() of garbage error = %v, want ErrCorrupt", err)
+	}
+
+	// A database migrated by a newer build can't be restored by this one
+	if _, err := db.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES (99991231000000, 1)"); err != nil {
+		t.Fatalf("Failed to add a version: %v", err)
+	}
+	buf.Reset()
+	if _, err := Write(ctx, db, mediaDir, &buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := Restore(ctx, &buf, filepath.Join(dir, "y.db"), filepath.Join(dir, "y")); !errors.Is(err, ErrVersion) {
+		t.Errorf("Restore() of a newer database error = %v, want ErrVersion", err)
+	}
+}
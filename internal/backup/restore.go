@@ -0,0 +1,211 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrCorrupt is returned when an archive's files don't match its manifest
+	ErrCorrupt = errors.New("backup archive is corrupt")
+	// ErrVersion is returned when an archive's database isn't at the
+	// migration version its manifest says, or is newer than this build
+	ErrVersion = errors.New("backup has the wrong migration version")
+)
+
+// beforeRestore is added to the names of the database and media directory
+// a restore replaces; they're kept until the next restore
+const beforeRestore = ".before-restore"
+
+// Restore verifies an archive and swaps it in for the database at dbPath and
+// the media directory. The server must be stopped first. Nothing is
+// replaced unless every file matches the manifest, the database passes its
+// integrity check, and its migration version is the manifest's and no newer
+// than this build's; an older one is migrated up when the server next
+// starts. What was there before is kept beside it, with ".before-restore"
+// added to the name.
+func Restore(ctx context.Context, r io.Reader, dbPath, mediaDir string) (Manifest, error) {
+	dbStage, err := os.MkdirTemp(filepath.Dir(dbPath), ".restore-*")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.RemoveAll(dbStage)
+
+	// The media is unpacked beside its directory so it can be renamed into
+	// place, whichever filesystem that's on
+	if err := os.MkdirAll(filepath.Dir(filepath.Clean(mediaDir)), 0o755); err != nil {
+		return Manifest{}, err
+	}
+	mediaStage, err := os.MkdirTemp(filepath.Dir(filepath.Clean(mediaDir)), ".restore-media-*")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.RemoveAll(mediaStage)
+
+	manifest, err := extract(r, func(name string) string {
+		if name == DatabaseName {
+			return filepath.Join(dbStage, DatabaseName)
+		}
+		return filepath.Join(mediaStage, filepath.FromSlash(strings.TrimPrefix(name, MediaPrefix)))
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	restored := filepath.Join(dbStage, DatabaseName)
+	if err := IntegrityCheck(ctx, restored); err != nil {
+		return Manifest{}, err
+	}
+	current, latest, err := SchemaVersions(ctx, restored)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read migration version: %w", err)
+	}
+	if current != manifest.Version {
+		return Manifest{}, fmt.Errorf("%w: the database is at %d but the manifest says %d", ErrVersion, current, manifest.Version)
+	}
+	if current > latest {
+		return Manifest{}, fmt.Errorf("%w: the database is at %d, newer than this build's %d; restore it with a newer build", ErrVersion, current, latest)
+	}
+
+	if err := SwapIn(restored, dbPath); err != nil {
+		return Manifest{}, err
+	}
+	if err := setAside(mediaDir); err != nil {
+		return Manifest{}, err
+	}
+	if err := os.Rename(mediaStage, mediaDir); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// SwapIn moves the database file at restored into place at dbPath. What was
+// there is kept beside it with ".before-restore" added to the name, along
+// with its WAL and shared memory files, which would otherwise be applied to
+// the restored database.
+func SwapIn(restored, dbPath string) error {
+	for _, suffix := range []string{"-wal", "-shm", ""} {
+		if err := setAside(dbPath + suffix); err != nil {
+			return err
+		}
+	}
+	return os.Rename(restored, dbPath)
+}
+
+// Verify reads an archive through and checks every file against its
+// manifest, without restoring anything
+func Verify(r io.Reader) (Manifest, error) {
+	return extract(r, nil)
+}
+
+// setAside renames path to path.before-restore, replacing any earlier one.
+// A path that doesn't exist is left alone.
+func setAside(path string) error {
+	if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	old := path + beforeRestore
+	if err := os.RemoveAll(old); err != nil {
+		return err
+	}
+	return os.Rename(path, old)
+}
+
+// extract reads an archive, writing each file to where dest says, and
+// checks them against the manifest. A nil dest only checks.
+func extract(r io.Reader, dest func(name string) string) (Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer gz.Close()
+
+	var manifest *Manifest
+	found := make(map[string]File)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return Manifest{}, fmt.Errorf("%w: %s isn't a regular file", ErrCorrupt, hdr.Name)
+		}
+
+		if hdr.Name == ManifestName {
+			manifest = new(Manifest)
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return Manifest{}, fmt.Errorf("%w: manifest: %v", ErrCorrupt, err)
+			}
+			continue
+		}
+
+		media := strings.HasPrefix(hdr.Name, MediaPrefix) && filepath.IsLocal(strings.TrimPrefix(hdr.Name, MediaPrefix))
+		if hdr.Name != DatabaseName && !media {
+			return Manifest{}, fmt.Errorf("%w: unexpected file %s", ErrCorrupt, hdr.Name)
+		}
+		if _, dup := found[hdr.Name]; dup {
+			return Manifest{}, fmt.Errorf("%w: %s appears twice", ErrCorrupt, hdr.Name)
+		}
+
+		w := io.Discard
+		var f *os.File
+		if dest != nil {
+			path := dest(hdr.Name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return Manifest{}, err
+			}
+			if f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644); err != nil {
+				return Manifest{}, err
+			}
+			w = f
+		}
+		hash := sha256.New()
+		n, err := io.Copy(io.MultiWriter(w, hash), tr)
+		if f != nil {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("%w: %s: %v", ErrCorrupt, hdr.Name, err)
+		}
+		found[hdr.Name] = File{Path: hdr.Name, Size: n, SHA256: hex.EncodeToString(hash.Sum(nil))}
+	}
+
+	if manifest == nil {
+		return Manifest{}, fmt.Errorf("%w: there's no %s", ErrCorrupt, ManifestName)
+	}
+	for _, want := range manifest.Files {
+		got, ok := found[want.Path]
+		if !ok {
+			return Manifest{}, fmt.Errorf("%w: %s is missing", ErrCorrupt, want.Path)
+		}
+		if got != want {
+			return Manifest{}, fmt.Errorf("%w: %s doesn't match its checksum", ErrCorrupt, want.Path)
+		}
+		delete(found, want.Path)
+	}
+	for name := range found {
+		return Manifest{}, fmt.Errorf("%w: %s isn't in the manifest", ErrCorrupt, name)
+	}
+	if !slices.ContainsFunc(manifest.Files, func(f File) bool { return f.Path == DatabaseName }) {
+		return Manifest{}, fmt.Errorf("%w: there's no %s", ErrCorrupt, DatabaseName)
+	}
+	return *manifest, nil
+}
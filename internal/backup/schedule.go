@@ -0,0 +1,239 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Retention is how many backups to keep: the newest in each of the last
+// Hourly hours, Daily days and Weekly weeks that have one. The newest backup
+// is always kept.
+type Retention struct {
+	Hourly int
+	Daily  int
+	Weekly int
+}
+
+// Status is how scheduled backups are going. The times are zero until
+// there's been one.
+type Status struct {
+	Dir         string
+	Interval    time.Duration
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastFile    string
+	LastError   string
+	// Stale is set when there's been no backup for two intervals
+	Stale bool
+}
+
+// snapshot is an archive in the backup directory
+type snapshot struct {
+	name string
+	at   time.Time
+}
+
+// Scheduler takes backups into a directory every interval and prunes them by
+// its retention policy
+type Scheduler struct {
+	db       *sql.DB
+	logger   *slog.Logger
+	dir      string
+	mediaDir string
+	interval time.Duration
+	keep     Retention
+	started  time.Time
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewScheduler creates a scheduler backing up db and mediaDir into dir. The
+// newest backup already in dir counts as the last one, so a restart doesn't
+// take another straight away.
+func NewScheduler(db *sql.DB, logger *slog.Logger, dir, mediaDir string, interval time.Duration, keep Retention) *Scheduler {
+	s := &Scheduler{
+		db:       db,
+		logger:   logger,
+		dir:      dir,
+		mediaDir: mediaDir,
+		interval: interval,
+		keep:     keep,
+		started:  time.Now(),
+		status:   Status{Dir: dir, Interval: interval},
+	}
+	if snapshots, err := s.list(); err == nil && len(snapshots) > 0 {
+		s.status.LastSuccess = snapshots[0].at
+		s.status.LastFile = snapshots[0].name
+	}
+	return s
+}
+
+// Run takes a backup whenever one is due, until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	delay := time.Duration(0)
+	if last := s.Status().LastSuccess; !last.IsZero() {
+		delay = max(s.interval-time.Since(last), 0)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if err := s.RunOnce(ctx); err != nil {
+			s.logger.Error("Scheduled backup failed", slog.String("error", err.Error()))
+		}
+		timer.Reset(s.interval)
+	}
+}
+
+// RunOnce takes a backup now, then prunes old ones. A failed prune is logged
+// but doesn't fail the backup.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	now := time.Now().UTC()
+	name := FileName(now)
+	manifest, err := s.write(ctx, name)
+
+	s.mu.Lock()
+	s.status.LastAttempt = now
+	if err != nil {
+		s.status.LastError = err.Error()
+	} else {
+		s.status.LastSuccess = now
+		s.status.LastFile = name
+		s.status.LastError = ""
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Backup written",
+		slog.String("path", filepath.Join(s.dir, name)),
+		slog.Int("files", len(manifest.Files)),
+	)
+	if err := s.prune(); err != nil {
+		s.logger.Warn("Failed to prune backups", slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// Status returns how scheduled backups are going
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	status := s.status
+	s.mu.Unlock()
+
+	last := status.LastSuccess
+	if last.IsZero() {
+		last = s.started
+	}
+	status.Stale = time.Since(last) > 2*s.interval
+	return status
+}
+
+// write takes a backup into the directory as name. It's written to a
+// temporary file first, so a failed backup leaves nothing behind.
+func (s *Scheduler) write(ctx context.Context, name string) (Manifest, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return Manifest{}, err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".dd-backup-*")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	manifest, err := Write(ctx, s.db, s.mediaDir, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Manifest{}, err
+	}
+	return manifest, os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
+
+// list returns the archives in the directory, newest first
+func (s *Scheduler) list() ([]snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var snapshots []snapshot
+	for _, e := range entries {
+		if at, ok := parseFileName(e.Name()); ok && e.Type().IsRegular() {
+			snapshots = append(snapshots, snapshot{name: e.Name(), at: at})
+		}
+	}
+	slices.SortFunc(snapshots, func(a, b snapshot) int { return b.at.Compare(a.at) })
+	return snapshots, nil
+}
+
+// prune deletes the archives the retention policy doesn't keep
+func (s *Scheduler) prune() error {
+	snapshots, err := s.list()
+	if err != nil {
+		return err
+	}
+	keep := s.keep.keep(snapshots)
+
+	var errs []error
+	for i, snap := range snapshots {
+		if keep[i] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, snap.name)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.logger.Debug("Pruned backup", slog.String("name", snap.name))
+	}
+	return errors.Join(errs...)
+}
+
+// keep marks the snapshots, newest first, that the policy keeps
+func (r Retention) keep(snapshots []snapshot) []bool {
+	keep := make([]bool, len(snapshots))
+	if len(snapshots) > 0 {
+		keep[0] = true
+	}
+
+	bucket := func(n int, period func(time.Time) string) {
+		seen := make(map[string]bool)
+		for i, snap := range snapshots {
+			if len(seen) == n {
+				return
+			}
+			p := period(snap.at)
+			if !seen[p] {
+				seen[p] = true
+				keep[i] = true
+			}
+		}
+	}
+	bucket(r.Hourly, func(t time.Time) string { return t.Format("2006010215") })
+	bucket(r.Daily, func(t time.Time) string { return t.Format("20060102") })
+	bucket(r.Weekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	})
+	return keep
+}
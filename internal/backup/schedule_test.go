@@ -0,0 +1,122 @@
+package backup
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestRetentionKeep tests which backups the retention policy keeps
+func TestRetentionKeep(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+	// Every half hour for three weeks, newest first
+	var snapshots []snapshot
+	for i := range 3 * 7 * 48 {
+		snapshots = append(snapshots, snapshot{at: now.Add(-time.Duration(i) * 30 * time.Minute)})
+	}
+
+	tests := []struct {
+		name string
+		keep Retention
+		want int
+	}{
+		{"nothing", Retention{}, 1},
+		{"hourly", Retention{Hourly: 3}, 3},
+		{"daily", Retention{Daily: 2}, 2},
+		// The hourly ones cover today's and yesterday's daily ones, and the
+		// daily ones this week's and last week's weekly ones
+		{"all", Retention{Hourly: 24, Daily: 7, Weekly: 4}, 24 + 5 + 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep := tt.keep.keep(snapshots)
+			if n := len(slices.DeleteFunc(slices.Clone(keep), func(k bool) bool { return !k })); n != tt.want {
+				t.Errorf("kept %d, want %d", n, tt.want)
+			}
+			if !keep[0] {
+				t.Error("the newest backup wasn't kept")
+			}
+		})
+	}
+}
+
+// TestSchedulerRunOnce tests that a scheduled backup is written, pruned
+// around and reported
+func TestSchedulerRunOnce(t *testing.T) {
+	dir := t.TempDir()
+	db := setupDB(t, filepath.Join(dir, "live.db"))
+	backups := filepath.Join(dir, "backups")
+	os.MkdirAll(backups, 0o755)
+
+	// Two old backups from the same day, and something else
+	old := time.Now().UTC().Add(-72 * time.Hour).Truncate(time.Hour)
+	for _, name := range []string{FileName(old), FileName(old.Add(time.Minute)), "notes.txt"} {
+		os.WriteFile(filepath.Join(backups, name), nil, 0o644)
+	}
+
+	s := NewScheduler(db, slog.New(slog.DiscardHandler), backups, filepath.Join(dir, "media"), time.Hour, Retention{Daily: 7})
+	if status := s.Status(); !status.Stale || status.LastFile != FileName(old.Add(time.Minute)) {
+		t.Fatalf("status before = %+v, want stale since the last old backup", status)
+	}
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	status := s.Status()
+	if status.Stale || status.LastError != "" || status.LastSuccess.IsZero() {
+		t.Errorf("status after = %+v", status)
+	}
+	if _, err := Verify(mustOpen(t, filepath.Join(backups, status.LastFile))); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// The older of the two from that day went
+	var names []string
+	entries, _ := os.ReadDir(backups)
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{FileName(old.Add(time.Minute)), status.LastFile, "notes.txt"}
+	if !slices.Equal(names, want) {
+		t.Errorf("backups = %v, want %v", names, want)
+	}
+}
+
+// TestIntegrityCheck tests that a damaged database file is caught
+func TestIntegrityCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "live.db")
+	setupDB(t, path).Close()
+
+	if err := IntegrityCheck(context.Background(), path); err != nil {
+		t.Fatalf("IntegrityCheck() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Scribble over everything after the first page
+	pageSize := int(data[16])<<8 | int(data[17])
+	for i := pageSize; i < len(data); i++ {
+		data[i] = 0x5a
+	}
+	os.WriteFile(path, data, 0o644)
+	if err := IntegrityCheck(context.Background(), path); err == nil {
+		t.Error("IntegrityCheck() of a damaged database succeeded")
+	}
+}
+
+func mustOpen(t *testing.T, path string) *os.File {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
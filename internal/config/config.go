@@ -17,6 +17,10 @@ type Config struct {
 	Auth     AuthConfig
 	Session  SessionConfig
 	Logging  LoggingConfig
+	Media    MediaConfig
+	Metadata MetadataConfig
+	Backup   BackupConfig
+	Replica  ReplicaConfig
 }
 
 type ServerConfig struct {
@@ -40,6 +44,40 @@ type SessionConfig struct {
 	Secure     bool
 }
 
+type MediaConfig struct {
+	Dir            string
+	MaxUploadBytes int64
+}
+
+// MetadataConfig points release lookups at Discogs, or anything speaking its
+// API. Lookups are off without a token.
+type MetadataConfig struct {
+	DiscogsURL   string
+	DiscogsToken string
+}
+
+// BackupConfig schedules automatic backups into Dir. They're off when
+// Interval is 0. The newest backup in each of the last KeepHourly hours,
+// KeepDaily days and KeepWeekly weeks is kept, and the rest deleted.
+type BackupConfig struct {
+	Dir        string
+	Interval   time.Duration
+	KeepHourly int
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// ReplicaConfig replicates the database continuously into Dir, which is off
+// when it's empty. New commits are shipped every SyncInterval and a snapshot
+// taken every SnapshotInterval; enough is kept to restore to any moment in
+// the last Retention.
+type ReplicaConfig struct {
+	Dir              string
+	SyncInterval     time.Duration
+	SnapshotInterval time.Duration
+	Retention        time.Duration
+}
+
 type LoggingConfig struct {
 	Level   slog.Level
 	Handler slog.Handler
@@ -58,6 +96,7 @@ func Load() (*Config, error) {
 	var flagEnv = flag.String("env", getEnv("ENVIRONMENT", "prod"), "environment: prod, dev")
 	var flagLogLevel = flag.String("log_level", getEnv("LOG_LEVEL", "info"), "log level: debug, info, warn, error")
 	var flagDatabase = flag.String("database", getEnv("DATABASE_PATH", "sqlite.db"), "sqlite database file path")
+	var flagMediaDir = flag.String("media_dir", getEnv("MEDIA_DIR", "media"), "directory for uploaded cover art")
 	flag.Parse()
 
 	cfg := &Config{
@@ -78,6 +117,27 @@ func Load() (*Config, error) {
 			Duration:   24 * time.Hour * 7, // 7 days
 			Secure:     *flagEnv == "prod" || *flagEnv == "production",
 		},
+		Media: MediaConfig{
+			Dir:            *flagMediaDir,
+			MaxUploadBytes: int64(getEnvInt("MEDIA_MAX_UPLOAD_MB", 10)) << 20,
+		},
+		Metadata: MetadataConfig{
+			DiscogsURL:   getEnv("DISCOGS_URL", "https://api.discogs.com"),
+			DiscogsToken: getEnv("DISCOGS_TOKEN", ""),
+		},
+		Backup: BackupConfig{
+			Dir:        getEnv("BACKUP_DIR", "backups"),
+			Interval:   getEnvDuration("BACKUP_INTERVAL", 0),
+			KeepHourly: getEnvInt("BACKUP_KEEP_HOURLY", 24),
+			KeepDaily:  getEnvInt("BACKUP_KEEP_DAILY", 7),
+			KeepWeekly: getEnvInt("BACKUP_KEEP_WEEKLY", 4),
+		},
+		Replica: ReplicaConfig{
+			Dir:              getEnv("REPLICA_DIR", ""),
+			SyncInterval:     getEnvDuration("REPLICA_SYNC_INTERVAL", time.Second),
+			SnapshotInterval: getEnvDuration("REPLICA_SNAPSHOT_INTERVAL", 24*time.Hour),
+			Retention:        getEnvDuration("REPLICA_RETENTION", 72*time.Hour),
+		},
 	}
 
 	// Set up logging
@@ -141,3 +201,12 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
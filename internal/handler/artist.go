@@ -1,8 +1,8 @@
 package handler
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -30,6 +30,7 @@ func (h *Handler) GetArtists() http.HandlerFunc {
 		h.renderer.Render(w, "artists", map[string]interface{}{
 			"Title":   "Artists",
 			"Artists": artists,
+			"Letters": groupArtistsByLetter(artists),
 		})
 	}
 }
@@ -54,7 +55,11 @@ func (h *Handler) CreateArtist() http.HandlerFunc {
 			return
 		}
 
-		artist, err := h.queries.CreateArtist(r.Context(), req.Name)
+		artist, err := h.createArtist(r.Context(), req.Name)
+		if errors.Is(err, errDuplicateArtist) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			h.logger.Error("Failed to create artist", slog.String("error", err.Error()), slog.String("name", req.Name))
 			http.Error(w, "Failed to create artist", http.StatusInternalServerError)
@@ -70,9 +75,7 @@ func (h *Handler) CreateArtist() http.HandlerFunc {
 			return
 		}
 
-		h.renderer.Render(w, "artists-list", map[string]interface{}{
-			"Artists": artists,
-		})
+		h.renderer.Render(w, "artists-list", groupArtistsByLetter(artists))
 	}
 }
 
@@ -103,21 +106,39 @@ func (h *Handler) GetArtist() http.HandlerFunc {
 			return
 		}
 
-		// fetch artist's records
-		records, err := h.queries.GetRecordsByArtist(r.Context(), sql.NullInt64{Int64: artistID, Valid: true})
+		// fetch every record crediting the artist, grouped by role
+		credits, err := h.artistCredits(r.Context(), artistID)
 		if err != nil {
 			h.logger.Error("Failed to retrieve artist records", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
 			// Not fatal - just show empty records list
-			records = []store.Record{}
+			credits = []CreditGroup{}
+		}
+
+		aliases, err := h.queries.ListArtistAliases(r.Context(), artistID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve aliases", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
+			aliases = []store.ArtistAlias{}
+		}
+
+		// admins can merge the artist into another
+		isAdmin := h.isAdmin(r.Context())
+		var artists []store.Artist
+		if isAdmin {
+			if artists, err = h.queries.ListArtists(r.Context()); err != nil {
+				h.logger.Error("Failed to retrieve artists", slog.String("error", err.Error()))
+			}
 		}
 
 		// render artist detail page
-		h.logger.Info("Artist retrieved", slog.Int64("artistID", artistID), slog.String("name", artist.Name), slog.Int("recordCount", len(records)))
+		h.logger.Info("Artist retrieved", slog.Int64("artistID", artistID), slog.String("name", artist.Name), slog.Int("roleCount", len(credits)))
 
 		err = h.renderer.Render(w, "artist-detail", map[string]interface{}{
 			"Title":   artist.Name,
 			"Artist":  artist,
-			"Records": records,
+			"Credits": credits,
+			"Aliases": aliases,
+			"IsAdmin": isAdmin,
+			"Artists": artists,
 		})
 		if err != nil {
 			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
@@ -146,10 +167,6 @@ func (h *Handler) UpdateArtist() http.HandlerFunc {
 		}
 
 		// parse and validate form data
-		type UpdateArtistRequest struct {
-			Name string `form:"name" validate:"required,min=2,max=100"`
-		}
-
 		var req UpdateArtistRequest
 		if err := h.bind(r, &req); err != nil {
 			// Check if it's a validation error
@@ -163,17 +180,14 @@ func (h *Handler) UpdateArtist() http.HandlerFunc {
 			return
 		}
 
-		// create database payload
-		data := store.UpdateArtistParams{
-			Name: req.Name,
-			ID:   artistID,
-		}
-
-		// update artist in database
-		artist, err := h.queries.UpdateArtist(r.Context(), data)
+		// update artist and their sort name in database
+		artist, err := h.updateArtist(r.Context(), artistID, req)
 		if err != nil {
-			h.logger.Error("Failed to update artist", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
-			http.Error(w, "Failed to update artist", http.StatusInternalServerError)
+			status, message := artistErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update artist", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
+			}
+			http.Error(w, message, status)
 			return
 		}
 
@@ -279,7 +293,11 @@ func (h *Handler) JsonCreateArtist() http.HandlerFunc {
 			return
 		}
 
-		artist, err := h.queries.CreateArtist(r.Context(), req.Name)
+		artist, err := h.createArtist(r.Context(), req.Name)
+		if errors.Is(err, errDuplicateArtist) {
+			h.writeErrorJSON(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			h.logger.Error("Failed to create artist", slog.String("error", err.Error()), slog.String("name", req.Name))
 			h.writeErrorJSON(w, "Failed to create artist", http.StatusInternalServerError)
@@ -314,15 +332,35 @@ func (h *Handler) JsonGetArtist() http.HandlerFunc {
 // PUT /api/v1/artists/{id}
 func (h *Handler) JsonUpdateArtist() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Update artist via API
-		// - Parse ID from path
-		// - Parse JSON body
-		// - Validate input
-		// - Update artist in database
-		// - Return updated artist
-		id := r.PathValue("id")
-		h.logger.Info("API update artist handler called", slog.String("id", id))
-		h.writeJSON(w, map[string]string{"message": "artist updated"}, http.StatusOK)
+		artistID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateArtistRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		artist, err := h.updateArtist(r.Context(), artistID, req)
+		if err != nil {
+			status, message := artistErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update artist", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.logger.Info("Artist updated via API", slog.Int64("artistID", artist.ID), slog.String("name", artist.Name))
+
+		h.writeJSON(w, artist, http.StatusOK)
 	}
 }
 
@@ -342,12 +380,25 @@ func (h *Handler) JsonDeleteArtist() http.HandlerFunc {
 // GET /api/v1/artists/{id}/records
 func (h *Handler) JsonGetRecordsByArtist() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get all records by artist
-		// - Parse ID from path
-		// - Query records for artist
-		// - Return JSON array
-		id := r.PathValue("id")
-		h.logger.Info("API get artist records handler called", slog.String("id", id))
-		h.writeJSON(w, []interface{}{}, http.StatusOK)
+		artistID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		artist, err := h.queries.GetArtist(r.Context(), artistID)
+		if err != nil {
+			h.writeErrorJSON(w, "Artist not found", http.StatusNotFound)
+			return
+		}
+
+		credits, err := h.artistCredits(r.Context(), artistID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve artist records", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
+			h.writeErrorJSON(w, "Failed to retrieve artist records", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, ArtistCreditsResponse{Artist: artist, Credits: credits}, http.StatusOK)
 	}
 }
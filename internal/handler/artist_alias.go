@@ -0,0 +1,426 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// errDuplicateArtist is returned when a new or renamed artist's name is
+	// already an artist's name or alias
+	errDuplicateArtist = errors.New("an artist with that name already exists")
+	// errAliasTaken is returned when an alias is already an artist's name or
+	// another alias
+	errAliasTaken = errors.New("that name already belongs to an artist")
+	// errAliasNotFound is returned when deleting an unknown alias
+	errAliasNotFound = errors.New("alias not found")
+	// errMergeArtistIntoSelf is returned when an artist is merged into
+	// themselves
+	errMergeArtistIntoSelf = errors.New("cannot merge an artist into itself")
+)
+
+// ArtistAliasRequest adds another name an artist is known by
+type ArtistAliasRequest struct {
+	Name string `form:"name" json:"name" validate:"required,notblank,max=100"`
+}
+
+// MergeArtistRequest merges an artist into another; the artist in the path
+// is deleted and their name becomes an alias
+type MergeArtistRequest struct {
+	IntoID int64 `form:"into_id" json:"into_id" validate:"required,min=1"`
+}
+
+// ArtistAliasesResponse is an artist with the other names they're known by
+type ArtistAliasesResponse struct {
+	Artist  store.Artist        `json:"artist"`
+	Aliases []store.ArtistAlias `json:"aliases"`
+}
+
+// createArtist adds an artist, refusing a name that is already an artist's
+// name or alias
+func (h *Handler) createArtist(ctx context.Context, name string) (store.Artist, error) {
+	name = strings.TrimSpace(name)
+	if _, err := h.queries.GetArtistByName(ctx, name); err == nil {
+		return store.Artist{}, errDuplicateArtist
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return store.Artist{}, err
+	}
+
+	var artist store.Artist
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		var err error
+		artist, err = insertArtist(ctx, q, name)
+		return err
+	})
+	if isUniqueViolation(err) {
+		return store.Artist{}, errDuplicateArtist
+	}
+	return artist, err
+}
+
+// createArtistAlias gives an artist another name. Returns sql.ErrNoRows if
+// the artist doesn't exist.
+func (h *Handler) createArtistAlias(ctx context.Context, artistID int64, req ArtistAliasRequest) (store.ArtistAlias, error) {
+	name := strings.TrimSpace(req.Name)
+	if _, err := h.queries.GetArtist(ctx, artistID); err != nil {
+		return store.ArtistAlias{}, err
+	}
+
+	if _, err := h.queries.GetArtistByName(ctx, name); err == nil {
+		return store.ArtistAlias{}, errAliasTaken
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return store.ArtistAlias{}, err
+	}
+
+	alias, err := h.queries.CreateArtistAlias(ctx, store.CreateArtistAliasParams{ArtistID: artistID, Name: name})
+	if isUniqueViolation(err) {
+		return store.ArtistAlias{}, errAliasTaken
+	}
+	return alias, err
+}
+
+// deleteArtistAlias removes one of an artist's aliases
+func (h *Handler) deleteArtistAlias(ctx context.Context, artistID, aliasID int64) error {
+	deleted, err := h.queries.DeleteArtistAlias(ctx, store.DeleteArtistAliasParams{ID: aliasID, ArtistID: artistID})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return errAliasNotFound
+	}
+	return nil
+}
+
+// mergeArtist folds one artist into another in a single transaction: their
+// records, track credits and aliases move over, they're deleted, and their
+// name becomes an alias so it still finds the artist they were merged into.
+// Returns sql.ErrNoRows if either artist doesn't exist.
+func (h *Handler) mergeArtist(ctx context.Context, fromID, intoID int64) (store.Artist, error) {
+	if fromID == intoID {
+		return store.Artist{}, errMergeArtistIntoSelf
+	}
+
+	var into store.Artist
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		from, err := q.GetArtist(ctx, fromID)
+		if err != nil {
+			return err
+		}
+		into, err = q.GetArtist(ctx, intoID)
+		if err != nil {
+			return err
+		}
+
+		if err := q.MoveArtistAliases(ctx, store.MoveArtistAliasesParams{IntoID: intoID, FromID: fromID}); err != nil {
+			return err
+		}
+		if err := q.MoveArtistCredits(ctx, store.MoveArtistCreditsParams{IntoID: intoID, FromID: fromID}); err != nil {
+			return err
+		}
+		// Records that credited both keep the other artist's credit
+		if err := q.DeleteArtistCredits(ctx, fromID); err != nil {
+			return err
+		}
+		if err := q.MoveTrackArtists(ctx, store.MoveTrackArtistsParams{IntoID: intoID, FromID: fromID}); err != nil {
+			return err
+		}
+		if err := q.DeleteArtist(ctx, fromID); err != nil {
+			return err
+		}
+
+		// The name may already be an alias, differing only in case
+		if _, err := q.GetArtistByName(ctx, from.Name); err == nil {
+			return nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		_, err = q.CreateArtistAlias(ctx, store.CreateArtistAliasParams{ArtistID: intoID, Name: from.Name})
+		return err
+	})
+	return into, err
+}
+
+func artistErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errMergeArtistIntoSelf):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, errDuplicateArtist), errors.Is(err, errAliasTaken):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, errAliasNotFound):
+		return http.StatusNotFound, "Alias not found"
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Artist not found"
+	default:
+		return http.StatusInternalServerError, "Failed to save artist"
+	}
+}
+
+// pathAliasID parses the {aliasID} path value
+func pathAliasID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(r.PathValue("aliasID"), 10, 64)
+}
+
+func (h *Handler) renderArtistAliases(w http.ResponseWriter, r *http.Request, artistID int64) {
+	artist, err := h.queries.GetArtist(r.Context(), artistID)
+	if err != nil {
+		http.Error(w, "Artist not found", http.StatusNotFound)
+		return
+	}
+
+	aliases, err := h.queries.ListArtistAliases(r.Context(), artistID)
+	if err != nil {
+		h.logger.Error("Failed to retrieve aliases", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
+		http.Error(w, "Failed to retrieve aliases", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderer.Render(w, "artist-aliases", map[string]interface{}{
+		"Artist":  artist,
+		"Aliases": aliases,
+	})
+}
+
+// HTML Handlers
+
+// POST /artists/{id}/aliases
+func (h *Handler) CreateArtistAlias() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artistID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req ArtistAliasRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.createArtistAlias(r.Context(), artistID, req); err != nil {
+			status, message := artistErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to add alias", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderArtistAliases(w, r, artistID)
+	}
+}
+
+// DELETE /artists/{id}/aliases/{aliasID}
+func (h *Handler) DeleteArtistAlias() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artistID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		aliasID, err := pathAliasID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: aliasID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.deleteArtistAlias(r.Context(), artistID, aliasID); err != nil {
+			status, message := artistErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to delete alias", slog.String("error", err.Error()), slog.Int64("artistID", artistID), slog.Int64("aliasID", aliasID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderArtistAliases(w, r, artistID)
+	}
+}
+
+// POST /artists/{id}/merge (admin)
+func (h *Handler) MergeArtist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artistID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req MergeArtistRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		into, err := h.mergeArtist(r.Context(), artistID, req.IntoID)
+		if err != nil {
+			status, message := artistErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to merge artists", slog.String("error", err.Error()), slog.Int64("artistID", artistID), slog.Int64("intoID", req.IntoID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.logger.Info("Artists merged", slog.Int64("artistID", artistID), slog.Int64("intoID", into.ID))
+
+		location := "/artists/" + strconv.FormatInt(into.ID, 10)
+		if r.Header.Get("HX-Request") == "true" {
+			w.Header().Set("HX-Redirect", location)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, location, http.StatusSeeOther)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/artists/{id}/aliases
+func (h *Handler) JsonGetArtistAliases() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artistID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		artist, err := h.queries.GetArtist(r.Context(), artistID)
+		if err != nil {
+			h.writeErrorJSON(w, "Artist not found", http.StatusNotFound)
+			return
+		}
+
+		aliases, err := h.queries.ListArtistAliases(r.Context(), artistID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve aliases", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
+			h.writeErrorJSON(w, "Failed to retrieve aliases", http.StatusInternalServerError)
+			return
+		}
+		if aliases == nil {
+			aliases = []store.ArtistAlias{}
+		}
+
+		h.writeJSON(w, ArtistAliasesResponse{Artist: artist, Aliases: aliases}, http.StatusOK)
+	}
+}
+
+// POST /api/v1/artists/{id}/aliases
+func (h *Handler) JsonCreateArtistAlias() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artistID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req ArtistAliasRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		alias, err := h.createArtistAlias(r.Context(), artistID, req)
+		if err != nil {
+			status, message := artistErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to add alias", slog.String("error", err.Error()), slog.Int64("artistID", artistID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, alias, http.StatusCreated)
+	}
+}
+
+// DELETE /api/v1/artists/{id}/aliases/{aliasID}
+func (h *Handler) JsonDeleteArtistAlias() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artistID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		aliasID, err := pathAliasID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: aliasID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.deleteArtistAlias(r.Context(), artistID, aliasID); err != nil {
+			status, message := artistErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to delete alias", slog.String("error", err.Error()), slog.Int64("artistID", artistID), slog.Int64("aliasID", aliasID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// POST /api/v1/admin/artists/{id}/merge
+func (h *Handler) JsonMergeArtist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artistID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req MergeArtistRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		into, err := h.mergeArtist(r.Context(), artistID, req.IntoID)
+		if err != nil {
+			status, message := artistErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to merge artists", slog.String("error", err.Error()), slog.Int64("artistID", artistID), slog.Int64("intoID", req.IntoID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.logger.Info("Artists merged via API", slog.Int64("artistID", artistID), slog.Int64("intoID", into.ID))
+
+		aliases, err := h.queries.ListArtistAliases(r.Context(), into.ID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve aliases", slog.String("error", err.Error()), slog.Int64("artistID", into.ID))
+			h.writeErrorJSON(w, "Failed to retrieve aliases", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, ArtistAliasesResponse{Artist: into, Aliases: aliases}, http.StatusOK)
+	}
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// TestArtistAlias_Lookup tests that artists are found by any alias and that
+// a name can only belong to one artist
+func TestArtistAlias_Lookup(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	beatles, err := h.createArtist(ctx, "The Beatles")
+	if err != nil {
+		t.Fatalf("createArtist() error = %v", err)
+	}
+	if _, err := h.createArtistAlias(ctx, beatles.ID, ArtistAliasRequest{Name: " Beatles, The "}); err != nil {
+		t.Fatalf("createArtistAlias() error = %v", err)
+	}
+
+	got, err := queries.GetArtistByName(ctx, "beatles, the")
+	if err != nil || got.ID != beatles.ID {
+		t.Errorf("GetArtistByName(alias) = %+v, %v, want The Beatles", got, err)
+	}
+
+	if _, err := h.createArtist(ctx, "The Beatles"); !errors.Is(err, errDuplicateArtist) {
+		t.Errorf("create existing name error = %v, want errDuplicateArtist", err)
+	}
+	if _, err := h.createArtist(ctx, "Beatles, The"); !errors.Is(err, errDuplicateArtist) {
+		t.Errorf("create alias as artist error = %v, want errDuplicateArtist", err)
+	}
+
+	stones, err := h.createArtist(ctx, "The Rolling Stones")
+	if err != nil {
+		t.Fatalf("createArtist() error = %v", err)
+	}
+	for _, name := range []string{"The Beatles", "BEATLES, THE"} {
+		if _, err := h.createArtistAlias(ctx, stones.ID, ArtistAliasRequest{Name: name}); !errors.Is(err, errAliasTaken) {
+			t.Errorf("createArtistAlias(%q) error = %v, want errAliasTaken", name, err)
+		}
+	}
+	if _, err := h.createArtistAlias(ctx, stones.ID+100, ArtistAliasRequest{Name: "Stones"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("alias unknown artist error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Records typed with an alias are credited to the artist
+	record, err := h.createRecord(ctx, CreateRecordRequest{Title: "Let It Be", ArtistName: "Beatles, The"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	if record.ArtistID.Int64 != beatles.ID {
+		t.Errorf("record artist = %d, want %d", record.ArtistID.Int64, beatles.ID)
+	}
+
+	aliases, err := queries.ListArtistAliases(ctx, beatles.ID)
+	if err != nil || len(aliases) != 1 {
+		t.Fatalf("ListArtistAliases() = %+v, %v, want one alias", aliases, err)
+	}
+	if err := h.deleteArtistAlias(ctx, stones.ID, aliases[0].ID); !errors.Is(err, errAliasNotFound) {
+		t.Errorf("delete another artist's alias error = %v, want errAliasNotFound", err)
+	}
+	if err := h.deleteArtistAlias(ctx, beatles.ID, aliases[0].ID); err != nil {
+		t.Errorf("deleteArtistAlias() error = %v", err)
+	}
+	if _, err := queries.GetArtistByName(ctx, "Beatles, The"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetArtistByName(deleted alias) error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// TestArtistAlias_Merge tests that merging moves an artist's records, tracks
+// and aliases, keeps their name as an alias and deletes them
+func TestArtistAlias_Merge(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	beatles, err := h.createArtist(ctx, "The Beatles")
+	if err != nil {
+		t.Fatalf("createArtist() error = %v", err)
+	}
+	dupe, err := h.createArtist(ctx, "Beatles")
+	if err != nil {
+		t.Fatalf("createArtist() error = %v", err)
+	}
+	if _, err := h.createArtistAlias(ctx, dupe.ID, ArtistAliasRequest{Name: "Fab Four"}); err != nil {
+		t.Fatalf("createArtistAlias() error = %v", err)
+	}
+
+	abbey, err := h.createRecord(ctx, CreateRecordRequest{Title: "Abbey Road", ArtistName: "Beatles"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	track, err := queries.CreateTrack(ctx, store.CreateTrackParams{
+		RecordID: abbey.ID,
+		Position: "A1",
+		Title:    "Come Together",
+		ArtistID: sql.NullInt64{Int64: dupe.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateTrack() error = %v", err)
+	}
+
+	// Credited to both, which has to end up as one credit
+	help, err := h.createRecord(ctx, CreateRecordRequest{Title: "Help!", ArtistName: "The Beatles"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	if err := h.addRecordArtist(ctx, help.ID, CreditRequest{ArtistID: dupe.ID}); err != nil {
+		t.Fatalf("addRecordArtist() error = %v", err)
+	}
+
+	if _, err := h.mergeArtist(ctx, dupe.ID, dupe.ID); !errors.Is(err, errMergeArtistIntoSelf) {
+		t.Errorf("merge into self error = %v, want errMergeArtistIntoSelf", err)
+	}
+	if _, err := h.mergeArtist(ctx, dupe.ID, beatles.ID+100); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("merge into unknown artist error = %v, want sql.ErrNoRows", err)
+	}
+
+	into, err := h.mergeArtist(ctx, dupe.ID, beatles.ID)
+	if err != nil {
+		t.Fatalf("mergeArtist() error = %v", err)
+	}
+	if into.ID != beatles.ID {
+		t.Errorf("merged into %d, want %d", into.ID, beatles.ID)
+	}
+
+	if _, err := queries.GetArtist(ctx, dupe.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("merged artist still exists: %v", err)
+	}
+	if record, _ := queries.GetRecord(ctx, abbey.ID); record.ArtistID.Int64 != beatles.ID {
+		t.Errorf("record artist = %d, want %d", record.ArtistID.Int64, beatles.ID)
+	}
+	if credits, _ := queries.ListRecordArtists(ctx, help.ID); len(credits) != 1 || credits[0].ArtistID != beatles.ID {
+		t.Errorf("credits = %+v, want one Beatles credit", credits)
+	}
+	if got, _ := queries.GetTrack(ctx, track.ID); got.ArtistID.Int64 != beatles.ID {
+		t.Errorf("track artist = %d, want %d", got.ArtistID.Int64, beatles.ID)
+	}
+
+	aliases, err := queries.ListArtistAliases(ctx, beatles.ID)
+	if err != nil {
+		t.Fatalf("ListArtistAliases() error = %v", err)
+	}
+	var names []string
+	for _, alias := range aliases {
+		names = append(names, alias.Name)
+	}
+	if strings.Join(names, ", ") != "Beatles, Fab Four" {
+		t.Errorf("aliases = %v, want [Beatles Fab Four]", names)
+	}
+
+	// Search finds the artist by their old names
+	resp, err := h.search(ctx, SearchRequest{Query: "fab"})
+	if err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+	if len(resp.Artists) != 1 || resp.Artists[0].ID != beatles.ID || resp.Artists[0].Snippet == "" {
+		t.Errorf("search artists = %+v, want The Beatles with an alias snippet", resp.Artists)
+	}
+}
+
+func TestJsonCreateArtist_Conflict(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	h := &Handler{
+		db:       db,
+		queries:  queries,
+		logger:   slog.New(slog.DiscardHandler),
+		validate: newValidator(),
+	}
+
+	post := func(handler http.HandlerFunc, id int64, body string) int {
+		r := httptest.NewRequest("POST", "/api/v1/artists", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.SetPathValue("id", strconv.FormatInt(id, 10))
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	if got := post(h.JsonCreateArtist(), 0, `{"name": "The Beatles"}`); got != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d", got, http.StatusCreated)
+	}
+	beatles, err := queries.GetArtistByName(context.Background(), "The Beatles")
+	if err != nil {
+		t.Fatalf("GetArtistByName() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		id      int64
+		body    string
+		want    int
+	}{
+		{"same name", h.JsonCreateArtist(), 0, `{"name": "The Beatles"}`, http.StatusConflict},
+		{"blank alias", h.JsonCreateArtistAlias(), beatles.ID, `{"name": "   "}`, http.StatusBadRequest},
+		{"alias", h.JsonCreateArtistAlias(), beatles.ID, `{"name": "Beatles"}`, http.StatusCreated},
+		{"alias as artist", h.JsonCreateArtist(), 0, `{"name": "beatles"}`, http.StatusConflict},
+		{"alias again", h.JsonCreateArtistAlias(), beatles.ID, `{"name": "BEATLES"}`, http.StatusConflict},
+		{"alias of unknown artist", h.JsonCreateArtistAlias(), beatles.ID + 100, `{"name": "Stones"}`, http.StatusNotFound},
+		{"merge into unknown artist", h.JsonMergeArtist(), beatles.ID, `{"into_id": 999}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := post(tt.handler, tt.id, tt.body); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
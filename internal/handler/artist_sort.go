@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// UpdateArtistRequest renames an artist. A blank sort name, or the one the
+// name would get anyway, is derived from the name and follows later renames;
+// anything else overrides it.
+type UpdateArtistRequest struct {
+	Name     string `form:"name" json:"name" validate:"required,min=2,max=100"`
+	SortName string `form:"sort_name" json:"sort_name" validate:"max=100"`
+}
+
+// ArtistLetter is the artists listed under one alphabetical heading
+type ArtistLetter struct {
+	Letter  string         `json:"letter"`
+	Artists []store.Artist `json:"artists"`
+}
+
+// insertArtist adds an artist with a sort name derived from their name
+func insertArtist(ctx context.Context, q *store.Queries, name string) (store.Artist, error) {
+	return q.CreateArtist(ctx, store.CreateArtistParams{
+		Name:     name,
+		SortName: store.ArtistSortName(name),
+	})
+}
+
+// updateArtist renames an artist and sets their sort name. Returns
+// sql.ErrNoRows if the artist doesn't exist.
+func (h *Handler) updateArtist(ctx context.Context, artistID int64, req UpdateArtistRequest) (store.Artist, error) {
+	name := strings.TrimSpace(req.Name)
+	sortName := strings.TrimSpace(req.SortName)
+	derived := store.ArtistSortName(name)
+	manual := sortName != "" && sortName != derived
+	if !manual {
+		sortName = derived
+	}
+
+	var artist store.Artist
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		if _, err := q.UpdateArtist(ctx, store.UpdateArtistParams{Name: name, ID: artistID}); err != nil {
+			if isUniqueViolation(err) {
+				return errDuplicateArtist
+			}
+			return err
+		}
+
+		var err error
+		artist, err = q.UpdateArtistSortName(ctx, store.UpdateArtistSortNameParams{
+			SortName:       sortName,
+			SortNameManual: manual,
+			ID:             artistID,
+		})
+		return err
+	})
+	return artist, err
+}
+
+// artistLetter is the heading an artist is listed under: the first letter
+// of their sort name, or "#" for names starting with a digit or symbol
+func artistLetter(artist store.Artist) string {
+	for _, r := range artist.SortName {
+		if unicode.IsLetter(r) {
+			return string(unicode.ToUpper(r))
+		}
+		break
+	}
+	return "#"
+}
+
+// groupArtistsByLetter splits artists, already in sort name order, under
+// alphabetical headings
+func groupArtistsByLetter(artists []store.Artist) []ArtistLetter {
+	letters := []ArtistLetter{}
+	for _, artist := range artists {
+		letter := artistLetter(artist)
+		if n := len(letters); n == 0 || letters[n-1].Letter != letter {
+			letters = append(letters, ArtistLetter{Letter: letter})
+		}
+		letters[len(letters)-1].Artists = append(letters[len(letters)-1].Artists, artist)
+	}
+	return letters
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/dd/data/sql/migrations"
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+func TestArtistSortName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"The Rolling Stones", "Rolling Stones, The"},
+		{"Ólafur Arnalds", "Olafur Arnalds"},
+		{"Sigur Rós", "Sigur Ros"},
+		{"A Tribe Called Quest", "Tribe Called Quest, A"},
+		{"Die Ärzte", "Arzte, Die"},
+		{"Los Lobos", "Lobos, Los"},
+		{"L'Impératrice", "Imperatrice, L'"},
+		{"Mötley Crüe", "Motley Crue"},
+		{"Ståle Storløkken", "Stale Storlokken"},
+		{"  The   Who ", "Who, The"},
+		{"The The", "The, The"},
+		{"The", "The"},
+		{"A-ha", "A-ha"},
+		{"As I Lay Dying", "As I Lay Dying"},
+		{"De La Soul", "De La Soul"},
+		{"Het Goede Doel", "Goede Doel, Het"},
+	}
+	for _, tt := range tests {
+		if got := store.ArtistSortName(tt.name); got != tt.want {
+			t.Errorf("ArtistSortName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestArtistSort_Listing tests that artists are listed by sort name under
+// alphabetical headings, and that a sort name set by hand survives renames
+func TestArtistSort_Listing(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	for _, name := range []string{"Zappa", "The Rolling Stones", "Ólafur Arnalds", "ABBA", "2Pac"} {
+		if _, err := h.createArtist(ctx, name); err != nil {
+			t.Fatalf("createArtist(%q) error = %v", name, err)
+		}
+	}
+	// Artists added along with a record get a sort name too
+	if _, err := h.createRecord(ctx, CreateRecordRequest{Title: "Exile on Main St.", ArtistName: "The Rolling Stones"}); err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	if _, err := h.createRecord(ctx, CreateRecordRequest{Title: "Whatever People Say I Am", ArtistName: "The Arctic Monkeys"}); err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+
+	artists, err := queries.ListArtists(ctx)
+	if err != nil {
+		t.Fatalf("ListArtists() error = %v", err)
+	}
+	var names []string
+	for _, artist := range artists {
+		names = append(names, artist.Name)
+	}
+	want := "2Pac, ABBA, The Arctic Monkeys, Ólafur Arnalds, The Rolling Stones, Zappa"
+	if got := strings.Join(names, ", "); got != want {
+		t.Errorf("artists = %s, want %s", got, want)
+	}
+
+	var letters []string
+	for _, group := range groupArtistsByLetter(artists) {
+		letters = append(letters, group.Letter+":"+strings.Repeat("*", len(group.Artists)))
+	}
+	if got := strings.Join(letters, " "); got != "#:* A:** O:* R:* Z:*" {
+		t.Errorf("letters = %s, want #:* A:** O:* R:* Z:*", got)
+	}
+
+	stones := artists[4]
+	updated, err := h.updateArtist(ctx, stones.ID, UpdateArtistRequest{Name: "The Rolling Stones", SortName: "Stones"})
+	if err != nil {
+		t.Fatalf("updateArtist() error = %v", err)
+	}
+	if updated.SortName != "Stones" || !updated.SortNameManual {
+		t.Errorf("overridden sort name = %q (manual %v), want Stones", updated.SortName, updated.SortNameManual)
+	}
+	if updated, _ = h.updateArtist(ctx, stones.ID, UpdateArtistRequest{Name: "Rolling Stones", SortName: "Stones"}); updated.SortName != "Stones" {
+		t.Errorf("sort name after rename = %q, want Stones", updated.SortName)
+	}
+	if updated, _ = h.updateArtist(ctx, stones.ID, UpdateArtistRequest{Name: "The Stones"}); updated.SortName != "Stones, The" || updated.SortNameManual {
+		t.Errorf("cleared sort name = %q (manual %v), want the derived Stones, The", updated.SortName, updated.SortNameManual)
+	}
+	if _, err := h.updateArtist(ctx, stones.ID, UpdateArtistRequest{Name: "Zappa"}); !errors.Is(err, errDuplicateArtist) {
+		t.Errorf("rename to taken name error = %v, want errDuplicateArtist", err)
+	}
+	if _, err := h.updateArtist(ctx, stones.ID+100, UpdateArtistRequest{Name: "Nobody"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("update unknown artist error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// TestArtistSort_Backfill tests that the migration adding sort names derives
+// them for artists that already exist
+func TestArtistSort_Backfill(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
+	if err != nil {
+		t.Fatalf("Failed to create migration provider: %v", err)
+	}
+	if _, err := provider.UpTo(ctx, 20261016220000); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO artists (name) VALUES ('The Beatles'), ('Björk'), ('De La Soul')`); err != nil {
+		t.Fatalf("Failed to insert artists: %v", err)
+	}
+	if _, err := provider.Up(ctx); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	artists, err := store.New(db).ListArtists(ctx)
+	if err != nil {
+		t.Fatalf("ListArtists() error = %v", err)
+	}
+	if len(artists) != 3 || artists[0].SortName != "Beatles, The" || artists[1].SortName != "Bjork" || artists[2].SortName != "De La Soul" {
+		t.Errorf("artists = %+v, want sort names Beatles, The, Bjork and De La Soul", artists)
+	}
+}
@@ -45,7 +45,7 @@ func TestCreateArtist_DuplicateName(t *testing.T) {
 	artistName := "The Beatles"
 
 	// Create first artist
-	artist1, err := queries.CreateArtist(ctx, artistName)
+	artist1, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: artistName, SortName: store.ArtistSortName(artistName)})
 	if err != nil {
 		t.Fatalf("Failed to create first artist: %v", err)
 	}
@@ -54,7 +54,7 @@ func TestCreateArtist_DuplicateName(t *testing.T) {
 	}
 
 	// Attempt to create duplicate
-	_, err = queries.CreateArtist(ctx, artistName)
+	_, err = queries.CreateArtist(ctx, store.CreateArtistParams{Name: artistName, SortName: store.ArtistSortName(artistName)})
 	if err == nil {
 		t.Error("CreateArtist() should fail for duplicate name")
 	}
@@ -95,7 +95,7 @@ func TestGetArtist_WithRecords(t *testing.T) {
 	ctx := context.Background()
 
 	// Create artist
-	artist, err := queries.CreateArtist(ctx, "Pink Floyd")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Pink Floyd", SortName: store.ArtistSortName("Pink Floyd")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
@@ -125,7 +125,7 @@ func TestGetArtist_WithRecords(t *testing.T) {
 	}
 
 	// Get artist's records
-	records, err := queries.GetRecordsByArtist(ctx, sql.NullInt64{Int64: artist.ID, Valid: true})
+	records, err := queries.GetRecordsByArtist(ctx, artist.ID)
 	if err != nil {
 		t.Fatalf("GetRecordsByArtist() error = %v", err)
 	}
@@ -151,13 +151,13 @@ func TestGetArtist_NoRecords(t *testing.T) {
 	ctx := context.Background()
 
 	// Create artist without records
-	artist, err := queries.CreateArtist(ctx, "New Artist")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "New Artist", SortName: store.ArtistSortName("New Artist")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
 
 	// Get artist's records (should be empty)
-	records, err := queries.GetRecordsByArtist(ctx, sql.NullInt64{Int64: artist.ID, Valid: true})
+	records, err := queries.GetRecordsByArtist(ctx, artist.ID)
 	if err != nil {
 		t.Fatalf("GetRecordsByArtist() error = %v", err)
 	}
@@ -177,7 +177,7 @@ func TestListArtists_Ordering(t *testing.T) {
 	// Create artists in non-alphabetical order
 	artistNames := []string{"Zeppelin", "Beatles", "Radiohead", "Nirvana"}
 	for _, name := range artistNames {
-		_, err := queries.CreateArtist(ctx, name)
+		_, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: name, SortName: store.ArtistSortName(name)})
 		if err != nil {
 			t.Fatalf("Failed to create artist %s: %v", name, err)
 		}
@@ -210,7 +210,7 @@ func TestCreateArtist_DatabaseConstraints(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("NOT NULL constraint on empty name", func(t *testing.T) {
-		_, err := queries.CreateArtist(ctx, "")
+		_, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "", SortName: store.ArtistSortName("")})
 		if err == nil {
 			t.Error("CreateArtist() should fail with empty name (NOT NULL constraint)")
 		}
@@ -220,13 +220,13 @@ func TestCreateArtist_DatabaseConstraints(t *testing.T) {
 		name := "The Beatles"
 		
 		// First insert should succeed
-		_, err := queries.CreateArtist(ctx, name)
+		_, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: name, SortName: store.ArtistSortName(name)})
 		if err != nil {
 			t.Fatalf("First CreateArtist() failed: %v", err)
 		}
 
 		// Second insert should fail
-		_, err = queries.CreateArtist(ctx, name)
+		_, err = queries.CreateArtist(ctx, store.CreateArtistParams{Name: name, SortName: store.ArtistSortName(name)})
 		if err == nil {
 			t.Error("CreateArtist() should fail for duplicate name (UNIQUE constraint)")
 		}
@@ -235,7 +235,7 @@ func TestCreateArtist_DatabaseConstraints(t *testing.T) {
 	t.Run("valid names are accepted", func(t *testing.T) {
 		validNames := []string{"U2", "Pink Floyd", "X"}
 		for _, name := range validNames {
-			_, err := queries.CreateArtist(ctx, name)
+			_, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: name, SortName: store.ArtistSortName(name)})
 			if err != nil {
 				t.Errorf("CreateArtist(%q) unexpected error: %v", name, err)
 			}
@@ -251,7 +251,7 @@ func TestUpdateArtist_Success(t *testing.T) {
 	ctx := context.Background()
 
 	// Create initial artist
-	artist, err := queries.CreateArtist(ctx, "The Beatle")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "The Beatle", SortName: store.ArtistSortName("The Beatle")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
@@ -306,12 +306,12 @@ func TestUpdateArtist_DuplicateName(t *testing.T) {
 	ctx := context.Background()
 
 	// Create two artists
-	artist1, err := queries.CreateArtist(ctx, "Pink Floyd")
+	artist1, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Pink Floyd", SortName: store.ArtistSortName("Pink Floyd")})
 	if err != nil {
 		t.Fatalf("Failed to create artist1: %v", err)
 	}
 
-	_, err = queries.CreateArtist(ctx, "The Beatles")
+	_, err = queries.CreateArtist(ctx, store.CreateArtistParams{Name: "The Beatles", SortName: store.ArtistSortName("The Beatles")})
 	if err != nil {
 		t.Fatalf("Failed to create artist2: %v", err)
 	}
@@ -335,7 +335,7 @@ func TestUpdateArtist_EmptyName(t *testing.T) {
 	ctx := context.Background()
 
 	// Create artist
-	artist, err := queries.CreateArtist(ctx, "Original Name")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Original Name", SortName: store.ArtistSortName("Original Name")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
@@ -359,7 +359,7 @@ func TestDeleteArtist_Success(t *testing.T) {
 	ctx := context.Background()
 
 	// Create artist
-	artist, err := queries.CreateArtist(ctx, "Temporary Artist")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Temporary Artist", SortName: store.ArtistSortName("Temporary Artist")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
@@ -402,7 +402,7 @@ func TestDeleteArtist_WithRecords(t *testing.T) {
 	ctx := context.Background()
 
 	// Create artist
-	artist, err := queries.CreateArtist(ctx, "Artist With Records")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Artist With Records", SortName: store.ArtistSortName("Artist With Records")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
@@ -459,7 +459,7 @@ func TestDeleteArtist_CountCheck(t *testing.T) {
 	artistNames := []string{"Artist1", "Artist2", "Artist3"}
 	var artistIDs []int64
 	for _, name := range artistNames {
-		artist, err := queries.CreateArtist(ctx, name)
+		artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: name, SortName: store.ArtistSortName(name)})
 		if err != nil {
 			t.Fatalf("Failed to create artist %s: %v", name, err)
 		}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dukerupert/dd/internal/backup"
+)
+
+// API Handlers
+
+// GET /api/v1/admin/backup
+// Downloads a backup archive of the database and media, taken while the
+// server keeps running. Admins only; see the router.
+func (h *Handler) JsonBackup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dw := &downloadWriter{
+			w:           w,
+			contentType: "application/gzip",
+			filename:    backup.FileName(time.Now()),
+		}
+		manifest, err := backup.Write(r.Context(), h.db, h.config.Media.Dir, dw)
+		if err != nil {
+			h.logger.Error("Failed to write backup", slog.String("error", err.Error()))
+			// Once the download has started the status can't change; it just
+			// ends early, and the archive fails to verify
+			if !dw.started {
+				h.writeErrorJSON(w, "Failed to write backup", http.StatusInternalServerError)
+			}
+			return
+		}
+		h.logger.Info("Backup downloaded",
+			slog.Int("files", len(manifest.Files)),
+			slog.Int64("version", manifest.Version),
+		)
+	}
+}
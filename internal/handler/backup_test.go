@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/backup"
+	"github.com/dukerupert/dd/internal/config"
+)
+
+// TestJsonBackup tests that the backup download is an archive that verifies
+func TestJsonBackup(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	h := &Handler{
+		logger:   slog.New(slog.DiscardHandler),
+		db:       db,
+		queries:  queries,
+		validate: newValidator(),
+		config:   &config.Config{Media: config.MediaConfig{Dir: t.TempDir()}},
+	}
+
+	w := httptest.NewRecorder()
+	h.JsonBackup()(w, httptest.NewRequest("GET", "/api/v1/admin/backup", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("status = %d, content type %q, body %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+	manifest, err := backup.Verify(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0].Path != backup.DatabaseName || manifest.Version == 0 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
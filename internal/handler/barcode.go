@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// normalizeBarcode strips spaces and hyphens from a scanned or typed barcode
+// and widens a 12-digit UPC-A to its 13-digit EAN-13 form, so both spellings
+// of the same code match
+func normalizeBarcode(s string) string {
+	s = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(s))
+	if len(s) == 12 {
+		return "0" + s
+	}
+	return s
+}
+
+// validBarcode reports whether s is a UPC-A or EAN-13 barcode with a correct
+// check digit. Separators are ignored.
+func validBarcode(s string) bool {
+	s = normalizeBarcode(s)
+	if len(s) != 13 {
+		return false
+	}
+
+	// GTIN check digit: weight the digits 3, 1, 3, ... from the right,
+	// skipping the check digit itself
+	sum := 0
+	for i := 11; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if (11-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+
+	check := int(s[12] - '0')
+	return check >= 0 && check <= 9 && (10-sum%10)%10 == check
+}
+
+// validateBarcode is the "barcode" validator tag
+func validateBarcode(fl validator.FieldLevel) bool {
+	return validBarcode(fl.Field().String())
+}
+
+// LookupBarcodeRequest is the query for a barcode lookup
+type LookupBarcodeRequest struct {
+	Barcode string `form:"barcode" validate:"required,barcode"`
+}
+
+// BarcodeLookupResponse says whether a scanned barcode is already in the
+// collection, and where each copy is
+type BarcodeLookupResponse struct {
+	Barcode string                          `json:"barcode"`
+	Owned   bool                            `json:"owned"`
+	Records []store.ListRecordsByBarcodeRow `json:"records"`
+}
+
+// GET /api/v1/records/lookup?barcode=
+func (h *Handler) JsonLookupRecordByBarcode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req LookupBarcodeRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your query parameters")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		barcode := normalizeBarcode(req.Barcode)
+		records, err := h.queries.ListRecordsByBarcode(r.Context(), sql.NullString{String: barcode, Valid: true})
+		if err != nil {
+			h.logger.Error("Failed to look up barcode", slog.String("error", err.Error()), slog.String("barcode", barcode))
+			h.writeErrorJSON(w, "Failed to look up barcode", http.StatusInternalServerError)
+			return
+		}
+		if records == nil {
+			records = []store.ListRecordsByBarcodeRow{}
+		}
+
+		h.writeJSON(w, BarcodeLookupResponse{
+			Barcode: barcode,
+			Owned:   len(records) > 0,
+			Records: records,
+		}, http.StatusOK)
+	}
+}
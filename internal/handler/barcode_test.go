@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// TestValidBarcode tests UPC-A and EAN-13 check digits and normalisation
+func TestValidBarcode(t *testing.T) {
+	tests := []struct {
+		barcode    string
+		want       bool
+		normalized string
+	}{
+		{"036000291452", true, "0036000291452"},
+		{"0036000291452", true, "0036000291452"},
+		{"4006381333931", true, "4006381333931"},
+		{"400-6381-33393-1", true, "4006381333931"},
+		{" 0 36000 29145 2 ", true, "0036000291452"},
+		{"036000291453", false, "0036000291453"},
+		{"4006381333930", false, "4006381333930"},
+		{"03600029145", false, "03600029145"},
+		{"40063813339310", false, "40063813339310"},
+		{"03600029145A", false, "003600029145A"},
+		{"", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.barcode, func(t *testing.T) {
+			if got := validBarcode(tt.barcode); got != tt.want {
+				t.Errorf("validBarcode(%q) = %v, want %v", tt.barcode, got, tt.want)
+			}
+			if got := normalizeBarcode(tt.barcode); got != tt.normalized {
+				t.Errorf("normalizeBarcode(%q) = %q, want %q", tt.barcode, got, tt.normalized)
+			}
+		})
+	}
+}
+
+// TestLookupRecordByBarcode tests that a scan finds owned copies and where
+// they are, whichever form of the barcode is scanned
+func TestLookupRecordByBarcode(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries, validate: newValidator()}
+
+	location, err := queries.CreateLocation(ctx, store.CreateLocationParams{Name: "Living Room Shelf"})
+	if err != nil {
+		t.Fatalf("Failed to create location: %v", err)
+	}
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+		Title:             "Kind of Blue",
+		CurrentLocationID: sql.NullInt64{Int64: location.ID, Valid: true},
+		Barcode:           sql.NullString{String: normalizeBarcode("036000291452"), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	lookup := func(barcode string) (*httptest.ResponseRecorder, BarcodeLookupResponse) {
+		r := httptest.NewRequest("GET", "/api/v1/records/lookup?barcode="+barcode, nil)
+		w := httptest.NewRecorder()
+		h.JsonLookupRecordByBarcode()(w, r)
+		var resp BarcodeLookupResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode lookup response: %v", err)
+			}
+		}
+		return w, resp
+	}
+
+	for _, barcode := range []string{"036000291452", "0036000291452", "0-36000-29145-2"} {
+		w, resp := lookup(barcode)
+		if w.Code != http.StatusOK {
+			t.Fatalf("lookup %s status = %d, body %s", barcode, w.Code, w.Body)
+		}
+		if !resp.Owned || len(resp.Records) != 1 || resp.Records[0].ID != record.ID {
+			t.Fatalf("lookup %s = %+v, want the record", barcode, resp)
+		}
+		if resp.Records[0].CurrentLocationName.String != "Living Room Shelf" {
+			t.Errorf("lookup %s location = %v", barcode, resp.Records[0].CurrentLocationName)
+		}
+	}
+
+	w, resp := lookup("4006381333931")
+	if w.Code != http.StatusOK || resp.Owned || resp.Records == nil || len(resp.Records) != 0 {
+		t.Errorf("lookup of unowned barcode = %d %+v, want not owned", w.Code, resp)
+	}
+
+	if w, _ := lookup("036000291453"); w.Code != http.StatusBadRequest {
+		t.Errorf("lookup with bad check digit status = %d, want 400", w.Code)
+	}
+	if w, _ := lookup(""); w.Code != http.StatusBadRequest {
+		t.Errorf("lookup without barcode status = %d, want 400", w.Code)
+	}
+}
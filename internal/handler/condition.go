@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// ConditionHistoryResponse is a record's regrade timeline, newest first
+type ConditionHistoryResponse struct {
+	History []store.ListConditionHistoryByRecordRow `json:"history"`
+}
+
+// recordRegrade adds a condition history entry if the grades changed between
+// before and after. It is called inside the same transaction as the update.
+func recordRegrade(ctx context.Context, q *store.Queries, before, after store.Record, note string) error {
+	if before.MediaGrade == after.MediaGrade && before.SleeveGrade == after.SleeveGrade {
+		return nil
+	}
+
+	_, err := q.CreateConditionHistory(ctx, store.CreateConditionHistoryParams{
+		RecordID:            after.ID,
+		MediaGrade:          after.MediaGrade,
+		SleeveGrade:         after.SleeveGrade,
+		PreviousMediaGrade:  before.MediaGrade,
+		PreviousSleeveGrade: before.SleeveGrade,
+		Note:                sql.NullString{String: note, Valid: note != ""},
+		UserID:              currentUserID(ctx),
+	})
+	return err
+}
+
+// updateRecordCondition regrades a record and records the change in the
+// condition history. Returns sql.ErrNoRows if the record doesn't exist.
+func (h *Handler) updateRecordCondition(ctx context.Context, recordID int64, req UpdateRecordConditionRequest) (store.Record, error) {
+	var record store.Record
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		before, err := q.GetRecord(ctx, recordID)
+		if err != nil {
+			return err
+		}
+
+		record, err = q.UpdateRecordCondition(ctx, store.UpdateRecordConditionParams{
+			MediaGrade:  sql.NullString{String: req.MediaGrade, Valid: req.MediaGrade != ""},
+			SleeveGrade: sql.NullString{String: req.SleeveGrade, Valid: req.SleeveGrade != ""},
+			ID:          recordID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordRegrade(ctx, q, before, record, req.Note)
+	})
+	return record, err
+}
+
+// conditionData loads what the record-condition partial needs: the current
+// grades, the grading scale and the timeline
+func (h *Handler) conditionData(ctx context.Context, recordID int64, mediaGrade, sleeveGrade sql.NullString) (map[string]interface{}, error) {
+	grades, err := h.queries.ListGrades(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := h.queries.ListConditionHistoryByRecord(ctx, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"RecordID":    recordID,
+		"MediaGrade":  mediaGrade,
+		"SleeveGrade": sleeveGrade,
+		"Grades":      grades,
+		"History":     history,
+	}, nil
+}
+
+// HTML Handlers
+
+// PUT /records/{id}/condition
+func (h *Handler) UpdateRecordCondition() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateRecordConditionRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		record, err := h.updateRecordCondition(r.Context(), recordID, req)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Record not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to update record condition", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to update record condition", http.StatusInternalServerError)
+			return
+		}
+
+		data, err := h.conditionData(r.Context(), record.ID, record.MediaGrade, record.SleeveGrade)
+		if err != nil {
+			h.logger.Error("Failed to retrieve condition history", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve condition history", http.StatusInternalServerError)
+			return
+		}
+
+		h.renderer.Render(w, "record-condition", data)
+	}
+}
+
+// API Handlers
+
+// PUT /api/v1/records/{id}/condition
+func (h *Handler) JsonUpdateRecordCondition() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateRecordConditionRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		record, err := h.updateRecordCondition(r.Context(), recordID, req)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to update record condition", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to update record condition", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, record, http.StatusOK)
+	}
+}
+
+// GET /api/v1/records/{id}/condition-history
+func (h *Handler) JsonGetConditionHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		history, err := h.queries.ListConditionHistoryByRecord(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve condition history", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve condition history", http.StatusInternalServerError)
+			return
+		}
+		if history == nil {
+			history = []store.ListConditionHistoryByRecordRow{}
+		}
+
+		h.writeJSON(w, ConditionHistoryResponse{History: history}, http.StatusOK)
+	}
+}
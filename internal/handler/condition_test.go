@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/dukerupert/dd/data/sql/migrations"
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+// TestListGrades tests that the grading scale is ordered best first
+func TestListGrades(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	grades, err := queries.ListGrades(context.Background())
+	if err != nil {
+		t.Fatalf("ListGrades() error = %v", err)
+	}
+
+	want := []string{"M", "NM", "VG+", "VG", "G+", "G", "F", "P"}
+	if len(grades) != len(want) {
+		t.Fatalf("len(grades) = %d, want %d", len(grades), len(want))
+	}
+	for i, grade := range grades {
+		if grade.Code != want[i] {
+			t.Errorf("grade %d = %q, want %q", i, grade.Code, want[i])
+		}
+	}
+}
+
+// TestRecordGrades_RejectUnknownGrade tests that the database only accepts
+// codes from the grading scale
+func TestRecordGrades_RejectUnknownGrade(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	_, err := queries.CreateRecord(context.Background(), store.CreateRecordParams{
+		Title:      "Bad Grade",
+		MediaGrade: sql.NullString{String: "Near Mint", Valid: true},
+	})
+	if err == nil {
+		t.Error("CreateRecord() with an unknown grade succeeded, want constraint error")
+	}
+}
+
+// TestUpdateRecordCondition_RecordsHistory tests that regrades and full
+// updates that change a grade are added to the timeline, and nothing else is
+func TestUpdateRecordCondition_RecordsHistory(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	// :memory: databases are per connection
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+		Title:       "Abbey Road",
+		MediaGrade:  sql.NullString{String: "M", Valid: true},
+		SleeveGrade: sql.NullString{String: "NM", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	updated, err := h.updateRecordCondition(ctx, record.ID, UpdateRecordConditionRequest{
+		MediaGrade:  "VG+",
+		SleeveGrade: "NM",
+		Note:        "Light scuff on side B",
+	})
+	if err != nil {
+		t.Fatalf("updateRecordCondition() error = %v", err)
+	}
+	if updated.MediaGrade.String != "VG+" {
+		t.Errorf("MediaGrade = %v, want VG+", updated.MediaGrade)
+	}
+
+	// Saving the same grades again is not a regrade
+	if _, err := h.updateRecordCondition(ctx, record.ID, UpdateRecordConditionRequest{MediaGrade: "VG+", SleeveGrade: "NM"}); err != nil {
+		t.Fatalf("updateRecordCondition() error = %v", err)
+	}
+
+	// A full update that only changes the title is not a regrade either
+	if _, err := h.updateRecord(ctx, record.ID, UpdateRecordRequest{Title: "Abbey Road (Remaster)", MediaGrade: "VG+", SleeveGrade: "NM"}); err != nil {
+		t.Fatalf("updateRecord() error = %v", err)
+	}
+
+	// A full update that changes the sleeve grade is
+	if _, err := h.updateRecord(ctx, record.ID, UpdateRecordRequest{
+		Title:         "Abbey Road (Remaster)",
+		MediaGrade:    "VG+",
+		SleeveGrade:   "VG",
+		ConditionNote: "Ring wear",
+	}); err != nil {
+		t.Fatalf("updateRecord() error = %v", err)
+	}
+
+	history, err := queries.ListConditionHistoryByRecord(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("ListConditionHistoryByRecord() error = %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("len(history) = %d, want 2", len(history))
+	}
+
+	latest, first := history[0], history[1]
+	if latest.PreviousSleeveGrade.String != "NM" || latest.SleeveGrade.String != "VG" || latest.Note.String != "Ring wear" {
+		t.Errorf("latest entry = sleeve %v -> %v (%v), want NM -> VG (Ring wear)", latest.PreviousSleeveGrade, latest.SleeveGrade, latest.Note)
+	}
+	if first.PreviousMediaGrade.String != "M" || first.MediaGrade.String != "VG+" || first.Note.String != "Light scuff on side B" {
+		t.Errorf("first entry = media %v -> %v (%v), want M -> VG+ (Light scuff on side B)", first.PreviousMediaGrade, first.MediaGrade, first.Note)
+	}
+	if first.UserID.Valid {
+		t.Errorf("anonymous regrade has user_id %v, want NULL", first.UserID)
+	}
+
+	if _, err := h.updateRecordCondition(ctx, record.ID+100, UpdateRecordConditionRequest{MediaGrade: "G"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("updateRecordCondition() on missing record error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// TestGradesMigration_MapsCondition tests that free-text conditions become
+// media grades and anything unrecognised is kept in the notes
+func TestGradesMigration_MapsCondition(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
+	if err != nil {
+		t.Fatalf("Failed to create migration provider: %v", err)
+	}
+	if _, err := provider.UpTo(ctx, 20261016100000); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO records (title, condition, notes, updated_at)
+VALUES ('Mapped', 'Near Mint', NULL, '2024-06-01 12:00:00'),
+       ('Unmapped', 'Excellent', 'Signed', '2024-06-01 12:00:00'),
+       ('Ungraded', NULL, NULL, '2024-06-01 12:00:00')`); err != nil {
+		t.Fatalf("Failed to insert records: %v", err)
+	}
+
+	if _, err := provider.Up(ctx); err != nil {
+		t.Fatalf("Failed to run grades migration: %v", err)
+	}
+
+	queries := store.New(db)
+	records, err := queries.ListRecords(ctx)
+	if err != nil {
+		t.Fatalf("ListRecords() error = %v", err)
+	}
+
+	for _, record := range records {
+		history, err := queries.ListConditionHistoryByRecord(ctx, record.ID)
+		if err != nil {
+			t.Fatalf("ListConditionHistoryByRecord() error = %v", err)
+		}
+
+		switch record.Title {
+		case "Mapped":
+			if record.MediaGrade.String != "NM" {
+				t.Errorf("Mapped: media_grade = %v, want NM", record.MediaGrade)
+			}
+			if len(history) != 1 {
+				t.Errorf("Mapped: %d history entries, want 1", len(history))
+			}
+		case "Unmapped":
+			if record.MediaGrade.Valid {
+				t.Errorf("Unmapped: media_grade = %v, want NULL", record.MediaGrade)
+			}
+			if record.Notes.String != "Signed\nCondition: Excellent" {
+				t.Errorf("Unmapped: notes = %q, want the old condition appended", record.Notes.String)
+			}
+			if len(history) != 0 {
+				t.Errorf("Unmapped: %d history entries, want 0", len(history))
+			}
+		case "Ungraded":
+			if record.MediaGrade.Valid || record.Notes.Valid {
+				t.Errorf("Ungraded: media_grade = %v, notes = %v, want both NULL", record.MediaGrade, record.Notes)
+			}
+		}
+
+		if got := record.UpdatedAt.Time.Format("2006-01-02"); got != "2024-06-01" {
+			t.Errorf("%s: updated_at = %s, want it left alone", record.Title, got)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// creditRoles are the roles an artist can be credited with, in the order the
+// artist page lists them
+var creditRoles = []string{"primary", "featuring", "producer", "remixer"}
+
+var (
+	// errCreditArtistNotFound is returned when a credit names an unknown artist
+	errCreditArtistNotFound = errors.New("artist not found")
+	// errDuplicateCredit is returned when the artist is already credited on the
+	// record in that role
+	errDuplicateCredit = errors.New("artist is already credited in that role")
+)
+
+// CreditRequest credits an artist on a record. Role defaults to primary.
+type CreditRequest struct {
+	ArtistID int64  `form:"artist_id" json:"artist_id" validate:"required,min=1"`
+	Role     string `form:"role" json:"role" validate:"omitempty,oneof=primary featuring producer remixer"`
+}
+
+// role returns the requested role, or primary
+func (req CreditRequest) role() string {
+	if req.Role == "" {
+		return "primary"
+	}
+	return req.Role
+}
+
+// SetRecordArtistsRequest replaces every credit on a record. The order of
+// Artists is the display order; the first primary credit is the lead artist.
+type SetRecordArtistsRequest struct {
+	Artists []CreditRequest `json:"artists" validate:"max=50,dive"`
+}
+
+// CreditGroup is an artist's records in one role
+type CreditGroup struct {
+	Role    string                       `json:"role"`
+	Records []store.ListArtistCreditsRow `json:"records"`
+}
+
+// ArtistCreditsResponse is an artist with their records grouped by role
+type ArtistCreditsResponse struct {
+	Artist  store.Artist  `json:"artist"`
+	Credits []CreditGroup `json:"credits"`
+}
+
+// artistCredits groups every record crediting the artist by role. Roles
+// without records are left out.
+func (h *Handler) artistCredits(ctx context.Context, artistID int64) ([]CreditGroup, error) {
+	rows, err := h.queries.ListArtistCredits(ctx, artistID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []CreditGroup{}
+	for _, role := range creditRoles {
+		var records []store.ListArtistCreditsRow
+		for _, row := range rows {
+			if row.Role == role {
+				records = append(records, row)
+			}
+		}
+		if len(records) > 0 {
+			groups = append(groups, CreditGroup{Role: role, Records: records})
+		}
+	}
+	return groups, nil
+}
+
+// addCredit inserts one credit after the record's existing ones, checking the
+// artist exists first
+func addCredit(ctx context.Context, q *store.Queries, recordID int64, req CreditRequest, position int64) error {
+	if _, err := q.GetArtist(ctx, req.ArtistID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errCreditArtistNotFound
+		}
+		return err
+	}
+
+	_, err := q.CreateRecordArtist(ctx, store.CreateRecordArtistParams{
+		RecordID: recordID,
+		ArtistID: req.ArtistID,
+		Role:     req.role(),
+		Position: position,
+	})
+	if isUniqueViolation(err) {
+		return errDuplicateCredit
+	}
+	return err
+}
+
+// addRecordArtist credits an artist at the end of the record's credits.
+// Returns sql.ErrNoRows if the record doesn't exist.
+func (h *Handler) addRecordArtist(ctx context.Context, recordID int64, req CreditRequest) error {
+	return h.withTx(ctx, func(q *store.Queries) error {
+		if _, err := q.GetRecord(ctx, recordID); err != nil {
+			return err
+		}
+
+		position, err := q.GetNextRecordArtistPosition(ctx, recordID)
+		if err != nil {
+			return err
+		}
+
+		return addCredit(ctx, q, recordID, req, position)
+	})
+}
+
+// setRecordArtists replaces every credit on a record in one transaction, so a
+// bad credit leaves the old ones in place. Returns sql.ErrNoRows if the
+// record doesn't exist.
+func (h *Handler) setRecordArtists(ctx context.Context, recordID int64, credits []CreditRequest) error {
+	return h.withTx(ctx, func(q *store.Queries) error {
+		if _, err := q.GetRecord(ctx, recordID); err != nil {
+			return err
+		}
+
+		if err := q.DeleteRecordArtists(ctx, recordID); err != nil {
+			return err
+		}
+
+		for i, credit := range credits {
+			if err := addCredit(ctx, q, recordID, credit, int64(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// creditErrorStatus maps an error from saving credits to a status and message
+func creditErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errCreditArtistNotFound):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, errDuplicateCredit):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Record not found"
+	default:
+		return http.StatusInternalServerError, "Failed to save credits"
+	}
+}
+
+// renderRecordArtists renders the editable credits partial for a record
+func (h *Handler) renderRecordArtists(w http.ResponseWriter, r *http.Request, recordID int64) {
+	credits, err := h.queries.ListRecordArtists(r.Context(), recordID)
+	if err != nil {
+		h.logger.Error("Failed to retrieve credits", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+		http.Error(w, "Failed to retrieve credits", http.StatusInternalServerError)
+		return
+	}
+
+	artists, err := h.queries.ListArtists(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to retrieve artists", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve artists", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderer.Render(w, "record-artists", map[string]interface{}{
+		"RecordID": recordID,
+		"Credits":  credits,
+		"Artists":  artists,
+		"Roles":    creditRoles,
+	})
+}
+
+// HTML Handlers
+
+// POST /records/{id}/artists
+func (h *Handler) CreateRecordArtist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req CreditRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.addRecordArtist(r.Context(), recordID, req); err != nil {
+			status, message := creditErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to add credit", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderRecordArtists(w, r, recordID)
+	}
+}
+
+// DELETE /records/{id}/artists/{artistID}/{role}
+func (h *Handler) DeleteRecordArtist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		artistID, err := strconv.ParseInt(r.PathValue("artistID"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid parameter: artistID", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := h.queries.DeleteRecordArtist(r.Context(), store.DeleteRecordArtistParams{
+			RecordID: recordID,
+			ArtistID: artistID,
+			Role:     r.PathValue("role"),
+		})
+		if err != nil {
+			h.logger.Error("Failed to remove credit", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to remove credit", http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			http.Error(w, "Credit not found", http.StatusNotFound)
+			return
+		}
+
+		h.renderRecordArtists(w, r, recordID)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/records/{id}/artists
+func (h *Handler) JsonGetRecordArtists() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		credits, err := h.queries.ListRecordArtists(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve credits", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve credits", http.StatusInternalServerError)
+			return
+		}
+		if credits == nil {
+			credits = []store.ListRecordArtistsRow{}
+		}
+
+		h.writeJSON(w, credits, http.StatusOK)
+	}
+}
+
+// PUT /api/v1/records/{id}/artists
+func (h *Handler) JsonSetRecordArtists() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req SetRecordArtistsRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.setRecordArtists(r.Context(), recordID, req.Artists); err != nil {
+			status, message := creditErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to save credits", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		credits, err := h.queries.ListRecordArtists(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve credits", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve credits", http.StatusInternalServerError)
+			return
+		}
+		if credits == nil {
+			credits = []store.ListRecordArtistsRow{}
+		}
+
+		h.writeJSON(w, credits, http.StatusOK)
+	}
+}
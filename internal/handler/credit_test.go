@@ -0,0 +1,309 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/dukerupert/dd/data/sql/migrations"
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+// TestSetRecordArtists_AnyCredit tests that every credit counts towards the
+// artist's records and that the first primary credit is the lead artist
+func TestSetRecordArtists_AnyCredit(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	// :memory: databases are per connection
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	lead, _ := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Daft Punk", SortName: store.ArtistSortName("Daft Punk")})
+	featured, _ := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Pharrell Williams", SortName: store.ArtistSortName("Pharrell Williams")})
+	producer, _ := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Nile Rodgers", SortName: store.ArtistSortName("Nile Rodgers")})
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Random Access Memories"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	if err := h.setRecordArtists(ctx, record.ID, []CreditRequest{
+		{ArtistID: lead.ID},
+		{ArtistID: featured.ID, Role: "featuring"},
+		{ArtistID: producer.ID, Role: "producer"},
+	}); err != nil {
+		t.Fatalf("setRecordArtists() error = %v", err)
+	}
+
+	got, err := queries.GetRecord(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("GetRecord() error = %v", err)
+	}
+	if got.ArtistID.Int64 != lead.ID {
+		t.Errorf("lead artist = %v, want %d", got.ArtistID, lead.ID)
+	}
+
+	for _, artist := range []store.Artist{lead, featured, producer} {
+		records, err := queries.GetRecordsByArtist(ctx, artist.ID)
+		if err != nil {
+			t.Fatalf("GetRecordsByArtist() error = %v", err)
+		}
+		if len(records) != 1 || records[0].ID != record.ID {
+			t.Errorf("GetRecordsByArtist(%s) = %d records, want the credited record", artist.Name, len(records))
+		}
+		count, err := queries.CountRecordsByArtist(ctx, artist.ID)
+		if err != nil || count != 1 {
+			t.Errorf("CountRecordsByArtist(%s) = %d, %v, want 1", artist.Name, count, err)
+		}
+	}
+
+	filtered, err := queries.FilterRecords(ctx, store.RecordFilter{ArtistID: sql.NullInt64{Int64: producer.ID, Valid: true}})
+	if err != nil {
+		t.Fatalf("FilterRecords() error = %v", err)
+	}
+	if len(filtered) != 1 {
+		t.Errorf("FilterRecords() by producer = %d records, want 1", len(filtered))
+	}
+
+	// A record is found by any credited artist's name
+	results, err := queries.SearchCollection(ctx, store.SearchCollectionParams{Query: "pharrell", Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchCollection() error = %v", err)
+	}
+	found := false
+	for _, result := range results {
+		if result.EntityType == "record" && result.EntityID == record.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("SearchCollection(pharrell) = %v, want the record", results)
+	}
+
+	// Reordering the primary credits changes the lead
+	if err := h.setRecordArtists(ctx, record.ID, []CreditRequest{
+		{ArtistID: featured.ID, Role: "primary"},
+		{ArtistID: lead.ID, Role: "primary"},
+	}); err != nil {
+		t.Fatalf("setRecordArtists() error = %v", err)
+	}
+	if got, _ := queries.GetRecord(ctx, record.ID); got.ArtistID.Int64 != featured.ID {
+		t.Errorf("lead artist after reorder = %v, want %d", got.ArtistID, featured.ID)
+	}
+	if count, _ := queries.CountRecordsByArtist(ctx, producer.ID); count != 0 {
+		t.Errorf("CountRecordsByArtist(producer) after replace = %d, want 0", count)
+	}
+
+	// A bad credit leaves the existing ones alone
+	if err := h.setRecordArtists(ctx, record.ID, []CreditRequest{{ArtistID: lead.ID}, {ArtistID: lead.ID}}); !errors.Is(err, errDuplicateCredit) {
+		t.Errorf("duplicate credit error = %v, want errDuplicateCredit", err)
+	}
+	if err := h.setRecordArtists(ctx, record.ID, []CreditRequest{{ArtistID: producer.ID + 100}}); !errors.Is(err, errCreditArtistNotFound) {
+		t.Errorf("unknown artist error = %v, want errCreditArtistNotFound", err)
+	}
+	credits, err := queries.ListRecordArtists(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("ListRecordArtists() error = %v", err)
+	}
+	if len(credits) != 2 || credits[0].ArtistName != "Pharrell Williams" || credits[1].ArtistName != "Daft Punk" {
+		t.Errorf("credits after failed replace = %+v, want Pharrell Williams, Daft Punk", credits)
+	}
+
+	if err := h.setRecordArtists(ctx, record.ID+100, nil); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("setRecordArtists() on missing record error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// TestRecordArtists_LegacyArtistID tests that writes to records.artist_id keep
+// the credits in step, and that deleting the lead artist promotes the next
+func TestRecordArtists_LegacyArtistID(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	first, _ := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Simon", SortName: store.ArtistSortName("Simon")})
+	second, _ := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Garfunkel", SortName: store.ArtistSortName("Garfunkel")})
+	other, _ := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Someone Else", SortName: store.ArtistSortName("Someone Else")})
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+		Title:    "Bookends",
+		ArtistID: sql.NullInt64{Int64: first.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	credits, _ := queries.ListRecordArtists(ctx, record.ID)
+	if len(credits) != 1 || credits[0].ArtistID != first.ID || credits[0].Role != "primary" {
+		t.Fatalf("credits after CreateRecord = %+v, want one primary credit", credits)
+	}
+
+	if err := h.addRecordArtist(ctx, record.ID, CreditRequest{ArtistID: second.ID}); err != nil {
+		t.Fatalf("addRecordArtist() error = %v", err)
+	}
+	if err := h.addRecordArtist(ctx, record.ID, CreditRequest{ArtistID: second.ID}); !errors.Is(err, errDuplicateCredit) {
+		t.Errorf("addRecordArtist() twice error = %v, want errDuplicateCredit", err)
+	}
+
+	// Changing artist_id directly replaces the lead credit
+	if _, err := h.updateRecord(ctx, record.ID, UpdateRecordRequest{Title: "Bookends", ArtistID: other.ID}); err != nil {
+		t.Fatalf("updateRecord() error = %v", err)
+	}
+	credits, _ = queries.ListRecordArtists(ctx, record.ID)
+	if len(credits) != 2 || credits[0].ArtistID != other.ID || credits[1].ArtistID != second.ID {
+		t.Errorf("credits after changing artist_id = %+v, want Someone Else, Garfunkel", credits)
+	}
+	if got, _ := queries.GetRecord(ctx, record.ID); got.ArtistID.Int64 != other.ID {
+		t.Errorf("lead artist = %v, want %d", got.ArtistID, other.ID)
+	}
+
+	// Saving the record with its lead unchanged leaves the credits alone
+	if _, err := h.updateRecord(ctx, record.ID, UpdateRecordRequest{Title: "Bookends (Remaster)", ArtistID: other.ID}); err != nil {
+		t.Fatalf("updateRecord() error = %v", err)
+	}
+	if credits, _ = queries.ListRecordArtists(ctx, record.ID); len(credits) != 2 {
+		t.Errorf("credits after title change = %+v, want 2", credits)
+	}
+
+	// Pointing artist_id at another primary credit promotes it to lead
+	if _, err := h.updateRecord(ctx, record.ID, UpdateRecordRequest{Title: "Bookends (Remaster)", ArtistID: second.ID}); err != nil {
+		t.Fatalf("updateRecord() error = %v", err)
+	}
+	credits, _ = queries.ListRecordArtists(ctx, record.ID)
+	if len(credits) != 1 || credits[0].ArtistID != second.ID {
+		t.Errorf("credits after promoting Garfunkel = %+v, want Garfunkel alone", credits)
+	}
+	if got, _ := queries.GetRecord(ctx, record.ID); got.ArtistID.Int64 != second.ID {
+		t.Errorf("lead artist = %v, want %d", got.ArtistID, second.ID)
+	}
+
+	if err := h.addRecordArtist(ctx, record.ID, CreditRequest{ArtistID: other.ID}); err != nil {
+		t.Fatalf("addRecordArtist() error = %v", err)
+	}
+	if err := queries.DeleteArtist(ctx, second.ID); err != nil {
+		t.Fatalf("DeleteArtist() error = %v", err)
+	}
+	if got, _ := queries.GetRecord(ctx, record.ID); got.ArtistID.Int64 != other.ID {
+		t.Errorf("lead artist after deleting the lead = %v, want %d", got.ArtistID, other.ID)
+	}
+}
+
+// TestArtistCredits_GroupsByRole tests the artist page grouping
+func TestArtistCredits_GroupsByRole(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	quincy, _ := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Quincy Jones", SortName: store.ArtistSortName("Quincy Jones")})
+	michael, _ := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Michael Jackson", SortName: store.ArtistSortName("Michael Jackson")})
+
+	own, _ := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Walking in Space", ArtistID: sql.NullInt64{Int64: quincy.ID, Valid: true}})
+	thriller, _ := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Thriller", ArtistID: sql.NullInt64{Int64: michael.ID, Valid: true}})
+	offTheWall, _ := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Off the Wall", ArtistID: sql.NullInt64{Int64: michael.ID, Valid: true}})
+
+	for _, id := range []int64{thriller.ID, offTheWall.ID} {
+		if err := h.addRecordArtist(ctx, id, CreditRequest{ArtistID: quincy.ID, Role: "producer"}); err != nil {
+			t.Fatalf("addRecordArtist() error = %v", err)
+		}
+	}
+
+	groups, err := h.artistCredits(ctx, quincy.ID)
+	if err != nil {
+		t.Fatalf("artistCredits() error = %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("len(groups) = %d, want 2", len(groups))
+	}
+	if groups[0].Role != "primary" || len(groups[0].Records) != 1 || groups[0].Records[0].ID != own.ID {
+		t.Errorf("primary group = %+v", groups[0])
+	}
+	if groups[1].Role != "producer" || len(groups[1].Records) != 2 {
+		t.Fatalf("producer group = %+v", groups[1])
+	}
+	if groups[1].Records[0].LeadArtistName.String != "Michael Jackson" {
+		t.Errorf("produced record lead = %v, want Michael Jackson", groups[1].Records[0].LeadArtistName)
+	}
+
+	empty, err := h.artistCredits(ctx, quincy.ID+100)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("artistCredits() for unknown artist = %v, %v, want none", empty, err)
+	}
+}
+
+// TestRecordArtistsMigration_BackfillsArtistID tests that existing artist_id
+// values become primary credits
+func TestRecordArtistsMigration_BackfillsArtistID(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+
+	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
+	if err != nil {
+		t.Fatalf("Failed to create migration provider: %v", err)
+	}
+	if _, err := provider.UpTo(ctx, 20261016120000); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO artists (id, name) VALUES (1, 'Miles Davis');
+INSERT INTO records (id, title, artist_id) VALUES (1, 'Kind of Blue', 1), (2, 'Unknown', NULL)`); err != nil {
+		t.Fatalf("Failed to insert records: %v", err)
+	}
+
+	if _, err := provider.Up(ctx); err != nil {
+		t.Fatalf("Failed to run record artists migration: %v", err)
+	}
+
+	queries := store.New(db)
+	credits, err := queries.ListRecordArtists(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListRecordArtists() error = %v", err)
+	}
+	if len(credits) != 1 || credits[0].ArtistID != 1 || credits[0].Role != "primary" {
+		t.Errorf("credits = %+v, want Miles Davis as primary", credits)
+	}
+	if credits, _ := queries.ListRecordArtists(ctx, 2); len(credits) != 0 {
+		t.Errorf("record without artist has credits %+v", credits)
+	}
+
+	record, err := queries.GetRecord(ctx, 1)
+	if err != nil || record.ArtistID.Int64 != 1 {
+		t.Errorf("artist_id after migration = %v, %v, want 1", record.ArtistID, err)
+	}
+
+	// Renaming an artist still reaches their records in the search index
+	if _, err := queries.UpdateArtist(ctx, store.UpdateArtistParams{Name: "Miles Dewey Davis", ID: 1}); err != nil {
+		t.Fatalf("UpdateArtist() error = %v", err)
+	}
+	results, err := queries.SearchCollection(ctx, store.SearchCollectionParams{Query: "dewey", Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchCollection() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("SearchCollection(dewey) = %v, want the artist and the record", results)
+	}
+
+	if _, err := provider.Down(ctx); err != nil {
+		t.Fatalf("Failed to roll back record artists migration: %v", err)
+	}
+	var artistID sql.NullInt64
+	if err := db.QueryRow(`SELECT artist_id FROM records WHERE id = 1`).Scan(&artistID); err != nil || artistID.Int64 != 1 {
+		t.Errorf("artist_id after rollback = %v, %v, want 1", artistID, err)
+	}
+}
@@ -0,0 +1,643 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// duplicateThreshold is the lowest score a pair of records needs to be
+// suggested as duplicates
+const duplicateThreshold = 0.75
+
+var (
+	// errMergeRecordIntoSelf is returned when a record is merged into itself
+	errMergeRecordIntoSelf = errors.New("cannot merge a record into itself")
+	// errMergeOnLoan is returned when the record being merged away is lent out
+	errMergeOnLoan = errors.New("the duplicate is on loan; return it before merging")
+)
+
+// duplicateWeights are how much each field counts toward a pair's score.
+// Only fields both records have are compared, so a pair isn't penalised for
+// details nobody entered.
+var duplicateWeights = struct {
+	Barcode, Artist, Title, Album, Catalog, Year float64
+}{
+	Barcode: 0.3,
+	Artist:  0.25,
+	Title:   0.35,
+	Album:   0.1,
+	Catalog: 0.2,
+	Year:    0.1,
+}
+
+// MergeRecordRequest merges a record into another; the record in the path is
+// deleted
+type MergeRecordRequest struct {
+	IntoID int64 `form:"into_id" json:"into_id" validate:"required,min=1"`
+}
+
+// DismissDuplicateRequest marks a suggested pair as different records
+type DismissDuplicateRequest struct {
+	RecordID    int64 `form:"record_id" json:"record_id" validate:"required,min=1"`
+	DuplicateID int64 `form:"duplicate_id" json:"duplicate_id" validate:"required,min=1,nefield=RecordID"`
+}
+
+// DuplicatePair is two records that look like the same one. Record is the
+// older of the two. Score runs from 0 to 1 and Matches says which fields
+// agree.
+type DuplicatePair struct {
+	Record    store.ListDuplicateCandidatesRow `json:"record"`
+	Duplicate store.ListDuplicateCandidatesRow `json:"duplicate"`
+	Score     float64                          `json:"score"`
+	Matches   []string                         `json:"matches"`
+}
+
+// Percent is the score as a whole percentage
+func (p DuplicatePair) Percent() int {
+	return int(math.Round(p.Score * 100))
+}
+
+// DuplicatesResponse is the pairs awaiting review, most likely first
+type DuplicatesResponse struct {
+	Pairs []DuplicatePair `json:"pairs"`
+}
+
+// duplicateTokens splits s into lowercase words of letters and digits,
+// dropping a leading "the" so "The Beatles" and "Beatles" agree
+func duplicateTokens(s string) []string {
+	tokens := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(tokens) > 1 && tokens[0] == "the" {
+		tokens = tokens[1:]
+	}
+	return tokens
+}
+
+// duplicateKey normalizes s for exact comparison: "Abbey Road!" and
+// "abbey-road" have the same key
+func duplicateKey(s string) string {
+	return strings.Join(duplicateTokens(s), "")
+}
+
+// textSimilarity is 1 for texts with the same key, otherwise the share of
+// words they have in common
+func textSimilarity(a, b string) float64 {
+	if duplicateKey(a) == duplicateKey(b) {
+		return 1
+	}
+
+	words := map[string]int{}
+	for _, t := range duplicateTokens(a) {
+		words[t] |= 1
+	}
+	for _, t := range duplicateTokens(b) {
+		words[t] |= 2
+	}
+	if len(words) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for _, sides := range words {
+		if sides == 3 {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(words))
+}
+
+// scoreDuplicate compares two records field by field and returns how alike
+// they are, from 0 to 1, and which fields match
+func scoreDuplicate(a, b store.ListDuplicateCandidatesRow) (float64, []string) {
+	var total, weight float64
+	var matches []string
+	compare := func(field string, w, similarity float64) {
+		total += w * similarity
+		weight += w
+		switch {
+		case similarity == 1:
+			matches = append(matches, "same "+field)
+		case similarity >= 0.5:
+			matches = append(matches, "similar "+field)
+		}
+	}
+	same := func(equal bool) float64 {
+		if equal {
+			return 1
+		}
+		return 0
+	}
+
+	if a.Barcode.String != "" && b.Barcode.String != "" {
+		compare("barcode", duplicateWeights.Barcode, same(normalizeBarcode(a.Barcode.String) == normalizeBarcode(b.Barcode.String)))
+	}
+	if duplicateKey(a.ArtistName.String) != "" && duplicateKey(b.ArtistName.String) != "" {
+		compare("artist", duplicateWeights.Artist, textSimilarity(a.ArtistName.String, b.ArtistName.String))
+	}
+	compare("title", duplicateWeights.Title, textSimilarity(a.Title, b.Title))
+	if duplicateKey(a.AlbumTitle.String) != "" && duplicateKey(b.AlbumTitle.String) != "" {
+		compare("album", duplicateWeights.Album, textSimilarity(a.AlbumTitle.String, b.AlbumTitle.String))
+	}
+	if duplicateKey(a.CatalogNumber.String) != "" && duplicateKey(b.CatalogNumber.String) != "" {
+		compare("catalog number", duplicateWeights.Catalog, same(duplicateKey(a.CatalogNumber.String) == duplicateKey(b.CatalogNumber.String)))
+	}
+	if a.ReleaseYear.Valid && b.ReleaseYear.Valid {
+		compare("year", duplicateWeights.Year, same(a.ReleaseYear.Int64 == b.ReleaseYear.Int64))
+	}
+
+	return total / weight, matches
+}
+
+// duplicateBlocks are the keys a record is filed under for comparison. Only
+// records sharing a key are scored against each other, which keeps the
+// finder from comparing every record with every other.
+func duplicateBlocks(r store.ListDuplicateCandidatesRow) []string {
+	var keys []string
+	add := func(prefix, key string) {
+		if key != "" {
+			keys = append(keys, prefix+key)
+		}
+	}
+
+	add("title:", duplicateKey(r.Title))
+	add("album:", duplicateKey(r.AlbumTitle.String))
+	add("catalog:", duplicateKey(r.CatalogNumber.String))
+	add("barcode:", normalizeBarcode(r.Barcode.String))
+	if words := duplicateTokens(r.Title); len(words) > 0 {
+		add("artist:", cmp.Or(duplicateKey(r.ArtistName.String), "-")+"/"+words[0])
+	}
+	return keys
+}
+
+// findDuplicates scores records that share a title, album, catalog number,
+// barcode or artist and first title word, and returns the pairs scoring at
+// least duplicateThreshold that haven't been dismissed, most likely first
+func (h *Handler) findDuplicates(ctx context.Context) ([]DuplicatePair, error) {
+	records, err := h.queries.ListDuplicateCandidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dismissals, err := h.queries.ListDismissedDuplicates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[[2]int64]bool{}
+	for _, d := range dismissals {
+		seen[[2]int64{d.RecordID, d.DuplicateID}] = true
+	}
+
+	blocks := map[string][]int{}
+	for i, r := range records {
+		for _, key := range duplicateBlocks(r) {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	pairs := []DuplicatePair{}
+	for _, block := range blocks {
+		for x, i := range block {
+			for _, j := range block[x+1:] {
+				// Records are listed by id, so i is the older record
+				key := [2]int64{records[i].ID, records[j].ID}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				score, matches := scoreDuplicate(records[i], records[j])
+				if score < duplicateThreshold {
+					continue
+				}
+				pairs = append(pairs, DuplicatePair{
+					Record:    records[i],
+					Duplicate: records[j],
+					Score:     score,
+					Matches:   matches,
+				})
+			}
+		}
+	}
+
+	slices.SortFunc(pairs, func(a, b DuplicatePair) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(a.Record.ID, b.Record.ID),
+			cmp.Compare(a.Duplicate.ID, b.Duplicate.ID),
+		)
+	})
+	return pairs, nil
+}
+
+// dismissDuplicate records that a suggested pair are different records.
+// Returns sql.ErrNoRows if either record doesn't exist.
+func (h *Handler) dismissDuplicate(ctx context.Context, req DismissDuplicateRequest) error {
+	return h.withTx(ctx, func(q *store.Queries) error {
+		for _, id := range []int64{req.RecordID, req.DuplicateID} {
+			if _, err := q.GetRecord(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		return q.DismissDuplicate(ctx, store.DismissDuplicateParams{
+			RecordID:    min(req.RecordID, req.DuplicateID),
+			DuplicateID: max(req.RecordID, req.DuplicateID),
+		})
+	})
+}
+
+// betterGrade returns whichever grade ranks higher, or the one that's set
+func betterGrade(a, b sql.NullString, ranks map[string]int64) sql.NullString {
+	if !a.Valid {
+		return b
+	}
+	if b.Valid && ranks[b.String] > ranks[a.String] {
+		return b
+	}
+	return a
+}
+
+// mergeNotes keeps both records' notes, without repeating notes one already
+// has
+func mergeNotes(a, b sql.NullString) sql.NullString {
+	into, from := strings.TrimSpace(a.String), strings.TrimSpace(b.String)
+	switch {
+	case from == "" || strings.Contains(into, from):
+		return a
+	case into == "":
+		return b
+	default:
+		return sql.NullString{String: into + "\n\n" + from, Valid: true}
+	}
+}
+
+// mergeRecords folds a duplicate record into another and deletes it, all in
+// one transaction. The kept record gains the duplicate's plays, loans, value
+// history, condition history, tags, credits and wishlist links, and its
+// tracklist, purchase and images where it has none of its own. Blank fields
+// are filled from the duplicate, notes are combined and the better grade of
+// each is kept. Returns sql.ErrNoRows if either record doesn't exist.
+func (h *Handler) mergeRecords(ctx context.Context, fromID, intoID int64) (store.Record, error) {
+	if fromID == intoID {
+		return store.Record{}, errMergeRecordIntoSelf
+	}
+
+	var into store.Record
+	var leftover, moved []string
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		from, err := q.GetRecord(ctx, fromID)
+		if err != nil {
+			return err
+		}
+		if _, err := q.GetRecord(ctx, intoID); err != nil {
+			return err
+		}
+
+		// A lent-out duplicate has to come back before it can be merged
+		if _, err := q.GetOpenLoanByRecord(ctx, fromID); err == nil {
+			return errMergeOnLoan
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		grades, err := q.ListGrades(ctx)
+		if err != nil {
+			return err
+		}
+		ranks := make(map[string]int64, len(grades))
+		for _, g := range grades {
+			ranks[g.Code] = g.Rank
+		}
+
+		offset, err := q.GetNextRecordArtistPosition(ctx, intoID)
+		if err != nil {
+			return err
+		}
+
+		intoImages, err := q.ListRecordImages(ctx, intoID)
+		if err != nil {
+			return err
+		}
+		fromImages, err := q.ListRecordImages(ctx, fromID)
+		if err != nil {
+			return err
+		}
+
+		move := store.MovePlaysParams{IntoID: intoID, FromID: fromID}
+		if err := q.MovePlays(ctx, move); err != nil {
+			return err
+		}
+		if err := q.MoveLoans(ctx, store.MoveLoansParams(move)); err != nil {
+			return err
+		}
+		if err := q.MoveRecordValues(ctx, store.MoveRecordValuesParams(move)); err != nil {
+			return err
+		}
+		if err := q.MoveConditionHistory(ctx, store.MoveConditionHistoryParams(move)); err != nil {
+			return err
+		}
+		if err := q.MoveRecordMovements(ctx, store.MoveRecordMovementsParams(move)); err != nil {
+			return err
+		}
+		if err := q.MoveWants(ctx, store.MoveWantsParams(move)); err != nil {
+			return err
+		}
+		if err := q.MoveTracks(ctx, store.MoveTracksParams(move)); err != nil {
+			return err
+		}
+		if err := q.MergeRecordTags(ctx, store.MergeRecordTagsParams(move)); err != nil {
+			return err
+		}
+		if err := q.MergeRecordPurchase(ctx, store.MergeRecordPurchaseParams(move)); err != nil {
+			return err
+		}
+		if err := q.MergeRecordImages(ctx, store.MergeRecordImagesParams(move)); err != nil {
+			return err
+		}
+		err = q.MergeRecordArtists(ctx, store.MergeRecordArtistsParams{
+			IntoID:         intoID,
+			PositionOffset: offset,
+			FromID:         fromID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Reread: the triggers have recounted plays and may have set the
+		// lead artist from the duplicate's credits
+		before, err := q.GetRecord(ctx, intoID)
+		if err != nil {
+			return err
+		}
+
+		into, err = q.UpdateRecord(ctx, store.UpdateRecordParams{
+			Title:             before.Title,
+			ArtistID:          before.ArtistID,
+			AlbumTitle:        cmp.Or(before.AlbumTitle, from.AlbumTitle),
+			ReleaseYear:       cmp.Or(before.ReleaseYear, from.ReleaseYear),
+			CurrentLocationID: cmp.Or(before.CurrentLocationID, from.CurrentLocationID),
+			HomeLocationID:    cmp.Or(before.HomeLocationID, from.HomeLocationID),
+			CatalogNumber:     cmp.Or(before.CatalogNumber, from.CatalogNumber),
+			MediaGrade:        betterGrade(before.MediaGrade, from.MediaGrade, ranks),
+			SleeveGrade:       betterGrade(before.SleeveGrade, from.SleeveGrade, ranks),
+			Notes:             mergeNotes(before.Notes, from.Notes),
+			Barcode:           cmp.Or(before.Barcode, from.Barcode),
+			ID:                intoID,
+		})
+		if err != nil {
+			return err
+		}
+		if !before.CurrentLocationID.Valid && from.CurrentLocationID.Valid {
+			// It takes the duplicate's place on the shelf
+			into.ShelfPosition = from.ShelfPosition
+			if err := q.SetRecordShelfPosition(ctx, store.SetRecordShelfPositionParams{
+				ShelfPosition: from.ShelfPosition,
+				ID:            intoID,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if err := recordRegrade(ctx, q, before, into, fmt.Sprintf("Merged with duplicate record #%d", fromID)); err != nil {
+			return err
+		}
+		if err := recordMove(ctx, q, before, into, fmt.Sprintf("Merged with duplicate record #%d", fromID)); err != nil {
+			return err
+		}
+
+		if err := q.DeleteRecord(ctx, fromID); err != nil {
+			return err
+		}
+
+		// Files are moved last, and moved back below if the merge fails after
+		// all. Images the kept record already has are deleted after commit.
+		for _, img := range fromImages {
+			if slices.ContainsFunc(intoImages, func(i store.RecordImage) bool { return i.Kind == img.Kind }) {
+				leftover = append(leftover, img.Kind)
+				continue
+			}
+			// Counted before the move, which may fail partway through
+			moved = append(moved, img.Kind)
+			if err := h.media.Move(fromID, intoID, img.Kind); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// The rollback left the images with the duplicate, so their files go
+		// back too
+		for _, kind := range moved {
+			if err := h.media.Move(intoID, fromID, kind); err != nil {
+				h.logger.Error("Failed to move merged record image back", slog.String("error", err.Error()), slog.Int64("recordID", fromID), slog.String("kind", kind))
+			}
+		}
+		return store.Record{}, err
+	}
+
+	for _, kind := range leftover {
+		if err := h.media.Delete(fromID, kind); err != nil {
+			h.logger.Error("Failed to delete merged record image", slog.String("error", err.Error()), slog.Int64("recordID", fromID), slog.String("kind", kind))
+		}
+	}
+	return into, nil
+}
+
+func duplicateErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errMergeRecordIntoSelf):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, errMergeOnLoan):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Record not found"
+	default:
+		return http.StatusInternalServerError, "Failed to merge records"
+	}
+}
+
+func (h *Handler) renderDuplicatesList(w http.ResponseWriter, r *http.Request) {
+	pairs, err := h.findDuplicates(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to find duplicates", slog.String("error", err.Error()))
+		http.Error(w, "Failed to find duplicates", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderer.Render(w, "duplicates-list", map[string]interface{}{
+		"Pairs": pairs,
+	})
+}
+
+// HTML Handlers
+
+// GET /duplicates
+func (h *Handler) GetDuplicates() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pairs, err := h.findDuplicates(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to find duplicates", slog.String("error", err.Error()))
+			http.Error(w, "Failed to find duplicates", http.StatusInternalServerError)
+			return
+		}
+
+		err = h.renderer.Render(w, "duplicates", map[string]interface{}{
+			"Title": "Duplicates",
+			"Pairs": pairs,
+		})
+		if err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// POST /records/{id}/merge
+func (h *Handler) MergeRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req MergeRecordRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.mergeRecords(r.Context(), recordID, req.IntoID); err != nil {
+			status, message := duplicateErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to merge records", slog.String("error", err.Error()), slog.Int64("recordID", recordID), slog.Int64("intoID", req.IntoID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderDuplicatesList(w, r)
+	}
+}
+
+// POST /duplicates/dismiss
+func (h *Handler) DismissDuplicate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req DismissDuplicateRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.dismissDuplicate(r.Context(), req); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Record not found", http.StatusNotFound)
+				return
+			}
+			h.logger.Error("Failed to dismiss duplicate", slog.String("error", err.Error()), slog.Int64("recordID", req.RecordID), slog.Int64("duplicateID", req.DuplicateID))
+			http.Error(w, "Failed to dismiss duplicate", http.StatusInternalServerError)
+			return
+		}
+
+		h.renderDuplicatesList(w, r)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/duplicates
+func (h *Handler) JsonGetDuplicates() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pairs, err := h.findDuplicates(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to find duplicates", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to find duplicates", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, DuplicatesResponse{Pairs: pairs}, http.StatusOK)
+	}
+}
+
+// POST /api/v1/records/{id}/merge
+func (h *Handler) JsonMergeRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req MergeRecordRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		record, err := h.mergeRecords(r.Context(), recordID, req.IntoID)
+		if err != nil {
+			status, message := duplicateErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to merge records", slog.String("error", err.Error()), slog.Int64("recordID", recordID), slog.Int64("intoID", req.IntoID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, record, http.StatusOK)
+	}
+}
+
+// POST /api/v1/duplicates/dismiss
+func (h *Handler) JsonDismissDuplicate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req DismissDuplicateRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.dismissDuplicate(r.Context(), req); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+				return
+			}
+			h.logger.Error("Failed to dismiss duplicate", slog.String("error", err.Error()), slog.Int64("recordID", req.RecordID), slog.Int64("duplicateID", req.DuplicateID))
+			h.writeErrorJSON(w, "Failed to dismiss duplicate", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,307 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/dd/internal/media"
+	"github.com/dukerupert/dd/internal/store"
+)
+
+func TestScoreDuplicate(t *testing.T) {
+	record := func(artist, title, catalog string, year int64) store.ListDuplicateCandidatesRow {
+		return store.ListDuplicateCandidatesRow{
+			Title:         title,
+			ArtistName:    sql.NullString{String: artist, Valid: artist != ""},
+			CatalogNumber: sql.NullString{String: catalog, Valid: catalog != ""},
+			ReleaseYear:   sql.NullInt64{Int64: year, Valid: year != 0},
+		}
+	}
+
+	tests := []struct {
+		name string
+		a, b store.ListDuplicateCandidatesRow
+		want bool
+	}{
+		{"same record typed differently", record("The Beatles", "Abbey Road", "PCS 7088", 1969), record("Beatles", "abbey road!", "PCS-7088", 1969), true},
+		{"missing details aren't held against a pair", record("Nirvana", "Bleach", "", 0), record("Nirvana", "Bleach", "SP 34", 1989), true},
+		{"same title by another artist", record("The Beatles", "Abbey Road", "", 1969), record("George Benson", "Abbey Road", "", 1970), false},
+		{"another pressing", record("Nirvana", "Nevermind", "DGC-24425", 1991), record("Nirvana", "Nevermind", "B0012", 2009), false},
+		{"another record by the artist", record("Nirvana", "Bleach", "", 0), record("Nirvana", "Nevermind", "", 0), false},
+	}
+	for _, tt := range tests {
+		score, matches := scoreDuplicate(tt.a, tt.b)
+		if got := score >= duplicateThreshold; got != tt.want {
+			t.Errorf("%s: score = %.2f (%v), duplicate = %v, want %v", tt.name, score, matches, got, tt.want)
+		}
+	}
+}
+
+// TestDuplicate_Find tests that records sharing a title or barcode are paired
+// up, best match first, and that dismissed pairs stay dismissed
+func TestDuplicate_Find(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	create := func(req CreateRecordRequest) store.Record {
+		t.Helper()
+		record, err := h.createRecord(ctx, req)
+		if err != nil {
+			t.Fatalf("createRecord(%q) error = %v", req.Title, err)
+		}
+		return record
+	}
+	abbey := create(CreateRecordRequest{Title: "Abbey Road", ArtistName: "The Beatles", ReleaseYear: 1969, CatalogNumber: "PCS 7088"})
+	abbeyAgain := create(CreateRecordRequest{Title: "abbey road", ArtistName: "Beatles", CatalogNumber: "PCS-7088"})
+	create(CreateRecordRequest{Title: "Abbey Road", ArtistName: "George Benson", ReleaseYear: 1970})
+	create(CreateRecordRequest{Title: "Let It Be", ArtistName: "The Beatles"})
+	nevermind := create(CreateRecordRequest{Title: "Nevermind", ArtistName: "Nirvana", Barcode: "720642442517"})
+	remaster := create(CreateRecordRequest{Title: "Nevermind (Remastered)", ArtistName: "Nirvana", Barcode: "0720642442517"})
+
+	pairs, err := h.findDuplicates(ctx)
+	if err != nil {
+		t.Fatalf("findDuplicates() error = %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("pairs = %+v, want 2", pairs)
+	}
+	if pairs[0].Record.ID != abbey.ID || pairs[0].Duplicate.ID != abbeyAgain.ID || pairs[0].Percent() != 100 {
+		t.Errorf("first pair = %d/%d at %d%%, want Abbey Road at 100%%", pairs[0].Record.ID, pairs[0].Duplicate.ID, pairs[0].Percent())
+	}
+	if pairs[1].Record.ID != nevermind.ID || pairs[1].Duplicate.ID != remaster.ID {
+		t.Errorf("second pair = %d/%d, want the Nevermind pressings", pairs[1].Record.ID, pairs[1].Duplicate.ID)
+	}
+
+	// The pair can be dismissed either way round
+	if err := h.dismissDuplicate(ctx, DismissDuplicateRequest{RecordID: remaster.ID, DuplicateID: nevermind.ID}); err != nil {
+		t.Fatalf("dismissDuplicate() error = %v", err)
+	}
+	if err := h.dismissDuplicate(ctx, DismissDuplicateRequest{RecordID: nevermind.ID, DuplicateID: remaster.ID}); err != nil {
+		t.Errorf("dismissDuplicate() again error = %v", err)
+	}
+	if err := h.dismissDuplicate(ctx, DismissDuplicateRequest{RecordID: nevermind.ID, DuplicateID: remaster.ID + 100}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("dismiss unknown record error = %v, want sql.ErrNoRows", err)
+	}
+
+	if pairs, err = h.findDuplicates(ctx); err != nil {
+		t.Fatalf("findDuplicates() error = %v", err)
+	}
+	if len(pairs) != 1 || pairs[0].Record.ID != abbey.ID {
+		t.Errorf("pairs after dismissal = %+v, want only Abbey Road", pairs)
+	}
+}
+
+// TestDuplicate_Merge tests that merging moves a duplicate's history onto the
+// kept record, keeps the better grades and deletes the duplicate
+func TestDuplicate_Merge(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	keep, err := h.createRecord(ctx, CreateRecordRequest{Title: "Abbey Road", ArtistName: "The Beatles", MediaGrade: "VG", SleeveGrade: "NM", Notes: "First pressing"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	dupe, err := h.createRecord(ctx, CreateRecordRequest{Title: "Abbey Road", ArtistName: "The Beatles", MediaGrade: "NM", SleeveGrade: "VG", Notes: "From Dad", CatalogNumber: "PCS 7088", ReleaseYear: 1969})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+
+	for _, name := range []string{"rock", "classics"} {
+		if _, err := h.tagRecord(ctx, dupe.ID, RecordTagRequest{Name: name}); err != nil {
+			t.Fatalf("tagRecord() error = %v", err)
+		}
+	}
+	if _, err := h.tagRecord(ctx, keep.ID, RecordTagRequest{Name: "rock"}); err != nil {
+		t.Fatalf("tagRecord() error = %v", err)
+	}
+	for range 2 {
+		if _, err := h.createPlay(ctx, dupe.ID, PlayRequest{}); err != nil {
+			t.Fatalf("createPlay() error = %v", err)
+		}
+	}
+	if _, err := h.addValue(ctx, dupe.ID, ValueRequest{Amount: 40}, now); err != nil {
+		t.Fatalf("addValue() error = %v", err)
+	}
+
+	if _, err := h.mergeRecords(ctx, keep.ID, keep.ID); !errors.Is(err, errMergeRecordIntoSelf) {
+		t.Errorf("merge into self error = %v, want errMergeRecordIntoSelf", err)
+	}
+	if _, err := h.mergeRecords(ctx, dupe.ID, keep.ID+100); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("merge into unknown record error = %v, want sql.ErrNoRows", err)
+	}
+
+	merged, err := h.mergeRecords(ctx, dupe.ID, keep.ID)
+	if err != nil {
+		t.Fatalf("mergeRecords() error = %v", err)
+	}
+	if merged.MediaGrade.String != "NM" || merged.SleeveGrade.String != "NM" {
+		t.Errorf("grades = %s/%s, want NM/NM", merged.MediaGrade.String, merged.SleeveGrade.String)
+	}
+	if merged.CatalogNumber.String != "PCS 7088" || merged.ReleaseYear.Int64 != 1969 {
+		t.Errorf("merged = %+v, want the duplicate's catalog number and year", merged)
+	}
+	if merged.Notes.String != "First pressing\n\nFrom Dad" {
+		t.Errorf("notes = %q, want both records' notes", merged.Notes.String)
+	}
+	if merged.PlayCount.Int64 != 2 {
+		t.Errorf("play count = %d, want the duplicate's 2 plays", merged.PlayCount.Int64)
+	}
+
+	if _, err := queries.GetRecord(ctx, dupe.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("duplicate still exists: %v", err)
+	}
+	if tags, _ := queries.ListTagsByRecord(ctx, keep.ID); len(tags) != 2 {
+		t.Errorf("tags = %+v, want rock and classics", tags)
+	}
+	if values, _ := queries.ListRecordValues(ctx, keep.ID); len(values) != 1 {
+		t.Errorf("values = %+v, want the duplicate's estimate", values)
+	}
+	if credits, _ := queries.ListRecordArtists(ctx, keep.ID); len(credits) != 1 {
+		t.Errorf("credits = %+v, want one Beatles credit", credits)
+	}
+	history, err := queries.ListConditionHistoryByRecord(ctx, keep.ID)
+	if err != nil || len(history) == 0 || !strings.Contains(history[0].Note.String, strconv.FormatInt(dupe.ID, 10)) {
+		t.Errorf("condition history = %+v (%v), want a regrade noting the merge", history, err)
+	}
+
+	// A record out on loan has to come back first
+	lent, err := h.createRecord(ctx, CreateRecordRequest{Title: "Abbey Road", ArtistName: "The Beatles"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	if _, err := h.lendRecord(ctx, lent.ID, LendRequest{BorrowerName: "Sam"}, now); err != nil {
+		t.Fatalf("lendRecord() error = %v", err)
+	}
+	if _, err := h.mergeRecords(ctx, lent.ID, keep.ID); !errors.Is(err, errMergeOnLoan) {
+		t.Errorf("merge lent record error = %v, want errMergeOnLoan", err)
+	}
+	if _, err := queries.GetRecord(ctx, lent.ID); err != nil {
+		t.Errorf("lent record was deleted: %v", err)
+	}
+}
+
+// TestDuplicate_MergeImagesRollback tests that a merge failing partway
+// through moving images puts the files back with the duplicate, where the
+// rolled back database still has them
+func TestDuplicate_MergeImagesRollback(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	files := media.NewStore(t.TempDir(), 1<<20)
+	h := &Handler{db: db, queries: queries, media: files, logger: slog.New(slog.DiscardHandler)}
+
+	keep, err := h.createRecord(ctx, CreateRecordRequest{Title: "Abbey Road", ArtistName: "The Beatles"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	dupe, err := h.createRecord(ctx, CreateRecordRequest{Title: "Abbey Road", ArtistName: "The Beatles"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	for _, kind := range []string{"front", "back"} {
+		if _, err := h.saveRecordImage(ctx, dupe.ID, kind, bytes.NewReader(testPNG(t, 50, 50))); err != nil {
+			t.Fatalf("saveRecordImage(%s) error = %v", kind, err)
+		}
+	}
+
+	// Something already in the way of the back cover fails its move after
+	// the front cover has gone
+	blocker := files.Path(keep.ID, "back", "")
+	if err := os.MkdirAll(filepath.Join(blocker, "in-the-way"), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	if _, err := h.mergeRecords(ctx, dupe.ID, keep.ID); err == nil {
+		t.Fatal("mergeRecords() error = nil, want the failed move")
+	}
+
+	if _, err := queries.GetRecord(ctx, dupe.ID); err != nil {
+		t.Errorf("duplicate was deleted: %v", err)
+	}
+	if images, _ := queries.ListRecordImages(ctx, dupe.ID); len(images) != 2 {
+		t.Errorf("duplicate images = %+v, want front and back", images)
+	}
+	for _, kind := range []string{"front", "back"} {
+		for _, size := range []string{"", media.SizeSmall} {
+			if _, err := os.Stat(files.Path(dupe.ID, kind, size)); err != nil {
+				t.Errorf("duplicate's %s %q file: %v", kind, size, err)
+			}
+		}
+	}
+	if _, err := os.Stat(files.Path(keep.ID, "front", "")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("kept record's front file error = %v, want it moved back", err)
+	}
+}
+
+func TestJsonMergeRecord(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{
+		db:       db,
+		queries:  queries,
+		logger:   slog.New(slog.DiscardHandler),
+		validate: newValidator(),
+	}
+
+	keep, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Bleach"})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+	dupe, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Bleach"})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+
+	merge := func(id int64, body string) int {
+		r := httptest.NewRequest("POST", "/api/v1/records/"+strconv.FormatInt(id, 10)+"/merge", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.SetPathValue("id", strconv.FormatInt(id, 10))
+		w := httptest.NewRecorder()
+		h.JsonMergeRecord()(w, r)
+		return w.Code
+	}
+	into := `{"into_id": ` + strconv.FormatInt(keep.ID, 10) + `}`
+
+	tests := []struct {
+		name string
+		id   int64
+		body string
+		want int
+	}{
+		{"no target", dupe.ID, `{}`, http.StatusBadRequest},
+		{"into itself", keep.ID, into, http.StatusBadRequest},
+		{"unknown record", dupe.ID + 100, into, http.StatusNotFound},
+		{"merged", dupe.ID, into, http.StatusOK},
+		{"already merged", dupe.ID, into, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := merge(tt.id, tt.body); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
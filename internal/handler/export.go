@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// exportContentTypes are the export formats and their content types
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+}
+
+// exportFormats lists the formats in the order the records page offers them
+var exportFormats = []string{"csv", "json", "ndjson"}
+
+// exportColumns is the CSV header. The names match the CSV import's, so an
+// export can be imported again.
+var exportColumns = []string{
+	"id", "title", "artist", "album_title", "release_year", "catalog_number",
+	"barcode", "media_grade", "sleeve_grade", "location", "home_location",
+	"play_count", "last_played_at", "track_count", "running_time", "notes",
+	"created_at", "updated_at",
+}
+
+// ExportRequest picks the export format; the records are filtered by the
+// same query parameters as the listing (ListRecordsRequest), without paging
+type ExportRequest struct {
+	Format string `form:"format" json:"format" validate:"omitempty,oneof=csv json ndjson"`
+}
+
+// ExportLink is a download of the current listing in one format
+type ExportLink struct {
+	Label string
+	URL   string
+}
+
+// ExportRecord is a record as exported, with artist and location names
+// joined in and unset fields left empty
+type ExportRecord struct {
+	ID             int64      `json:"id"`
+	Title          string     `json:"title"`
+	ArtistID       int64      `json:"artist_id,omitempty"`
+	Artist         string     `json:"artist,omitempty"`
+	AlbumTitle     string     `json:"album_title,omitempty"`
+	ReleaseYear    int64      `json:"release_year,omitempty"`
+	CatalogNumber  string     `json:"catalog_number,omitempty"`
+	Barcode        string     `json:"barcode,omitempty"`
+	MediaGrade     string     `json:"media_grade,omitempty"`
+	SleeveGrade    string     `json:"sleeve_grade,omitempty"`
+	LocationID     int64      `json:"location_id,omitempty"`
+	Location       string     `json:"location,omitempty"`
+	HomeLocationID int64      `json:"home_location_id,omitempty"`
+	HomeLocation   string     `json:"home_location,omitempty"`
+	PlayCount      int64      `json:"play_count"`
+	LastPlayedAt   *time.Time `json:"last_played_at,omitempty"`
+	TrackCount     int64      `json:"track_count"`
+	RunningTime    int64      `json:"running_time"`
+	Notes          string     `json:"notes,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// newExportRecord flattens a record listing row for export
+func newExportRecord(row store.ListRecordsWithDetailsRow) ExportRecord {
+	timePtr := func(t sql.NullTime) *time.Time {
+		if !t.Valid {
+			return nil
+		}
+		return &t.Time
+	}
+	return ExportRecord{
+		ID:             row.ID,
+		Title:          row.Title,
+		ArtistID:       row.ArtistID.Int64,
+		Artist:         row.ArtistName.String,
+		AlbumTitle:     row.AlbumTitle.String,
+		ReleaseYear:    row.ReleaseYear.Int64,
+		CatalogNumber:  row.CatalogNumber.String,
+		Barcode:        row.Barcode.String,
+		MediaGrade:     row.MediaGrade.String,
+		SleeveGrade:    row.SleeveGrade.String,
+		LocationID:     row.CurrentLocationID.Int64,
+		Location:       row.CurrentLocationName.String,
+		HomeLocationID: row.HomeLocationID.Int64,
+		HomeLocation:   row.HomeLocationName.String,
+		PlayCount:      row.PlayCount.Int64,
+		LastPlayedAt:   timePtr(row.LastPlayedAt),
+		TrackCount:     row.TrackCount,
+		RunningTime:    row.RunningTime,
+		Notes:          row.Notes.String,
+		CreatedAt:      timePtr(row.CreatedAt),
+		UpdatedAt:      timePtr(row.UpdatedAt),
+	}
+}
+
+// csvRow returns the record's values in exportColumns order
+func (e ExportRecord) csvRow() []string {
+	number := func(n int64) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.FormatInt(n, 10)
+	}
+	timestamp := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		strconv.FormatInt(e.ID, 10), e.Title, e.Artist, e.AlbumTitle, number(e.ReleaseYear), e.CatalogNumber,
+		e.Barcode, e.MediaGrade, e.SleeveGrade, e.Location, e.HomeLocation, strconv.FormatInt(e.PlayCount, 10),
+		timestamp(e.LastPlayedAt), strconv.FormatInt(e.TrackCount, 10), strconv.FormatInt(e.RunningTime, 10),
+		e.Notes, timestamp(e.CreatedAt), timestamp(e.UpdatedAt),
+	}
+}
+
+// exportFilter is the listing filter for an export: every matching record,
+// in the listing's order
+func exportFilter(req ListRecordsRequest) store.RecordFilter {
+	filter := req.toFilter()
+	filter.Limit, filter.Offset = 0, 0
+	return filter
+}
+
+// exportLinks builds a download link per format for the records listing
+// query, dropping the paging and view parameters
+func exportLinks(query url.Values) []ExportLink {
+	q := url.Values{}
+	for k, v := range query {
+		switch k {
+		case "page", "per_page", "view", "format":
+			continue
+		}
+		q[k] = v
+	}
+
+	links := make([]ExportLink, 0, len(exportFormats))
+	for _, format := range exportFormats {
+		q.Set("format", format)
+		links = append(links, ExportLink{Label: strings.ToUpper(format), URL: "/api/v1/export?" + q.Encode()})
+	}
+	return links
+}
+
+// exportRecords streams the records matching the filter to w, one row at a
+// time
+func (h *Handler) exportRecords(ctx context.Context, w io.Writer, format string, filter store.RecordFilter) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return err
+		}
+		if err := h.queries.EachFilteredRecord(ctx, filter, func(row store.ListRecordsWithDetailsRow) error {
+			return cw.Write(newExportRecord(row).csvRow())
+		}); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case "json":
+		// A JSON array, written an element at a time. The opening bracket
+		// waits for the first record so a failed query leaves nothing written.
+		enc := json.NewEncoder(w)
+		n := 0
+		if err := h.queries.EachFilteredRecord(ctx, filter, func(row store.ListRecordsWithDetailsRow) error {
+			sep := ","
+			if n == 0 {
+				sep = "["
+			}
+			n++
+			if _, err := io.WriteString(w, sep); err != nil {
+				return err
+			}
+			return enc.Encode(newExportRecord(row))
+		}); err != nil {
+			return err
+		}
+		end := "]\n"
+		if n == 0 {
+			end = "[]\n"
+		}
+		_, err := io.WriteString(w, end)
+		return err
+
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		return h.queries.EachFilteredRecord(ctx, filter, func(row store.ListRecordsWithDetailsRow) error {
+			return enc.Encode(newExportRecord(row))
+		})
+
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// downloadWriter sets the download headers on the first write, so a
+// download that fails before writing anything can still answer with an error
+type downloadWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+// start sets the download headers, once
+func (dw *downloadWriter) start() {
+	if dw.started {
+		return
+	}
+	dw.started = true
+	dw.w.Header().Set("Content-Type", dw.contentType)
+	dw.w.Header().Set("Content-Disposition", `attachment; filename="`+dw.filename+`"`)
+	dw.w.Header().Set("Cache-Control", "no-store")
+}
+
+func (dw *downloadWriter) Write(p []byte) (int, error) {
+	dw.start()
+	return dw.w.Write(p)
+}
+
+// API Handlers
+
+// GET /api/v1/export
+func (h *Handler) JsonExportRecords() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ExportRequest
+		var filter ListRecordsRequest
+		for _, v := range []interface{}{&req, &filter} {
+			if err := h.bindQuery(r, v); err != nil {
+				if validationErrs, ok := err.(validator.ValidationErrors); ok {
+					h.writeValidationErrorJSON(w, validationErrs, "Please check your query parameters")
+					return
+				}
+				h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if req.Format == "" {
+			req.Format = "csv"
+		}
+
+		ew := &downloadWriter{
+			w:           w,
+			contentType: exportContentTypes[req.Format],
+			filename:    fmt.Sprintf("records-%s.%s", time.Now().Format("2006-01-02"), req.Format),
+		}
+		if err := h.exportRecords(r.Context(), ew, req.Format, exportFilter(filter)); err != nil {
+			h.logger.Error("Failed to export records", slog.String("error", err.Error()), slog.String("format", req.Format))
+			// Once the download has started the status can't change; it just
+			// ends early
+			if !ew.started {
+				h.writeErrorJSON(w, "Failed to export records", http.StatusInternalServerError)
+			}
+			return
+		}
+		// An empty NDJSON export writes nothing but is still a download
+		ew.start()
+	}
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// TestExportRecords tests each format and that the listing filters apply
+func TestExportRecords(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{
+		logger:   slog.New(slog.DiscardHandler),
+		db:       db,
+		queries:  queries,
+		validate: newValidator(),
+	}
+
+	station, err := queries.CreateLocation(ctx, store.CreateLocationParams{Name: "Cleaning Station"})
+	if err != nil {
+		t.Fatalf("Failed to create location: %v", err)
+	}
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Fleetwood Mac", SortName: store.ArtistSortName("Fleetwood Mac")})
+	if err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+	for _, r := range []struct {
+		title    string
+		year     int64
+		location int64
+		barcode  string
+	}{{"Rumours", 1977, station.ID, "0720642442517"}, {"Tusk", 1979, 0, ""}, {"Tango in the Night", 1987, station.ID, ""}} {
+		if _, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+			Title:             r.title,
+			ArtistID:          sql.NullInt64{Int64: artist.ID, Valid: true},
+			ReleaseYear:       sql.NullInt64{Int64: r.year, Valid: true},
+			CurrentLocationID: sql.NullInt64{Int64: r.location, Valid: r.location > 0},
+			MediaGrade:        sql.NullString{String: "VG+", Valid: true},
+			Barcode:           sql.NullString{String: r.barcode, Valid: r.barcode != ""},
+		}); err != nil {
+			t.Fatalf("Failed to create record: %v", err)
+		}
+	}
+
+	export := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.JsonExportRecords()(w, httptest.NewRequest("GET", "/api/v1/export?"+query, nil))
+		return w
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		w := export("year_from=1970&year_to=1979&per_page=1")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+			t.Fatalf("status = %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Disposition"), `attachment; filename="records-`) {
+			t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+		}
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("read CSV: %v", err)
+		}
+		// Paging is ignored: every 1970s record is exported
+		if len(rows) != 3 || rows[1][1] != "Rumours" || rows[2][1] != "Tusk" {
+			t.Fatalf("rows = %q", rows)
+		}
+		if rows[1][2] != "Fleetwood Mac" || rows[1][4] != "1977" || rows[1][6] != "0720642442517" || rows[1][9] != "Cleaning Station" || rows[2][9] != "" {
+			t.Errorf("row = %q", rows[1])
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		w := export("format=json&location_id=" + strconv.FormatInt(station.ID, 10))
+		var records []ExportRecord
+		if err := json.NewDecoder(w.Body).Decode(&records); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(records) != 2 || records[0].Title != "Rumours" || records[1].Title != "Tango in the Night" {
+			t.Fatalf("records = %+v", records)
+		}
+		if records[0].Location != "Cleaning Station" || records[0].MediaGrade != "VG+" || records[0].CreatedAt == nil {
+			t.Errorf("record = %+v", records[0])
+		}
+
+		w = export("format=json&year_from=2000")
+		if strings.TrimSpace(w.Body.String()) != "[]" {
+			t.Errorf("empty export = %q, want []", w.Body)
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := export("format=ndjson&sort=year&order=desc")
+		if w.Header().Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("content type = %q", w.Header().Get("Content-Type"))
+		}
+		var titles []string
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var record ExportRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatalf("decode line %q: %v", scanner.Text(), err)
+			}
+			titles = append(titles, record.Title)
+		}
+		if strings.Join(titles, ",") != "Tango in the Night,Tusk,Rumours" {
+			t.Errorf("titles = %v", titles)
+		}
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		for _, query := range []string{"format=xml", "year_from=12"} {
+			if w := export(query); w.Code != http.StatusBadRequest || w.Header().Get("Content-Disposition") != "" {
+				t.Errorf("%s: status = %d, want a 400 error", query, w.Code)
+			}
+		}
+	})
+
+	// A CSV export imports again as it is
+	t.Run("round trip", func(t *testing.T) {
+		w := export("format=csv")
+		report, err := h.importFile(ctx, "csv", bytes.NewReader(w.Body.Bytes()), nil, true)
+		if err != nil {
+			t.Fatalf("importFile() error = %v", err)
+		}
+		if report.Imported != 3 || len(report.Rejected) != 0 || len(report.NewArtists) != 0 || len(report.NewLocations) != 0 {
+			t.Errorf("import report = %+v", report)
+		}
+
+		// Imported into an empty collection, the barcode comes back
+		emptyDB, emptyQueries := setupTestDB(t)
+		defer emptyDB.Close()
+		emptyDB.SetMaxOpenConns(1)
+		empty := &Handler{logger: h.logger, db: emptyDB, queries: emptyQueries, validate: h.validate}
+		if _, err := empty.importFile(ctx, "csv", bytes.NewReader(w.Body.Bytes()), nil, false); err != nil {
+			t.Fatalf("importFile() error = %v", err)
+		}
+		records, err := emptyQueries.ListRecordsByBarcode(ctx, sql.NullString{String: "0720642442517", Valid: true})
+		if err != nil || len(records) != 1 || records[0].Title != "Rumours" {
+			t.Errorf("records by barcode = %+v, %v, want Rumours", records, err)
+		}
+	})
+}
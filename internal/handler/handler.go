@@ -1,34 +1,93 @@
 package handler
 
 import (
+	"context"
+	"database/sql"
 	"log/slog"
 
+	"github.com/dukerupert/dd/internal/backup"
 	"github.com/dukerupert/dd/internal/config"
+	"github.com/dukerupert/dd/internal/media"
+	"github.com/dukerupert/dd/internal/metadata"
 	"github.com/dukerupert/dd/internal/renderer"
 	"github.com/dukerupert/dd/internal/store"
 	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
 )
 
 // Handler holds dependencies for all HTTP handlers
 type Handler struct {
 	logger   *slog.Logger
+	db       *sql.DB
 	queries  *store.Queries
 	renderer *renderer.Renderer
 	validate *validator.Validate
 	config   *config.Config
+	media    *media.Store
+	// metadata looks up releases; nil when lookups aren't configured
+	metadata metadata.MetadataProvider
+	// backups takes scheduled backups; nil when they're off
+	backups *backup.Scheduler
 }
 
 // New creates a new Handler with all dependencies
-func New(logger *slog.Logger, queries *store.Queries, renderer *renderer.Renderer, cfg *config.Config) *Handler {
-	return &Handler{
+func New(logger *slog.Logger, db *sql.DB, queries *store.Queries, renderer *renderer.Renderer, cfg *config.Config) *Handler {
+	h := &Handler{
 		logger:   logger,
+		db:       db,
 		queries:  queries,
 		renderer: renderer,
-		validate: validator.New(),
+		validate: newValidator(),
 		config:   cfg,
+		media:    media.NewStore(cfg.Media.Dir, cfg.Media.MaxUploadBytes),
 	}
+	if cfg.Metadata.DiscogsToken != "" {
+		h.metadata = metadata.NewDiscogsClient(cfg.Metadata.DiscogsURL, cfg.Metadata.DiscogsToken)
+	}
+	if cfg.Backup.Interval > 0 {
+		h.backups = backup.NewScheduler(db, logger, cfg.Backup.Dir, cfg.Media.Dir, cfg.Backup.Interval, backup.Retention{
+			Hourly: cfg.Backup.KeepHourly,
+			Daily:  cfg.Backup.KeepDaily,
+			Weekly: cfg.Backup.KeepWeekly,
+		})
+	}
+	return h
+}
+
+// newValidator returns a validator with the app's custom tags registered
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("barcode", validateBarcode)
+	v.RegisterValidation("notblank", validators.NotBlank)
+	return v
 }
 
 func (h *Handler) Logger() *slog.Logger {
 	return h.logger
 }
+
+// Backups returns the backup scheduler, or nil when scheduled backups are off
+func (h *Handler) Backups() *backup.Scheduler {
+	return h.backups
+}
+
+// UploadLimit is the request body limit for image uploads: the image size
+// limit plus room for the rest of the multipart body
+func (h *Handler) UploadLimit() int64 {
+	return h.media.MaxBytes() + 1<<20
+}
+
+// withTx runs fn inside a single database transaction, committing if fn
+// returns nil and rolling back otherwise
+func (h *Handler) withTx(ctx context.Context, fn func(q *store.Queries) error) error {
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(h.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
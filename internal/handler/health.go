@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// HealthResponse reports whether the server is healthy. Status is "ok", or
+// "degraded" when the database can't be reached or backups have stopped.
+type HealthResponse struct {
+	Status   string        `json:"status"`
+	Database string        `json:"database"`
+	Backup   *BackupHealth `json:"backup,omitempty"`
+}
+
+// BackupHealth is how scheduled backups are going. AgeSeconds is how long
+// ago the last one was taken; Stale is set when that's over two intervals.
+// Where backups go and what last went wrong are only shown to admins.
+type BackupHealth struct {
+	Interval    string     `json:"interval"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	AgeSeconds  *int64     `json:"age_seconds,omitempty"`
+	Stale       bool       `json:"stale"`
+	Dir         string     `json:"dir,omitempty"`
+	LastFile    string     `json:"last_file,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// health checks the database and backups. detail adds the backup directory,
+// file and error, which aren't for anonymous callers.
+func (h *Handler) health(ctx context.Context, detail bool) HealthResponse {
+	resp := HealthResponse{Status: "ok", Database: "ok"}
+
+	if err := h.db.PingContext(ctx); err != nil {
+		h.logger.Error("Health check failed to reach the database", slog.String("error", err.Error()))
+		resp.Status = "degraded"
+		resp.Database = "unavailable"
+	}
+
+	if h.backups != nil {
+		status := h.backups.Status()
+		health := &BackupHealth{
+			Interval: status.Interval.String(),
+			Stale:    status.Stale,
+		}
+		if detail {
+			health.Dir = status.Dir
+			health.LastFile = status.LastFile
+			health.LastError = status.LastError
+		}
+		if !status.LastAttempt.IsZero() {
+			health.LastAttempt = &status.LastAttempt
+		}
+		if !status.LastSuccess.IsZero() {
+			health.LastSuccess = &status.LastSuccess
+			age := int64(time.Since(status.LastSuccess).Seconds())
+			health.AgeSeconds = &age
+		}
+		if status.Stale {
+			resp.Status = "degraded"
+		}
+		resp.Backup = health
+	}
+	return resp
+}
+
+// writeHealth writes a health check, answering 503 when degraded
+func (h *Handler) writeHealth(w http.ResponseWriter, resp HealthResponse) {
+	code := http.StatusOK
+	if resp.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	h.writeJSON(w, resp, code)
+}
+
+// API Handlers
+
+// GET /api/v1/health
+// Public, for monitoring. Answers 503 when degraded, so a check on the status
+// code notices backups silently stopping.
+func (h *Handler) JsonHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.writeHealth(w, h.health(r.Context(), false))
+	}
+}
+
+// GET /api/v1/admin/health
+// The health check with where backups go and why the last one failed.
+// Admins only; see the router.
+func (h *Handler) JsonAdminHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.writeHealth(w, h.health(r.Context(), true))
+	}
+}
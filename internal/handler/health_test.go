@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/dd/internal/backup"
+)
+
+// TestJsonHealth tests the health check with and without stale backups
+func TestJsonHealth(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	logger := slog.New(slog.DiscardHandler)
+	h := &Handler{
+		logger:   logger,
+		db:       db,
+		queries:  queries,
+		validate: newValidator(),
+	}
+
+	get := func(handler http.HandlerFunc) (int, HealthResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+		var resp HealthResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return w.Code, resp
+	}
+	check := func() (int, HealthResponse) {
+		t.Helper()
+		return get(h.JsonHealth())
+	}
+
+	if code, resp := check(); code != http.StatusOK || resp.Status != "ok" || resp.Backup != nil {
+		t.Errorf("without backups: status %d, %+v", code, resp)
+	}
+
+	// The last backup is three hours old, with an hourly schedule
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, backup.FileName(time.Now().Add(-3*time.Hour))), nil, 0o644)
+	h.backups = backup.NewScheduler(db, logger, dir, filepath.Join(dir, "media"), time.Hour, backup.Retention{Hourly: 1})
+
+	code, resp := check()
+	if code != http.StatusServiceUnavailable || resp.Status != "degraded" || resp.Database != "ok" {
+		t.Fatalf("stale backups: status %d, %+v", code, resp)
+	}
+	if !resp.Backup.Stale || resp.Backup.AgeSeconds == nil || *resp.Backup.AgeSeconds < 3*60*60-60 || resp.Backup.Interval != "1h0m0s" {
+		t.Errorf("stale backup health = %+v", resp.Backup)
+	}
+
+	if err := h.backups.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	code, resp = check()
+	if code != http.StatusOK || resp.Backup.Stale || resp.Backup.LastAttempt == nil {
+		t.Errorf("after a backup: status %d, %+v", code, resp.Backup)
+	}
+	// Only admins see where backups go
+	if resp.Backup.Dir != "" || resp.Backup.LastFile != "" {
+		t.Errorf("public backup health = %+v, want no paths", resp.Backup)
+	}
+	if _, resp := get(h.JsonAdminHealth()); resp.Backup.Dir != dir || resp.Backup.LastFile == "" {
+		t.Errorf("admin backup health = %+v, want the directory and last file", resp.Backup)
+	}
+}
@@ -2,13 +2,17 @@ package handler
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
 
+	"github.com/dukerupert/dd/internal/middleware"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -78,6 +82,24 @@ func (h *Handler) bindForm(r *http.Request, v interface{}) error {
 	return h.validate.Struct(v)
 }
 
+// queryNormalizer is a query request that tidies its values before they're
+// validated
+type queryNormalizer interface {
+	normalizeQuery()
+}
+
+// bindQuery binds URL query parameters to struct using form tags
+func (h *Handler) bindQuery(r *http.Request, v interface{}) error {
+	if err := h.mapFormToStruct(r, v); err != nil {
+		return err
+	}
+	if n, ok := v.(queryNormalizer); ok {
+		n.normalizeQuery()
+	}
+
+	return h.validate.Struct(v)
+}
+
 // mapFormToStruct maps form values to struct fields
 func (h *Handler) mapFormToStruct(r *http.Request, v interface{}) error {
 	rv := reflect.ValueOf(v)
@@ -124,12 +146,131 @@ func (h *Handler) mapFormToStruct(r *http.Request, v interface{}) error {
 			if boolVal, err := strconv.ParseBool(formValue); err == nil {
 				fieldValue.SetBool(boolVal)
 			}
+		case reflect.Slice:
+			setFormSlice(fieldValue, r.Form[formTag])
 		}
 	}
 
 	return nil
 }
 
+// setFormSlice fills a []string or []int64 field from a repeated parameter
+// (?tag=1&tag=2). Each value may also be a comma-separated list; blanks and
+// unparsable numbers are skipped.
+func setFormSlice(field reflect.Value, values []string) {
+	slice := reflect.MakeSlice(field.Type(), 0, len(values))
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			switch field.Type().Elem().Kind() {
+			case reflect.String:
+				slice = reflect.Append(slice, reflect.ValueOf(part))
+			case reflect.Int64:
+				if intVal, err := strconv.ParseInt(part, 10, 64); err == nil {
+					slice = reflect.Append(slice, reflect.ValueOf(intVal))
+				}
+			}
+		}
+	}
+	field.Set(slice)
+}
+
+// Pagination describes a page of results and how to reach its neighbours
+type Pagination struct {
+	Page       int64  `json:"page"`
+	PerPage    int64  `json:"per_page"`
+	Total      int64  `json:"total"`
+	TotalPages int64  `json:"total_pages"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+}
+
+// newPagination builds pagination metadata. Next and Prev are query-only
+// relative links (e.g. "?page=3&sort=year") that keep every other parameter
+// of query so filters survive paging; they resolve against the current path,
+// which matters for the API where StripPrefix has already removed "/api".
+func newPagination(query url.Values, page, perPage, total int64) Pagination {
+	p := Pagination{
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	}
+
+	if perPage > 0 {
+		p.TotalPages = (total + perPage - 1) / perPage
+	}
+	p.HasNext = page < p.TotalPages
+	p.HasPrev = page > 1
+
+	pageURL := func(n int64) string {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("page", strconv.FormatInt(n, 10))
+		return "?" + q.Encode()
+	}
+	if p.HasNext {
+		p.Next = pageURL(page + 1)
+	}
+	if p.HasPrev {
+		p.Prev = pageURL(page - 1)
+	}
+
+	return p
+}
+
+// pathID parses the {id} path value
+func pathID(r *http.Request) (int64, error) {
+	id := r.PathValue("id")
+	if id == "" {
+		return 0, fmt.Errorf("missing parameter: id")
+	}
+	return strconv.ParseInt(id, 10, 64)
+}
+
+// currentUserID returns the logged in user's ID, or NULL for anonymous
+// requests
+func currentUserID(ctx context.Context) sql.NullString {
+	if id, ok := middleware.GetUserID(ctx); ok && id != "anonymous" && id != "" {
+		return sql.NullString{String: id, Valid: true}
+	}
+	return sql.NullString{}
+}
+
+// isAdmin reports whether the logged in user is an admin, for showing
+// admin-only actions. The admin routes themselves check the role too.
+func (h *Handler) isAdmin(ctx context.Context) bool {
+	userID := currentUserID(ctx)
+	if !userID.Valid {
+		return false
+	}
+	user, err := h.queries.GetUserByID(ctx, userID.String)
+	return err == nil && user.Role == "admin"
+}
+
+// isUniqueViolation reports whether err is a SQLite UNIQUE constraint failure
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// writeValidationErrorJSON writes a 400 response listing the failed fields
+func (h *Handler) writeValidationErrorJSON(w http.ResponseWriter, errs validator.ValidationErrors, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(ValidationErrorResponse{
+		Error:   "Validation failed",
+		Message: message,
+		Details: h.getValidationErrors(errs),
+	})
+}
+
 // writeJSON writes JSON response
 func (h *Handler) writeJSON(w http.ResponseWriter, data interface{}, statusCode int) error {
 	// Encode to buffer first to catch errors before writing headers
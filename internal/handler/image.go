@@ -0,0 +1,393 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/dukerupert/dd/internal/media"
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// imageKinds are the images a record can have, in the order the record page
+// shows them
+var imageKinds = []string{"front", "back", "label"}
+
+// errImageMissing is returned when an upload has no image file
+var errImageMissing = errors.New("choose an image to upload")
+
+// RecordImageResponse is an image's metadata with links to the original and
+// each thumbnail size
+type RecordImageResponse struct {
+	store.RecordImage
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// ImageSlot is one kind of image on the record page. Image is nil until one
+// is uploaded.
+type ImageSlot struct {
+	Kind  string
+	Image *store.RecordImage
+}
+
+// imageURL links to an image or one of its thumbnails under prefix ("" for
+// the HTML routes, "/api/v1" for the API). The checksum in ?v= changes whenever
+// the image is replaced, so the URL can be cached for good.
+func imageURL(prefix string, image store.RecordImage, size string) string {
+	url := fmt.Sprintf("%s/records/%d/images/%s", prefix, image.RecordID, image.Kind)
+	if size != "" {
+		url += "/" + size
+	}
+	return url + "?v=" + image.Checksum
+}
+
+// newRecordImageResponse adds API links to an image's metadata
+func newRecordImageResponse(image store.RecordImage) RecordImageResponse {
+	thumbnails := make(map[string]string, len(media.ThumbnailSizes))
+	for size := range media.ThumbnailSizes {
+		thumbnails[size] = imageURL("/api/v1", image, size)
+	}
+	return RecordImageResponse{
+		RecordImage: image,
+		URL:         imageURL("/api/v1", image, ""),
+		Thumbnails:  thumbnails,
+	}
+}
+
+// saveRecordImage stores an uploaded image with its thumbnails and records
+// it, replacing any previous image of the same kind. Returns sql.ErrNoRows if
+// the record doesn't exist.
+func (h *Handler) saveRecordImage(ctx context.Context, recordID int64, kind string, r io.Reader) (store.RecordImage, error) {
+	if _, err := h.queries.GetRecord(ctx, recordID); err != nil {
+		return store.RecordImage{}, err
+	}
+
+	image, err := h.media.Save(recordID, kind, r)
+	if err != nil {
+		return store.RecordImage{}, err
+	}
+
+	return h.queries.UpsertRecordImage(ctx, store.UpsertRecordImageParams{
+		RecordID:    recordID,
+		Kind:        kind,
+		ContentType: image.ContentType,
+		Width:       int64(image.Width),
+		Height:      int64(image.Height),
+		SizeBytes:   image.Size,
+		Checksum:    image.Checksum,
+	})
+}
+
+// deleteRecordImage removes an image and its files. Returns sql.ErrNoRows if
+// the record has no image of that kind.
+func (h *Handler) deleteRecordImage(ctx context.Context, recordID int64, kind string) error {
+	deleted, err := h.queries.DeleteRecordImage(ctx, store.DeleteRecordImageParams{RecordID: recordID, Kind: kind})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return h.media.Delete(recordID, kind)
+}
+
+// imageSlots lists every kind of image for a record, uploaded or not
+func (h *Handler) imageSlots(ctx context.Context, recordID int64) ([]ImageSlot, error) {
+	images, err := h.queries.ListRecordImages(ctx, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]ImageSlot, 0, len(imageKinds))
+	for _, kind := range imageKinds {
+		slot := ImageSlot{Kind: kind}
+		for i := range images {
+			if images[i].Kind == kind {
+				slot.Image = &images[i]
+			}
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil
+}
+
+// uploadedImage returns the "image" file from a multipart upload
+func uploadedImage(r *http.Request) (multipart.File, error) {
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, media.ErrTooLarge
+		}
+		return nil, errImageMissing
+	}
+	return file, nil
+}
+
+// pathImageKind returns the {kind} path parameter, or false if it isn't a
+// known kind
+func pathImageKind(r *http.Request) (string, bool) {
+	kind := r.PathValue("kind")
+	return kind, slices.Contains(imageKinds, kind)
+}
+
+// imageErrorStatus maps an error from saving an image to a status and message
+func imageErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge, err.Error()
+	case errors.Is(err, media.ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType, err.Error()
+	case errors.Is(err, media.ErrTooManyPixels), errors.Is(err, errImageMissing):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Record not found"
+	default:
+		return http.StatusInternalServerError, "Failed to save image"
+	}
+}
+
+// renderRecordImages renders the cover art partial for a record
+func (h *Handler) renderRecordImages(w http.ResponseWriter, r *http.Request, recordID int64) {
+	slots, err := h.imageSlots(r.Context(), recordID)
+	if err != nil {
+		h.logger.Error("Failed to retrieve images", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+		http.Error(w, "Failed to retrieve images", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderer.Render(w, "record-images", map[string]interface{}{
+		"RecordID":    recordID,
+		"ImageSlots":  slots,
+		"MaxUploadMB": h.media.MaxBytes() >> 20,
+	})
+}
+
+// HTML Handlers
+
+// GET /records/{id}/images/{kind}, GET /records/{id}/images/{kind}/{size}
+// and the same under /api/v1. Serves an original or a thumbnail with its
+// checksum as the ETag.
+func (h *Handler) ServeRecordImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		kind, ok := pathImageKind(r)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		size := r.PathValue("size")
+		if _, ok := media.ThumbnailSizes[size]; size != "" && !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		image, err := h.queries.GetRecordImage(r.Context(), store.GetRecordImageParams{RecordID: recordID, Kind: kind})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.NotFound(w, r)
+				return
+			}
+			h.logger.Error("Failed to retrieve image", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve image", http.StatusInternalServerError)
+			return
+		}
+
+		file, err := os.Open(h.media.Path(recordID, kind, size))
+		if err != nil {
+			h.logger.Warn("Image file missing", slog.String("error", err.Error()), slog.Int64("recordID", recordID), slog.String("kind", kind))
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		contentType, etag := image.ContentType, image.Checksum
+		if size != "" {
+			contentType, etag = "image/jpeg", image.Checksum+"-"+size
+		}
+
+		// A URL carrying the current version never changes; anything else
+		// revalidates against the ETag
+		if r.URL.Query().Get("v") == image.Checksum {
+			w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "private, no-cache")
+		}
+		w.Header().Set("ETag", `"`+etag+`"`)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		http.ServeContent(w, r, "", image.UpdatedAt.Time, file)
+	}
+}
+
+// POST /records/{id}/images/{kind}
+func (h *Handler) UploadRecordImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		kind, ok := pathImageKind(r)
+		if !ok {
+			http.Error(w, "Invalid parameter: kind", http.StatusBadRequest)
+			return
+		}
+
+		file, err := uploadedImage(r)
+		if err != nil {
+			status, message := imageErrorStatus(err)
+			http.Error(w, message, status)
+			return
+		}
+		defer file.Close()
+
+		if _, err := h.saveRecordImage(r.Context(), recordID, kind, file); err != nil {
+			status, message := imageErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to save image", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderRecordImages(w, r, recordID)
+	}
+}
+
+// DELETE /records/{id}/images/{kind}
+func (h *Handler) DeleteRecordImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		kind, ok := pathImageKind(r)
+		if !ok {
+			http.Error(w, "Invalid parameter: kind", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.deleteRecordImage(r.Context(), recordID, kind); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Image not found", http.StatusNotFound)
+				return
+			}
+			h.logger.Error("Failed to delete image", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to delete image", http.StatusInternalServerError)
+			return
+		}
+
+		h.renderRecordImages(w, r, recordID)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/records/{id}/images
+func (h *Handler) JsonGetRecordImages() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		images, err := h.queries.ListRecordImages(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve images", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve images", http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]RecordImageResponse, 0, len(images))
+		for _, image := range images {
+			response = append(response, newRecordImageResponse(image))
+		}
+
+		h.writeJSON(w, response, http.StatusOK)
+	}
+}
+
+// POST /api/v1/records/{id}/images/{kind}
+func (h *Handler) JsonUploadRecordImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		kind, ok := pathImageKind(r)
+		if !ok {
+			h.writeErrorJSON(w, "Invalid parameter: kind", http.StatusBadRequest)
+			return
+		}
+
+		file, err := uploadedImage(r)
+		if err != nil {
+			status, message := imageErrorStatus(err)
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+		defer file.Close()
+
+		image, err := h.saveRecordImage(r.Context(), recordID, kind, file)
+		if err != nil {
+			status, message := imageErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to save image", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, newRecordImageResponse(image), http.StatusCreated)
+	}
+}
+
+// DELETE /api/v1/records/{id}/images/{kind}
+func (h *Handler) JsonDeleteRecordImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		kind, ok := pathImageKind(r)
+		if !ok {
+			h.writeErrorJSON(w, "Invalid parameter: kind", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.deleteRecordImage(r.Context(), recordID, kind); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				h.writeErrorJSON(w, "Image not found", http.StatusNotFound)
+				return
+			}
+			h.logger.Error("Failed to delete image", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to delete image", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/media"
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// testPNG returns a w x h grey PNG
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// uploadRequest builds a multipart upload of data as the "image" field
+func uploadRequest(t *testing.T, recordID int64, kind string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("image", "cover.png")
+	if err != nil {
+		t.Fatalf("CreateFormFile() error = %v", err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	r := httptest.NewRequest("POST", "/api/v1/records/"+strconv.FormatInt(recordID, 10)+"/images/"+kind, &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	r.SetPathValue("id", strconv.FormatInt(recordID, 10))
+	r.SetPathValue("kind", kind)
+	return r
+}
+
+// TestRecordImages_UploadAndServe tests uploading, replacing, serving with
+// ETags and deleting cover art
+func TestRecordImages_UploadAndServe(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries, media: media.NewStore(t.TempDir(), 1<<20)}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Nevermind"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	h.JsonUploadRecordImage()(w, uploadRequest(t, record.ID, "front", testPNG(t, 500, 500)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload status = %d, body %s", w.Code, w.Body)
+	}
+	var uploaded RecordImageResponse
+	if err := json.NewDecoder(w.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("decode upload response: %v", err)
+	}
+	if uploaded.Width != 500 || uploaded.ContentType != "image/png" || uploaded.Thumbnails[media.SizeSmall] == "" {
+		t.Errorf("upload response = %+v", uploaded)
+	}
+
+	// The listing carries the front cover's checksum for its thumbnail
+	records, err := queries.FilterRecords(ctx, store.RecordFilter{})
+	if err != nil {
+		t.Fatalf("FilterRecords() error = %v", err)
+	}
+	if len(records) != 1 || records[0].CoverChecksum.String != uploaded.Checksum {
+		t.Errorf("listing cover checksum = %v, want %s", records[0].CoverChecksum, uploaded.Checksum)
+	}
+
+	serve := func(size, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/records/"+strconv.FormatInt(record.ID, 10)+"/images/front", nil)
+		r.SetPathValue("id", strconv.FormatInt(record.ID, 10))
+		r.SetPathValue("kind", "front")
+		r.SetPathValue("size", size)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h.ServeRecordImage()(w, r)
+		return w
+	}
+
+	original := serve("", "")
+	if original.Code != http.StatusOK || original.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("serve original = %d %s", original.Code, original.Header().Get("Content-Type"))
+	}
+	etag := original.Header().Get("ETag")
+	if etag != `"`+uploaded.Checksum+`"` {
+		t.Errorf("ETag = %s, want the checksum", etag)
+	}
+	if got := serve("", etag); got.Code != http.StatusNotModified {
+		t.Errorf("conditional GET status = %d, want 304", got.Code)
+	}
+
+	thumb := serve(media.SizeMedium, "")
+	if thumb.Code != http.StatusOK || thumb.Header().Get("Content-Type") != "image/jpeg" || thumb.Header().Get("ETag") == etag {
+		t.Errorf("serve thumbnail = %d %s %s", thumb.Code, thumb.Header().Get("Content-Type"), thumb.Header().Get("ETag"))
+	}
+	if got := serve("xl", ""); got.Code != http.StatusNotFound {
+		t.Errorf("unknown size status = %d, want 404", got.Code)
+	}
+
+	// Replacing changes the ETag, so the old one no longer matches
+	if _, err := h.saveRecordImage(ctx, record.ID, "front", bytes.NewReader(testPNG(t, 300, 200))); err != nil {
+		t.Fatalf("saveRecordImage() replace error = %v", err)
+	}
+	if got := serve("", etag); got.Code != http.StatusOK {
+		t.Errorf("stale conditional GET status = %d, want 200", got.Code)
+	}
+
+	if _, err := h.saveRecordImage(ctx, record.ID+100, "front", bytes.NewReader(testPNG(t, 10, 10))); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("upload to unknown record error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := h.deleteRecordImage(ctx, record.ID, "front"); err != nil {
+		t.Fatalf("deleteRecordImage() error = %v", err)
+	}
+	if _, err := os.Stat(h.media.Path(record.ID, "front", "")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file still exists after delete: %v", err)
+	}
+	if got := serve("", ""); got.Code != http.StatusNotFound {
+		t.Errorf("serve after delete status = %d, want 404", got.Code)
+	}
+	if err := h.deleteRecordImage(ctx, record.ID, "front"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("delete again error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// TestRecordImages_UploadErrors tests the status of rejected uploads
+func TestRecordImages_UploadErrors(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries, media: media.NewStore(t.TempDir(), 1<<20)}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Bleach"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		request    *http.Request
+		bodyLimit  int64
+		wantStatus int
+	}{
+		{"not an image", uploadRequest(t, record.ID, "front", []byte("%PDF-1.4 nope")), 0, http.StatusUnsupportedMediaType},
+		{"unknown kind", uploadRequest(t, record.ID, "inner", testPNG(t, 10, 10)), 0, http.StatusBadRequest},
+		{"unknown record", uploadRequest(t, record.ID+100, "front", testPNG(t, 10, 10)), 0, http.StatusNotFound},
+		{"body over limit", uploadRequest(t, record.ID, "front", testPNG(t, 200, 200)), 100, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if tt.bodyLimit > 0 {
+				tt.request.Body = http.MaxBytesReader(w, tt.request.Body, tt.bodyLimit)
+			}
+			h.JsonUploadRecordImage()(w, tt.request)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	images, err := queries.ListRecordImages(ctx, record.ID)
+	if err != nil || len(images) != 0 {
+		t.Errorf("ListRecordImages() = %v, %v, want none", images, err)
+	}
+}
@@ -0,0 +1,762 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// maxImportRows caps the rows in one CSV import
+const maxImportRows = 5000
+
+// maxPreviewRows caps the rows listed on the import page; the API report
+// lists them all
+const maxPreviewRows = 100
+
+// importSources are the upload formats: a CSV with chosen columns, a Discogs
+// collection export and a CLZ Music XML export
+var importSources = []string{"csv", "discogs", "clz"}
+
+var (
+	errImportMissing     = errors.New("choose a file to import")
+	errImportTooManyRows = fmt.Errorf("the file has more than %d records; split it into smaller files", maxImportRows)
+	errCSVEmpty          = errors.New("the CSV has no header row")
+	errCSVNoTitle        = errors.New("no column holds record titles; choose one for Title")
+	errCSVColumn         = errors.New("column not found in the CSV")
+	errLocationAmbiguous = errors.New(`more than one location has that name; give its full path, e.g. "Living room / Kallax"`)
+
+	// errDryRun rolls back a dry run once every row has been tried
+	errDryRun = errors.New("dry run")
+)
+
+// importColumn is a record field a CSV column can hold. When no column is
+// chosen for it, the first header matching one of Headers (ignoring case) is
+// used.
+type importColumn struct {
+	Field   string
+	Label   string
+	Headers []string
+}
+
+// importColumns are the fields a CSV import fills in, in the order the
+// mapping form shows them
+var importColumns = []importColumn{
+	{"title", "Title", []string{"title", "record", "name"}},
+	{"artist", "Artist", []string{"artist", "artist name", "band"}},
+	{"album_title", "Album title", []string{"album_title", "album title", "album"}},
+	{"release_year", "Release year", []string{"release_year", "release year", "year", "released"}},
+	{"catalog_number", "Catalog number", []string{"catalog_number", "catalog number", "catalog #", "catalog#", "catno", "cat no"}},
+	{"barcode", "Barcode", []string{"barcode", "upc", "ean"}},
+	{"media_grade", "Media grade", []string{"media_grade", "media grade", "media condition"}},
+	{"sleeve_grade", "Sleeve grade", []string{"sleeve_grade", "sleeve grade", "sleeve condition"}},
+	{"location", "Location", []string{"location", "current location"}},
+	{"home_location", "Home location", []string{"home_location", "home location", "home"}},
+	{"notes", "Notes", []string{"notes", "note", "comments"}},
+}
+
+// ImportRequest is sent with an upload. For a CSV, the column for each field
+// is chosen with column_<field> (e.g. column_title=Album Name). DryRun tries
+// every row and reports what would be imported without saving anything.
+// Report "csv" returns only the rejected rows, as a CSV download.
+type ImportRequest struct {
+	DryRun bool   `form:"dry_run" json:"dry_run"`
+	Report string `form:"report" json:"report" validate:"omitempty,oneof=json csv"`
+}
+
+// ImportedRow is a row that was imported, or would be in a dry run. For a row
+// matching a record already in the collection, RecordID is that record.
+type ImportedRow struct {
+	Line        int    `json:"line"`
+	RecordID    int64  `json:"record_id,omitempty"`
+	Title       string `json:"title"`
+	Artist      string `json:"artist,omitempty"`
+	ReleaseYear int32  `json:"release_year,omitempty"`
+	Location    string `json:"location,omitempty"`
+}
+
+// RejectedRow is a row left out of an import, with its values as read and
+// why it was rejected
+type RejectedRow struct {
+	Line   int      `json:"line"`
+	Errors []string `json:"errors"`
+	Values []string `json:"values"`
+}
+
+// ImportReport is the outcome of an import. Columns maps each field to the
+// column or element it was read from. Rows were created and Matched were
+// already in the collection and left alone (only Discogs and CLZ imports
+// look for them); Rejected rows were skipped. In a dry run nothing is saved,
+// and the report is what the import would do.
+type ImportReport struct {
+	Source           string            `json:"source"`
+	DryRun           bool              `json:"dry_run"`
+	Columns          map[string]string `json:"columns"`
+	Total            int               `json:"total"`
+	Imported         int               `json:"imported"`
+	Rows             []ImportedRow     `json:"rows"`
+	Matched          []ImportedRow     `json:"matched"`
+	Rejected         []RejectedRow     `json:"rejected"`
+	NewArtists       []string          `json:"new_artists"`
+	MatchedArtists   []string          `json:"matched_artists"`
+	NewLocations     []string          `json:"new_locations"`
+	MatchedLocations []string          `json:"matched_locations"`
+
+	header []string
+}
+
+// importSource is one entry read from an upload: where it was, its values as
+// read (for the rejected rows download) and its importColumns fields
+type importSource struct {
+	line   int
+	values []string
+	fields map[string]string
+}
+
+// importUpload is an upload read into entries, ready to import
+type importUpload struct {
+	source  string
+	header  []string
+	columns map[string]string
+	entries []importSource
+}
+
+// importRow is an entry read into a new record
+type importRow struct {
+	line         int
+	record       CreateRecordRequest
+	location     string
+	homeLocation string
+}
+
+// uploadedFile returns the "file" from a multipart upload
+func uploadedFile(r *http.Request) (io.ReadCloser, error) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, err
+		}
+		return nil, errImportMissing
+	}
+	return file, nil
+}
+
+// pathImportSource returns the {source} path parameter, or false if it isn't
+// a known format
+func pathImportSource(r *http.Request) (string, bool) {
+	source := r.PathValue("source")
+	return source, slices.Contains(importSources, source)
+}
+
+// chosenColumns reads the column_<field> choices from a CSV upload
+func chosenColumns(r *http.Request, fields []importColumn) map[string]string {
+	chosen := make(map[string]string)
+	for _, c := range fields {
+		if header := strings.TrimSpace(r.FormValue("column_" + c.Field)); header != "" {
+			chosen[c.Field] = header
+		}
+	}
+	return chosen
+}
+
+// mapColumns finds the column index for each of the fields, from the chosen
+// headers or by matching the usual header names
+func mapColumns(header []string, fields []importColumn, chosen map[string]string) (map[string]int, error) {
+	find := func(name string) int {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	columns := make(map[string]int)
+	for _, c := range fields {
+		if name, ok := chosen[c.Field]; ok {
+			i := find(name)
+			if i < 0 {
+				return nil, fmt.Errorf("%s: %q: %w", c.Label, name, errCSVColumn)
+			}
+			columns[c.Field] = i
+			continue
+		}
+		for _, name := range c.Headers {
+			if i := find(name); i >= 0 {
+				columns[c.Field] = i
+				break
+			}
+		}
+	}
+	return columns, nil
+}
+
+// readCSV reads an uploaded CSV into its header and rows, noting the line
+// each row starts on. Rows may have more or fewer fields than the header.
+func readCSV(r io.Reader) (header []string, rows [][]string, lines []int, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err = cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, nil, errCSVEmpty
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Spreadsheets often save a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(rows) == maxImportRows {
+			return nil, nil, nil, errImportTooManyRows
+		}
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+	return header, rows, lines, nil
+}
+
+// readMappedCSV reads a CSV whose columns are chosen by the user, or matched
+// by their usual names. A title column is required.
+func readMappedCSV(r io.Reader, chosen map[string]string) (importUpload, error) {
+	header, values, lines, err := readCSV(r)
+	if err != nil {
+		return importUpload{}, err
+	}
+	columns, err := mapColumns(header, importColumns, chosen)
+	if err != nil {
+		return importUpload{}, err
+	}
+	if _, ok := columns["title"]; !ok {
+		return importUpload{}, errCSVNoTitle
+	}
+
+	return mappedUpload("csv", header, values, lines, columns), nil
+}
+
+// mappedUpload reads CSV rows into entries, taking each field from its
+// mapped column
+func mappedUpload(source string, header []string, values [][]string, lines []int, columns map[string]int) importUpload {
+	upload := importUpload{source: source, header: header, columns: make(map[string]string, len(columns))}
+	for field, i := range columns {
+		upload.columns[field] = header[i]
+	}
+	for n, row := range values {
+		fields := make(map[string]string, len(columns))
+		for field, i := range columns {
+			if i < len(row) {
+				fields[field] = row[i]
+			}
+		}
+		upload.entries = append(upload.entries, importSource{line: lines[n], values: row, fields: fields})
+	}
+	return upload
+}
+
+// parseImportRow reads an entry into a new record and checks it against the
+// CreateRecordRequest rules, returning every problem found
+func (h *Handler) parseImportRow(fields map[string]string) (importRow, []string) {
+	get := func(field string) string {
+		return strings.TrimSpace(fields[field])
+	}
+
+	row := importRow{
+		record: CreateRecordRequest{
+			Title:         get("title"),
+			ArtistName:    get("artist"),
+			AlbumTitle:    get("album_title"),
+			CatalogNumber: get("catalog_number"),
+			Barcode:       get("barcode"),
+			MediaGrade:    normalizeGrade(get("media_grade")),
+			SleeveGrade:   normalizeGrade(get("sleeve_grade")),
+			Notes:         get("notes"),
+		},
+		location:     get("location"),
+		homeLocation: get("home_location"),
+	}
+
+	var problems []string
+	if year := get("release_year"); year != "" {
+		n, err := strconv.ParseInt(year, 10, 32)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ReleaseYear %q is not a year", year))
+		} else {
+			row.record.ReleaseYear = int32(n)
+		}
+	}
+	if err := h.validate.Struct(row.record); err != nil {
+		for _, e := range h.getValidationErrors(err) {
+			problems = append(problems, e.Message)
+		}
+	}
+	for _, name := range []string{row.location, row.homeLocation} {
+		if n := len([]rune(name)); n > 0 && (n < 2 || n > 100) {
+			problems = append(problems, fmt.Sprintf("location %q must be 2 to 100 characters", name))
+		}
+	}
+	return row, problems
+}
+
+// gradeNames maps grade names, and the other ways collection apps write
+// them, to grade codes
+var gradeNames = map[string]string{
+	"mint":           "M",
+	"near mint":      "NM",
+	"m-":             "NM",
+	"very good plus": "VG+",
+	"very good":      "VG",
+	"good plus":      "G+",
+	"good":           "G",
+	"fair":           "F",
+	"poor":           "P",
+}
+
+// ungraded are conditions that aren't a grade at all
+var ungraded = map[string]bool{"generic": true, "no cover": true, "not graded": true}
+
+// gradeCode matches the code in a Discogs condition, e.g. "Near Mint (NM or M-)"
+var gradeCode = regexp.MustCompile(`\(([A-Za-z]+\+?)[^)]*\)$`)
+
+// normalizeGrade turns a grade as written in an import into its code. A code
+// or name is accepted in any case, as is a Discogs condition; conditions that
+// aren't grades become empty, and anything else is upper-cased for
+// validation to reject.
+func normalizeGrade(s string) string {
+	if m := gradeCode.FindStringSubmatch(s); m != nil {
+		return strings.ToUpper(m[1])
+	}
+	lower := strings.ToLower(s)
+	if code, ok := gradeNames[lower]; ok {
+		return code
+	}
+	if ungraded[lower] {
+		return ""
+	}
+	return strings.ToUpper(s)
+}
+
+// resolveLocation returns the id of the location with the name, creating it
+// at the top level if there's none. A bare name matches a top-level location
+// first, or else the only location further down with that name; a name that
+// several nested locations share is ambiguous. A full path such as
+// "Living room / Kallax / Row 2" is followed down from the top, creating the
+// parts that don't exist yet. created reports whether anything was added.
+func resolveLocation(ctx context.Context, q *store.Queries, name string) (id int64, created bool, err error) {
+	var names []string
+	for _, part := range strings.Split(name, locationPathSeparator) {
+		if part = strings.TrimSpace(part); part != "" {
+			names = append(names, part)
+		}
+	}
+	if len(names) < 2 {
+		matches, err := q.ListLocationsByName(ctx, name)
+		switch {
+		case err != nil:
+			return 0, false, err
+		case len(matches) == 0:
+			location, err := q.CreateLocation(ctx, store.CreateLocationParams{Name: name})
+			return location.ID, true, err
+		case !matches[0].ParentID.Valid || len(matches) == 1:
+			return matches[0].ID, false, nil
+		default:
+			return 0, false, fmt.Errorf("location %q: %w", name, errLocationAmbiguous)
+		}
+	}
+
+	var parentID sql.NullInt64
+	for _, part := range names {
+		location, err := q.GetChildLocationByName(ctx, store.GetChildLocationByNameParams{Name: part, ParentID: parentID})
+		if errors.Is(err, sql.ErrNoRows) {
+			location, err = q.CreateLocation(ctx, store.CreateLocationParams{Name: part, ParentID: parentID})
+			created = true
+		}
+		if err != nil {
+			return 0, created, err
+		}
+		parentID = sql.NullInt64{Int64: location.ID, Valid: true}
+	}
+	return parentID.Int64, created, nil
+}
+
+// importFile reads an upload in the source's format and imports it
+func (h *Handler) importFile(ctx context.Context, source string, r io.Reader, chosen map[string]string, dryRun bool) (ImportReport, error) {
+	var upload importUpload
+	var err error
+	switch source {
+	case "discogs":
+		upload, err = readDiscogsCSV(r)
+	case "clz":
+		upload, err = readCLZ(r)
+	default:
+		upload, err = readMappedCSV(r, chosen)
+	}
+	if err != nil {
+		return ImportReport{}, err
+	}
+	return h.importRecords(ctx, upload, dryRun)
+}
+
+// importRecords imports the records in an upload. Entries that fail
+// validation are rejected and reported; the rest are added in a single
+// transaction, along with any artists and locations they name that don't
+// exist yet. Discogs and CLZ entries matching a record already in the
+// collection, by barcode or by title, artist and catalog number, are left
+// out. A dry run does the same and rolls it all back.
+func (h *Handler) importRecords(ctx context.Context, upload importUpload, dryRun bool) (ImportReport, error) {
+	report := ImportReport{
+		Source:           upload.source,
+		DryRun:           dryRun,
+		Columns:          upload.columns,
+		Total:            len(upload.entries),
+		Rows:             []ImportedRow{},
+		Matched:          []ImportedRow{},
+		Rejected:         []RejectedRow{},
+		NewArtists:       []string{},
+		MatchedArtists:   []string{},
+		NewLocations:     []string{},
+		MatchedLocations: []string{},
+		header:           upload.header,
+	}
+	dedupe := upload.source != "csv"
+
+	var rows []importRow
+	for _, entry := range upload.entries {
+		row, problems := h.parseImportRow(entry.fields)
+		row.line = entry.line
+		if len(problems) > 0 {
+			report.Rejected = append(report.Rejected, RejectedRow{Line: entry.line, Errors: problems, Values: entry.values})
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		artists := make(map[string]int64)
+		locations := make(map[string]int64)
+		created := make(map[int64]bool)
+
+		artist := func(name string) (int64, error) {
+			if id, ok := artists[name]; ok {
+				return id, nil
+			}
+			id, isNew, err := resolveArtist(ctx, q, name)
+			if err != nil {
+				return 0, err
+			}
+			if isNew {
+				report.NewArtists = append(report.NewArtists, name)
+			} else {
+				report.MatchedArtists = append(report.MatchedArtists, name)
+			}
+			artists[name] = id
+			return id, nil
+		}
+		location := func(name string) (int64, error) {
+			if name == "" {
+				return 0, nil
+			}
+			if id, ok := locations[name]; ok {
+				return id, nil
+			}
+			id, isNew, err := resolveLocation(ctx, q, name)
+			if err != nil {
+				return 0, err
+			}
+			if isNew {
+				report.NewLocations = append(report.NewLocations, name)
+			} else {
+				report.MatchedLocations = append(report.MatchedLocations, name)
+			}
+			locations[name] = id
+			return id, nil
+		}
+
+		for _, row := range rows {
+			var err error
+			if name := row.record.ArtistName; name != "" {
+				if row.record.ArtistID, err = artist(name); err != nil {
+					return err
+				}
+			}
+
+			imported := ImportedRow{
+				Line:        row.line,
+				Title:       row.record.Title,
+				Artist:      row.record.ArtistName,
+				ReleaseYear: row.record.ReleaseYear,
+				Location:    row.location,
+			}
+
+			if dedupe {
+				id, err := matchRecord(ctx, q, row.record)
+				if err != nil {
+					return err
+				}
+				if id > 0 {
+					// A dry run's records are rolled back; only link to ones
+					// that will still be there
+					if !dryRun || !created[id] {
+						imported.RecordID = id
+					}
+					report.Matched = append(report.Matched, imported)
+					continue
+				}
+			}
+
+			if row.record.CurrentLocationID, err = location(row.location); err != nil {
+				return err
+			}
+			if row.record.HomeLocationID, err = location(row.homeLocation); err != nil {
+				return err
+			}
+
+			record, err := insertRecord(ctx, q, row.record, nil)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", row.line, err)
+			}
+			created[record.ID] = true
+			if !dryRun {
+				imported.RecordID = record.ID
+			}
+			report.Rows = append(report.Rows, imported)
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return ImportReport{}, err
+	}
+
+	report.Imported = len(report.Rows)
+	return report, nil
+}
+
+// matchRecord returns the id of a record already in the collection that req
+// duplicates, or 0 if there's none. A barcode decides on its own; otherwise
+// the title, artist and catalog number must all match.
+func matchRecord(ctx context.Context, q *store.Queries, req CreateRecordRequest) (int64, error) {
+	if id, err := matchBarcode(ctx, q, req.Barcode); err != nil || id > 0 {
+		return id, err
+	}
+
+	id, err := q.FindMatchingRecord(ctx, store.FindMatchingRecordParams{
+		Title:         req.Title,
+		ArtistID:      sql.NullInt64{Int64: req.ArtistID, Valid: req.ArtistID > 0},
+		CatalogNumber: req.CatalogNumber,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// matchBarcode returns the id of the first record with the barcode, or 0 if
+// there's none or no barcode
+func matchBarcode(ctx context.Context, q *store.Queries, barcode string) (int64, error) {
+	if barcode == "" {
+		return 0, nil
+	}
+	copies, err := q.ListRecordsByBarcode(ctx, sql.NullString{String: normalizeBarcode(barcode), Valid: true})
+	if err != nil || len(copies) == 0 {
+		return 0, err
+	}
+	return copies[0].ID, nil
+}
+
+// writeRejectedCSV writes an import's rejected rows as CSV: the columns of
+// the upload, then the line each row was on and why it was rejected. Fixed
+// rows can be imported again as they are.
+func writeRejectedCSV(w io.Writer, report ImportReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(append([]string{}, report.header...), "line", "errors")); err != nil {
+		return err
+	}
+	for _, row := range report.Rejected {
+		values := make([]string, len(report.header), len(report.header)+2)
+		copy(values, row.Values)
+		values = append(values, strconv.Itoa(row.Line), strings.Join(row.Errors, "; "))
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// importErrorStatus maps an import error to an HTTP status and message
+func importErrorStatus(err error) (int, string) {
+	var maxBytesErr *http.MaxBytesError
+	var parseErr *csv.ParseError
+	var syntaxErr *xml.SyntaxError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge, "the file is too large; split it into smaller files"
+	case errors.As(err, &parseErr):
+		return http.StatusBadRequest, "the CSV can't be read: " + parseErr.Error()
+	case errors.As(err, &syntaxErr):
+		return http.StatusBadRequest, "the XML can't be read: " + syntaxErr.Error()
+	case errors.Is(err, errImportMissing), errors.Is(err, errImportTooManyRows), errors.Is(err, errCSVEmpty),
+		errors.Is(err, errCSVNoTitle), errors.Is(err, errCSVColumn), errors.Is(err, errDiscogsFormat),
+		errors.Is(err, errCLZFormat), errors.Is(err, errLocationAmbiguous):
+		return http.StatusBadRequest, err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to import records"
+	}
+}
+
+// HTML Handlers
+
+// GET /imports
+func (h *Handler) GetImports() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.renderer.Render(w, "imports", map[string]interface{}{
+			"Title":   "Import records",
+			"Columns": importColumns,
+			"MaxRows": maxImportRows,
+		})
+	}
+}
+
+// POST /imports/{source}
+func (h *Handler) ImportRecords() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		source, ok := pathImportSource(r)
+		if !ok {
+			http.Error(w, "Invalid parameter: source", http.StatusBadRequest)
+			return
+		}
+
+		// The file is read first so the multipart form is parsed before binding
+		file, err := uploadedFile(r)
+		if err != nil {
+			status, message := importErrorStatus(err)
+			http.Error(w, message, status)
+			return
+		}
+		defer file.Close()
+
+		var req ImportRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := h.importFile(r.Context(), source, file, chosenColumns(r, importColumns), req.DryRun)
+		if err != nil {
+			status, message := importErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to import records", slog.String("error", err.Error()))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		data := map[string]interface{}{
+			"Report":  report,
+			"Preview": report.Rows[:min(len(report.Rows), maxPreviewRows)],
+			"Matched": report.Matched[:min(len(report.Matched), maxPreviewRows)],
+		}
+		// The rejected rows are offered as a download straight from the page,
+		// since the upload isn't kept
+		if len(report.Rejected) > 0 {
+			var buf bytes.Buffer
+			if err := writeRejectedCSV(&buf, report); err != nil {
+				h.logger.Error("Failed to write rejected rows", slog.String("error", err.Error()))
+			} else {
+				data["RejectedURL"] = template.URL("data:text/csv;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()))
+			}
+		}
+
+		h.renderer.Render(w, "import-report", data)
+	}
+}
+
+// API Handlers
+
+// POST /api/v1/imports/{source}
+func (h *Handler) JsonImportRecords() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		source, ok := pathImportSource(r)
+		if !ok {
+			h.writeErrorJSON(w, "Invalid parameter: source", http.StatusBadRequest)
+			return
+		}
+
+		file, err := uploadedFile(r)
+		if err != nil {
+			status, message := importErrorStatus(err)
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+		defer file.Close()
+
+		var req ImportRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := h.importFile(r.Context(), source, file, chosenColumns(r, importColumns), req.DryRun)
+		if err != nil {
+			status, message := importErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to import records", slog.String("error", err.Error()))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		if req.Report == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="rejected-rows.csv"`)
+			if err := writeRejectedCSV(w, report); err != nil {
+				h.logger.Error("Failed to write rejected rows", slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		status := http.StatusCreated
+		if report.DryRun {
+			status = http.StatusOK
+		}
+		h.writeJSON(w, report, status)
+	}
+}
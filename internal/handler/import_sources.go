@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/dukerupert/dd/internal/metadata"
+)
+
+var (
+	errDiscogsFormat = errors.New("this isn't a Discogs collection export: it needs Artist and Title columns")
+	errCLZFormat     = errors.New("this isn't a CLZ Music export: there's no <musicinfo> or <music> in it")
+)
+
+// discogsColumns maps the headers of a Discogs collection export, lower-cased
+// with spaces removed, to import fields. Newer exports put "Collection" in
+// front of the collection's own fields.
+var discogsColumns = map[string]string{
+	"catalog#":                  "catalog_number",
+	"artist":                    "artist",
+	"title":                     "title",
+	"released":                  "release_year",
+	"collectionfolder":          "location",
+	"collectionmediacondition":  "media_grade",
+	"mediacondition":            "media_grade",
+	"collectionsleevecondition": "sleeve_grade",
+	"sleevecondition":           "sleeve_grade",
+	"collectionnotes":           "notes",
+	"notes":                     "notes",
+}
+
+// discogsDefaultFolder is the folder Discogs files records in until they're
+// moved; it isn't a place
+const discogsDefaultFolder = "Uncategorized"
+
+// clzHeader names the values of a CLZ entry in the rejected rows download
+var clzHeader = []string{"title", "artist", "release_year", "catalog_number", "barcode", "media_grade", "sleeve_grade", "location", "notes"}
+
+// clzMusic is the part of a CLZ Music XML <music> entry we read
+type clzMusic struct {
+	Title   string `xml:"title"`
+	Artists []struct {
+		Name string `xml:"displayname"`
+	} `xml:"artists>artist"`
+	Year            string `xml:"releasedate>year>displayname"`
+	LabelNumber     string `xml:"labelnumber"`
+	CatalogNumber   string `xml:"catalognumber"`
+	UPC             string `xml:"upc"`
+	Barcode         string `xml:"barcode"`
+	Condition       string `xml:"condition>displayname"`
+	SleeveCondition string `xml:"sleevecondition>displayname"`
+	Location        string `xml:"location>displayname"`
+	Notes           string `xml:"notes"`
+}
+
+// leadingYear returns the year a release date starts with ("1991" from
+// "1991-09-24"), or "" if it has none. Discogs writes 0 for an unknown year.
+func leadingYear(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 4 || s[:4] == "0000" {
+		return ""
+	}
+	for _, r := range s[:4] {
+		if !unicode.IsDigit(r) {
+			return ""
+		}
+	}
+	return s[:4]
+}
+
+// readDiscogsCSV reads a Discogs collection export. The title is also the
+// album title, the collection folder is the location and the conditions are
+// the grades; artist names lose the " (2)" Discogs adds to tell them apart.
+func readDiscogsCSV(r io.Reader) (importUpload, error) {
+	header, values, lines, err := readCSV(r)
+	if err != nil {
+		return importUpload{}, err
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		key := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(h), " ", ""))
+		if field, ok := discogsColumns[key]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	_, hasArtist := columns["artist"]
+	_, hasTitle := columns["title"]
+	if !hasArtist || !hasTitle {
+		return importUpload{}, errDiscogsFormat
+	}
+
+	upload := importUpload{source: "discogs", header: header, columns: make(map[string]string, len(columns)+1)}
+	for field, i := range columns {
+		upload.columns[field] = header[i]
+	}
+	upload.columns["album_title"] = header[columns["title"]]
+
+	for n, row := range values {
+		fields := make(map[string]string, len(columns)+1)
+		for field, i := range columns {
+			if i < len(row) {
+				fields[field] = strings.TrimSpace(row[i])
+			}
+		}
+		fields["artist"] = metadata.StripDisambiguation(fields["artist"])
+		fields["album_title"] = fields["title"]
+		fields["release_year"] = leadingYear(fields["release_year"])
+		if strings.EqualFold(fields["location"], discogsDefaultFolder) {
+			fields["location"] = ""
+		}
+		upload.entries = append(upload.entries, importSource{line: lines[n], values: row, fields: fields})
+	}
+	return upload, nil
+}
+
+// readCLZ reads a CLZ Music XML export, one record per <music> entry. The
+// first artist is the record's artist and the condition is the media grade.
+// Entries are numbered by the line they start on.
+func readCLZ(r io.Reader) (importUpload, error) {
+	upload := importUpload{
+		source: "clz",
+		header: clzHeader,
+		columns: map[string]string{
+			"title":          "title",
+			"album_title":    "title",
+			"artist":         "artists/artist",
+			"release_year":   "releasedate/year",
+			"catalog_number": "labelnumber",
+			"barcode":        "upc",
+			"media_grade":    "condition",
+			"sleeve_grade":   "sleevecondition",
+			"location":       "location",
+			"notes":          "notes",
+		},
+	}
+
+	dec := xml.NewDecoder(r)
+	isCLZ := false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return importUpload{}, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "musicinfo":
+			isCLZ = true
+		case "music":
+			isCLZ = true
+			line, _ := dec.InputPos()
+			var m clzMusic
+			if err := dec.DecodeElement(&m, &start); err != nil {
+				return importUpload{}, err
+			}
+			if len(upload.entries) == maxImportRows {
+				return importUpload{}, errImportTooManyRows
+			}
+			upload.entries = append(upload.entries, clzEntry(line, m))
+		}
+	}
+	if !isCLZ {
+		return importUpload{}, errCLZFormat
+	}
+	return upload, nil
+}
+
+// clzEntry reads a <music> entry into import fields
+func clzEntry(line int, m clzMusic) importSource {
+	fields := map[string]string{
+		"title":          strings.TrimSpace(m.Title),
+		"album_title":    strings.TrimSpace(m.Title),
+		"release_year":   leadingYear(m.Year),
+		"catalog_number": strings.TrimSpace(cmp.Or(m.LabelNumber, m.CatalogNumber)),
+		"barcode":        strings.TrimSpace(cmp.Or(m.UPC, m.Barcode)),
+		"media_grade":    strings.TrimSpace(m.Condition),
+		"sleeve_grade":   strings.TrimSpace(m.SleeveCondition),
+		"location":       strings.TrimSpace(m.Location),
+		"notes":          strings.TrimSpace(m.Notes),
+	}
+	if len(m.Artists) > 0 {
+		fields["artist"] = strings.TrimSpace(m.Artists[0].Name)
+	}
+
+	values := make([]string, len(clzHeader))
+	for i, field := range clzHeader {
+		values[i] = fields[field]
+	}
+	return importSource{line: line, values: values, fields: fields}
+}
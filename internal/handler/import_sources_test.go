@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+const testDiscogsCSV = "Catalog#,Artist,Title,Label,Format,Rating,Released,release_id,CollectionFolder,Date Added,Collection Media Condition,Collection Sleeve Condition,Collection Notes\n" +
+	"DGC-24425,Nirvana,Nevermind,DGC,\"LP, Album\",5,1991,367084,Living Room,2024-01-02 10:00:00,Near Mint (NM or M-),Very Good Plus (VG+),Gatefold\n" +
+	"DGC-24607,Nirvana,In Utero,DGC,\"LP, Album\",,1993-09-21,1151406,Uncategorized,2024-01-02 10:00:00,Very Good (VG),Generic,\n" +
+	"none,Hole (2),Live Through This,DGC,LP,,0,99,Attic,2024-01-02 10:00:00,Mint (M),,\n"
+
+const testCLZ = `<?xml version="1.0" encoding="UTF-8"?>
+<musicinfo>
+  <musiclist>
+    <music>
+      <title>Nevermind</title>
+      <artists><artist><displayname>Nirvana</displayname></artist></artists>
+      <releasedate><year><displayname>1991</displayname></year></releasedate>
+      <labelnumber>DGC-24425</labelnumber>
+      <upc>720642442517</upc>
+      <condition><displayname>Near Mint</displayname></condition>
+      <location><displayname>Living Room</displayname></location>
+    </music>
+    <music>
+      <title>Dirt</title>
+      <artists><artist><displayname>Alice in Chains</displayname></artist><artist><displayname>Layne Staley</displayname></artist></artists>
+      <notes>First pressing</notes>
+    </music>
+  </musiclist>
+</musicinfo>
+`
+
+// TestReadDiscogsCSV tests how a Discogs collection export maps to fields
+func TestReadDiscogsCSV(t *testing.T) {
+	upload, err := readDiscogsCSV(strings.NewReader(testDiscogsCSV))
+	if err != nil {
+		t.Fatalf("readDiscogsCSV() error = %v", err)
+	}
+	if upload.source != "discogs" || len(upload.entries) != 3 || upload.columns["location"] != "CollectionFolder" {
+		t.Fatalf("upload = %+v", upload)
+	}
+
+	tests := []struct {
+		entry int
+		field string
+		want  string
+	}{
+		{0, "catalog_number", "DGC-24425"},
+		{0, "album_title", "Nevermind"},
+		{0, "release_year", "1991"},
+		{0, "location", "Living Room"},
+		{0, "media_grade", "Near Mint (NM or M-)"},
+		{0, "notes", "Gatefold"},
+		{1, "release_year", "1993"},
+		{1, "location", ""},
+		{2, "artist", "Hole"},
+		{2, "release_year", ""},
+	}
+	for _, tt := range tests {
+		if got := upload.entries[tt.entry].fields[tt.field]; got != tt.want {
+			t.Errorf("entry %d %s = %q, want %q", tt.entry, tt.field, got, tt.want)
+		}
+	}
+	if upload.entries[1].line != 3 {
+		t.Errorf("line = %d, want 3", upload.entries[1].line)
+	}
+
+	if _, err := readDiscogsCSV(strings.NewReader("Album,Band\nNevermind,Nirvana\n")); !errors.Is(err, errDiscogsFormat) {
+		t.Errorf("readDiscogsCSV() of another CSV error = %v, want errDiscogsFormat", err)
+	}
+}
+
+// TestReadCLZ tests how a CLZ Music export maps to fields
+func TestReadCLZ(t *testing.T) {
+	upload, err := readCLZ(strings.NewReader(testCLZ))
+	if err != nil {
+		t.Fatalf("readCLZ() error = %v", err)
+	}
+	if upload.source != "clz" || len(upload.entries) != 2 {
+		t.Fatalf("upload = %+v", upload)
+	}
+
+	first, second := upload.entries[0].fields, upload.entries[1].fields
+	if first["title"] != "Nevermind" || first["artist"] != "Nirvana" || first["release_year"] != "1991" ||
+		first["catalog_number"] != "DGC-24425" || first["barcode"] != "720642442517" ||
+		first["media_grade"] != "Near Mint" || first["location"] != "Living Room" {
+		t.Errorf("first entry = %v", first)
+	}
+	if second["artist"] != "Alice in Chains" || second["notes"] != "First pressing" || second["release_year"] != "" {
+		t.Errorf("second entry = %v", second)
+	}
+	if upload.entries[0].line != 4 || len(upload.entries[0].values) != len(clzHeader) {
+		t.Errorf("entry line %d, values %q", upload.entries[0].line, upload.entries[0].values)
+	}
+
+	if _, err := readCLZ(strings.NewReader("<collection><album/></collection>")); !errors.Is(err, errCLZFormat) {
+		t.Errorf("readCLZ() of other XML error = %v, want errCLZFormat", err)
+	}
+	if _, err := readCLZ(strings.NewReader("<musicinfo><music>")); err == nil {
+		t.Error("readCLZ() of truncated XML succeeded")
+	}
+}
+
+// TestImportRecords_Dedupe tests that Discogs and CLZ imports leave records
+// already in the collection alone
+func TestImportRecords_Dedupe(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{
+		logger:   slog.New(slog.DiscardHandler),
+		db:       db,
+		queries:  queries,
+		validate: newValidator(),
+	}
+
+	nirvana, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Nirvana", SortName: store.ArtistSortName("Nirvana")})
+	if err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+	nevermind, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+		Title:         "nevermind",
+		ArtistID:      sql.NullInt64{Int64: nirvana.ID, Valid: true},
+		CatalogNumber: sql.NullString{String: "DGC-24425", Valid: true},
+		Barcode:       sql.NullString{String: "0720642442517", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	report, err := h.importFile(ctx, "discogs", strings.NewReader(testDiscogsCSV), nil, false)
+	if err != nil {
+		t.Fatalf("importFile() error = %v", err)
+	}
+	if report.Source != "discogs" || report.Imported != 2 || len(report.Matched) != 1 || len(report.Rejected) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.Matched[0].RecordID != nevermind.ID {
+		t.Errorf("matched record = %d, want %d", report.Matched[0].RecordID, nevermind.ID)
+	}
+	if strings.Join(report.MatchedArtists, ",") != "Nirvana" || strings.Join(report.NewArtists, ",") != "Hole" {
+		t.Errorf("matched artists %v, new artists %v", report.MatchedArtists, report.NewArtists)
+	}
+	// Nevermind matched, so its Living Room isn't needed
+	if strings.Join(report.NewLocations, ",") != "Attic" {
+		t.Errorf("new locations = %v", report.NewLocations)
+	}
+
+	record, err := queries.GetRecord(ctx, report.Rows[0].RecordID)
+	if err != nil {
+		t.Fatalf("GetRecord() error = %v", err)
+	}
+	if record.Title != "In Utero" || record.MediaGrade.String != "VG" || record.SleeveGrade.Valid || record.ReleaseYear.Int64 != 1993 {
+		t.Errorf("imported record = %+v", record)
+	}
+
+	// Importing the same export again matches everything
+	report, err = h.importFile(ctx, "discogs", strings.NewReader(testDiscogsCSV), nil, false)
+	if err != nil {
+		t.Fatalf("importFile() again error = %v", err)
+	}
+	if report.Imported != 0 || len(report.Matched) != 3 {
+		t.Errorf("second import report = %+v", report)
+	}
+
+	// A CLZ entry with a known barcode matches on that alone
+	report, err = h.importFile(ctx, "clz", strings.NewReader(strings.Replace(testCLZ, "DGC-24425", "DGCD-24425", 1)), nil, true)
+	if err != nil {
+		t.Fatalf("importFile() of CLZ error = %v", err)
+	}
+	if report.Imported != 1 || len(report.Matched) != 1 || report.Matched[0].RecordID != nevermind.ID {
+		t.Errorf("CLZ report = %+v", report)
+	}
+}
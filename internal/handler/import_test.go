@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// importCSVRequest builds a multipart CSV upload with the given form fields
+func importCSVRequest(t *testing.T, body string, fields map[string]string) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	part, err := mw.CreateFormFile("file", "records.csv")
+	if err != nil {
+		t.Fatalf("CreateFormFile() error = %v", err)
+	}
+	part.Write([]byte(body))
+	mw.Close()
+
+	r := httptest.NewRequest("POST", "/api/v1/imports/csv", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	r.SetPathValue("source", "csv")
+	return r
+}
+
+const testImportCSV = "\ufeffAlbum Name,Band,Year,Shelf,Media\n" +
+	"Nevermind,Nirvana,1991,Living Room,nm\n" +
+	"In Utero,Nirvana,1993,Living Room,VG+\n" +
+	"Live Through This,Hole,nineteen,Attic,\n" +
+	",Hole,1994,,\n" +
+	"Bleach,Nirvana,1989,Attic,Z\n"
+
+var testImportColumns = map[string]string{
+	"column_title":        "album name",
+	"column_artist":       "Band",
+	"column_release_year": "Year",
+	"column_location":     "Shelf",
+	"column_media_grade":  "Media",
+}
+
+// TestImportCSV tests a dry run, then importing the same CSV
+func TestImportCSV(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{
+		logger:   slog.New(slog.DiscardHandler),
+		db:       db,
+		queries:  queries,
+		validate: newValidator(),
+	}
+
+	nirvana, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Nirvana", SortName: store.ArtistSortName("Nirvana")})
+	if err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+
+	post := func(fields map[string]string) (*httptest.ResponseRecorder, ImportReport) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.JsonImportRecords()(w, importCSVRequest(t, testImportCSV, fields))
+		var report ImportReport
+		if w.Header().Get("Content-Type") == "application/json" {
+			json.NewDecoder(bytes.NewReader(w.Body.Bytes())).Decode(&report)
+		}
+		return w, report
+	}
+
+	fields := map[string]string{"dry_run": "true"}
+	for k, v := range testImportColumns {
+		fields[k] = v
+	}
+	w, report := post(fields)
+	if w.Code != http.StatusOK {
+		t.Fatalf("dry run status = %d, body %s", w.Code, w.Body)
+	}
+	if !report.DryRun || report.Total != 5 || report.Imported != 2 || len(report.Rejected) != 3 {
+		t.Fatalf("dry run report = %+v", report)
+	}
+	if report.Columns["title"] != "Album Name" || report.Columns["release_year"] != "Year" {
+		t.Errorf("columns = %v", report.Columns)
+	}
+	if len(report.NewLocations) != 1 || report.NewLocations[0] != "Living Room" || len(report.NewArtists) != 0 {
+		t.Errorf("new artists %v and locations %v, want just Living Room", report.NewArtists, report.NewLocations)
+	}
+	wantLines := []int{4, 5, 6}
+	for i, row := range report.Rejected {
+		if row.Line != wantLines[i] || len(row.Errors) == 0 {
+			t.Errorf("rejected row %d = %+v, want line %d with errors", i, row, wantLines[i])
+		}
+	}
+
+	// Nothing was saved
+	if records, _ := queries.ListRecords(ctx); len(records) != 0 {
+		t.Errorf("dry run saved %d records", len(records))
+	}
+	if _, err := queries.GetLocationByName(ctx, "Living Room"); err == nil {
+		t.Error("dry run saved a location")
+	}
+
+	delete(fields, "dry_run")
+	w, report = post(fields)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("import status = %d, body %s", w.Code, w.Body)
+	}
+	if report.DryRun || report.Imported != 2 || report.Rows[0].RecordID == 0 {
+		t.Fatalf("import report = %+v", report)
+	}
+	record, err := queries.GetRecord(ctx, report.Rows[0].RecordID)
+	if err != nil {
+		t.Fatalf("GetRecord() error = %v", err)
+	}
+	room, err := queries.GetLocationByName(ctx, "Living Room")
+	if err != nil {
+		t.Fatalf("GetLocationByName() error = %v", err)
+	}
+	if record.Title != "Nevermind" || record.ArtistID.Int64 != nirvana.ID || record.ReleaseYear.Int64 != 1991 ||
+		record.MediaGrade.String != "NM" || record.CurrentLocationID.Int64 != room.ID {
+		t.Errorf("imported record = %+v", record)
+	}
+
+	// The rejected rows download as CSV with the reasons added
+	fields["report"] = "csv"
+	w, _ = post(fields)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("report status = %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	if len(rows) != 4 || rows[0][0] != "Album Name" || rows[0][5] != "line" || rows[1][0] != "Live Through This" || rows[1][5] != "4" || rows[1][6] == "" {
+		t.Errorf("rejected rows CSV = %q", rows)
+	}
+}
+
+// TestImportCSV_Errors tests uploads that can't be imported at all
+func TestImportCSV_Errors(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	h := &Handler{
+		logger:   slog.New(slog.DiscardHandler),
+		db:       db,
+		queries:  queries,
+		validate: newValidator(),
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		fields map[string]string
+	}{
+		{"empty file", "", nil},
+		{"no title column", "artist,year\nNirvana,1991\n", nil},
+		{"unknown chosen column", "title\nNevermind\n", map[string]string{"column_artist": "Band"}},
+		{"malformed CSV", "title\n\"Nevermind\n", nil},
+		{"bad report format", "title\nNevermind\n", map[string]string{"report": "xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.JsonImportRecords()(w, importCSVRequest(t, tt.body, tt.fields))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body)
+			}
+		})
+	}
+
+	t.Run("no file", func(t *testing.T) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("dry_run", "true")
+		mw.Close()
+		r := httptest.NewRequest("POST", "/api/v1/imports/csv", &buf)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.SetPathValue("source", "csv")
+		w := httptest.NewRecorder()
+		h.JsonImportRecords()(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+}
+
+// TestNormalizeGrade tests the ways a grade is written in imports
+func TestNormalizeGrade(t *testing.T) {
+	tests := map[string]string{
+		"nm":                   "NM",
+		"vg+":                  "VG+",
+		"Near Mint":            "NM",
+		"Very Good Plus":       "VG+",
+		"Near Mint (NM or M-)": "NM",
+		"Very Good Plus (VG+)": "VG+",
+		"Generic":              "",
+		"Not Graded":           "",
+		"Z":                    "Z",
+	}
+	for in, want := range tests {
+		if got := normalizeGrade(in); got != want {
+			t.Errorf("normalizeGrade(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
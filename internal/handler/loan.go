@@ -0,0 +1,727 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// errRecordOnLoan is returned when lending a record that's already lent
+	errRecordOnLoan = errors.New("record is already on loan")
+	// errRecordNotOnLoan is returned when returning a record that isn't lent
+	errRecordNotOnLoan = errors.New("record is not on loan")
+	// errBorrowerNotFound is returned when lending to an unknown borrower id
+	errBorrowerNotFound = errors.New("borrower not found")
+	// errDuplicateBorrower is returned when a borrower with the name exists
+	errDuplicateBorrower = errors.New("a borrower with that name already exists")
+	// errBorrowerHasLoans is returned when deleting a borrower with loans
+	errBorrowerHasLoans = errors.New("borrower has loans and can't be deleted")
+)
+
+// BorrowerRequest is the body for adding or editing a borrower. Contact is
+// free text: a phone number, an email, where they live.
+type BorrowerRequest struct {
+	Name    string `form:"name" json:"name" validate:"required,notblank,max=100"`
+	Contact string `form:"contact" json:"contact" validate:"max=200"`
+	Notes   string `form:"notes" json:"notes" validate:"max=1000"`
+}
+
+// LendRequest lends a record to a borrower by id, or by name. A name that
+// isn't a borrower yet adds them. DueAt is a date, e.g. 2026-11-01; the loan
+// is overdue from the day after.
+type LendRequest struct {
+	BorrowerID   int64  `form:"borrower_id" json:"borrower_id" validate:"required_without=BorrowerName,omitempty,min=1"`
+	BorrowerName string `form:"borrower_name" json:"borrower_name" validate:"required_without=BorrowerID,omitempty,notblank,max=100"`
+	DueAt        string `form:"due_at" json:"due_at" validate:"omitempty,datetime=2006-01-02"`
+	Notes        string `form:"notes" json:"notes" validate:"max=1000"`
+}
+
+// dueAt returns the due date, if there is one
+func (req LendRequest) dueAt() sql.NullTime {
+	t, err := time.Parse("2006-01-02", req.DueAt)
+	return sql.NullTime{Time: t, Valid: err == nil}
+}
+
+// LoanResponse is a loan and the record, moved to or from the "On loan"
+// location
+type LoanResponse struct {
+	Loan   store.Loan   `json:"loan"`
+	Record store.Record `json:"record"`
+}
+
+// today is the start of the current day, UTC. Loans due before it are
+// overdue.
+func today(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
+
+// loanLocation returns the "On loan" location, adding it the first time
+func loanLocation(ctx context.Context, q *store.Queries) (store.Location, error) {
+	location, err := q.GetLoanLocation(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return q.CreateLoanLocation(ctx)
+	}
+	return location, err
+}
+
+// lendRecord lends a record and moves it to the "On loan" location, in one
+// transaction. Returns sql.ErrNoRows if the record doesn't exist,
+// errBorrowerNotFound or errRecordOnLoan.
+func (h *Handler) lendRecord(ctx context.Context, recordID int64, req LendRequest, now time.Time) (LoanResponse, error) {
+	var resp LoanResponse
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		record, err := q.GetRecord(ctx, recordID)
+		if err != nil {
+			return err
+		}
+		if _, err := q.GetOpenLoanByRecord(ctx, recordID); err == nil {
+			return errRecordOnLoan
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		var borrower store.Borrower
+		if req.BorrowerID > 0 {
+			borrower, err = q.GetBorrower(ctx, req.BorrowerID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return errBorrowerNotFound
+			}
+		} else {
+			name := strings.TrimSpace(req.BorrowerName)
+			borrower, err = q.GetBorrowerByName(ctx, name)
+			if errors.Is(err, sql.ErrNoRows) {
+				borrower, err = q.CreateBorrower(ctx, store.CreateBorrowerParams{Name: name})
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		location, err := loanLocation(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		resp.Loan, err = q.CreateLoan(ctx, store.CreateLoanParams{
+			RecordID:   recordID,
+			BorrowerID: borrower.ID,
+			LentAt:     now.UTC().Truncate(time.Second),
+			DueAt:      req.dueAt(),
+			Notes:      sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+		})
+		if isUniqueViolation(err) {
+			return errRecordOnLoan
+		}
+		if err != nil {
+			return err
+		}
+
+		resp.Record, err = moveRecord(ctx, q, record, sql.NullInt64{Int64: location.ID, Valid: true}, "Lent to "+borrower.Name)
+		return err
+	})
+	return resp, err
+}
+
+// returnRecord closes a record's open loan and, if it's still "On loan",
+// moves it back to its home location, or the default location if it hasn't
+// got one, in one transaction. Returns sql.ErrNoRows if the record doesn't
+// exist or errRecordNotOnLoan.
+func (h *Handler) returnRecord(ctx context.Context, recordID int64, now time.Time) (LoanResponse, error) {
+	var resp LoanResponse
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		record, err := q.GetRecord(ctx, recordID)
+		if err != nil {
+			return err
+		}
+		open, err := q.GetOpenLoanByRecord(ctx, recordID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errRecordNotOnLoan
+		}
+		if err != nil {
+			return err
+		}
+
+		resp.Loan, err = q.ReturnLoan(ctx, store.ReturnLoanParams{
+			ReturnedAt: sql.NullTime{Time: now.UTC().Truncate(time.Second), Valid: true},
+			ID:         open.ID,
+		})
+		if err != nil {
+			return err
+		}
+		resp.Record = record
+
+		location, err := q.GetLoanLocation(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if record.CurrentLocationID != (sql.NullInt64{Int64: location.ID, Valid: true}) {
+			// It's already been put somewhere by hand
+			return nil
+		}
+
+		home := record.HomeLocationID
+		if !home.Valid {
+			def, err := q.GetDefaultLocation(ctx)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			home = sql.NullInt64{Int64: def.ID, Valid: err == nil}
+		}
+		resp.Record, err = moveRecord(ctx, q, record, home, "Back from "+open.BorrowerName)
+		return err
+	})
+	return resp, err
+}
+
+// loans lists the open loans, or only the overdue ones
+func (h *Handler) loans(ctx context.Context, overdue bool, now time.Time) ([]store.ListOpenLoansRow, error) {
+	if !overdue {
+		loans, err := h.queries.ListOpenLoans(ctx)
+		if loans == nil {
+			loans = []store.ListOpenLoansRow{}
+		}
+		return loans, err
+	}
+
+	rows, err := h.queries.ListOverdueLoans(ctx, sql.NullTime{Time: today(now), Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	loans := make([]store.ListOpenLoansRow, 0, len(rows))
+	for _, row := range rows {
+		loans = append(loans, store.ListOpenLoansRow(row))
+	}
+	return loans, nil
+}
+
+// loanData gathers what the record page's loan section shows: the open loan,
+// if any, the record's past loans and the borrowers to lend to
+func (h *Handler) loanData(ctx context.Context, recordID int64) (map[string]interface{}, error) {
+	var loan *store.GetOpenLoanByRecordRow
+	open, err := h.queries.GetOpenLoanByRecord(ctx, recordID)
+	if err == nil {
+		loan = &open
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	history, err := h.queries.ListLoansByRecord(ctx, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	borrowers, err := h.queries.ListBorrowers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"RecordID":  recordID,
+		"Loan":      loan,
+		"Loans":     history,
+		"Borrowers": borrowers,
+		"Today":     today(time.Now()),
+	}, nil
+}
+
+// createBorrower adds a borrower, mapping a name clash to
+// errDuplicateBorrower
+func (h *Handler) createBorrower(ctx context.Context, req BorrowerRequest) (store.Borrower, error) {
+	borrower, err := h.queries.CreateBorrower(ctx, store.CreateBorrowerParams{
+		Name:    strings.TrimSpace(req.Name),
+		Contact: sql.NullString{String: req.Contact, Valid: req.Contact != ""},
+		Notes:   sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+	})
+	if isUniqueViolation(err) {
+		return store.Borrower{}, errDuplicateBorrower
+	}
+	return borrower, err
+}
+
+// updateBorrower edits a borrower. Returns sql.ErrNoRows if they don't exist.
+func (h *Handler) updateBorrower(ctx context.Context, borrowerID int64, req BorrowerRequest) (store.Borrower, error) {
+	borrower, err := h.queries.UpdateBorrower(ctx, store.UpdateBorrowerParams{
+		Name:    strings.TrimSpace(req.Name),
+		Contact: sql.NullString{String: req.Contact, Valid: req.Contact != ""},
+		Notes:   sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+		ID:      borrowerID,
+	})
+	if isUniqueViolation(err) {
+		return store.Borrower{}, errDuplicateBorrower
+	}
+	return borrower, err
+}
+
+// deleteBorrower deletes a borrower who has never borrowed anything. Returns
+// sql.ErrNoRows if they don't exist or errBorrowerHasLoans.
+func (h *Handler) deleteBorrower(ctx context.Context, borrowerID int64) error {
+	return h.withTx(ctx, func(q *store.Queries) error {
+		count, err := q.CountLoansByBorrower(ctx, borrowerID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errBorrowerHasLoans
+		}
+
+		deleted, err := q.DeleteBorrower(ctx, borrowerID)
+		if err == nil && deleted == 0 {
+			return sql.ErrNoRows
+		}
+		return err
+	})
+}
+
+// renderBorrowersList renders the borrowers on the loans page
+func (h *Handler) renderBorrowersList(w http.ResponseWriter, r *http.Request) {
+	borrowers, err := h.queries.ListBorrowers(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to retrieve borrowers", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve borrowers", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderer.Render(w, "borrowers-list", map[string]interface{}{
+		"Borrowers": borrowers,
+	})
+}
+
+// loanErrorStatus maps an error from lending or returning a record to a
+// status and message
+func loanErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Record not found"
+	case errors.Is(err, errBorrowerNotFound):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, errRecordOnLoan), errors.Is(err, errRecordNotOnLoan):
+		return http.StatusConflict, err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to save loan"
+	}
+}
+
+// borrowerErrorStatus maps an error from saving a borrower to a status and
+// message
+func borrowerErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Borrower not found"
+	case errors.Is(err, errDuplicateBorrower), errors.Is(err, errBorrowerHasLoans):
+		return http.StatusConflict, err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to save borrower"
+	}
+}
+
+// loanDone reloads the page a record was lent or returned from, since its
+// location changes too
+func loanDone(w http.ResponseWriter, r *http.Request, recordID int64) {
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Refresh", "true")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, "/records/"+strconv.FormatInt(recordID, 10), http.StatusSeeOther)
+}
+
+// HTML Handlers
+
+// GET /loans
+// ?overdue=true lists only the overdue loans
+func (h *Handler) GetLoans() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		overdue := r.URL.Query().Get("overdue") == "true"
+		loans, err := h.loans(r.Context(), overdue, now)
+		if err != nil {
+			h.logger.Error("Failed to retrieve loans", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve loans", http.StatusInternalServerError)
+			return
+		}
+
+		borrowers, err := h.queries.ListBorrowers(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve borrowers", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve borrowers", http.StatusInternalServerError)
+			return
+		}
+
+		err = h.renderer.Render(w, "loans", map[string]interface{}{
+			"Title":     "Loans",
+			"Loans":     loans,
+			"Overdue":   overdue,
+			"Borrowers": borrowers,
+			"Today":     today(now),
+		})
+		if err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// POST /records/{id}/lend
+func (h *Handler) LendRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req LendRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.lendRecord(r.Context(), recordID, req, time.Now()); err != nil {
+			status, message := loanErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to lend record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		loanDone(w, r, recordID)
+	}
+}
+
+// POST /records/{id}/return
+func (h *Handler) ReturnRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.returnRecord(r.Context(), recordID, time.Now()); err != nil {
+			status, message := loanErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to return record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		loanDone(w, r, recordID)
+	}
+}
+
+// POST /borrowers
+func (h *Handler) CreateBorrower() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req BorrowerRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.createBorrower(r.Context(), req); err != nil {
+			status, message := borrowerErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to create borrower", slog.String("error", err.Error()), slog.String("name", req.Name))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderBorrowersList(w, r)
+	}
+}
+
+// PUT /borrowers/{id}
+func (h *Handler) UpdateBorrower() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		borrowerID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req BorrowerRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.updateBorrower(r.Context(), borrowerID, req); err != nil {
+			status, message := borrowerErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update borrower", slog.String("error", err.Error()), slog.Int64("borrowerID", borrowerID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderBorrowersList(w, r)
+	}
+}
+
+// DELETE /borrowers/{id}
+func (h *Handler) DeleteBorrower() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		borrowerID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.deleteBorrower(r.Context(), borrowerID); err != nil {
+			status, message := borrowerErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to delete borrower", slog.String("error", err.Error()), slog.Int64("borrowerID", borrowerID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderBorrowersList(w, r)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/loans
+// The records out on loan, soonest due first. ?overdue=true lists only the
+// overdue ones.
+func (h *Handler) JsonGetLoans() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loans, err := h.loans(r.Context(), r.URL.Query().Get("overdue") == "true", time.Now())
+		if err != nil {
+			h.logger.Error("Failed to retrieve loans", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to retrieve loans", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, loans, http.StatusOK)
+	}
+}
+
+// GET /api/v1/loans/overdue
+// The same as /api/v1/loans?overdue=true
+func (h *Handler) JsonGetOverdueLoans() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loans, err := h.loans(r.Context(), true, time.Now())
+		if err != nil {
+			h.logger.Error("Failed to retrieve overdue loans", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to retrieve loans", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, loans, http.StatusOK)
+	}
+}
+
+// GET /api/v1/records/{id}/loans
+func (h *Handler) JsonGetRecordLoans() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		loans, err := h.queries.ListLoansByRecord(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve loans", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve loans", http.StatusInternalServerError)
+			return
+		}
+		if loans == nil {
+			loans = []store.ListLoansByRecordRow{}
+		}
+
+		h.writeJSON(w, loans, http.StatusOK)
+	}
+}
+
+// POST /api/v1/records/{id}/lend
+func (h *Handler) JsonLendRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req LendRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.lendRecord(r.Context(), recordID, req, time.Now())
+		if err != nil {
+			status, message := loanErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to lend record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, resp, http.StatusCreated)
+	}
+}
+
+// POST /api/v1/records/{id}/return
+func (h *Handler) JsonReturnRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.returnRecord(r.Context(), recordID, time.Now())
+		if err != nil {
+			status, message := loanErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to return record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, resp, http.StatusOK)
+	}
+}
+
+// GET /api/v1/borrowers
+func (h *Handler) JsonGetBorrowers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		borrowers, err := h.queries.ListBorrowers(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve borrowers", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to retrieve borrowers", http.StatusInternalServerError)
+			return
+		}
+		if borrowers == nil {
+			borrowers = []store.ListBorrowersRow{}
+		}
+
+		h.writeJSON(w, borrowers, http.StatusOK)
+	}
+}
+
+// POST /api/v1/borrowers
+func (h *Handler) JsonCreateBorrower() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req BorrowerRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		borrower, err := h.createBorrower(r.Context(), req)
+		if err != nil {
+			status, message := borrowerErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to create borrower", slog.String("error", err.Error()), slog.String("name", req.Name))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, borrower, http.StatusCreated)
+	}
+}
+
+// PUT /api/v1/borrowers/{id}
+func (h *Handler) JsonUpdateBorrower() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		borrowerID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req BorrowerRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		borrower, err := h.updateBorrower(r.Context(), borrowerID, req)
+		if err != nil {
+			status, message := borrowerErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update borrower", slog.String("error", err.Error()), slog.Int64("borrowerID", borrowerID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, borrower, http.StatusOK)
+	}
+}
+
+// DELETE /api/v1/borrowers/{id}
+func (h *Handler) JsonDeleteBorrower() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		borrowerID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.deleteBorrower(r.Context(), borrowerID); err != nil {
+			status, message := borrowerErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to delete borrower", slog.String("error", err.Error()), slog.Int64("borrowerID", borrowerID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// TestLoans_LendAndReturn tests that lending moves a record to the "On loan"
+// location and returning moves it home, or to the default location
+func TestLoans_LendAndReturn(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	shelf, err := queries.CreateLocation(ctx, store.CreateLocationParams{Name: "Shelf A"})
+	if err != nil {
+		t.Fatalf("CreateLocation() error = %v", err)
+	}
+	def, err := queries.GetDefaultLocation(ctx)
+	if err != nil {
+		t.Fatalf("GetDefaultLocation() error = %v", err)
+	}
+	homed, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+		Title:             "Bleach",
+		CurrentLocationID: sql.NullInt64{Int64: shelf.ID, Valid: true},
+		HomeLocationID:    sql.NullInt64{Int64: shelf.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+	homeless, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Nevermind"})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+
+	lent, err := h.lendRecord(ctx, homed.ID, LendRequest{BorrowerName: " Sam ", DueAt: "2026-10-30"}, now)
+	if err != nil {
+		t.Fatalf("lendRecord() error = %v", err)
+	}
+	onLoan, err := queries.GetLoanLocation(ctx)
+	if err != nil {
+		t.Fatalf("GetLoanLocation() error = %v", err)
+	}
+	if lent.Record.CurrentLocationID.Int64 != onLoan.ID {
+		t.Errorf("lent record location = %v, want On loan (%d)", lent.Record.CurrentLocationID, onLoan.ID)
+	}
+	if !lent.Loan.LentAt.Equal(now) || lent.Loan.DueAt.Time.Format("2006-01-02") != "2026-10-30" {
+		t.Errorf("loan = %+v, want lent now and due 2026-10-30", lent.Loan)
+	}
+
+	if _, err := h.lendRecord(ctx, homed.ID, LendRequest{BorrowerName: "Jo"}, now); !errors.Is(err, errRecordOnLoan) {
+		t.Errorf("lend twice error = %v, want errRecordOnLoan", err)
+	}
+	if _, err := h.lendRecord(ctx, homeless.ID, LendRequest{BorrowerID: lent.Loan.BorrowerID + 100}, now); !errors.Is(err, errBorrowerNotFound) {
+		t.Errorf("unknown borrower error = %v, want errBorrowerNotFound", err)
+	}
+	if _, err := h.lendRecord(ctx, homed.ID+100, LendRequest{BorrowerName: "Sam"}, now); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unknown record error = %v, want sql.ErrNoRows", err)
+	}
+	// Names match a borrower ignoring case
+	second, err := h.lendRecord(ctx, homeless.ID, LendRequest{BorrowerName: "sam"}, now)
+	if err != nil {
+		t.Fatalf("lendRecord() error = %v", err)
+	}
+	if second.Loan.BorrowerID != lent.Loan.BorrowerID {
+		t.Errorf("borrower = %d, want the existing Sam (%d)", second.Loan.BorrowerID, lent.Loan.BorrowerID)
+	}
+
+	returned, err := h.returnRecord(ctx, homed.ID, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("returnRecord() error = %v", err)
+	}
+	if !returned.Loan.ReturnedAt.Valid || returned.Record.CurrentLocationID.Int64 != shelf.ID {
+		t.Errorf("returned = %+v, want returned to Shelf A", returned)
+	}
+	if _, err := h.returnRecord(ctx, homed.ID, now); !errors.Is(err, errRecordNotOnLoan) {
+		t.Errorf("return twice error = %v, want errRecordNotOnLoan", err)
+	}
+
+	returned, err = h.returnRecord(ctx, homeless.ID, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("returnRecord() error = %v", err)
+	}
+	if returned.Record.CurrentLocationID.Int64 != def.ID {
+		t.Errorf("homeless record location = %v, want the default (%d)", returned.Record.CurrentLocationID, def.ID)
+	}
+
+	// It can go out again, and the history keeps both loans
+	if _, err := h.lendRecord(ctx, homed.ID, LendRequest{BorrowerName: "Jo"}, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("lendRecord() again error = %v", err)
+	}
+	history, err := queries.ListLoansByRecord(ctx, homed.ID)
+	if err != nil {
+		t.Fatalf("ListLoansByRecord() error = %v", err)
+	}
+	if len(history) != 2 || history[0].BorrowerName != "Jo" || history[1].BorrowerName != "Sam" {
+		t.Errorf("history = %+v, want Jo then Sam", history)
+	}
+}
+
+// TestLoans_Overdue tests that loans are overdue from the day after they're
+// due, and that loans without a due date never are
+func TestLoans_Overdue(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+	lentAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	for _, loan := range []struct{ title, due string }{
+		{"Due yesterday", "2026-10-15"},
+		{"Due today", "2026-10-16"},
+		{"Long overdue", "2026-10-02"},
+		{"No due date", ""},
+	} {
+		record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: loan.title})
+		if err != nil {
+			t.Fatalf("CreateRecord() error = %v", err)
+		}
+		if _, err := h.lendRecord(ctx, record.ID, LendRequest{BorrowerName: "Sam", DueAt: loan.due}, lentAt); err != nil {
+			t.Fatalf("lendRecord(%s) error = %v", loan.title, err)
+		}
+	}
+
+	now := time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)
+	overdue, err := h.loans(ctx, true, now)
+	if err != nil {
+		t.Fatalf("loans() error = %v", err)
+	}
+	var titles []string
+	for _, loan := range overdue {
+		titles = append(titles, loan.Title)
+	}
+	if strings.Join(titles, ",") != "Long overdue,Due yesterday" {
+		t.Errorf("overdue = %v, want Long overdue then Due yesterday", titles)
+	}
+
+	open, err := h.loans(ctx, false, now)
+	if err != nil {
+		t.Fatalf("loans() error = %v", err)
+	}
+	if len(open) != 4 || open[3].Title != "No due date" {
+		t.Errorf("open loans = %+v, want 4 with the undated one last", open)
+	}
+}
+
+// TestBorrowers_Delete tests that only borrowers who've never borrowed
+// anything can be deleted
+func TestBorrowers_Delete(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	sam, err := h.createBorrower(ctx, BorrowerRequest{Name: "Sam", Contact: "555-0100"})
+	if err != nil {
+		t.Fatalf("createBorrower() error = %v", err)
+	}
+	if _, err := h.createBorrower(ctx, BorrowerRequest{Name: "SAM"}); !errors.Is(err, errDuplicateBorrower) {
+		t.Errorf("duplicate borrower error = %v, want errDuplicateBorrower", err)
+	}
+	jo, err := h.createBorrower(ctx, BorrowerRequest{Name: "Jo"})
+	if err != nil {
+		t.Fatalf("createBorrower() error = %v", err)
+	}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Bleach"})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+	if _, err := h.lendRecord(ctx, record.ID, LendRequest{BorrowerID: sam.ID}, time.Now()); err != nil {
+		t.Fatalf("lendRecord() error = %v", err)
+	}
+	if _, err := h.returnRecord(ctx, record.ID, time.Now()); err != nil {
+		t.Fatalf("returnRecord() error = %v", err)
+	}
+
+	if err := h.deleteBorrower(ctx, sam.ID); !errors.Is(err, errBorrowerHasLoans) {
+		t.Errorf("delete borrower with loans error = %v, want errBorrowerHasLoans", err)
+	}
+	if err := h.deleteBorrower(ctx, jo.ID); err != nil {
+		t.Errorf("deleteBorrower() error = %v", err)
+	}
+	if err := h.deleteBorrower(ctx, jo.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("delete unknown borrower error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// TestJsonLendRecord tests the lend endpoint's validation and statuses
+func TestJsonLendRecord(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{
+		db:       db,
+		queries:  queries,
+		logger:   slog.New(slog.DiscardHandler),
+		validate: newValidator(),
+	}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Bleach"})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+
+	lend := func(body string) int {
+		r := httptest.NewRequest("POST", "/api/v1/records/"+strconv.FormatInt(record.ID, 10)+"/lend", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.SetPathValue("id", strconv.FormatInt(record.ID, 10))
+		w := httptest.NewRecorder()
+		h.JsonLendRecord()(w, r)
+		return w.Code
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"no borrower", `{}`, http.StatusBadRequest},
+		{"blank borrower", `{"borrower_name": "   "}`, http.StatusBadRequest},
+		{"bad due date", `{"borrower_name": "Sam", "due_at": "next week"}`, http.StatusBadRequest},
+		{"lent", `{"borrower_name": "Sam", "due_at": "2026-10-30"}`, http.StatusCreated},
+		{"already lent", `{"borrower_name": "Jo"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if got := lend(tt.body); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
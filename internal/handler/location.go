@@ -1,20 +1,30 @@
 package handler
 
 import (
+	"database/sql"
+	"errors"
 	"log/slog"
 	"net/http"
+
+	"github.com/go-playground/validator/v10"
 )
 
 type CreateLocationRequest struct {
 	Name        string `form:"name" json:"name" validate:"required,min=2,max=100"`
 	Description string `form:"description" json:"description" validate:"max=500"`
 	IsDefault   bool   `form:"is_default" json:"is_default"`
+	ParentID    int64  `form:"parent_id" json:"parent_id" validate:"omitempty,min=1"`
+	// Capacity is how many records fit; 0 means it isn't tracked
+	Capacity int64 `form:"capacity" json:"capacity" validate:"omitempty,min=1,max=100000"`
 }
 
 type UpdateLocationRequest struct {
 	Name        string `form:"name" json:"name" validate:"required,min=2,max=100"`
 	Description string `form:"description" json:"description" validate:"max=500"`
 	IsDefault   bool   `form:"is_default" json:"is_default"`
+	ParentID    int64  `form:"parent_id" json:"parent_id" validate:"omitempty,min=1"`
+	// Capacity is how many records fit; 0 means it isn't tracked
+	Capacity int64 `form:"capacity" json:"capacity" validate:"omitempty,min=1,max=100000"`
 }
 
 // HTML Handlers
@@ -22,83 +32,175 @@ type UpdateLocationRequest struct {
 // GET /locations
 func (h *Handler) GetLocations() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get all locations
-		// - Query database
-		// - Render locations list page
-		locations, err := h.queries.ListLocations(r.Context())
+		tree, err := h.locationTree(r.Context())
 		if err != nil {
 			h.logger.Error("Failed to retrieve locations", slog.String("error", err.Error()))
 			http.Error(w, "Failed to retrieve locations", http.StatusInternalServerError)
 			return
 		}
 
-		h.renderer.Render(w, "locations", map[string]interface{}{
+		if err := h.renderer.Render(w, "locations", map[string]interface{}{
 			"Title":     "Locations",
-			"Locations": locations,
-		})
+			"Locations": tree,
+		}); err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
 	}
 }
 
 // GET /locations/new
 func (h *Handler) GetCreateLocationForm() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Render new location form
-		h.renderer.Render(w, "create-location-form", nil)
+		locations, err := h.locationOptions(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve locations", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve locations", http.StatusInternalServerError)
+			return
+		}
+
+		h.renderer.Render(w, "create-location-form", map[string]interface{}{
+			"Locations": locations,
+			"ParentID":  r.URL.Query().Get("parent_id"),
+		})
 	}
 }
 
 // POST /locations
 func (h *Handler) CreateLocation() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Create new location
-		// - Parse form data
-		// - Validate input
-		// - Create location in database
-		// - Return location row partial for HTMX
-		h.logger.Info("Create location handler called")
-		h.renderer.Render(w, "locations-row", nil)
+		var req CreateLocationRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		location, err := h.createLocation(r.Context(), req)
+		if err != nil {
+			status, message := locationErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to create location", slog.String("error", err.Error()), slog.String("name", req.Name))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.logger.Info("Location created", slog.Int64("locationID", location.ID), slog.String("name", location.Name))
+
+		h.renderLocationsTree(w, r)
 	}
 }
 
 // GET /locations/{id}
+// Shows the records directly in the location in shelf order
 func (h *Handler) GetLocation() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get location details
-		// - Parse ID from path
-		// - Query location and records at that location
-		// - Render location detail page
-		id := r.PathValue("id")
-		h.logger.Info("Get location handler called", slog.String("id", id))
-		h.renderer.Render(w, "location-detail", nil)
+		locationID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		shelf, err := h.shelf(r.Context(), locationID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Location not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to retrieve shelf", slog.String("error", err.Error()), slog.Int64("locationID", locationID))
+			http.Error(w, "Failed to retrieve location", http.StatusInternalServerError)
+			return
+		}
+
+		if err := h.renderer.Render(w, "location-detail", map[string]interface{}{
+			"Title": shelf.Path,
+			"Shelf": shelf,
+		}); err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
 	}
 }
 
 // GET /locations/{id}/edit
 func (h *Handler) GetUpdateLocationForm() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Render edit location form
-		// - Parse ID from path
-		// - Query location
-		// - Render edit form with current data
-		id := r.PathValue("id")
-		h.logger.Info("Get location edit form handler called", slog.String("id", id))
-		h.renderer.Render(w, "update-location-form", nil)
+		locationID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		location, err := h.queries.GetLocation(r.Context(), locationID)
+		if err != nil {
+			http.Error(w, "Location not found", http.StatusNotFound)
+			return
+		}
+
+		tree, err := h.locationTree(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve locations", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve locations", http.StatusInternalServerError)
+			return
+		}
+
+		// A location can't move inside itself, so it and what's in it
+		// aren't offered as parents
+		h.renderer.Render(w, "update-location-form", map[string]interface{}{
+			"Location":  location,
+			"Locations": flattenLocations(tree, location.ID),
+		})
 	}
 }
 
 // PUT /locations/{id}
 func (h *Handler) UpdateLocation() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Update location
-		// - Parse ID from path
-		// - Parse form data
-		// - Validate input
-		// - Update location in database
-		// - Return updated location row partial for HTMX
-		id := r.PathValue("id")
-		h.logger.Info("Update location handler called", slog.String("id", id))
-		h.renderer.Render(w, "locations-row", nil)
+		locationID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateLocationRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.updateLocation(r.Context(), locationID, req); err != nil {
+			status, message := locationErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update location", slog.String("error", err.Error()), slog.Int64("locationID", locationID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderLocationsTree(w, r)
+	}
+}
+
+// renderLocationsTree renders the location tree for HTMX to swap in after a
+// location is added or moved
+func (h *Handler) renderLocationsTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.locationTree(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to retrieve locations", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve locations", http.StatusInternalServerError)
+		return
 	}
+	h.renderer.Render(w, "locations-tree", tree)
 }
 
 // DELETE /locations/{id}
@@ -133,58 +235,102 @@ func (h *Handler) SetDefaultLocation() http.HandlerFunc {
 // GET /v1/locations
 func (h *Handler) JsonGetLocations() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get locations list
-		// - Parse query params (page, limit, search)
-		// - Query database with filters
-		// - Return JSON array
-		locations, err := h.queries.ListLocations(r.Context())
+		tree, err := h.locationTree(r.Context())
 		if err != nil {
 			h.logger.Error("Failed to retrieve locations", slog.String("error", err.Error()))
 			h.writeErrorJSON(w, "Failed to retrieve locations", http.StatusInternalServerError)
 			return
 		}
-		h.writeJSON(w, locations, http.StatusOK)
+		h.writeJSON(w, tree, http.StatusOK)
 	}
 }
 
 // POST /v1/locations
 func (h *Handler) JsonCreateLocation() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Create location via API
-		// - Parse JSON body
-		// - Validate input
-		// - Create location in database
-		// - Return created location with 201 status
-		h.logger.Info("API create location handler called")
-		h.writeJSON(w, map[string]string{"message": "location created"}, http.StatusCreated)
+		var req CreateLocationRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		location, err := h.createLocation(r.Context(), req)
+		if err != nil {
+			status, message := locationErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to create location", slog.String("error", err.Error()), slog.String("name", req.Name))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.logger.Info("Location created via API", slog.Int64("locationID", location.ID), slog.String("name", location.Name))
+
+		h.writeJSON(w, location, http.StatusCreated)
 	}
 }
 
-// GET /v1/records/{id}
+// GET /v1/locations/{id}
 func (h *Handler) JsonGetLocation() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get single location
-		// - Parse ID from path
-		// - Query location from database
-		// - Return JSON
-		id := r.PathValue("id")
-		h.logger.Info("API get location handler called", slog.String("id", id))
-		h.writeJSON(w, map[string]string{"message": "location details"}, http.StatusOK)
+		locationID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		tree, err := h.locationTree(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve locations", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to retrieve location", http.StatusInternalServerError)
+			return
+		}
+		for _, location := range flattenLocations(tree, 0) {
+			if location.ID == locationID {
+				h.writeJSON(w, location, http.StatusOK)
+				return
+			}
+		}
+		h.writeErrorJSON(w, "Location not found", http.StatusNotFound)
 	}
 }
 
 // PUT /v1/locations/{id}
 func (h *Handler) JsonUpdateLocation() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Update location via API
-		// - Parse ID from path
-		// - Parse JSON body
-		// - Validate input
-		// - Update location in database
-		// - Return updated location
-		id := r.PathValue("id")
-		h.logger.Info("API update location handler called", slog.String("id", id))
-		h.writeJSON(w, map[string]string{"message": "location updated"}, http.StatusOK)
+		locationID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateLocationRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		location, err := h.updateLocation(r.Context(), locationID, req)
+		if err != nil {
+			status, message := locationErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update location", slog.String("error", err.Error()), slog.Int64("locationID", locationID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.logger.Info("Location updated via API", slog.Int64("locationID", location.ID), slog.String("name", location.Name))
+
+		h.writeJSON(w, location, http.StatusOK)
 	}
 }
 
@@ -215,14 +361,39 @@ func (h *Handler) JsonSetDefaultLocation() http.HandlerFunc {
 }
 
 // GET /v1/locations/{id}/records
+//
+// Lists the records in the location and every location inside it, taking
+// the same filter, sort and paging parameters as GET /v1/records
 func (h *Handler) JsonGetRecordsByLocation() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get all records at location
-		// - Parse ID from path
-		// - Query records at location
-		// - Return JSON array
-		id := r.PathValue("id")
-		h.logger.Info("API get location records handler called", slog.String("id", id))
-		h.writeJSON(w, []interface{}{}, http.StatusOK)
+		locationID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetLocation(r.Context(), locationID); err != nil {
+			h.writeErrorJSON(w, "Location not found", http.StatusNotFound)
+			return
+		}
+
+		var req ListRecordsRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your query parameters")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.LocationID = locationID
+
+		result, err := h.listRecords(r.Context(), r.URL.Query(), req)
+		if err != nil {
+			h.logger.Error("Failed to retrieve location records", slog.String("error", err.Error()), slog.Int64("locationID", locationID))
+			h.writeErrorJSON(w, "Failed to retrieve location records", http.StatusInternalServerError)
+			return
+		}
+		h.writeJSON(w, result, http.StatusOK)
 	}
 }
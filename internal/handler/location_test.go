@@ -297,7 +297,7 @@ func TestDeleteLocation_WithRecords(t *testing.T) {
 	ctx := context.Background()
 
 	// Create artist
-	artist, err := queries.CreateArtist(ctx, "Test Artist")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Test Artist", SortName: store.ArtistSortName("Test Artist")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+var (
+	errLocationCycle          = errors.New("a location can't be inside itself")
+	errParentLocationNotFound = errors.New("parent location not found")
+	errDuplicateLocation      = errors.New("a location with that name is already there")
+	errLoanLocationNested     = errors.New("the On loan location can't be nested")
+)
+
+// locationPathSeparator joins the names in a location's full path
+const locationPathSeparator = " / "
+
+// LocationNode is a location in the location tree. Records counts the
+// records directly in it and TotalRecords those anywhere inside it too.
+// Capacity, if set, is measured against Records.
+type LocationNode struct {
+	ID           int64           `json:"id"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	Path         string          `json:"path"`
+	ParentID     int64           `json:"parent_id,omitempty"`
+	IsDefault    bool            `json:"is_default"`
+	IsLoan       bool            `json:"is_loan"`
+	Records      int64           `json:"records"`
+	TotalRecords int64           `json:"total_records"`
+	Capacity     int64           `json:"capacity,omitempty"`
+	Full         bool            `json:"full"`
+	Children     []*LocationNode `json:"children"`
+}
+
+// locationTree returns the top-level locations with the ones inside them,
+// each level in name order
+func (h *Handler) locationTree(ctx context.Context) ([]*LocationNode, error) {
+	rows, err := h.queries.ListLocationTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int64]*LocationNode, len(rows))
+	for _, row := range rows {
+		nodes[row.ID] = &LocationNode{
+			ID:           row.ID,
+			Name:         row.Name,
+			Description:  row.Description.String,
+			Path:         row.Path,
+			ParentID:     row.ParentID.Int64,
+			IsDefault:    row.IsDefault.Bool,
+			IsLoan:       row.IsLoan,
+			Records:      row.RecordCount,
+			TotalRecords: row.TotalRecordCount,
+			Capacity:     row.Capacity.Int64,
+			Full:         row.Capacity.Valid && row.RecordCount >= row.Capacity.Int64,
+			Children:     []*LocationNode{},
+		}
+	}
+
+	roots := []*LocationNode{}
+	for _, row := range rows {
+		node := nodes[row.ID]
+		if parent, ok := nodes[node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+// flattenLocations lists the locations in a tree with each one followed by
+// the ones inside it, for choosing a location by its path. The location
+// except, and everything inside it, is left out.
+func flattenLocations(nodes []*LocationNode, except int64) []*LocationNode {
+	flat := []*LocationNode{}
+	for _, node := range nodes {
+		if node.ID == except {
+			continue
+		}
+		flat = append(flat, node)
+		flat = append(flat, flattenLocations(node.Children, except)...)
+	}
+	return flat
+}
+
+// locationOptions lists every location by path, parents before the
+// locations inside them
+func (h *Handler) locationOptions(ctx context.Context) ([]*LocationNode, error) {
+	tree, err := h.locationTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return flattenLocations(tree, 0), nil
+}
+
+// checkLocationParent makes sure a location can go inside parentID: the
+// parent exists, the location isn't the parent or one of its ancestors, and
+// the parent holds nothing else with the same name. Nothing goes inside the
+// On loan location. locationID is 0 for a new location.
+func checkLocationParent(ctx context.Context, q *store.Queries, locationID int64, name string, parentID sql.NullInt64) error {
+	if parentID.Valid {
+		parent, err := q.GetLocation(ctx, parentID.Int64)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errParentLocationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if parent.IsLoan {
+			return errLoanLocationNested
+		}
+	}
+	if locationID != 0 && parentID.Valid {
+		within, err := q.IsLocationWithin(ctx, store.IsLocationWithinParams{AncestorID: locationID, LocationID: parentID.Int64})
+		if err != nil {
+			return err
+		}
+		if within != 0 {
+			return errLocationCycle
+		}
+	}
+
+	sibling, err := q.GetChildLocationByName(ctx, store.GetChildLocationByNameParams{Name: name, ParentID: parentID})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return err
+	case sibling.ID != locationID:
+		return errDuplicateLocation
+	}
+	return nil
+}
+
+// createLocation adds a location, inside ParentID if one is given, making it
+// the default if asked
+func (h *Handler) createLocation(ctx context.Context, req CreateLocationRequest) (store.Location, error) {
+	name := strings.TrimSpace(req.Name)
+	parentID := sql.NullInt64{Int64: req.ParentID, Valid: req.ParentID > 0}
+
+	var location store.Location
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		if err := checkLocationParent(ctx, q, 0, name, parentID); err != nil {
+			return err
+		}
+
+		var err error
+		location, err = q.CreateLocation(ctx, store.CreateLocationParams{
+			Name:        name,
+			Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+			IsDefault:   sql.NullBool{Bool: req.IsDefault, Valid: true},
+			ParentID:    parentID,
+			Capacity:    sql.NullInt64{Int64: req.Capacity, Valid: req.Capacity > 0},
+		})
+		if err != nil || !req.IsDefault {
+			return err
+		}
+		return q.SetDefaultLocation(ctx, location.ID)
+	})
+	return location, err
+}
+
+// updateLocation renames, describes and moves a location. Moving it inside
+// itself or a location within it returns errLocationCycle, and the On loan
+// location stays at the top level. Returns sql.ErrNoRows if the location
+// doesn't exist.
+func (h *Handler) updateLocation(ctx context.Context, locationID int64, req UpdateLocationRequest) (store.Location, error) {
+	name := strings.TrimSpace(req.Name)
+	parentID := sql.NullInt64{Int64: req.ParentID, Valid: req.ParentID > 0}
+
+	var location store.Location
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		current, err := q.GetLocation(ctx, locationID)
+		if err != nil {
+			return err
+		}
+		if current.IsLoan && parentID.Valid {
+			return errLoanLocationNested
+		}
+		if err := checkLocationParent(ctx, q, locationID, name, parentID); err != nil {
+			return err
+		}
+
+		location, err = q.UpdateLocation(ctx, store.UpdateLocationParams{
+			Name:        name,
+			Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+			IsDefault:   sql.NullBool{Bool: req.IsDefault, Valid: true},
+			ParentID:    parentID,
+			Capacity:    sql.NullInt64{Int64: req.Capacity, Valid: req.Capacity > 0},
+			ID:          locationID,
+		})
+		if err != nil || !req.IsDefault {
+			return err
+		}
+		return q.SetDefaultLocation(ctx, location.ID)
+	})
+	return location, err
+}
+
+func locationErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errLocationCycle), errors.Is(err, errParentLocationNotFound), errors.Is(err, errLoanLocationNested):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, errDuplicateLocation):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Location not found"
+	default:
+		return http.StatusInternalServerError, "Failed to save location"
+	}
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// TestLocationTree tests that locations nest, show their full path and count
+// the records inside them however deep
+func TestLocationTree(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	create := func(name string, parentID int64) store.Location {
+		t.Helper()
+		location, err := h.createLocation(ctx, CreateLocationRequest{Name: name, ParentID: parentID})
+		if err != nil {
+			t.Fatalf("createLocation(%q) error = %v", name, err)
+		}
+		return location
+	}
+	room := create("Living room", 0)
+	kallax := create("Kallax", room.ID)
+	row := create("Row 2", kallax.ID)
+	cube := create("Cube 3", row.ID)
+	create("Cube 4", row.ID)
+
+	for _, place := range []struct {
+		title      string
+		locationID int64
+	}{{"Blue", cube.ID}, {"Kind of Blue", cube.ID}, {"Rumours", kallax.ID}, {"Abbey Road", room.ID}} {
+		if _, err := h.createRecord(ctx, CreateRecordRequest{Title: place.title, CurrentLocationID: place.locationID}); err != nil {
+			t.Fatalf("createRecord(%q) error = %v", place.title, err)
+		}
+	}
+
+	tree, err := h.locationTree(ctx)
+	if err != nil {
+		t.Fatalf("locationTree() error = %v", err)
+	}
+	counts := map[string]string{}
+	for _, node := range flattenLocations(tree, 0) {
+		counts[node.Path] = strings.Repeat("*", int(node.Records)) + "/" + strings.Repeat("*", int(node.TotalRecords))
+	}
+	want := map[string]string{
+		"Living room":                           "*/****",
+		"Living room / Kallax":                  "*/***",
+		"Living room / Kallax / Row 2":          "/**",
+		"Living room / Kallax / Row 2 / Cube 3": "**/**",
+		"Living room / Kallax / Row 2 / Cube 4": "/",
+	}
+	for path, count := range want {
+		if counts[path] != count {
+			t.Errorf("%s: records/total = %q, want %q", path, counts[path], count)
+		}
+	}
+
+	// Filtering by a location includes everything inside it, and records
+	// show where they are by full path
+	records, err := queries.FilterRecords(ctx, store.RecordFilter{
+		LocationID: sql.NullInt64{Int64: kallax.ID, Valid: true},
+		Sort:       "title",
+		Limit:      10,
+	})
+	if err != nil {
+		t.Fatalf("FilterRecords() error = %v", err)
+	}
+	var got []string
+	for _, record := range records {
+		got = append(got, record.Title+" @ "+record.CurrentLocationName.String)
+	}
+	if want := "Blue @ Living room / Kallax / Row 2 / Cube 3, Kind of Blue @ Living room / Kallax / Row 2 / Cube 3, Rumours @ Living room / Kallax"; strings.Join(got, ", ") != want {
+		t.Errorf("records in Kallax = %v, want %s", got, want)
+	}
+
+	all, err := queries.ListRecordsWithDetails(ctx)
+	if err != nil {
+		t.Fatalf("ListRecordsWithDetails() error = %v", err)
+	}
+	for _, record := range all {
+		if record.Title == "Abbey Road" && record.CurrentLocationName.String != "Living room" {
+			t.Errorf("Abbey Road location = %q, want Living room", record.CurrentLocationName.String)
+		}
+	}
+
+	// Deleting a location moves the locations inside it up a level
+	if err := queries.DeleteLocation(ctx, row.ID); err != nil {
+		t.Fatalf("DeleteLocation() error = %v", err)
+	}
+	moved, err := queries.GetLocation(ctx, cube.ID)
+	if err != nil || moved.ParentID.Int64 != kallax.ID {
+		t.Errorf("Cube 3 parent after delete = %+v, %v, want Kallax", moved.ParentID, err)
+	}
+}
+
+// TestLocationTree_Moves tests that a location can't be moved inside itself
+// and that names are unique within a location
+func TestLocationTree_Moves(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	room, err := h.createLocation(ctx, CreateLocationRequest{Name: "Living room"})
+	if err != nil {
+		t.Fatalf("createLocation() error = %v", err)
+	}
+	shelf, err := h.createLocation(ctx, CreateLocationRequest{Name: "Shelf", ParentID: room.ID})
+	if err != nil {
+		t.Fatalf("createLocation() error = %v", err)
+	}
+	crate, err := h.createLocation(ctx, CreateLocationRequest{Name: "Crate", ParentID: shelf.ID})
+	if err != nil {
+		t.Fatalf("createLocation() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		locationID int64
+		req        UpdateLocationRequest
+		want       error
+	}{
+		{"into itself", room.ID, UpdateLocationRequest{Name: "Living room", ParentID: room.ID}, errLocationCycle},
+		{"into a child", room.ID, UpdateLocationRequest{Name: "Living room", ParentID: shelf.ID}, errLocationCycle},
+		{"into a grandchild", room.ID, UpdateLocationRequest{Name: "Living room", ParentID: crate.ID}, errLocationCycle},
+		{"into an unknown location", crate.ID, UpdateLocationRequest{Name: "Crate", ParentID: crate.ID + 100}, errParentLocationNotFound},
+		{"next to a namesake", crate.ID, UpdateLocationRequest{Name: "Shelf", ParentID: room.ID}, errDuplicateLocation},
+		{"unknown location", crate.ID + 100, UpdateLocationRequest{Name: "Nowhere"}, sql.ErrNoRows},
+	}
+	for _, tt := range tests {
+		if _, err := h.updateLocation(ctx, tt.locationID, tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// The same name is fine somewhere else
+	if _, err := h.createLocation(ctx, CreateLocationRequest{Name: "Shelf", ParentID: shelf.ID}); err != nil {
+		t.Errorf("createLocation(Shelf inside Shelf) error = %v", err)
+	}
+
+	moved, err := h.updateLocation(ctx, crate.ID, UpdateLocationRequest{Name: "Crate", ParentID: room.ID})
+	if err != nil || moved.ParentID.Int64 != room.ID {
+		t.Errorf("move Crate to Living room = %+v, %v", moved, err)
+	}
+
+	// The database refuses cycles that get past the app
+	if _, err := db.ExecContext(ctx, `UPDATE locations SET parent_id = ? WHERE id = ?`, shelf.ID, room.ID); err == nil || !strings.Contains(err.Error(), "inside itself") {
+		t.Errorf("cycle through SQL error = %v, want the trigger to abort", err)
+	}
+
+	loans, err := queries.CreateLoanLocation(ctx)
+	if err != nil {
+		t.Fatalf("CreateLoanLocation() error = %v", err)
+	}
+	if _, err := h.createLocation(ctx, CreateLocationRequest{Name: "Bag", ParentID: loans.ID}); !errors.Is(err, errLoanLocationNested) {
+		t.Errorf("create inside On loan error = %v, want errLoanLocationNested", err)
+	}
+	if _, err := h.updateLocation(ctx, loans.ID, UpdateLocationRequest{Name: "On loan", ParentID: room.ID}); !errors.Is(err, errLoanLocationNested) {
+		t.Errorf("move On loan error = %v, want errLoanLocationNested", err)
+	}
+}
+
+// TestResolveLocation_Path tests that imports follow a location path down
+// the tree, adding the parts that are missing
+func TestResolveLocation_Path(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	id, created, err := resolveLocation(ctx, queries, "Living room / Kallax / Row 2")
+	if err != nil || !created {
+		t.Fatalf("resolveLocation() = %d, %v, %v, want a new location", id, created, err)
+	}
+	again, created, err := resolveLocation(ctx, queries, "Living room / Kallax / Row 2")
+	if err != nil || created || again != id {
+		t.Errorf("resolveLocation() again = %d, %v, %v, want %d", again, created, err, id)
+	}
+
+	sibling, created, err := resolveLocation(ctx, queries, "Living room / Kallax / Row 3")
+	if err != nil || !created {
+		t.Fatalf("resolveLocation(Row 3) = %d, %v, %v", sibling, created, err)
+	}
+	row2, _ := queries.GetLocation(ctx, id)
+	row3, _ := queries.GetLocation(ctx, sibling)
+	if row2.ParentID != row3.ParentID || !row2.ParentID.Valid {
+		t.Errorf("Row 2 and Row 3 parents = %+v and %+v, want the same Kallax", row2.ParentID, row3.ParentID)
+	}
+
+	if total, _ := queries.CountLocations(ctx); total != 3+4 {
+		t.Errorf("locations = %d, want the 3 seeded and 4 imported", total)
+	}
+}
+
+// TestResolveLocation_Name tests that a bare name in an import matches a
+// top-level location before a nested one, and is rejected when it could be
+// one of several nested locations
+func TestResolveLocation_Name(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	for _, path := range []string{"Living room / Kallax / Row 2", "Bedroom / Kallax / Row 3"} {
+		if _, _, err := resolveLocation(ctx, queries, path); err != nil {
+			t.Fatalf("resolveLocation(%q) error = %v", path, err)
+		}
+	}
+	row3, _, err := resolveLocation(ctx, queries, "Bedroom / Kallax / Row 3")
+	if err != nil {
+		t.Fatalf("resolveLocation(Row 3) error = %v", err)
+	}
+
+	if _, _, err := resolveLocation(ctx, queries, "Kallax"); !errors.Is(err, errLocationAmbiguous) {
+		t.Errorf("resolveLocation(Kallax) error = %v, want errLocationAmbiguous", err)
+	}
+	if id, created, err := resolveLocation(ctx, queries, "Row 3"); err != nil || created || id != row3 {
+		t.Errorf("resolveLocation(Row 3) = %d, %v, %v, want %d", id, created, err, row3)
+	}
+
+	top, err := queries.CreateLocation(ctx, store.CreateLocationParams{Name: "Kallax"})
+	if err != nil {
+		t.Fatalf("CreateLocation() error = %v", err)
+	}
+	if id, created, err := resolveLocation(ctx, queries, "Kallax"); err != nil || created || id != top.ID {
+		t.Errorf("resolveLocation(Kallax) = %d, %v, %v, want the top-level %d", id, created, err, top.ID)
+	}
+
+	attic, created, err := resolveLocation(ctx, queries, "Attic")
+	if err != nil || !created {
+		t.Fatalf("resolveLocation(Attic) = %d, %v, %v, want a new location", attic, created, err)
+	}
+	if location, _ := queries.GetLocation(ctx, attic); location.ParentID.Valid {
+		t.Errorf("Attic parent = %+v, want none", location.ParentID)
+	}
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dukerupert/dd/internal/metadata"
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// errLookupsDisabled is returned when no metadata provider is configured
+var errLookupsDisabled = errors.New("release lookups are turned off; set DISCOGS_TOKEN to turn them on")
+
+// ReleaseSearchRequest searches the metadata provider. A barcode or catalog
+// number is enough on its own.
+type ReleaseSearchRequest struct {
+	Query         string `form:"q" json:"q" validate:"required_without_all=Barcode CatalogNumber,max=200"`
+	Barcode       string `form:"barcode" json:"barcode" validate:"omitempty,barcode"`
+	CatalogNumber string `form:"catalog_number" json:"catalog_number" validate:"max=100"`
+}
+
+// ReleaseImport is a looked-up release and the new record it fills in
+type ReleaseImport struct {
+	Release metadata.Release    `json:"release"`
+	Record  CreateRecordRequest `json:"record"`
+}
+
+// searchReleases runs a search against the metadata provider
+func (h *Handler) searchReleases(ctx context.Context, req ReleaseSearchRequest) ([]metadata.SearchResult, error) {
+	if h.metadata == nil {
+		return nil, errLookupsDisabled
+	}
+	return h.metadata.Search(ctx, metadata.SearchQuery{
+		Query:         req.Query,
+		Barcode:       normalizeBarcode(req.Barcode),
+		CatalogNumber: req.CatalogNumber,
+	})
+}
+
+// release returns a release from the local cache, fetching and caching it on
+// first use. A cache that can't be read or written only costs a refetch.
+func (h *Handler) release(ctx context.Context, id string) (metadata.Release, error) {
+	if h.metadata == nil {
+		return metadata.Release{}, errLookupsDisabled
+	}
+	key := store.GetCachedReleaseParams{Provider: h.metadata.Name(), ReleaseID: id}
+
+	var release metadata.Release
+	cached, err := h.queries.GetCachedRelease(ctx, key)
+	if err == nil {
+		if err := json.Unmarshal([]byte(cached.Data), &release); err == nil {
+			return release, nil
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		h.logger.Warn("Failed to read release cache", slog.String("error", err.Error()), slog.String("releaseID", id))
+	}
+
+	release, err = h.metadata.Release(ctx, id)
+	if err != nil {
+		return metadata.Release{}, err
+	}
+
+	data, err := json.Marshal(release)
+	if err != nil {
+		return metadata.Release{}, err
+	}
+	if err := h.queries.UpsertCachedRelease(ctx, store.UpsertCachedReleaseParams{
+		Provider:  key.Provider,
+		ReleaseID: key.ReleaseID,
+		Data:      string(data),
+	}); err != nil {
+		h.logger.Warn("Failed to cache release", slog.String("error", err.Error()), slog.String("releaseID", id))
+	}
+	return release, nil
+}
+
+// importRelease looks up a release and fills in a new record from it. The
+// lead artist is matched by name; if there's no such artist yet, ArtistName
+// is set so creating the record adds them.
+func (h *Handler) importRelease(ctx context.Context, id string) (ReleaseImport, error) {
+	release, err := h.release(ctx, id)
+	if err != nil {
+		return ReleaseImport{}, err
+	}
+
+	req := CreateRecordRequest{
+		Title:         truncate(release.Title, 200),
+		AlbumTitle:    truncate(release.Title, 200),
+		CatalogNumber: truncate(release.CatalogNumber, 100),
+		ReleaseID:     release.ID,
+	}
+	if release.Year >= 1900 && release.Year <= 2100 {
+		req.ReleaseYear = int32(release.Year)
+	}
+	// Discogs barcodes are typed in by hand and not always valid
+	if validBarcode(release.Barcode) {
+		req.Barcode = normalizeBarcode(release.Barcode)
+	}
+	if len(release.Artists) > 0 {
+		req.ArtistName = truncate(release.Artists[0], 100)
+		artist, err := h.queries.GetArtistByName(ctx, req.ArtistName)
+		if err == nil {
+			req.ArtistID = artist.ID
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return ReleaseImport{}, err
+		}
+	}
+
+	return ReleaseImport{Release: release, Record: req}, nil
+}
+
+// resolveArtist returns the id of the artist with the name, creating them if
+// there's none. created reports whether they were added.
+func resolveArtist(ctx context.Context, q *store.Queries, name string) (id int64, created bool, err error) {
+	artist, err := q.GetArtistByName(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		artist, err = insertArtist(ctx, q, name)
+		created = true
+	}
+	return artist.ID, created, err
+}
+
+// importTracks adds a looked-up tracklist to a new record. Positions are
+// reduced to letters and digits, as the tracklist editor requires; tracks
+// without one are numbered in order, and a repeated position keeps the
+// first track.
+func importTracks(ctx context.Context, q *store.Queries, recordID int64, tracks []metadata.Track) error {
+	seen := make(map[string]bool)
+	for i, t := range tracks {
+		position := strings.ToUpper(strings.Map(func(r rune) rune {
+			if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return -1
+			}
+			return r
+		}, t.Position))
+		if position == "" {
+			position = strconv.Itoa(i + 1)
+		}
+		position = truncate(position, 10)
+		if seen[position] {
+			continue
+		}
+		seen[position] = true
+
+		side := TrackRequest{Position: position}.side()
+		var duration sql.NullInt64
+		if seconds, err := parseDuration(t.Duration); err == nil {
+			duration = sql.NullInt64{Int64: seconds, Valid: true}
+		}
+
+		if _, err := q.CreateTrack(ctx, store.CreateTrackParams{
+			RecordID:        recordID,
+			Side:            sql.NullString{String: side, Valid: side != ""},
+			Position:        position,
+			Title:           truncate(t.Title, 200),
+			DurationSeconds: duration,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); len(runes) > n {
+		return strings.TrimSpace(string(runes[:n]))
+	}
+	return s
+}
+
+// metadataErrorStatus maps a release lookup error to an HTTP status and
+// message
+func metadataErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errLookupsDisabled):
+		return http.StatusNotImplemented, err.Error()
+	case errors.Is(err, metadata.ErrNotFound):
+		return http.StatusNotFound, "Release not found"
+	case errors.Is(err, metadata.ErrRateLimited):
+		return http.StatusServiceUnavailable, err.Error()
+	default:
+		return http.StatusBadGateway, "Failed to look up releases"
+	}
+}
+
+// HTML Handlers
+
+// GET /records/new/releases
+func (h *Handler) SearchReleases() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ReleaseSearchRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		results, err := h.searchReleases(r.Context(), req)
+		if err != nil {
+			status, message := metadataErrorStatus(err)
+			if status == http.StatusBadGateway {
+				h.logger.Error("Failed to search releases", slog.String("error", err.Error()))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderer.Render(w, "release-search-results", map[string]interface{}{
+			"Results": results,
+		})
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/metadata/search
+func (h *Handler) JsonSearchReleases() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ReleaseSearchRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your query parameters")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		results, err := h.searchReleases(r.Context(), req)
+		if err != nil {
+			status, message := metadataErrorStatus(err)
+			if status == http.StatusBadGateway {
+				h.logger.Error("Failed to search releases", slog.String("error", err.Error()))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, results, http.StatusOK)
+	}
+}
+
+// GET /api/v1/metadata/releases/{id}
+func (h *Handler) JsonGetRelease() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		imported, err := h.importRelease(r.Context(), id)
+		if err != nil {
+			status, message := metadataErrorStatus(err)
+			if status == http.StatusBadGateway {
+				h.logger.Error("Failed to look up release", slog.String("error", err.Error()), slog.String("releaseID", id))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, imported, http.StatusOK)
+	}
+}
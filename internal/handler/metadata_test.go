@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/metadata"
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// discogsStandIn serves two releases and a search in the shape of the Discogs
+// API, counting release fetches
+func discogsStandIn(t *testing.T, fetches *atomic.Int32) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /database/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results": [{"id": 367084, "title": "Nirvana - Nevermind", "year": "1991", "label": ["DGC"], "catno": "DGC-24425"}]}`))
+	})
+	mux.HandleFunc("GET /releases/367084", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`{
+			"id": 367084, "title": "Nevermind", "year": 1991,
+			"artists": [{"name": "Nirvana"}],
+			"labels": [{"name": "DGC", "catno": "DGC-24425"}],
+			"identifiers": [{"type": "Barcode", "value": "7 20642 44251 7"}],
+			"tracklist": [
+				{"position": "A1", "type_": "track", "title": "Smells Like Teen Spirit", "duration": "5:01"},
+				{"position": "A2", "type_": "track", "title": "In Bloom", "duration": "4:14"},
+				{"position": "B-1", "type_": "track", "title": "Come as You Are", "duration": "3:39"},
+				{"position": "", "type_": "track", "title": "Endless, Nameless", "duration": "?"}
+			]
+		}`))
+	})
+	mux.HandleFunc("GET /releases/2", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`{"id": 2, "title": "Live Through This", "year": 1994, "artists": [{"name": "Hole (3)"}],
+			"identifiers": [{"type": "Barcode", "value": "1234"}], "tracklist": []}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// getRelease calls JsonGetRelease for id
+func getRelease(h *Handler, id string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/api/v1/metadata/releases/"+id, nil)
+	r.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+	h.JsonGetRelease()(w, r)
+	return w
+}
+
+// TestImportRelease tests filling in, caching and creating a record from a
+// looked-up release
+func TestImportRelease(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var fetches atomic.Int32
+	srv := discogsStandIn(t, &fetches)
+	ctx := context.Background()
+	h := &Handler{
+		logger:   slog.New(slog.DiscardHandler),
+		db:       db,
+		queries:  queries,
+		validate: newValidator(),
+		metadata: metadata.NewDiscogsClient(srv.URL, "secret"),
+	}
+
+	nirvana, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Nirvana", SortName: store.ArtistSortName("Nirvana")})
+	if err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+
+	w := getRelease(h, "367084")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get release status = %d, body %s", w.Code, w.Body)
+	}
+	var imported ReleaseImport
+	if err := json.NewDecoder(w.Body).Decode(&imported); err != nil {
+		t.Fatalf("decode release: %v", err)
+	}
+	want := CreateRecordRequest{
+		Title:         "Nevermind",
+		AlbumTitle:    "Nevermind",
+		ArtistID:      nirvana.ID,
+		ArtistName:    "Nirvana",
+		ReleaseYear:   1991,
+		CatalogNumber: "DGC-24425",
+		Barcode:       "0720642442517",
+		ReleaseID:     "367084",
+	}
+	if imported.Record != want {
+		t.Errorf("prefilled record = %+v, want %+v", imported.Record, want)
+	}
+	if err := h.validate.Struct(imported.Record); err != nil {
+		t.Errorf("prefilled record doesn't validate: %v", err)
+	}
+
+	// A second look, and creating the record, are served from the cache
+	h.metadata = metadata.NewDiscogsClient(srv.URL, "secret")
+	if w := getRelease(h, "367084"); w.Code != http.StatusOK {
+		t.Fatalf("cached get release status = %d", w.Code)
+	}
+
+	body, _ := json.Marshal(imported.Record)
+	r := httptest.NewRequest("POST", "/api/v1/records", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	h.JsonCreateRecord()(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create record status = %d, body %s", w.Code, w.Body)
+	}
+	var record store.Record
+	if err := json.NewDecoder(w.Body).Decode(&record); err != nil {
+		t.Fatalf("decode record: %v", err)
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("release fetched %d times, want once", fetches.Load())
+	}
+	if record.ArtistID.Int64 != nirvana.ID || record.Barcode.String != "0720642442517" || record.ReleaseYear.Int64 != 1991 {
+		t.Errorf("created record = %+v", record)
+	}
+
+	// Tracks without a side list first
+	tracks, err := queries.ListTracksByRecord(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("ListTracksByRecord() error = %v", err)
+	}
+	wantTracks := []struct {
+		side, position string
+		duration       int64
+	}{{"", "4", 0}, {"A", "A1", 301}, {"A", "A2", 254}, {"B", "B1", 219}}
+	if len(tracks) != len(wantTracks) {
+		t.Fatalf("imported %d tracks, want %d", len(tracks), len(wantTracks))
+	}
+	for i, want := range wantTracks {
+		got := tracks[i]
+		if got.Side.String != want.side || got.Position != want.position || got.DurationSeconds.Int64 != want.duration {
+			t.Errorf("track %d = %s %s %v, want %+v", i, got.Side.String, got.Position, got.DurationSeconds, want)
+		}
+	}
+
+	// An artist who isn't in the collection yet is added by name; an invalid
+	// barcode is left out
+	w = getRelease(h, "2")
+	if err := json.NewDecoder(w.Body).Decode(&imported); err != nil {
+		t.Fatalf("decode release: %v", err)
+	}
+	if imported.Record.ArtistID != 0 || imported.Record.ArtistName != "Hole" || imported.Record.Barcode != "" {
+		t.Errorf("prefilled record = %+v, want new artist Hole and no barcode", imported.Record)
+	}
+	created, err := h.createRecord(ctx, imported.Record)
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	hole, err := queries.GetArtistByName(ctx, "Hole")
+	if err != nil || created.ArtistID.Int64 != hole.ID {
+		t.Errorf("record artist = %v, want the new artist (%v)", created.ArtistID, err)
+	}
+
+	if _, err := h.createRecord(ctx, CreateRecordRequest{Title: "Nobody's", ArtistID: 999}); err != errCreditArtistNotFound {
+		t.Errorf("createRecord() with unknown artist error = %v, want errCreditArtistNotFound", err)
+	}
+}
+
+// TestReleaseLookups_Errors tests the status of failed lookups and searches
+func TestReleaseLookups_Errors(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	var fetches atomic.Int32
+	srv := discogsStandIn(t, &fetches)
+	h := &Handler{
+		logger:   slog.New(slog.DiscardHandler),
+		db:       db,
+		queries:  queries,
+		validate: newValidator(),
+		metadata: metadata.NewDiscogsClient(srv.URL, "secret"),
+	}
+
+	if w := getRelease(h, "404"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown release status = %d, want 404", w.Code)
+	}
+
+	search := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.JsonSearchReleases()(w, httptest.NewRequest("GET", "/api/v1/metadata/search?"+query, nil))
+		return w
+	}
+	w := search("q=nevermind")
+	var results []metadata.SearchResult
+	if err := json.NewDecoder(w.Body).Decode(&results); err != nil || len(results) != 1 || results[0].ID != "367084" {
+		t.Errorf("search = %d %+v (%v)", w.Code, results, err)
+	}
+	if w := search(""); w.Code != http.StatusBadRequest {
+		t.Errorf("empty search status = %d, want 400", w.Code)
+	}
+	if w := search("barcode=123"); w.Code != http.StatusBadRequest {
+		t.Errorf("bad barcode search status = %d, want 400", w.Code)
+	}
+
+	h.metadata = nil
+	if w := search("q=nevermind"); w.Code != http.StatusNotImplemented {
+		t.Errorf("search without a provider status = %d, want 501", w.Code)
+	}
+	if w := getRelease(h, "367084"); w.Code != http.StatusNotImplemented {
+		t.Errorf("release without a provider status = %d, want 501", w.Code)
+	}
+}
@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// errRecordHasNoHome is returned when putting away a record without a
+	// home location
+	errRecordHasNoHome = errors.New("record has no home location")
+	// errRecordAtHome is returned when putting away a record that's home
+	errRecordAtHome = errors.New("record is already home")
+)
+
+// PutAwayRequest returns records to their home locations
+type PutAwayRequest struct {
+	RecordIDs []int64 `form:"record_id" json:"record_ids" validate:"required,max=1000,dive,min=1"`
+}
+
+// PutAwaySkip is a record that wasn't put away, and why
+type PutAwaySkip struct {
+	RecordID int64  `json:"record_id"`
+	Reason   string `json:"reason"`
+}
+
+// PutAwayResponse counts the records put away and lists the ones that
+// weren't. Warnings name the home locations that are now full.
+type PutAwayResponse struct {
+	Moved    int           `json:"moved"`
+	Skipped  []PutAwaySkip `json:"skipped"`
+	Warnings []string      `json:"warnings"`
+}
+
+// recordMove logs a change of location. Nothing is logged if the record
+// didn't move.
+func recordMove(ctx context.Context, q *store.Queries, before, after store.Record, note string) error {
+	if before.CurrentLocationID == after.CurrentLocationID {
+		return nil
+	}
+
+	_, err := q.CreateRecordMovement(ctx, store.CreateRecordMovementParams{
+		RecordID:       after.ID,
+		FromLocationID: before.CurrentLocationID,
+		ToLocationID:   after.CurrentLocationID,
+		Note:           sql.NullString{String: note, Valid: note != ""},
+		UserID:         currentUserID(ctx),
+	})
+	return err
+}
+
+// putAwayRecord moves a record back to its home location. Returns
+// errRecordHasNoHome, errRecordAtHome, or errRecordOnLoan for a record that's
+// lent out, which comes home when it's returned.
+func putAwayRecord(ctx context.Context, q *store.Queries, recordID int64) (store.Record, error) {
+	record, err := q.GetRecord(ctx, recordID)
+	if err != nil {
+		return record, err
+	}
+	if !record.HomeLocationID.Valid {
+		return record, errRecordHasNoHome
+	}
+	if record.CurrentLocationID == record.HomeLocationID {
+		return record, errRecordAtHome
+	}
+	if _, err := q.GetOpenLoanByRecord(ctx, recordID); err == nil {
+		return record, errRecordOnLoan
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return record, err
+	}
+
+	return moveRecord(ctx, q, record, record.HomeLocationID, "Put away")
+}
+
+// putAway returns records to their home locations in one transaction,
+// skipping the ones that can't be put away rather than failing
+func (h *Handler) putAway(ctx context.Context, req PutAwayRequest) (PutAwayResponse, error) {
+	resp := PutAwayResponse{Skipped: []PutAwaySkip{}, Warnings: []string{}}
+	homes := map[int64]bool{}
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		for _, id := range req.RecordIDs {
+			record, err := putAwayRecord(ctx, q, id)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				resp.Skipped = append(resp.Skipped, PutAwaySkip{RecordID: id, Reason: "record not found"})
+			case errors.Is(err, errRecordHasNoHome), errors.Is(err, errRecordAtHome), errors.Is(err, errRecordOnLoan):
+				resp.Skipped = append(resp.Skipped, PutAwaySkip{RecordID: id, Reason: err.Error()})
+			case err != nil:
+				return err
+			default:
+				resp.Moved++
+				homes[record.HomeLocationID.Int64] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return PutAwayResponse{}, err
+	}
+
+	for locationID := range homes {
+		shelf, err := h.shelf(ctx, locationID)
+		if err != nil {
+			return resp, err
+		}
+		if shelf.Warning != "" {
+			resp.Warnings = append(resp.Warnings, shelf.Warning)
+		}
+	}
+	slices.Sort(resp.Warnings)
+	return resp, nil
+}
+
+// putAwayOne returns a record to its home location and reports where it
+// ended up. Returns sql.ErrNoRows if the record doesn't exist, or the errors
+// of putAwayRecord.
+func (h *Handler) putAwayOne(ctx context.Context, recordID int64) (MoveRecordResponse, error) {
+	var record store.Record
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		var err error
+		record, err = putAwayRecord(ctx, q, recordID)
+		return err
+	})
+	if err != nil {
+		return MoveRecordResponse{}, err
+	}
+	return h.moveResponse(ctx, record)
+}
+
+// awayFromHome lists the records that aren't in their home location
+func (h *Handler) awayFromHome(ctx context.Context) ([]store.ListRecordsAwayFromHomeRow, error) {
+	records, err := h.queries.ListRecordsAwayFromHome(ctx)
+	if records == nil {
+		records = []store.ListRecordsAwayFromHomeRow{}
+	}
+	return records, err
+}
+
+// recordMovements lists where a record has been, latest first
+func (h *Handler) recordMovements(ctx context.Context, recordID int64) ([]store.ListRecordMovementsRow, error) {
+	movements, err := h.queries.ListRecordMovements(ctx, recordID)
+	if movements == nil {
+		movements = []store.ListRecordMovementsRow{}
+	}
+	return movements, err
+}
+
+func putAwayErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errRecordHasNoHome):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, errRecordAtHome), errors.Is(err, errRecordOnLoan):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Record not found"
+	default:
+		return http.StatusInternalServerError, "Failed to put record away"
+	}
+}
+
+// HTML Handlers
+
+// GET /records/away
+// Lists the records that aren't in their home location
+func (h *Handler) GetRecordsAway() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		records, err := h.awayFromHome(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve records away from home", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve records", http.StatusInternalServerError)
+			return
+		}
+
+		if err := h.renderer.Render(w, "records-away", map[string]interface{}{
+			"Title":   "Not at home",
+			"Records": records,
+		}); err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// POST /records/put-away
+func (h *Handler) PutAwayRecords() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PutAwayRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := h.putAway(r.Context(), req)
+		if err != nil {
+			h.logger.Error("Failed to put records away", slog.String("error", err.Error()))
+			http.Error(w, "Failed to put records away", http.StatusInternalServerError)
+			return
+		}
+
+		records, err := h.awayFromHome(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve records away from home", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve records", http.StatusInternalServerError)
+			return
+		}
+		h.renderer.Render(w, "records-away-list", map[string]interface{}{
+			"Records": records,
+			"Result":  result,
+		})
+	}
+}
+
+// POST /records/{id}/put-away
+func (h *Handler) PutAwayRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.putAwayOne(r.Context(), recordID)
+		if err != nil {
+			status, message := putAwayErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to put record away", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderRecordLocation(w, r, recordID, resp.Warning)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/records/away
+func (h *Handler) JsonGetRecordsAway() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		records, err := h.awayFromHome(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve records away from home", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to retrieve records", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, records, http.StatusOK)
+	}
+}
+
+// POST /api/v1/records/put-away
+func (h *Handler) JsonPutAwayRecords() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PutAwayRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.putAway(r.Context(), req)
+		if err != nil {
+			h.logger.Error("Failed to put records away", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to put records away", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, resp, http.StatusOK)
+	}
+}
+
+// POST /api/v1/records/{id}/put-away
+func (h *Handler) JsonPutAwayRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.putAwayOne(r.Context(), recordID)
+		if err != nil {
+			status, message := putAwayErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to put record away", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, resp, http.StatusOK)
+	}
+}
+
+// GET /api/v1/records/{id}/movements
+func (h *Handler) JsonGetRecordMovements() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		movements, err := h.recordMovements(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve movements", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve movements", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, movements, http.StatusOK)
+	}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/dd/data/sql/migrations"
+	"github.com/dukerupert/dd/internal/middleware"
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+// TestRecordMovements tests that every change of location is logged with
+// who made it, whichever way the record moved
+func TestRecordMovements(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	if _, err := queries.CreateUser(ctx, store.CreateUserParams{ID: "u1", Email: "dale@example.com", Username: "dale", PasswordHash: "x", Role: "user"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	ctx = context.WithValue(ctx, middleware.UserIDKey, "u1")
+
+	kallax, err := h.createLocation(ctx, CreateLocationRequest{Name: "Kallax"})
+	if err != nil {
+		t.Fatalf("createLocation() error = %v", err)
+	}
+	turntable, err := h.createLocation(ctx, CreateLocationRequest{Name: "Turntable"})
+	if err != nil {
+		t.Fatalf("createLocation() error = %v", err)
+	}
+
+	record, err := h.createRecord(ctx, CreateRecordRequest{Title: "Blue", CurrentLocationID: kallax.ID, HomeLocationID: kallax.ID})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	if _, err := h.updateRecordLocation(ctx, record.ID, UpdateRecordLocationRequest{CurrentLocationID: turntable.ID}); err != nil {
+		t.Fatalf("updateRecordLocation() error = %v", err)
+	}
+	// Moving it where it already is isn't a movement
+	if _, err := h.updateRecordLocation(ctx, record.ID, UpdateRecordLocationRequest{CurrentLocationID: turntable.ID}); err != nil {
+		t.Fatalf("updateRecordLocation() again error = %v", err)
+	}
+	now := time.Now()
+	if _, err := h.lendRecord(ctx, record.ID, LendRequest{BorrowerName: "Sam"}, now); err != nil {
+		t.Fatalf("lendRecord() error = %v", err)
+	}
+	if _, err := h.returnRecord(ctx, record.ID, now); err != nil {
+		t.Fatalf("returnRecord() error = %v", err)
+	}
+
+	movements, err := h.recordMovements(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("recordMovements() error = %v", err)
+	}
+	var got []string
+	for _, m := range movements {
+		got = append(got, m.FromLocationName.String+">"+m.ToLocationName.String+" "+m.Note.String)
+		if m.Username.String != "dale" {
+			t.Errorf("movement %d by %q, want dale", m.ID, m.Username.String)
+		}
+	}
+	want := "On loan>Kallax Back from Sam | Turntable>On loan Lent to Sam | Kallax>Turntable  | >Kallax Added"
+	if strings.Join(got, " | ") != want {
+		t.Errorf("movements = %s, want %s", strings.Join(got, " | "), want)
+	}
+}
+
+// TestPutAway tests that records away from home are listed and put back,
+// one at a time or in bulk, leaving the ones that can't go home alone
+func TestPutAway(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	kallax, err := h.createLocation(ctx, CreateLocationRequest{Name: "Kallax", Capacity: 3})
+	if err != nil {
+		t.Fatalf("createLocation() error = %v", err)
+	}
+	turntable, err := h.createLocation(ctx, CreateLocationRequest{Name: "Turntable"})
+	if err != nil {
+		t.Fatalf("createLocation() error = %v", err)
+	}
+
+	ids := map[string]int64{}
+	for _, add := range []struct {
+		title         string
+		current, home int64
+	}{
+		{"Aja", kallax.ID, kallax.ID},
+		{"Blue", turntable.ID, kallax.ID},
+		{"Kind of Blue", turntable.ID, kallax.ID},
+		{"Rumours", 0, kallax.ID},
+		{"Tusk", turntable.ID, 0},
+	} {
+		record, err := h.createRecord(ctx, CreateRecordRequest{Title: add.title, CurrentLocationID: add.current, HomeLocationID: add.home})
+		if err != nil {
+			t.Fatalf("createRecord(%q) error = %v", add.title, err)
+		}
+		ids[add.title] = record.ID
+	}
+	if _, err := h.lendRecord(ctx, ids["Kind of Blue"], LendRequest{BorrowerName: "Sam"}, time.Now()); err != nil {
+		t.Fatalf("lendRecord() error = %v", err)
+	}
+
+	away, err := h.awayFromHome(ctx)
+	if err != nil {
+		t.Fatalf("awayFromHome() error = %v", err)
+	}
+	var titles []string
+	for _, record := range away {
+		titles = append(titles, record.Title)
+		if record.Title == "Kind of Blue" && !record.OnLoan {
+			t.Errorf("Kind of Blue isn't shown as on loan")
+		}
+	}
+	if got := strings.Join(titles, ", "); got != "Blue, Kind of Blue, Rumours" {
+		t.Errorf("away from home = %s, want Blue, Kind of Blue, Rumours", got)
+	}
+
+	moved, err := h.putAwayOne(ctx, ids["Blue"])
+	if err != nil {
+		t.Fatalf("putAwayOne() error = %v", err)
+	}
+	if want := "Kallax, position 2 of 2, last, after Aja"; moved.Position.Summary != want {
+		t.Errorf("summary = %q, want %q", moved.Position.Summary, want)
+	}
+
+	for _, tt := range []struct {
+		name string
+		id   int64
+		want error
+	}{
+		{"already home", ids["Blue"], errRecordAtHome},
+		{"on loan", ids["Kind of Blue"], errRecordOnLoan},
+		{"no home", ids["Tusk"], errRecordHasNoHome},
+		{"unknown record", ids["Tusk"] + 100, sql.ErrNoRows},
+	} {
+		if _, err := h.putAwayOne(ctx, tt.id); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	result, err := h.putAway(ctx, PutAwayRequest{RecordIDs: []int64{ids["Rumours"], ids["Kind of Blue"], ids["Tusk"]}})
+	if err != nil {
+		t.Fatalf("putAway() error = %v", err)
+	}
+	if result.Moved != 1 || len(result.Skipped) != 2 {
+		t.Errorf("putAway() = %+v, want 1 moved and 2 skipped", result)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0] != "Kallax is full (3 of 3)" {
+		t.Errorf("warnings = %v, want Kallax is full (3 of 3)", result.Warnings)
+	}
+
+	away, err = h.awayFromHome(ctx)
+	if err != nil || len(away) != 1 || away[0].Title != "Kind of Blue" {
+		t.Errorf("away from home after putting away = %+v, %v, want only Kind of Blue", away, err)
+	}
+}
+
+// TestRecordMovements_Backfill tests that records already shelved start
+// their history where they are, dated when they were added, or undated when
+// that isn't known
+func TestRecordMovements_Backfill(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
+	if err != nil {
+		t.Fatalf("Failed to create migration provider: %v", err)
+	}
+	if _, err := provider.UpTo(ctx, 20261017010000); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO records (title, current_location_id, created_at, updated_at)
+		VALUES ('Aja', 1, '2020-01-01 00:00:00', '2021-06-01 00:00:00'),
+		       ('Rumours', 1, NULL, NULL),
+		       ('Tusk', NULL, '2020-01-01 00:00:00', NULL)`)
+	if err != nil {
+		t.Fatalf("Failed to insert records: %v", err)
+	}
+	if _, err := provider.Up(ctx); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	q := store.New(db)
+	want := map[int64]sql.NullTime{
+		1: {Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		2: {},
+	}
+	for id, movedAt := range want {
+		movements, err := q.ListRecordMovements(ctx, id)
+		if err != nil {
+			t.Fatalf("ListRecordMovements() error = %v", err)
+		}
+		if len(movements) != 1 || movements[0].MovedAt.Valid != movedAt.Valid || !movements[0].MovedAt.Time.Equal(movedAt.Time) || movements[0].ToLocationName.String != "Main Collection" {
+			t.Errorf("record %d movements = %+v, want one into Main Collection at %v", id, movements, movedAt)
+		}
+	}
+	if movements, _ := q.ListRecordMovements(ctx, 3); len(movements) != 0 {
+		t.Errorf("unshelved record movements = %+v, want none", movements)
+	}
+}
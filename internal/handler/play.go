@@ -0,0 +1,435 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultPlaysPerPage   = 25
+	defaultPlayChartLimit = 10
+)
+
+// errPlayInFuture is returned when a play is dated after the current time
+var errPlayInFuture = errors.New("played_at cannot be in the future")
+
+// PlayRequest is the body for logging or editing a play. PlayedAt is RFC 3339;
+// leave it empty to log the play at the current time.
+type PlayRequest struct {
+	PlayedAt string `form:"played_at" json:"played_at" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Side     string `form:"side" json:"side" validate:"omitempty,max=10"`
+	Notes    string `form:"notes" json:"notes" validate:"omitempty,max=1000"`
+}
+
+// playedAt resolves the requested play time, defaulting to now. Times are
+// stored in UTC at second precision so they compare cleanly with
+// CURRENT_TIMESTAMP values.
+func (req PlayRequest) playedAt(now time.Time) (time.Time, error) {
+	playedAt := now
+	if req.PlayedAt != "" {
+		t, err := time.Parse(time.RFC3339, req.PlayedAt)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if t.After(now) {
+			return time.Time{}, errPlayInFuture
+		}
+		playedAt = t
+	}
+	return playedAt.UTC().Truncate(time.Second), nil
+}
+
+// ListPlaysRequest holds the paging parameters for a record's play history
+type ListPlaysRequest struct {
+	Page    int64 `form:"page" json:"page" validate:"omitempty,min=1"`
+	PerPage int64 `form:"per_page" json:"per_page" validate:"omitempty,min=1,max=100"`
+}
+
+// PlayWindowRequest selects the time window for the recent and most played
+// charts. Days takes precedence over Since; Until is inclusive.
+type PlayWindowRequest struct {
+	Days  int64  `form:"days" json:"days" validate:"omitempty,min=1,max=36500"`
+	Since string `form:"since" json:"since" validate:"omitempty,datetime=2006-01-02"`
+	Until string `form:"until" json:"until" validate:"omitempty,datetime=2006-01-02"`
+	Limit int64  `form:"limit" json:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// window returns the half-open [since, until) range of play times to include
+func (req PlayWindowRequest) window(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	since := time.Time{}
+	until := now.Add(time.Second)
+
+	if t, err := time.Parse("2006-01-02", req.Since); err == nil {
+		since = t
+	}
+	if req.Days > 0 {
+		since = now.AddDate(0, 0, -int(req.Days))
+	}
+	if t, err := time.Parse("2006-01-02", req.Until); err == nil {
+		until = t.AddDate(0, 0, 1)
+	}
+
+	return since, until
+}
+
+// PlayResponse is returned after logging or editing a play, together with the
+// record's updated play count and last played time
+type PlayResponse struct {
+	Play   store.Play   `json:"play"`
+	Record store.Record `json:"record"`
+}
+
+// PlayListResponse is a page of a record's play history, newest first
+type PlayListResponse struct {
+	Plays      []store.Play `json:"plays"`
+	Pagination Pagination   `json:"pagination"`
+}
+
+// createPlay logs a play of the record for the current user
+func (h *Handler) createPlay(ctx context.Context, recordID int64, req PlayRequest) (PlayResponse, error) {
+	playedAt, err := req.playedAt(time.Now())
+	if err != nil {
+		return PlayResponse{}, err
+	}
+
+	play, err := h.queries.CreatePlay(ctx, store.CreatePlayParams{
+		RecordID: recordID,
+		UserID:   currentUserID(ctx),
+		PlayedAt: playedAt,
+		Side:     sql.NullString{String: req.Side, Valid: req.Side != ""},
+		Notes:    sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+	})
+	if err != nil {
+		return PlayResponse{}, err
+	}
+
+	record, err := h.queries.GetRecord(ctx, recordID)
+	if err != nil {
+		return PlayResponse{}, err
+	}
+
+	return PlayResponse{Play: play, Record: record}, nil
+}
+
+// HTML Handlers
+
+// POST /records/{id}/play
+func (h *Handler) PlayRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.logger.Error("Failed to retrieve record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		result, err := h.createPlay(r.Context(), recordID, PlayRequest{})
+		if err != nil {
+			h.logger.Error("Failed to record play", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to record play", http.StatusInternalServerError)
+			return
+		}
+
+		h.logger.Info("Record played", slog.Int64("recordID", recordID), slog.Int64("playCount", result.Record.PlayCount.Int64))
+		h.renderer.Render(w, "record-play-count", result.Record)
+	}
+}
+
+// API Handlers
+
+// POST /api/v1/records/{id}/play
+func (h *Handler) JsonPlayRecord() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		result, err := h.createPlay(r.Context(), recordID, PlayRequest{})
+		if err != nil {
+			h.logger.Error("Failed to record play", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to record play", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, result, http.StatusCreated)
+	}
+}
+
+// POST /api/v1/records/{id}/plays
+func (h *Handler) JsonCreatePlay() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req PlayRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		result, err := h.createPlay(r.Context(), recordID, req)
+		if errors.Is(err, errPlayInFuture) {
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to record play", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to record play", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, result, http.StatusCreated)
+	}
+}
+
+// GET /api/v1/records/{id}/plays
+func (h *Handler) JsonGetRecordPlays() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req ListPlaysRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your query parameters")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		result, err := h.listPlays(r.Context(), r.URL.Query(), recordID, req)
+		if err != nil {
+			h.logger.Error("Failed to retrieve plays", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve plays", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, result, http.StatusOK)
+	}
+}
+
+// listPlays returns one page of a record's play history
+func (h *Handler) listPlays(ctx context.Context, query url.Values, recordID int64, req ListPlaysRequest) (PlayListResponse, error) {
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PerPage < 1 {
+		req.PerPage = defaultPlaysPerPage
+	}
+
+	plays, err := h.queries.ListPlaysByRecord(ctx, store.ListPlaysByRecordParams{
+		RecordID: recordID,
+		Limit:    req.PerPage,
+		Offset:   (req.Page - 1) * req.PerPage,
+	})
+	if err != nil {
+		return PlayListResponse{}, err
+	}
+	if plays == nil {
+		plays = []store.Play{}
+	}
+
+	total, err := h.queries.CountPlaysByRecord(ctx, recordID)
+	if err != nil {
+		return PlayListResponse{}, err
+	}
+
+	return PlayListResponse{
+		Plays:      plays,
+		Pagination: newPagination(query, req.Page, req.PerPage, total),
+	}, nil
+}
+
+// PUT /api/v1/plays/{id}
+func (h *Handler) JsonUpdatePlay() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req PlayRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		existing, err := h.queries.GetPlay(r.Context(), playID)
+		if err != nil {
+			h.writeErrorJSON(w, "Play not found", http.StatusNotFound)
+			return
+		}
+
+		// Omitting played_at keeps the original time rather than resetting it
+		playedAt := existing.PlayedAt
+		if req.PlayedAt != "" {
+			playedAt, err = req.playedAt(time.Now())
+			if err != nil {
+				h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		play, err := h.queries.UpdatePlay(r.Context(), store.UpdatePlayParams{
+			PlayedAt: playedAt,
+			Side:     sql.NullString{String: req.Side, Valid: req.Side != ""},
+			Notes:    sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+			ID:       playID,
+		})
+		if err != nil {
+			h.logger.Error("Failed to update play", slog.String("error", err.Error()), slog.Int64("playID", playID))
+			h.writeErrorJSON(w, "Failed to update play", http.StatusInternalServerError)
+			return
+		}
+
+		record, err := h.queries.GetRecord(r.Context(), play.RecordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve record", slog.String("error", err.Error()), slog.Int64("recordID", play.RecordID))
+			h.writeErrorJSON(w, "Failed to retrieve record", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, PlayResponse{Play: play, Record: record}, http.StatusOK)
+	}
+}
+
+// DELETE /api/v1/plays/{id}
+func (h *Handler) JsonDeletePlay() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetPlay(r.Context(), playID); err != nil {
+			h.writeErrorJSON(w, "Play not found", http.StatusNotFound)
+			return
+		}
+
+		if err := h.queries.DeletePlay(r.Context(), playID); err != nil {
+			h.logger.Error("Failed to delete play", slog.String("error", err.Error()), slog.Int64("playID", playID))
+			h.writeErrorJSON(w, "Failed to delete play", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GET /api/v1/records/recent
+func (h *Handler) JsonGetRecordsByRecent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PlayWindowRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your query parameters")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Limit == 0 {
+			req.Limit = defaultPlayChartLimit
+		}
+
+		since, until := req.window(time.Now())
+		records, err := h.queries.GetRecentlyPlayedRecords(r.Context(), store.GetRecentlyPlayedRecordsParams{
+			Since: since,
+			Until: until,
+			Limit: req.Limit,
+		})
+		if err != nil {
+			h.logger.Error("Failed to retrieve recently played records", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to retrieve records", http.StatusInternalServerError)
+			return
+		}
+		if records == nil {
+			records = []store.Record{}
+		}
+
+		h.writeJSON(w, records, http.StatusOK)
+	}
+}
+
+// GET /api/v1/records/popular
+func (h *Handler) JsonGetRecordsByPopular() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PlayWindowRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your query parameters")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Limit == 0 {
+			req.Limit = defaultPlayChartLimit
+		}
+
+		since, until := req.window(time.Now())
+		records, err := h.queries.GetMostPlayedRecords(r.Context(), store.GetMostPlayedRecordsParams{
+			Since: since,
+			Until: until,
+			Limit: req.Limit,
+		})
+		if err != nil {
+			h.logger.Error("Failed to retrieve most played records", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to retrieve records", http.StatusInternalServerError)
+			return
+		}
+		if records == nil {
+			records = []store.GetMostPlayedRecordsRow{}
+		}
+
+		h.writeJSON(w, records, http.StatusOK)
+	}
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/dd/data/sql/migrations"
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+// TestPlays_KeepRecordInSync tests that logging, backdating and deleting plays
+// keeps the record's play_count and last_played_at consistent
+func TestPlays_KeepRecordInSync(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+		Title:     "Blue Train",
+		PlayCount: sql.NullInt64{Int64: 0, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	base := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	var plays []store.Play
+	for i := 0; i < 3; i++ {
+		play, err := queries.CreatePlay(ctx, store.CreatePlayParams{
+			RecordID: record.ID,
+			PlayedAt: base.AddDate(0, 0, i),
+			Side:     sql.NullString{String: "A", Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("CreatePlay() error = %v", err)
+		}
+		plays = append(plays, play)
+	}
+
+	check := func(step string, wantCount int64, wantLast time.Time) {
+		t.Helper()
+		got, err := queries.GetRecord(ctx, record.ID)
+		if err != nil {
+			t.Fatalf("%s: GetRecord() error = %v", step, err)
+		}
+		if got.PlayCount.Int64 != wantCount {
+			t.Errorf("%s: play_count = %d, want %d", step, got.PlayCount.Int64, wantCount)
+		}
+		if wantLast.IsZero() {
+			if got.LastPlayedAt.Valid {
+				t.Errorf("%s: last_played_at = %v, want NULL", step, got.LastPlayedAt.Time)
+			}
+			return
+		}
+		if !got.LastPlayedAt.Time.Equal(wantLast) {
+			t.Errorf("%s: last_played_at = %v, want %v", step, got.LastPlayedAt.Time, wantLast)
+		}
+	}
+
+	check("after logging", 3, base.AddDate(0, 0, 2))
+
+	// Backdating the latest play moves last_played_at back to the next latest
+	_, err = queries.UpdatePlay(ctx, store.UpdatePlayParams{
+		ID:       plays[2].ID,
+		PlayedAt: base.AddDate(0, 0, -7),
+	})
+	if err != nil {
+		t.Fatalf("UpdatePlay() error = %v", err)
+	}
+	check("after backdating", 3, base.AddDate(0, 0, 1))
+
+	// Deleting a play lowers the count
+	if err := queries.DeletePlay(ctx, plays[1].ID); err != nil {
+		t.Fatalf("DeletePlay() error = %v", err)
+	}
+	check("after deleting one", 2, base)
+
+	if err := queries.DeletePlay(ctx, plays[0].ID); err != nil {
+		t.Fatalf("DeletePlay() error = %v", err)
+	}
+	if err := queries.DeletePlay(ctx, plays[2].ID); err != nil {
+		t.Fatalf("DeletePlay() error = %v", err)
+	}
+	check("after deleting all", 0, time.Time{})
+}
+
+// TestListPlaysByRecord tests that play history is newest first and removed
+// with the record
+func TestListPlaysByRecord(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Giant Steps"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	base := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	for _, offset := range []int{2, 0, 1} {
+		if _, err := queries.CreatePlay(ctx, store.CreatePlayParams{
+			RecordID: record.ID,
+			PlayedAt: base.AddDate(0, 0, offset),
+		}); err != nil {
+			t.Fatalf("CreatePlay() error = %v", err)
+		}
+	}
+
+	plays, err := queries.ListPlaysByRecord(ctx, store.ListPlaysByRecordParams{RecordID: record.ID, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListPlaysByRecord() error = %v", err)
+	}
+	if len(plays) != 2 {
+		t.Fatalf("len(plays) = %d, want 2", len(plays))
+	}
+	if !plays[0].PlayedAt.Equal(base.AddDate(0, 0, 2)) || !plays[1].PlayedAt.Equal(base.AddDate(0, 0, 1)) {
+		t.Errorf("plays not newest first: %v, %v", plays[0].PlayedAt, plays[1].PlayedAt)
+	}
+
+	if err := queries.DeleteRecord(ctx, record.ID); err != nil {
+		t.Fatalf("DeleteRecord() error = %v", err)
+	}
+	count, err := queries.CountPlaysByRecord(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("CountPlaysByRecord() error = %v", err)
+	}
+	if count != 0 {
+		t.Errorf("plays after deleting record = %d, want 0", count)
+	}
+}
+
+// TestPlayCharts_TimeWindow tests most and recently played over a window
+func TestPlayCharts_TimeWindow(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	old, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Old Favourite"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+	recent, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "New Favourite"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		queries.CreatePlay(ctx, store.CreatePlayParams{RecordID: old.ID, PlayedAt: now.AddDate(0, 0, -60)})
+	}
+	queries.CreatePlay(ctx, store.CreatePlayParams{RecordID: old.ID, PlayedAt: now.AddDate(0, 0, -10)})
+	for i := 0; i < 2; i++ {
+		queries.CreatePlay(ctx, store.CreatePlayParams{RecordID: recent.ID, PlayedAt: now.AddDate(0, 0, -2)})
+	}
+
+	since, until := PlayWindowRequest{Days: 30}.window(now)
+
+	mostPlayed, err := queries.GetMostPlayedRecords(ctx, store.GetMostPlayedRecordsParams{Since: since, Until: until, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetMostPlayedRecords() error = %v", err)
+	}
+	if len(mostPlayed) != 2 {
+		t.Fatalf("len(mostPlayed) = %d, want 2", len(mostPlayed))
+	}
+	if mostPlayed[0].ID != recent.ID || mostPlayed[0].WindowPlayCount != 2 {
+		t.Errorf("top of last 30 days = %q with %d plays, want %q with 2", mostPlayed[0].Title, mostPlayed[0].WindowPlayCount, recent.Title)
+	}
+	if mostPlayed[1].WindowPlayCount != 1 || mostPlayed[1].PlayCount.Int64 != 6 {
+		t.Errorf("second = %d window plays, %d total, want 1 and 6", mostPlayed[1].WindowPlayCount, mostPlayed[1].PlayCount.Int64)
+	}
+
+	// All time the older record wins
+	since, until = PlayWindowRequest{}.window(now)
+	mostPlayed, err = queries.GetMostPlayedRecords(ctx, store.GetMostPlayedRecordsParams{Since: since, Until: until, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetMostPlayedRecords() error = %v", err)
+	}
+	if len(mostPlayed) != 2 || mostPlayed[0].ID != old.ID {
+		t.Errorf("all-time most played = %v, want %q first", mostPlayed, old.Title)
+	}
+
+	// Recently played within an explicit window excludes later plays
+	windowUntil := now.AddDate(0, 0, -5).Format("2006-01-02")
+	since, until = PlayWindowRequest{Until: windowUntil}.window(now)
+	recentlyPlayed, err := queries.GetRecentlyPlayedRecords(ctx, store.GetRecentlyPlayedRecordsParams{Since: since, Until: until, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetRecentlyPlayedRecords() error = %v", err)
+	}
+	if len(recentlyPlayed) != 1 || recentlyPlayed[0].ID != old.ID {
+		t.Errorf("recently played until %s = %v, want only %q", windowUntil, recentlyPlayed, old.Title)
+	}
+}
+
+// TestPlaysMigration_BackfillsPlayCount tests that existing play counts are
+// carried into the plays table
+func TestPlaysMigration_BackfillsPlayCount(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
+	if err != nil {
+		t.Fatalf("Failed to create migration provider: %v", err)
+	}
+	if _, err := provider.UpTo(ctx, 20261016090000); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO records (title, play_count, last_played_at)
+VALUES ('Played', 3, '2025-12-24 18:30:00'), ('Unplayed', 0, NULL)`); err != nil {
+		t.Fatalf("Failed to insert records: %v", err)
+	}
+
+	if _, err := provider.Up(ctx); err != nil {
+		t.Fatalf("Failed to run plays migration: %v", err)
+	}
+
+	queries := store.New(db)
+	records, err := queries.ListRecords(ctx)
+	if err != nil {
+		t.Fatalf("ListRecords() error = %v", err)
+	}
+
+	want := map[string]int64{"Played": 3, "Unplayed": 0}
+	for _, record := range records {
+		count, err := queries.CountPlaysByRecord(ctx, record.ID)
+		if err != nil {
+			t.Fatalf("CountPlaysByRecord() error = %v", err)
+		}
+		if count != want[record.Title] {
+			t.Errorf("%s: %d plays, want %d", record.Title, count, want[record.Title])
+		}
+		if record.PlayCount.Int64 != want[record.Title] {
+			t.Errorf("%s: play_count = %d, want %d", record.Title, record.PlayCount.Int64, want[record.Title])
+		}
+	}
+}
@@ -1,8 +1,24 @@
 package handler
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/dd/internal/metadata"
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultRecordsPerPage = 25
+	maxRecordsPerPage     = 100
 )
 
 type CreateRecordRequest struct {
@@ -13,8 +29,18 @@ type CreateRecordRequest struct {
 	CurrentLocationID int64  `form:"current_location_id" json:"current_location_id" validate:"omitempty,min=1"`
 	HomeLocationID    int64  `form:"home_location_id" json:"home_location_id" validate:"omitempty,min=1"`
 	CatalogNumber     string `form:"catalog_number" json:"catalog_number" validate:"max=100"`
-	Condition         string `form:"condition" json:"condition" validate:"omitempty,oneof=Mint 'Near Mint' 'Very Good' Good Fair Poor"`
+	MediaGrade        string `form:"media_grade" json:"media_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	SleeveGrade       string `form:"sleeve_grade" json:"sleeve_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
 	Notes             string `form:"notes" json:"notes" validate:"max=1000"`
+	// Barcode is a UPC-A or EAN-13; spaces and hyphens are ignored
+	Barcode string `form:"barcode" json:"barcode" validate:"omitempty,barcode"`
+	// ArtistName is used when ArtistID is empty; the artist is added if
+	// there's none by that name
+	ArtistName string `form:"artist_name" json:"artist_name" validate:"max=100"`
+	// ReleaseID is a looked-up release whose tracklist is added to the record
+	ReleaseID string `form:"release_id" json:"release_id" validate:"omitempty,max=50,alphanum"`
+	// WantID is a want the record fulfils; it's marked acquired
+	WantID int64 `form:"want_id" json:"want_id" validate:"omitempty,min=1"`
 }
 
 type UpdateRecordRequest struct {
@@ -25,16 +51,277 @@ type UpdateRecordRequest struct {
 	CurrentLocationID int64  `form:"current_location_id" json:"current_location_id" validate:"omitempty,min=1"`
 	HomeLocationID    int64  `form:"home_location_id" json:"home_location_id" validate:"omitempty,min=1"`
 	CatalogNumber     string `form:"catalog_number" json:"catalog_number" validate:"max=100"`
-	Condition         string `form:"condition" json:"condition" validate:"omitempty,oneof=Mint 'Near Mint' 'Very Good' Good Fair Poor"`
+	MediaGrade        string `form:"media_grade" json:"media_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	SleeveGrade       string `form:"sleeve_grade" json:"sleeve_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
 	Notes             string `form:"notes" json:"notes" validate:"max=1000"`
+	// Barcode is a UPC-A or EAN-13; spaces and hyphens are ignored
+	Barcode string `form:"barcode" json:"barcode" validate:"omitempty,barcode"`
+	// ConditionNote is kept in the condition history if the grades change
+	ConditionNote string `form:"condition_note" json:"condition_note" validate:"max=1000"`
 }
 
 type UpdateRecordLocationRequest struct {
 	CurrentLocationID int64 `form:"current_location_id" json:"current_location_id" validate:"required,min=1"`
 }
 
+// UpdateRecordConditionRequest regrades a record. Both grades are replaced;
+// an empty grade clears it.
 type UpdateRecordConditionRequest struct {
-	Condition string `form:"condition" json:"condition" validate:"required,oneof=Mint 'Near Mint' 'Very Good' Good Fair Poor"`
+	MediaGrade  string `form:"media_grade" json:"media_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	SleeveGrade string `form:"sleeve_grade" json:"sleeve_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	Note        string `form:"note" json:"note" validate:"max=1000"`
+}
+
+// ListRecordsRequest holds the filter, sort and paging query parameters shared
+// by GET /records and GET /api/v1/records. Tag ids can be repeated
+// (tags_any=1&tags_any=2) or comma-separated (tags_all=1,2). View only
+// affects the HTML page.
+type ListRecordsRequest struct {
+	ArtistID       int64   `form:"artist_id" json:"artist_id" validate:"omitempty,min=1"`
+	LocationID     int64   `form:"location_id" json:"location_id" validate:"omitempty,min=1"`
+	HomeLocationID int64   `form:"home_location_id" json:"home_location_id" validate:"omitempty,min=1"`
+	MediaGrade     string  `form:"media_grade" json:"media_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	SleeveGrade    string  `form:"sleeve_grade" json:"sleeve_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	MinMediaGrade  string  `form:"media_grade_min" json:"media_grade_min" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	MinSleeveGrade string  `form:"sleeve_grade_min" json:"sleeve_grade_min" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	YearFrom       int32   `form:"year_from" json:"year_from" validate:"omitempty,min=1900,max=2100"`
+	YearTo         int32   `form:"year_to" json:"year_to" validate:"omitempty,min=1900,max=2100"`
+	Played         string  `form:"played" json:"played" validate:"omitempty,oneof=true false"`
+	CreatedSince   string  `form:"created_since" json:"created_since" validate:"omitempty,datetime=2006-01-02"`
+	Query          string  `form:"q" json:"q" validate:"max=200"`
+	TagsAny        []int64 `form:"tags_any" json:"tags_any" validate:"max=20,dive,min=1"`
+	TagsAll        []int64 `form:"tags_all" json:"tags_all" validate:"max=20,dive,min=1"`
+	Sort           string  `form:"sort" json:"sort" validate:"omitempty,oneof=title artist year play_count last_played_at created_at media_grade sleeve_grade running_time"`
+	Order          string  `form:"order" json:"order" validate:"omitempty,oneof=asc desc"`
+	Page           int64   `form:"page" json:"page" validate:"omitempty,min=1"`
+	PerPage        int64   `form:"per_page" json:"per_page" validate:"omitempty,min=1,max=100"`
+	View           string  `form:"view" json:"view" validate:"omitempty,oneof=table grid"`
+}
+
+// RecordListResponse is a single page of the record listing
+type RecordListResponse struct {
+	Records    []store.ListRecordsWithDetailsRow `json:"records"`
+	Pagination Pagination                        `json:"pagination"`
+}
+
+// normalizeQuery puts back the "+" of a grade written unescaped in a URL
+// (?media_grade_min=VG+), which decodes as a space
+func (req *ListRecordsRequest) normalizeQuery() {
+	for _, grade := range []*string{&req.MediaGrade, &req.SleeveGrade, &req.MinMediaGrade, &req.MinSleeveGrade} {
+		if code, ok := strings.CutSuffix(*grade, " "); ok {
+			*grade = code + "+"
+		}
+	}
+}
+
+// toFilter converts the request into a store filter, applying defaults
+func (req ListRecordsRequest) toFilter() store.RecordFilter {
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PerPage < 1 {
+		req.PerPage = defaultRecordsPerPage
+	}
+	if req.PerPage > maxRecordsPerPage {
+		req.PerPage = maxRecordsPerPage
+	}
+
+	f := store.RecordFilter{
+		Query:     req.Query,
+		AnyTagIDs: req.TagsAny,
+		AllTagIDs: req.TagsAll,
+		Sort:      req.Sort,
+		Desc:      req.Order == "desc",
+		Limit:     req.PerPage,
+		Offset:    (req.Page - 1) * req.PerPage,
+	}
+
+	if req.ArtistID > 0 {
+		f.ArtistID = sql.NullInt64{Int64: req.ArtistID, Valid: true}
+	}
+	if req.LocationID > 0 {
+		f.LocationID = sql.NullInt64{Int64: req.LocationID, Valid: true}
+	}
+	if req.HomeLocationID > 0 {
+		f.HomeLocationID = sql.NullInt64{Int64: req.HomeLocationID, Valid: true}
+	}
+	if req.MediaGrade != "" {
+		f.MediaGrade = sql.NullString{String: req.MediaGrade, Valid: true}
+	}
+	if req.SleeveGrade != "" {
+		f.SleeveGrade = sql.NullString{String: req.SleeveGrade, Valid: true}
+	}
+	if req.MinMediaGrade != "" {
+		f.MinMediaGrade = sql.NullString{String: req.MinMediaGrade, Valid: true}
+	}
+	if req.MinSleeveGrade != "" {
+		f.MinSleeveGrade = sql.NullString{String: req.MinSleeveGrade, Valid: true}
+	}
+	if req.YearFrom > 0 {
+		f.YearFrom = sql.NullInt64{Int64: int64(req.YearFrom), Valid: true}
+	}
+	if req.YearTo > 0 {
+		f.YearTo = sql.NullInt64{Int64: int64(req.YearTo), Valid: true}
+	}
+	if req.Played != "" {
+		f.Played = sql.NullBool{Bool: req.Played == "true", Valid: true}
+	}
+	if since, err := time.Parse("2006-01-02", req.CreatedSince); err == nil {
+		f.CreatedSince = sql.NullTime{Time: since, Valid: true}
+	}
+
+	return f
+}
+
+// listRecords runs the filter and count queries for a record listing request
+func (h *Handler) listRecords(ctx context.Context, query url.Values, req ListRecordsRequest) (RecordListResponse, error) {
+	filter := req.toFilter()
+
+	records, err := h.queries.FilterRecords(ctx, filter)
+	if err != nil {
+		return RecordListResponse{}, err
+	}
+	if records == nil {
+		records = []store.ListRecordsWithDetailsRow{}
+	}
+
+	total, err := h.queries.CountFilteredRecords(ctx, filter)
+	if err != nil {
+		return RecordListResponse{}, err
+	}
+
+	page := filter.Offset/filter.Limit + 1
+	return RecordListResponse{
+		Records:    records,
+		Pagination: newPagination(query, page, filter.Limit, total),
+	}, nil
+}
+
+// createRecord adds a record, resolving ArtistName to an artist when no
+// ArtistID is given, adding the tracklist of ReleaseID and marking WantID
+// acquired, all in one transaction. The release is looked up first so the
+// transaction doesn't wait on the network. Returns errCreditArtistNotFound if
+// ArtistID is unknown, and errWantNotFound or errWantAcquired for WantID.
+func (h *Handler) createRecord(ctx context.Context, req CreateRecordRequest) (store.Record, error) {
+	var release metadata.Release
+	if req.ReleaseID != "" {
+		var err error
+		if release, err = h.release(ctx, req.ReleaseID); err != nil {
+			return store.Record{}, err
+		}
+	}
+
+	var record store.Record
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		var err error
+		record, err = insertRecord(ctx, q, req, release.Tracks)
+		if err != nil || req.WantID == 0 {
+			return err
+		}
+		return acquireWant(ctx, q, req.WantID, record.ID)
+	})
+	return record, err
+}
+
+// insertRecord adds a record and its tracks with q, resolving ArtistName to
+// an artist when no ArtistID is given, shelves it alphabetically and starts
+// its movement history. Returns errCreditArtistNotFound if
+// ArtistID is unknown.
+func insertRecord(ctx context.Context, q *store.Queries, req CreateRecordRequest, tracks []metadata.Track) (store.Record, error) {
+	artistID := req.ArtistID
+	if artistID > 0 {
+		if _, err := q.GetArtist(ctx, artistID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.Record{}, errCreditArtistNotFound
+			}
+			return store.Record{}, err
+		}
+	} else if name := strings.TrimSpace(req.ArtistName); name != "" {
+		var err error
+		if artistID, _, err = resolveArtist(ctx, q, name); err != nil {
+			return store.Record{}, err
+		}
+	}
+
+	record, err := q.CreateRecord(ctx, store.CreateRecordParams{
+		Title:             req.Title,
+		ArtistID:          sql.NullInt64{Int64: artistID, Valid: artistID > 0},
+		AlbumTitle:        sql.NullString{String: req.AlbumTitle, Valid: req.AlbumTitle != ""},
+		ReleaseYear:       sql.NullInt64{Int64: int64(req.ReleaseYear), Valid: req.ReleaseYear > 0},
+		CurrentLocationID: sql.NullInt64{Int64: req.CurrentLocationID, Valid: req.CurrentLocationID > 0},
+		HomeLocationID:    sql.NullInt64{Int64: req.HomeLocationID, Valid: req.HomeLocationID > 0},
+		CatalogNumber:     sql.NullString{String: req.CatalogNumber, Valid: req.CatalogNumber != ""},
+		MediaGrade:        sql.NullString{String: req.MediaGrade, Valid: req.MediaGrade != ""},
+		SleeveGrade:       sql.NullString{String: req.SleeveGrade, Valid: req.SleeveGrade != ""},
+		Notes:             sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+		Barcode:           sql.NullString{String: normalizeBarcode(req.Barcode), Valid: req.Barcode != ""},
+	})
+	if err != nil {
+		return store.Record{}, err
+	}
+	if record, err = placeRecord(ctx, q, record); err != nil {
+		return store.Record{}, err
+	}
+	if err := recordMove(ctx, q, store.Record{}, record, "Added"); err != nil {
+		return store.Record{}, err
+	}
+
+	return record, importTracks(ctx, q, record.ID, tracks)
+}
+
+// createRecordErrorStatus maps a createRecord error to an HTTP status and
+// message
+func createRecordErrorStatus(err error) (int, string) {
+	if errors.Is(err, errCreditArtistNotFound) || errors.Is(err, errWantNotFound) {
+		return http.StatusBadRequest, err.Error()
+	}
+	if errors.Is(err, errWantAcquired) {
+		return http.StatusConflict, err.Error()
+	}
+	if errors.Is(err, errLookupsDisabled) || errors.Is(err, metadata.ErrNotFound) || errors.Is(err, metadata.ErrRateLimited) {
+		return metadataErrorStatus(err)
+	}
+	return http.StatusInternalServerError, "Failed to create record"
+}
+
+// updateRecord replaces a record's fields. A change of grade is recorded in
+// the condition history in the same transaction, and a record moved to
+// another location is logged and shelved there alphabetically. Returns
+// sql.ErrNoRows if the record doesn't exist.
+func (h *Handler) updateRecord(ctx context.Context, recordID int64, req UpdateRecordRequest) (store.Record, error) {
+	var record store.Record
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		before, err := q.GetRecord(ctx, recordID)
+		if err != nil {
+			return err
+		}
+
+		record, err = q.UpdateRecord(ctx, store.UpdateRecordParams{
+			Title:             req.Title,
+			ArtistID:          sql.NullInt64{Int64: req.ArtistID, Valid: req.ArtistID > 0},
+			AlbumTitle:        sql.NullString{String: req.AlbumTitle, Valid: req.AlbumTitle != ""},
+			ReleaseYear:       sql.NullInt64{Int64: int64(req.ReleaseYear), Valid: req.ReleaseYear > 0},
+			CurrentLocationID: sql.NullInt64{Int64: req.CurrentLocationID, Valid: req.CurrentLocationID > 0},
+			HomeLocationID:    sql.NullInt64{Int64: req.HomeLocationID, Valid: req.HomeLocationID > 0},
+			CatalogNumber:     sql.NullString{String: req.CatalogNumber, Valid: req.CatalogNumber != ""},
+			MediaGrade:        sql.NullString{String: req.MediaGrade, Valid: req.MediaGrade != ""},
+			SleeveGrade:       sql.NullString{String: req.SleeveGrade, Valid: req.SleeveGrade != ""},
+			Notes:             sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+			Barcode:           sql.NullString{String: normalizeBarcode(req.Barcode), Valid: req.Barcode != ""},
+			ID:                recordID,
+		})
+		if err != nil {
+			return err
+		}
+		if record.CurrentLocationID != before.CurrentLocationID {
+			if record, err = moveRecord(ctx, q, before, record.CurrentLocationID, ""); err != nil {
+				return err
+			}
+		}
+
+		return recordRegrade(ctx, q, before, record, req.ConditionNote)
+	})
+	return record, err
 }
 
 // HTML Handlers
@@ -42,59 +329,299 @@ type UpdateRecordConditionRequest struct {
 // GET /records
 func (h *Handler) GetRecords() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get all records
-		// - Query database with joins for artist/location names
-		// - Handle search and filter query params
-		// - Render records list page
-		records, err := h.queries.ListRecordsWithDetails(r.Context())
+		var req ListRecordsRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := h.listRecords(r.Context(), r.URL.Query(), req)
 		if err != nil {
 			h.logger.Error("Failed to retrieve records", slog.String("error", err.Error()))
 			http.Error(w, "Failed to retrieve records", http.StatusInternalServerError)
 			return
 		}
 
-		h.renderer.Render(w, "albums", map[string]interface{}{
-			"Title":   "Records",
-			"Records": records,
-		})
+		data := map[string]interface{}{
+			"Title":      "Records",
+			"Records":    result.Records,
+			"Pagination": result.Pagination,
+			"Filter":     req,
+			"Exports":    exportLinks(r.URL.Query()),
+		}
+
+		// HTMX filter and paging requests only need the table
+		if r.Header.Get("HX-Request") == "true" {
+			h.renderer.Render(w, "records-table", data)
+			return
+		}
+
+		artists, err := h.queries.ListArtists(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve artists", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve artists", http.StatusInternalServerError)
+			return
+		}
+
+		locations, err := h.locationOptions(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve locations", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve locations", http.StatusInternalServerError)
+			return
+		}
+
+		grades, err := h.queries.ListGrades(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve grades", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve grades", http.StatusInternalServerError)
+			return
+		}
+
+		tags, err := h.queries.ListTags(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve tags", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve tags", http.StatusInternalServerError)
+			return
+		}
+
+		data["Artists"] = artists
+		data["Locations"] = locations
+		data["Grades"] = grades
+		data["Tags"] = tags
+
+		if err := h.renderer.Render(w, "albums", data); err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
 	}
 }
 
 // POST /records
 func (h *Handler) CreateRecord() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Create new record
-		// - Parse form data
-		// - Validate input
-		// - Create record in database
-		// - Return record row partial for HTMX
-		h.logger.Info("Create record handler called")
-		h.renderer.Render(w, "records-row", nil)
+		var req CreateRecordRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		record, err := h.createRecord(r.Context(), req)
+		if err != nil {
+			status, message := createRecordErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to create record", slog.String("error", err.Error()))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		location := "/records/" + strconv.FormatInt(record.ID, 10)
+		if r.Header.Get("HX-Request") == "true" {
+			w.Header().Set("HX-Redirect", location)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		http.Redirect(w, r, location, http.StatusSeeOther)
 	}
 }
 
 // GET /records/{id}
 func (h *Handler) GetRecord() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get record details
-		// - Parse ID from path
-		// - Query record with artist/location details
-		// - Render record detail page
-		id := r.PathValue("id")
-		h.logger.Info("Get record handler called", slog.String("id", id))
-		h.renderer.Render(w, "record-detail", nil)
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		record, err := h.queries.GetRecordWithDetails(r.Context(), recordID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Record not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to retrieve record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve record", http.StatusInternalServerError)
+			return
+		}
+
+		data, err := h.conditionData(r.Context(), record.ID, record.MediaGrade, record.SleeveGrade)
+		if err != nil {
+			h.logger.Error("Failed to retrieve condition history", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve condition history", http.StatusInternalServerError)
+			return
+		}
+		tracklist, err := h.tracklist(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve tracks", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve tracks", http.StatusInternalServerError)
+			return
+		}
+
+		credits, err := h.queries.ListRecordArtists(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve credits", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve credits", http.StatusInternalServerError)
+			return
+		}
+
+		tags, err := h.queries.ListTagsByRecord(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve record tags", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve tags", http.StatusInternalServerError)
+			return
+		}
+
+		slots, err := h.imageSlots(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve images", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve images", http.StatusInternalServerError)
+			return
+		}
+
+		artists, err := h.queries.ListArtists(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve artists", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve artists", http.StatusInternalServerError)
+			return
+		}
+
+		loans, err := h.loanData(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve loans", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve loans", http.StatusInternalServerError)
+			return
+		}
+		for k, v := range loans {
+			data[k] = v
+		}
+
+		value, err := h.recordValue(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve value", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve value", http.StatusInternalServerError)
+			return
+		}
+		data["Value"] = value
+
+		location, err := h.recordLocationData(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve record location", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			http.Error(w, "Failed to retrieve record location", http.StatusInternalServerError)
+			return
+		}
+		for k, v := range location {
+			data[k] = v
+		}
+
+		data["Title"] = record.Title
+		data["Record"] = record
+		data["Tracklist"] = tracklist
+		data["Credits"] = credits
+		data["Artists"] = artists
+		data["Roles"] = creditRoles
+		data["Tags"] = tags
+		data["Kinds"] = tagKinds
+		data["ImageSlots"] = slots
+		data["MaxUploadMB"] = h.media.MaxBytes() >> 20
+
+		if err := h.renderer.Render(w, "record-detail", data); err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
 	}
 }
 
 // GET /records/new
 func (h *Handler) GetCreateRecordForm() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Render new record form
-		// - Query artists for dropdown
-		// - Query locations for dropdown
-		// - Render form
-		h.logger.Info("Get new record form handler called")
-		h.renderer.Render(w, "create-record-form", nil)
+		data := map[string]interface{}{
+			"Title":          "Add record",
+			"Form":           CreateRecordRequest{},
+			"LookupsEnabled": h.metadata != nil,
+		}
+
+		// ?want_id= fills the form in from a want, which is marked acquired
+		// when the record is added
+		if id, err := strconv.ParseInt(r.URL.Query().Get("want_id"), 10, 64); err == nil {
+			want, err := h.queries.GetWant(r.Context(), id)
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Want not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				h.logger.Error("Failed to retrieve want", slog.String("error", err.Error()), slog.Int64("wantID", id))
+				http.Error(w, "Failed to retrieve want", http.StatusInternalServerError)
+				return
+			}
+			if want.AcquiredAt.Valid {
+				http.Error(w, errWantAcquired.Error(), http.StatusConflict)
+				return
+			}
+
+			form := wantRecord(want)
+			if artist, err := h.queries.GetArtistByName(r.Context(), want.ArtistName); err == nil {
+				form.ArtistID = artist.ID
+			}
+			data["Form"] = form
+			data["Want"] = want
+		}
+
+		// ?release_id= fills the form in from a looked-up release
+		if id := r.URL.Query().Get("release_id"); id != "" {
+			imported, err := h.importRelease(r.Context(), id)
+			if err != nil {
+				status, message := metadataErrorStatus(err)
+				if status == http.StatusBadGateway {
+					h.logger.Error("Failed to look up release", slog.String("error", err.Error()), slog.String("releaseID", id))
+				}
+				http.Error(w, message, status)
+				return
+			}
+			imported.Record.WantID = data["Form"].(CreateRecordRequest).WantID
+			data["Form"] = imported.Record
+			data["Release"] = imported.Release
+		}
+
+		artists, err := h.queries.ListArtists(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve artists", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve artists", http.StatusInternalServerError)
+			return
+		}
+
+		locations, err := h.locationOptions(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve locations", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve locations", http.StatusInternalServerError)
+			return
+		}
+
+		grades, err := h.queries.ListGrades(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve grades", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve grades", http.StatusInternalServerError)
+			return
+		}
+
+		data["Artists"] = artists
+		data["Locations"] = locations
+		data["Grades"] = grades
+
+		if err := h.renderer.Render(w, "record-new", data); err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
 	}
 }
 
@@ -148,77 +675,113 @@ func (h *Handler) DeleteRecord() http.HandlerFunc {
 	}
 }
 
-// POST /records/{id}/play
-func (h *Handler) PlayRecord() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Increment play count
-		// - Parse ID from path
-		// - Call RecordPlayback query (updates play_count and last_played_at)
-		// - Return updated record row or play count display for HTMX
-		id := r.PathValue("id")
-		h.logger.Info("Record play handler called", slog.String("id", id))
-		h.renderer.Render(w, "record-play-count", nil)
-	}
-}
-
 // API Handlers
 
 // GET /api/v1/records
 func (h *Handler) JsonGetRecords() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get records list
-		// - Parse query params (page, limit, search, artist_id, location_id, condition, year, sort)
-		// - Query database with filters and joins
-		// - Return JSON array with pagination metadata
-		h.logger.Info("API get records handler called")
-		records, err := h.queries.ListRecordsWithDetails(r.Context())
+		var req ListRecordsRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your query parameters")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := h.listRecords(r.Context(), r.URL.Query(), req)
 		if err != nil {
 			h.logger.Error("Failed to retrieve records", slog.String("error", err.Error()))
 			h.writeErrorJSON(w, "Failed to retrieve records", http.StatusInternalServerError)
 			return
 		}
-		h.writeJSON(w, records, http.StatusOK)
+		h.writeJSON(w, result, http.StatusOK)
 	}
 }
 
 // POST /api/v1/records
 func (h *Handler) JsonCreateRecord() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Create record via API
-		// - Parse JSON body
-		// - Validate input
-		// - Create record in database
-		// - Return created record with 201 status
-		h.logger.Info("API create record handler called")
-		h.writeJSON(w, map[string]string{"message": "record created"}, http.StatusCreated)
+		var req CreateRecordRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		record, err := h.createRecord(r.Context(), req)
+		if err != nil {
+			status, message := createRecordErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to create record", slog.String("error", err.Error()))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, record, http.StatusCreated)
 	}
 }
 
 // GET /api/v1/records/{id}
 func (h *Handler) JsonGetRecord() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get single record
-		// - Parse ID from path
-		// - Query record with details from database
-		// - Return JSON
-		id := r.PathValue("id")
-		h.logger.Info("API get record handler called", slog.String("id", id))
-		h.writeJSON(w, map[string]string{"message": "record details"}, http.StatusOK)
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		record, err := h.queries.GetRecordWithDetails(r.Context(), recordID)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to retrieve record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve record", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, record, http.StatusOK)
 	}
 }
 
 // PUT /api/v1/records/{id}
 func (h *Handler) JsonUpdateRecord() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Update record via API
-		// - Parse ID from path
-		// - Parse JSON body
-		// - Validate input
-		// - Update record in database
-		// - Return updated record
-		id := r.PathValue("id")
-		h.logger.Info("API update record handler called", slog.String("id", id))
-		h.writeJSON(w, map[string]string{"message": "record updated"}, http.StatusOK)
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateRecordRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		record, err := h.updateRecord(r.Context(), recordID, req)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to update record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to update record", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, record, http.StatusOK)
 	}
 }
 
@@ -234,40 +797,3 @@ func (h *Handler) JsonDeleteRecord() http.HandlerFunc {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
-
-// POST /api/v1/records/{api}/play
-func (h *Handler) JsonPlayRecord() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Increment play count via API
-		// - Parse ID from path
-		// - Call RecordPlayback query
-		// - Return updated record
-		id := r.PathValue("id")
-		h.logger.Info("API record play handler called", slog.String("id", id))
-		h.writeJSON(w, map[string]string{"message": "play recorded"}, http.StatusOK)
-	}
-}
-
-// GET /api/v1/records/recent
-func (h *Handler) JsonGetRecordsByRecent() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get recently played records
-		// - Parse limit query param (default 10)
-		// - Call GetRecentlyPlayedRecords query
-		// - Return JSON array
-		h.logger.Info("API get recent records handler called")
-		h.writeJSON(w, []interface{}{}, http.StatusOK)
-	}
-}
-
-// GET /api/v1/records/popular
-func (h *Handler) JsonGetRecordsByPopular() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Get most played records
-		// - Parse limit query param (default 10)
-		// - Call GetMostPlayedRecords query
-		// - Return JSON array
-		h.logger.Info("API get popular records handler called")
-		h.writeJSON(w, []interface{}{}, http.StatusOK)
-	}
-}
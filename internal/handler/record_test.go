@@ -17,7 +17,7 @@ func TestCreateRecord_Success(t *testing.T) {
 	ctx := context.Background()
 
 	// Create artist
-	artist, err := queries.CreateArtist(ctx, "Pink Floyd")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Pink Floyd", SortName: store.ArtistSortName("Pink Floyd")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
@@ -41,7 +41,8 @@ func TestCreateRecord_Success(t *testing.T) {
 		CurrentLocationID: sql.NullInt64{Int64: location.ID, Valid: true},
 		HomeLocationID:    sql.NullInt64{Int64: location.ID, Valid: true},
 		CatalogNumber:     sql.NullString{String: "SHVL 804", Valid: true},
-		Condition:         sql.NullString{String: "Near Mint", Valid: true},
+		MediaGrade:        sql.NullString{String: "NM", Valid: true},
+		SleeveGrade:       sql.NullString{String: "VG+", Valid: true},
 		Notes:             sql.NullString{String: "Original UK pressing", Valid: true},
 		PlayCount:         sql.NullInt64{Int64: 0, Valid: true},
 	})
@@ -115,7 +116,7 @@ func TestGetRecordWithDetails(t *testing.T) {
 	ctx := context.Background()
 
 	// Create artist
-	artist, err := queries.CreateArtist(ctx, "The Beatles")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "The Beatles", SortName: store.ArtistSortName("The Beatles")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
@@ -190,7 +191,7 @@ func TestUpdateRecord(t *testing.T) {
 	}
 
 	// Create artist for update
-	artist, err := queries.CreateArtist(ctx, "Updated Artist")
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Updated Artist", SortName: store.ArtistSortName("Updated Artist")})
 	if err != nil {
 		t.Fatalf("Failed to create artist: %v", err)
 	}
@@ -202,7 +203,7 @@ func TestUpdateRecord(t *testing.T) {
 		ArtistID:    sql.NullInt64{Int64: artist.ID, Valid: true},
 		AlbumTitle:  sql.NullString{String: "New Album", Valid: true},
 		ReleaseYear: sql.NullInt64{Int64: 2020, Valid: true},
-		Condition:   sql.NullString{String: "Good", Valid: true},
+		MediaGrade:  sql.NullString{String: "G", Valid: true},
 		Notes:       sql.NullString{String: "Updated notes", Valid: true},
 	})
 	if err != nil {
@@ -277,9 +278,9 @@ func TestUpdateRecordCondition(t *testing.T) {
 
 	// Create record
 	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{
-		Title:     "Test Record",
-		Condition: sql.NullString{String: "Mint", Valid: true},
-		PlayCount: sql.NullInt64{Int64: 0, Valid: true},
+		Title:      "Test Record",
+		MediaGrade: sql.NullString{String: "M", Valid: true},
+		PlayCount:  sql.NullInt64{Int64: 0, Valid: true},
 	})
 	if err != nil {
 		t.Fatalf("Failed to create record: %v", err)
@@ -287,15 +288,19 @@ func TestUpdateRecordCondition(t *testing.T) {
 
 	// Update condition
 	updated, err := queries.UpdateRecordCondition(ctx, store.UpdateRecordConditionParams{
-		Condition: sql.NullString{String: "Good", Valid: true},
-		ID:        record.ID,
+		MediaGrade:  sql.NullString{String: "G", Valid: true},
+		SleeveGrade: sql.NullString{String: "VG", Valid: true},
+		ID:          record.ID,
 	})
 	if err != nil {
 		t.Fatalf("UpdateRecordCondition() error = %v", err)
 	}
 
-	if !updated.Condition.Valid || updated.Condition.String != "Good" {
-		t.Errorf("Condition = %v, want %v", updated.Condition, "Good")
+	if !updated.MediaGrade.Valid || updated.MediaGrade.String != "G" {
+		t.Errorf("MediaGrade = %v, want %v", updated.MediaGrade, "G")
+	}
+	if !updated.SleeveGrade.Valid || updated.SleeveGrade.String != "VG" {
+		t.Errorf("SleeveGrade = %v, want %v", updated.SleeveGrade, "VG")
 	}
 }
 
@@ -325,9 +330,13 @@ func TestRecordPlayback(t *testing.T) {
 	}
 
 	// Record playback
-	played, err := queries.RecordPlayback(ctx, record.ID)
+	first := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	if _, err := queries.CreatePlay(ctx, store.CreatePlayParams{RecordID: record.ID, PlayedAt: first}); err != nil {
+		t.Fatalf("CreatePlay() error = %v", err)
+	}
+	played, err := queries.GetRecord(ctx, record.ID)
 	if err != nil {
-		t.Fatalf("RecordPlayback() error = %v", err)
+		t.Fatalf("GetRecord() error = %v", err)
 	}
 
 	// Verify play count incremented
@@ -340,13 +349,14 @@ func TestRecordPlayback(t *testing.T) {
 		t.Error("LastPlayedAt should be set after playback")
 	}
 
-	// Wait to ensure timestamp difference
-	time.Sleep(1100 * time.Millisecond)
-
 	// Record playback again
-	played2, err := queries.RecordPlayback(ctx, record.ID)
+	second := first.Add(30 * time.Minute)
+	if _, err := queries.CreatePlay(ctx, store.CreatePlayParams{RecordID: record.ID, PlayedAt: second}); err != nil {
+		t.Fatalf("CreatePlay() second time error = %v", err)
+	}
+	played2, err := queries.GetRecord(ctx, record.ID)
 	if err != nil {
-		t.Fatalf("RecordPlayback() second time error = %v", err)
+		t.Fatalf("GetRecord() error = %v", err)
 	}
 
 	// Verify play count incremented again
@@ -362,6 +372,9 @@ func TestRecordPlayback(t *testing.T) {
 	if !played2.LastPlayedAt.Time.After(played.LastPlayedAt.Time) {
 		t.Error("LastPlayedAt should be updated to more recent time")
 	}
+	if !played2.LastPlayedAt.Time.Equal(second) {
+		t.Errorf("LastPlayedAt = %v, want %v", played2.LastPlayedAt.Time, second)
+	}
 }
 
 // TestDeleteRecord tests record deletion
@@ -401,12 +414,12 @@ func TestGetRecordsByArtist(t *testing.T) {
 	ctx := context.Background()
 
 	// Create artists
-	artist1, err := queries.CreateArtist(ctx, "Artist 1")
+	artist1, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Artist 1", SortName: store.ArtistSortName("Artist 1")})
 	if err != nil {
 		t.Fatalf("Failed to create artist 1: %v", err)
 	}
 
-	artist2, err := queries.CreateArtist(ctx, "Artist 2")
+	artist2, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Artist 2", SortName: store.ArtistSortName("Artist 2")})
 	if err != nil {
 		t.Fatalf("Failed to create artist 2: %v", err)
 	}
@@ -436,7 +449,7 @@ func TestGetRecordsByArtist(t *testing.T) {
 	}
 
 	// Get records for artist 1
-	records1, err := queries.GetRecordsByArtist(ctx, sql.NullInt64{Int64: artist1.ID, Valid: true})
+	records1, err := queries.GetRecordsByArtist(ctx, artist1.ID)
 	if err != nil {
 		t.Fatalf("GetRecordsByArtist() error = %v", err)
 	}
@@ -446,7 +459,7 @@ func TestGetRecordsByArtist(t *testing.T) {
 	}
 
 	// Get records for artist 2
-	records2, err := queries.GetRecordsByArtist(ctx, sql.NullInt64{Int64: artist2.ID, Valid: true})
+	records2, err := queries.GetRecordsByArtist(ctx, artist2.ID)
 	if err != nil {
 		t.Fatalf("GetRecordsByArtist() error = %v", err)
 	}
@@ -551,16 +564,21 @@ func TestGetMostPlayedRecords(t *testing.T) {
 	})
 
 	// Simulate plays
-	for i := 0; i < 5; i++ {
-		queries.RecordPlayback(ctx, record1.ID)
-	}
-	for i := 0; i < 3; i++ {
-		queries.RecordPlayback(ctx, record2.ID)
+	now := time.Now().UTC().Truncate(time.Second)
+	play := func(recordID int64, n int) {
+		for i := 0; i < n; i++ {
+			queries.CreatePlay(ctx, store.CreatePlayParams{RecordID: recordID, PlayedAt: now})
+		}
 	}
-	queries.RecordPlayback(ctx, record3.ID)
+	play(record1.ID, 5)
+	play(record2.ID, 3)
+	play(record3.ID, 1)
 
 	// Get most played
-	mostPlayed, err := queries.GetMostPlayedRecords(ctx, 10)
+	mostPlayed, err := queries.GetMostPlayedRecords(ctx, store.GetMostPlayedRecordsParams{
+		Until: now.Add(time.Second),
+		Limit: 10,
+	})
 	if err != nil {
 		t.Fatalf("GetMostPlayedRecords() error = %v", err)
 	}
@@ -641,4 +659,264 @@ func TestSearchRecordsByTitle(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// createFilterFixtures creates a small collection for the record filter tests
+func createFilterFixtures(t *testing.T, queries *store.Queries) (store.Artist, store.Artist, store.Location, store.Location) {
+	t.Helper()
+	ctx := context.Background()
+
+	floyd, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Pink Floyd", SortName: store.ArtistSortName("Pink Floyd")})
+	if err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+	davis, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Miles Davis", SortName: store.ArtistSortName("Miles Davis")})
+	if err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+
+	shelf, err := queries.CreateLocation(ctx, store.CreateLocationParams{
+		Name:      "Shelf",
+		IsDefault: sql.NullBool{Bool: true, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create location: %v", err)
+	}
+	cleaning, err := queries.CreateLocation(ctx, store.CreateLocationParams{
+		Name:      "Cleaning",
+		IsDefault: sql.NullBool{Bool: false, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create location: %v", err)
+	}
+
+	fixtures := []struct {
+		title    string
+		artist   store.Artist
+		year     int64
+		location store.Location
+		grade    string
+		plays    int
+		notes    string
+	}{
+		{"Dark Side of the Moon", floyd, 1973, shelf, "NM", 3, "Gatefold"},
+		{"Wish You Were Here", floyd, 1975, cleaning, "G", 0, ""},
+		{"Animals", floyd, 1977, shelf, "NM", 1, ""},
+		{"Kind of Blue", davis, 1959, shelf, "VG", 0, "Six-eye label"},
+		{"Bitches Brew", davis, 1970, cleaning, "NM", 2, ""},
+	}
+
+	for _, f := range fixtures {
+		record, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+			Title:             f.title,
+			ArtistID:          sql.NullInt64{Int64: f.artist.ID, Valid: true},
+			ReleaseYear:       sql.NullInt64{Int64: f.year, Valid: true},
+			CurrentLocationID: sql.NullInt64{Int64: f.location.ID, Valid: true},
+			HomeLocationID:    sql.NullInt64{Int64: shelf.ID, Valid: true},
+			MediaGrade:        sql.NullString{String: f.grade, Valid: true},
+			Notes:             sql.NullString{String: f.notes, Valid: f.notes != ""},
+			PlayCount:         sql.NullInt64{Int64: 0, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create record: %v", err)
+		}
+		for i := 0; i < f.plays; i++ {
+			if _, err := queries.CreatePlay(ctx, store.CreatePlayParams{
+				RecordID: record.ID,
+				PlayedAt: time.Now().UTC().Truncate(time.Second),
+			}); err != nil {
+				t.Fatalf("Failed to record play: %v", err)
+			}
+		}
+	}
+
+	return floyd, davis, shelf, cleaning
+}
+
+// TestFilterRecords_Combinations tests combining record filters
+func TestFilterRecords_Combinations(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	floyd, davis, shelf, cleaning := createFilterFixtures(t, queries)
+
+	tests := []struct {
+		name   string
+		filter store.RecordFilter
+		want   []string
+	}{
+		{
+			"no filters",
+			store.RecordFilter{},
+			[]string{"Animals", "Bitches Brew", "Dark Side of the Moon", "Kind of Blue", "Wish You Were Here"},
+		},
+		{
+			"artist",
+			store.RecordFilter{ArtistID: sql.NullInt64{Int64: davis.ID, Valid: true}},
+			[]string{"Bitches Brew", "Kind of Blue"},
+		},
+		{
+			"artist and location",
+			store.RecordFilter{
+				ArtistID:   sql.NullInt64{Int64: floyd.ID, Valid: true},
+				LocationID: sql.NullInt64{Int64: shelf.ID, Valid: true},
+			},
+			[]string{"Animals", "Dark Side of the Moon"},
+		},
+		{
+			"home location",
+			store.RecordFilter{HomeLocationID: sql.NullInt64{Int64: cleaning.ID, Valid: true}},
+			nil,
+		},
+		{
+			"media grade and year range",
+			store.RecordFilter{
+				MediaGrade: sql.NullString{String: "NM", Valid: true},
+				YearFrom:   sql.NullInt64{Int64: 1970, Valid: true},
+				YearTo:     sql.NullInt64{Int64: 1975, Valid: true},
+			},
+			[]string{"Bitches Brew", "Dark Side of the Moon"},
+		},
+		{
+			"media grade VG+ or better",
+			store.RecordFilter{MinMediaGrade: sql.NullString{String: "VG+", Valid: true}},
+			[]string{"Animals", "Bitches Brew", "Dark Side of the Moon"},
+		},
+		{
+			"media grade VG or better by artist",
+			store.RecordFilter{
+				ArtistID:      sql.NullInt64{Int64: davis.ID, Valid: true},
+				MinMediaGrade: sql.NullString{String: "VG", Valid: true},
+			},
+			[]string{"Bitches Brew", "Kind of Blue"},
+		},
+		{
+			"ungraded sleeves never match a minimum",
+			store.RecordFilter{MinSleeveGrade: sql.NullString{String: "P", Valid: true}},
+			nil,
+		},
+		{
+			"unplayed",
+			store.RecordFilter{Played: sql.NullBool{Bool: false, Valid: true}},
+			[]string{"Kind of Blue", "Wish You Were Here"},
+		},
+		{
+			"played at location",
+			store.RecordFilter{
+				Played:     sql.NullBool{Bool: true, Valid: true},
+				LocationID: sql.NullInt64{Int64: cleaning.ID, Valid: true},
+			},
+			[]string{"Bitches Brew"},
+		},
+		{
+			"free text matches artist name",
+			store.RecordFilter{Query: "davis"},
+			[]string{"Bitches Brew", "Kind of Blue"},
+		},
+		{
+			"free text matches notes",
+			store.RecordFilter{Query: "gatefold"},
+			[]string{"Dark Side of the Moon"},
+		},
+		{
+			"created since the future",
+			store.RecordFilter{CreatedSince: sql.NullTime{Time: time.Now().Add(24 * time.Hour), Valid: true}},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := queries.FilterRecords(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("FilterRecords() error = %v", err)
+			}
+
+			if len(records) != len(tt.want) {
+				t.Fatalf("FilterRecords() returned %d records, want %d", len(records), len(tt.want))
+			}
+			for i, record := range records {
+				if record.Title != tt.want[i] {
+					t.Errorf("Record %d = %q, want %q", i, record.Title, tt.want[i])
+				}
+			}
+
+			count, err := queries.CountFilteredRecords(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("CountFilteredRecords() error = %v", err)
+			}
+			if count != int64(len(tt.want)) {
+				t.Errorf("CountFilteredRecords() = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+// TestFilterRecords_SortAndPaginate tests sort keys, direction and paging
+func TestFilterRecords_SortAndPaginate(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	createFilterFixtures(t, queries)
+
+	tests := []struct {
+		name   string
+		filter store.RecordFilter
+		want   []string
+	}{
+		{
+			"year ascending",
+			store.RecordFilter{Sort: store.RecordSortYear},
+			[]string{"Kind of Blue", "Bitches Brew", "Dark Side of the Moon", "Wish You Were Here", "Animals"},
+		},
+		{
+			"play count descending",
+			store.RecordFilter{Sort: store.RecordSortPlayCount, Desc: true, Limit: 3},
+			[]string{"Dark Side of the Moon", "Bitches Brew", "Animals"},
+		},
+		{
+			"artist then second page",
+			store.RecordFilter{Sort: store.RecordSortArtist, Limit: 2, Offset: 2},
+			[]string{"Dark Side of the Moon", "Wish You Were Here"},
+		},
+		{
+			"media grade by rank, not alphabetically",
+			store.RecordFilter{Sort: store.RecordSortMediaGrade},
+			[]string{"Wish You Were Here", "Kind of Blue", "Dark Side of the Moon", "Animals", "Bitches Brew"},
+		},
+		{
+			"unknown sort key falls back to title",
+			store.RecordFilter{Sort: "title; DROP TABLE records", Limit: 1},
+			[]string{"Animals"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := queries.FilterRecords(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("FilterRecords() error = %v", err)
+			}
+
+			if len(records) != len(tt.want) {
+				t.Fatalf("FilterRecords() returned %d records, want %d", len(records), len(tt.want))
+			}
+			for i, record := range records {
+				if record.Title != tt.want[i] {
+					t.Errorf("Record %d = %q, want %q", i, record.Title, tt.want[i])
+				}
+			}
+		})
+	}
+
+	// Paging must not change the total
+	count, err := queries.CountFilteredRecords(ctx, store.RecordFilter{Limit: 2, Offset: 4})
+	if err != nil {
+		t.Fatalf("CountFilteredRecords() error = %v", err)
+	}
+	if count != 5 {
+		t.Errorf("CountFilteredRecords() = %d, want 5", count)
+	}
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchRequest holds the query parameters accepted by the search endpoints
+type SearchRequest struct {
+	Query string `form:"q" json:"q" validate:"required,max=200"`
+	Limit int64  `form:"limit" json:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// SearchResult is a single ranked hit. Title and Snippet are HTML-escaped with
+// the matched terms wrapped in <mark></mark>.
+type SearchResult struct {
+	Type    string  `json:"type"`
+	ID      int64   `json:"id"`
+	Title   string  `json:"title"`
+	Artist  string  `json:"artist,omitempty"`
+	Snippet string  `json:"snippet,omitempty"`
+	URL     string  `json:"url"`
+	Rank    float64 `json:"rank"`
+}
+
+// SearchResponse groups search results by entity type, best match first
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Total   int            `json:"total"`
+	Records []SearchResult `json:"records"`
+	Artists []SearchResult `json:"artists"`
+}
+
+// highlightHTML escapes s and turns the FTS match markers into <mark> tags
+func highlightHTML(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, store.SearchMatchStart, "<mark>")
+	return strings.ReplaceAll(s, store.SearchMatchEnd, "</mark>")
+}
+
+// search runs the full-text query and groups the ranked hits by entity type
+func (h *Handler) search(ctx context.Context, req SearchRequest) (SearchResponse, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	resp := SearchResponse{
+		Query:   req.Query,
+		Records: []SearchResult{},
+		Artists: []SearchResult{},
+	}
+
+	rows, err := h.queries.SearchCollection(ctx, store.SearchCollectionParams{
+		Query: req.Query,
+		Limit: limit,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	for _, row := range rows {
+		result := SearchResult{
+			Type: row.EntityType,
+			ID:   row.EntityID,
+			Rank: row.Rank,
+		}
+
+		switch row.EntityType {
+		case store.SearchEntityRecord:
+			result.Title = highlightHTML(row.Title)
+			result.Artist = highlightHTML(row.ArtistName)
+			result.Snippet = highlightHTML(row.Snippet)
+			result.URL = fmt.Sprintf("/records/%d", row.EntityID)
+			resp.Records = append(resp.Records, result)
+		case store.SearchEntityArtist:
+			result.Title = highlightHTML(row.ArtistName)
+			// A snippet other than the name means an alias matched
+			if row.Snippet != row.ArtistName {
+				result.Snippet = highlightHTML(row.Snippet)
+			}
+			result.URL = fmt.Sprintf("/artists/%d", row.EntityID)
+			resp.Artists = append(resp.Artists, result)
+		}
+	}
+	resp.Total = len(resp.Records) + len(resp.Artists)
+
+	return resp, nil
+}
+
+// HTML Handlers
+
+// GET /search
+func (h *Handler) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := map[string]interface{}{
+			"Title": "Search",
+		}
+
+		// An empty box clears the dropdown rather than showing an error
+		if strings.TrimSpace(r.URL.Query().Get("q")) != "" {
+			var req SearchRequest
+			if err := h.bindQuery(r, &req); err != nil {
+				if validationErrs, ok := err.(validator.ValidationErrors); ok {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+					return
+				}
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			results, err := h.search(r.Context(), req)
+			if err != nil {
+				h.logger.Error("Failed to search collection", slog.String("error", err.Error()), slog.String("query", req.Query))
+				http.Error(w, "Failed to search collection", http.StatusInternalServerError)
+				return
+			}
+			data["Query"] = req.Query
+			data["Results"] = results
+		}
+
+		// The navigation search box only needs the results dropdown
+		if r.Header.Get("HX-Request") == "true" {
+			h.renderer.Render(w, "search-results", data)
+			return
+		}
+
+		if err := h.renderer.Render(w, "search", data); err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// JSON API Handlers
+
+// GET /api/v1/search
+func (h *Handler) JsonSearch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SearchRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your query parameters")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		results, err := h.search(r.Context(), req)
+		if err != nil {
+			h.logger.Error("Failed to search collection", slog.String("error", err.Error()), slog.String("query", req.Query))
+			h.writeErrorJSON(w, "Failed to search collection", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, results, http.StatusOK)
+	}
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// searchIDs returns the entity IDs of the rows of the given type
+func searchIDs(rows []store.SearchCollectionRow, entityType string) []int64 {
+	var ids []int64
+	for _, row := range rows {
+		if row.EntityType == entityType {
+			ids = append(ids, row.EntityID)
+		}
+	}
+	return ids
+}
+
+// TestSearchCollection_IndexStaysInSync tests that the triggers keep the
+// full-text index current as records and artists change
+func TestSearchCollection_IndexStaysInSync(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	artist, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "Pink Floyd", SortName: store.ArtistSortName("Pink Floyd")})
+	if err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+		Title:     "Animals",
+		ArtistID:  sql.NullInt64{Int64: artist.ID, Valid: true},
+		Notes:     sql.NullString{String: "Gatefold sleeve, minor ring wear", Valid: true},
+		PlayCount: sql.NullInt64{Int64: 0, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	search := func(q string) []store.SearchCollectionRow {
+		t.Helper()
+		rows, err := queries.SearchCollection(ctx, store.SearchCollectionParams{Query: q, Limit: 10})
+		if err != nil {
+			t.Fatalf("SearchCollection(%q) failed: %v", q, err)
+		}
+		return rows
+	}
+
+	// New record is searchable by title, notes and artist name
+	for _, q := range []string{"animals", "gatefold", "floyd"} {
+		if ids := searchIDs(search(q), store.SearchEntityRecord); len(ids) != 1 || ids[0] != record.ID {
+			t.Errorf("search %q record IDs = %v, want [%d]", q, ids, record.ID)
+		}
+	}
+
+	// New artist is searchable on its own
+	if ids := searchIDs(search("pink"), store.SearchEntityArtist); len(ids) != 1 || ids[0] != artist.ID {
+		t.Errorf("search pink artist IDs = %v, want [%d]", ids, artist.ID)
+	}
+
+	// Updating the record replaces its indexed text
+	_, err = queries.UpdateRecord(ctx, store.UpdateRecordParams{
+		ID:       record.ID,
+		Title:    "Wish You Were Here",
+		ArtistID: sql.NullInt64{Int64: artist.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update record: %v", err)
+	}
+	if ids := searchIDs(search("animals"), store.SearchEntityRecord); len(ids) != 0 {
+		t.Errorf("old title still matches records %v", ids)
+	}
+	if ids := searchIDs(search("wish"), store.SearchEntityRecord); len(ids) != 1 {
+		t.Errorf("new title matches %v, want 1 record", ids)
+	}
+
+	// Renaming the artist updates the artist row and their records
+	_, err = queries.UpdateArtist(ctx, store.UpdateArtistParams{ID: artist.ID, Name: "The Pink Floyd Sound"})
+	if err != nil {
+		t.Fatalf("Failed to rename artist: %v", err)
+	}
+	rows := search("sound")
+	if ids := searchIDs(rows, store.SearchEntityArtist); len(ids) != 1 {
+		t.Errorf("renamed artist matches %v, want 1 artist", ids)
+	}
+	if ids := searchIDs(rows, store.SearchEntityRecord); len(ids) != 1 {
+		t.Errorf("renamed artist's records match %v, want 1 record", ids)
+	}
+
+	// Deleting removes the record from the index
+	if err := queries.DeleteRecord(ctx, record.ID); err != nil {
+		t.Fatalf("Failed to delete record: %v", err)
+	}
+	if ids := searchIDs(search("wish"), store.SearchEntityRecord); len(ids) != 0 {
+		t.Errorf("deleted record still matches: %v", ids)
+	}
+}
+
+// TestSearchCollection_Ranking tests that title matches outrank note matches
+// and that highlights mark the matched terms
+func TestSearchCollection_Ranking(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	inNotes, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+		Title: "Meddle",
+		Notes: sql.NullString{String: "Bought with Echoes single", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+	inTitle, err := queries.CreateRecord(ctx, store.CreateRecordParams{
+		Title: "Echoes",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	rows, err := queries.SearchCollection(ctx, store.SearchCollectionParams{Query: "echoes", Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchCollection failed: %v", err)
+	}
+
+	ids := searchIDs(rows, store.SearchEntityRecord)
+	if len(ids) != 2 || ids[0] != inTitle.ID || ids[1] != inNotes.ID {
+		t.Fatalf("record IDs = %v, want [%d %d]", ids, inTitle.ID, inNotes.ID)
+	}
+
+	want := store.SearchMatchStart + "Echoes" + store.SearchMatchEnd
+	if rows[0].Title != want {
+		t.Errorf("Title = %q, want %q", rows[0].Title, want)
+	}
+	if !strings.Contains(rows[1].Snippet, want) {
+		t.Errorf("Snippet = %q, want it to contain %q", rows[1].Snippet, want)
+	}
+}
+
+// TestSearchCollection_UntrustedInput tests that FTS syntax in user input
+// is treated as plain text rather than failing the query
+func TestSearchCollection_UntrustedInput(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	if _, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Kind of Blue"}); err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	for _, q := range []string{`"kind`, "kind OR", "NEAR(blue", "-blue", "blue*", "title:kind", "   "} {
+		if _, err := queries.SearchCollection(ctx, store.SearchCollectionParams{Query: q, Limit: 10}); err != nil {
+			t.Errorf("SearchCollection(%q) failed: %v", q, err)
+		}
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"blue", `"blue"*`},
+		{"kind of  blue", `"kind"* "of"* "blue"*`},
+		{`"miles*`, `"miles"*`},
+		{`say"what`, `"say""what"*`},
+		{"NEAR(x", `"NEAR(x"*`},
+	}
+
+	for _, tt := range tests {
+		if got := store.FTSQuery(tt.input); got != tt.want {
+			t.Errorf("FTSQuery(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	in := "<b>" + store.SearchMatchStart + "Tom & Jerry" + store.SearchMatchEnd
+	want := "&lt;b&gt;<mark>Tom &amp; Jerry</mark>"
+	if got := highlightHTML(in); got != want {
+		t.Errorf("highlightHTML() = %q, want %q", got, want)
+	}
+}
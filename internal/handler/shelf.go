@@ -0,0 +1,574 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	errLocationNotFound = errors.New("location not found")
+	errRecordNotOnShelf = errors.New("record isn't in this location")
+)
+
+// ReorderShelfRequest puts the records in a location in the given order.
+// Records in the location that aren't listed keep their order after them.
+type ReorderShelfRequest struct {
+	RecordIDs []int64 `form:"record_id" json:"record_ids" validate:"required,max=5000,dive,min=1"`
+}
+
+// ShelfRecord is a record at its position on a shelf, counting from 1
+type ShelfRecord struct {
+	Position   int64  `json:"position"`
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	ArtistName string `json:"artist_name,omitempty"`
+}
+
+// Shelf is the records directly in a location, in order, and how full it is
+type Shelf struct {
+	LocationID int64         `json:"location_id"`
+	Path       string        `json:"path"`
+	Capacity   int64         `json:"capacity,omitempty"`
+	Warning    string        `json:"warning,omitempty"`
+	Records    []ShelfRecord `json:"records"`
+}
+
+// ShelfPosition says exactly where a record is: "Main Collection, position
+// 312 of 800, between X and Y". Before and After are its neighbours. A record
+// that hasn't been put anywhere has no location.
+type ShelfPosition struct {
+	RecordID   int64        `json:"record_id"`
+	LocationID int64        `json:"location_id,omitempty"`
+	Path       string       `json:"path,omitempty"`
+	Position   int64        `json:"position,omitempty"`
+	Count      int64        `json:"count"`
+	Capacity   int64        `json:"capacity,omitempty"`
+	Before     *ShelfRecord `json:"before,omitempty"`
+	After      *ShelfRecord `json:"after,omitempty"`
+	Summary    string       `json:"summary"`
+}
+
+// MoveRecordResponse is a record after a move and where it ended up,
+// warning when the location is now full
+type MoveRecordResponse struct {
+	Record   store.Record  `json:"record"`
+	Position ShelfPosition `json:"position"`
+	Warning  string        `json:"warning,omitempty"`
+}
+
+// String is how a record is named on a shelf: "Artist – Title"
+func (r ShelfRecord) String() string {
+	if r.ArtistName == "" {
+		return r.Title
+	}
+	return r.ArtistName + " – " + r.Title
+}
+
+// placeRecord puts a record on the shelf of its current location in
+// alphabetical order, by artist sort name then title, in front of the first
+// record that sorts after it
+func placeRecord(ctx context.Context, q *store.Queries, record store.Record) (store.Record, error) {
+	if !record.CurrentLocationID.Valid {
+		return record, nil
+	}
+	position, err := q.AlphabeticalShelfPosition(ctx, store.AlphabeticalShelfPositionParams{
+		LocationID: record.CurrentLocationID,
+		RecordID:   record.ID,
+	})
+	if err != nil {
+		return record, err
+	}
+	record.ShelfPosition = sql.NullInt64{Int64: position, Valid: true}
+
+	if err := q.MakeShelfRoom(ctx, store.MakeShelfRoomParams{
+		LocationID: record.CurrentLocationID,
+		Position:   record.ShelfPosition,
+	}); err != nil {
+		return record, err
+	}
+	return record, q.SetRecordShelfPosition(ctx, store.SetRecordShelfPositionParams{
+		ShelfPosition: record.ShelfPosition,
+		ID:            record.ID,
+	})
+}
+
+// moveRecord moves a record to a location, or nowhere, shelves it there
+// alphabetically and logs the move with note. A record already there is left
+// where it is on the shelf.
+func moveRecord(ctx context.Context, q *store.Queries, record store.Record, locationID sql.NullInt64, note string) (store.Record, error) {
+	if record.CurrentLocationID == locationID {
+		return record, nil
+	}
+	moved, err := q.UpdateRecordLocation(ctx, store.UpdateRecordLocationParams{
+		CurrentLocationID: locationID,
+		ID:                record.ID,
+	})
+	if err != nil {
+		return moved, err
+	}
+	if moved, err = placeRecord(ctx, q, moved); err != nil {
+		return moved, err
+	}
+	return moved, recordMove(ctx, q, record, moved, note)
+}
+
+// updateRecordLocation moves a record into a location, shelving it
+// alphabetically, and reports where it went. Returns sql.ErrNoRows if the
+// record doesn't exist or errLocationNotFound.
+func (h *Handler) updateRecordLocation(ctx context.Context, recordID int64, req UpdateRecordLocationRequest) (MoveRecordResponse, error) {
+	var record store.Record
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		before, err := q.GetRecord(ctx, recordID)
+		if err != nil {
+			return err
+		}
+		if _, err := q.GetLocation(ctx, req.CurrentLocationID); errors.Is(err, sql.ErrNoRows) {
+			return errLocationNotFound
+		} else if err != nil {
+			return err
+		}
+
+		record, err = moveRecord(ctx, q, before, sql.NullInt64{Int64: req.CurrentLocationID, Valid: true}, "")
+		return err
+	})
+	if err != nil {
+		return MoveRecordResponse{}, err
+	}
+	return h.moveResponse(ctx, record)
+}
+
+// moveResponse reports where a record that's just moved ended up and warns
+// if that location is now full
+func (h *Handler) moveResponse(ctx context.Context, record store.Record) (MoveRecordResponse, error) {
+	position, err := h.shelfPosition(ctx, record.ID)
+	if err != nil {
+		return MoveRecordResponse{}, err
+	}
+	return MoveRecordResponse{
+		Record:   record,
+		Position: position,
+		Warning:  capacityWarning(position.Path, position.Count, position.Capacity),
+	}, nil
+}
+
+// shelf lists the records directly in a location in shelf order. Returns
+// sql.ErrNoRows if the location doesn't exist.
+func (h *Handler) shelf(ctx context.Context, locationID int64) (Shelf, error) {
+	location, err := h.queries.GetLocation(ctx, locationID)
+	if err != nil {
+		return Shelf{}, err
+	}
+	path, err := h.queries.GetLocationPath(ctx, locationID)
+	if err != nil {
+		return Shelf{}, err
+	}
+	records, err := shelfRecords(ctx, h.queries, locationID)
+	if err != nil {
+		return Shelf{}, err
+	}
+
+	return Shelf{
+		LocationID: locationID,
+		Path:       path,
+		Capacity:   location.Capacity.Int64,
+		Warning:    capacityWarning(path, int64(len(records)), location.Capacity.Int64),
+		Records:    records,
+	}, nil
+}
+
+// shelfRecords numbers the records directly in a location from 1 in shelf
+// order
+func shelfRecords(ctx context.Context, q *store.Queries, locationID int64) ([]ShelfRecord, error) {
+	rows, err := q.ListShelfRecords(ctx, sql.NullInt64{Int64: locationID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]ShelfRecord, len(rows))
+	for i, row := range rows {
+		records[i] = ShelfRecord{
+			Position:   int64(i + 1),
+			ID:         row.ID,
+			Title:      row.Title,
+			ArtistName: row.ArtistName.String,
+		}
+	}
+	return records, nil
+}
+
+// reorderShelf puts the records in a location in the order given, followed
+// by any it doesn't list in the order they were in. Returns sql.ErrNoRows if
+// the location doesn't exist, or errRecordNotOnShelf if a record is
+// elsewhere.
+func (h *Handler) reorderShelf(ctx context.Context, locationID int64, req ReorderShelfRequest) error {
+	return h.withTx(ctx, func(q *store.Queries) error {
+		if _, err := q.GetLocation(ctx, locationID); err != nil {
+			return err
+		}
+		records, err := shelfRecords(ctx, q, locationID)
+		if err != nil {
+			return err
+		}
+
+		placed := make(map[int64]bool, len(records))
+		for _, record := range records {
+			placed[record.ID] = false
+		}
+		order := make([]int64, 0, len(records))
+		for _, id := range req.RecordIDs {
+			done, ok := placed[id]
+			if !ok {
+				return fmt.Errorf("%w: %d", errRecordNotOnShelf, id)
+			}
+			if !done {
+				order = append(order, id)
+				placed[id] = true
+			}
+		}
+		for _, record := range records {
+			if !placed[record.ID] {
+				order = append(order, record.ID)
+			}
+		}
+
+		for i, id := range order {
+			if err := q.SetRecordShelfPosition(ctx, store.SetRecordShelfPositionParams{
+				ShelfPosition: sql.NullInt64{Int64: int64(i + 1), Valid: true},
+				ID:            id,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// shelfPosition looks up exactly where a record is. Returns sql.ErrNoRows if
+// the record doesn't exist.
+func (h *Handler) shelfPosition(ctx context.Context, recordID int64) (ShelfPosition, error) {
+	record, err := h.queries.GetRecord(ctx, recordID)
+	if err != nil {
+		return ShelfPosition{}, err
+	}
+	pos := ShelfPosition{RecordID: recordID, Summary: "Not put away anywhere"}
+	if !record.CurrentLocationID.Valid {
+		return pos, nil
+	}
+
+	shelf, err := h.shelf(ctx, record.CurrentLocationID.Int64)
+	if err != nil {
+		return ShelfPosition{}, err
+	}
+	pos.LocationID = shelf.LocationID
+	pos.Path = shelf.Path
+	pos.Count = int64(len(shelf.Records))
+	pos.Capacity = shelf.Capacity
+
+	for i, r := range shelf.Records {
+		if r.ID != recordID {
+			continue
+		}
+		pos.Position = r.Position
+		if i > 0 {
+			pos.Before = &shelf.Records[i-1]
+		}
+		if i < len(shelf.Records)-1 {
+			pos.After = &shelf.Records[i+1]
+		}
+	}
+	pos.Summary = positionSummary(pos)
+	return pos, nil
+}
+
+// positionSummary describes a shelf position in words, e.g. "Main
+// Collection, position 312 of 800, between X and Y"
+func positionSummary(pos ShelfPosition) string {
+	summary := fmt.Sprintf("%s, position %d of %d", pos.Path, pos.Position, pos.Count)
+	switch {
+	case pos.Before != nil && pos.After != nil:
+		return summary + fmt.Sprintf(", between %s and %s", pos.Before, pos.After)
+	case pos.After != nil:
+		return summary + fmt.Sprintf(", first, before %s", pos.After)
+	case pos.Before != nil:
+		return summary + fmt.Sprintf(", last, after %s", pos.Before)
+	default:
+		return summary
+	}
+}
+
+// capacityWarning says when a location with a capacity is full or over it
+func capacityWarning(path string, count, capacity int64) string {
+	switch {
+	case capacity == 0 || count < capacity:
+		return ""
+	case count == capacity:
+		return fmt.Sprintf("%s is full (%d of %d)", path, count, capacity)
+	default:
+		return fmt.Sprintf("%s is over capacity (%d of %d)", path, count, capacity)
+	}
+}
+
+// recordLocationData is what the record page's location section shows:
+// where the record is, where it lives, the locations it can be moved to and
+// where it's been
+func (h *Handler) recordLocationData(ctx context.Context, recordID int64) (map[string]interface{}, error) {
+	record, err := h.queries.GetRecord(ctx, recordID)
+	if err != nil {
+		return nil, err
+	}
+	position, err := h.shelfPosition(ctx, recordID)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := h.locationOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := h.recordMovements(ctx, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	var home string
+	if record.HomeLocationID.Valid {
+		if home, err = h.queries.GetLocationPath(ctx, record.HomeLocationID.Int64); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]interface{}{
+		"RecordID":  recordID,
+		"Position":  position,
+		"Locations": locations,
+		"Movements": movements,
+		"Home":      home,
+		"AtHome":    record.HomeLocationID.Valid && record.HomeLocationID == record.CurrentLocationID,
+	}, nil
+}
+
+// renderRecordLocation renders the record page's location section after a
+// move, with the movement history swapped in alongside it
+func (h *Handler) renderRecordLocation(w http.ResponseWriter, r *http.Request, recordID int64, warning string) {
+	data, err := h.recordLocationData(r.Context(), recordID)
+	if err != nil {
+		h.logger.Error("Failed to retrieve record location", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+		http.Error(w, "Failed to retrieve record location", http.StatusInternalServerError)
+		return
+	}
+	data["Warning"] = warning
+	data["Moved"] = true
+	h.renderer.Render(w, "record-location", data)
+}
+
+func shelfErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errLocationNotFound), errors.Is(err, errRecordNotOnShelf):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Not found"
+	default:
+		return http.StatusInternalServerError, "Failed to update shelf"
+	}
+}
+
+// HTML Handlers
+
+// PUT /locations/{id}/shelf
+func (h *Handler) ReorderShelf() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locationID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req ReorderShelfRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.reorderShelf(r.Context(), locationID, req); err != nil {
+			status, message := shelfErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to reorder shelf", slog.String("error", err.Error()), slog.Int64("locationID", locationID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		shelf, err := h.shelf(r.Context(), locationID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve shelf", slog.String("error", err.Error()), slog.Int64("locationID", locationID))
+			http.Error(w, "Failed to retrieve shelf", http.StatusInternalServerError)
+			return
+		}
+		h.renderer.Render(w, "location-shelf", shelf)
+	}
+}
+
+// PUT /records/{id}/location
+func (h *Handler) UpdateRecordLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateRecordLocationRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.updateRecordLocation(r.Context(), recordID, req)
+		if err != nil {
+			status, message := shelfErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to move record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderRecordLocation(w, r, recordID, resp.Warning)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/locations/{id}/shelf
+func (h *Handler) JsonGetShelf() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locationID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		shelf, err := h.shelf(r.Context(), locationID)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeErrorJSON(w, "Location not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to retrieve shelf", slog.String("error", err.Error()), slog.Int64("locationID", locationID))
+			h.writeErrorJSON(w, "Failed to retrieve shelf", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, shelf, http.StatusOK)
+	}
+}
+
+// PUT /api/v1/locations/{id}/shelf
+func (h *Handler) JsonReorderShelf() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locationID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req ReorderShelfRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.reorderShelf(r.Context(), locationID, req); err != nil {
+			status, message := shelfErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to reorder shelf", slog.String("error", err.Error()), slog.Int64("locationID", locationID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		shelf, err := h.shelf(r.Context(), locationID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve shelf", slog.String("error", err.Error()), slog.Int64("locationID", locationID))
+			h.writeErrorJSON(w, "Failed to retrieve shelf", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, shelf, http.StatusOK)
+	}
+}
+
+// PUT /api/v1/records/{id}/location
+func (h *Handler) JsonUpdateRecordLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateRecordLocationRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := h.updateRecordLocation(r.Context(), recordID, req)
+		if err != nil {
+			status, message := shelfErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to move record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, resp, http.StatusOK)
+	}
+}
+
+// GET /api/v1/records/{id}/position
+func (h *Handler) JsonGetRecordPosition() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		position, err := h.shelfPosition(r.Context(), recordID)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to retrieve record position", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve record position", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, position, http.StatusOK)
+	}
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/dd/data/sql/migrations"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+// TestShelf_Placement tests that records are shelved alphabetically as they
+// arrive, can be put in any order, and that a record's position is described
+// with its neighbours
+func TestShelf_Placement(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	crate, err := h.createLocation(ctx, CreateLocationRequest{Name: "Crate", Capacity: 3})
+	if err != nil {
+		t.Fatalf("createLocation() error = %v", err)
+	}
+	def, err := queries.GetDefaultLocation(ctx)
+	if err != nil {
+		t.Fatalf("GetDefaultLocation() error = %v", err)
+	}
+
+	ids := map[string]int64{}
+	for _, add := range []struct {
+		title, artist string
+		locationID    int64
+	}{
+		{"Blue", "Joni Mitchell", crate.ID},
+		{"Abbey Road", "The Beatles", crate.ID},
+		{"Arrival", "ABBA", crate.ID},
+		{"Rumours", "Fleetwood Mac", def.ID},
+	} {
+		record, err := h.createRecord(ctx, CreateRecordRequest{Title: add.title, ArtistName: add.artist, CurrentLocationID: add.locationID})
+		if err != nil {
+			t.Fatalf("createRecord(%q) error = %v", add.title, err)
+		}
+		ids[add.title] = record.ID
+	}
+
+	order := func() string {
+		t.Helper()
+		shelf, err := h.shelf(ctx, crate.ID)
+		if err != nil {
+			t.Fatalf("shelf() error = %v", err)
+		}
+		var titles []string
+		for _, record := range shelf.Records {
+			titles = append(titles, record.Title)
+		}
+		return strings.Join(titles, ", ")
+	}
+	if got := order(); got != "Arrival, Abbey Road, Blue" {
+		t.Errorf("shelf = %s, want Arrival, Abbey Road, Blue", got)
+	}
+	if shelf, _ := h.shelf(ctx, crate.ID); shelf.Warning != "Crate is full (3 of 3)" {
+		t.Errorf("warning = %q, want Crate is full (3 of 3)", shelf.Warning)
+	}
+
+	// Moving a record in puts it in alphabetically and warns that it's over
+	moved, err := h.updateRecordLocation(ctx, ids["Rumours"], UpdateRecordLocationRequest{CurrentLocationID: crate.ID})
+	if err != nil {
+		t.Fatalf("updateRecordLocation() error = %v", err)
+	}
+	if want := "Crate, position 3 of 4, between The Beatles – Abbey Road and Joni Mitchell – Blue"; moved.Position.Summary != want {
+		t.Errorf("summary = %q, want %q", moved.Position.Summary, want)
+	}
+	if moved.Warning != "Crate is over capacity (4 of 3)" {
+		t.Errorf("warning = %q, want Crate is over capacity (4 of 3)", moved.Warning)
+	}
+
+	if err := h.reorderShelf(ctx, crate.ID, ReorderShelfRequest{RecordIDs: []int64{ids["Blue"], ids["Arrival"]}}); err != nil {
+		t.Fatalf("reorderShelf() error = %v", err)
+	}
+	if got := order(); got != "Blue, Arrival, Abbey Road, Rumours" {
+		t.Errorf("reordered shelf = %s, want Blue, Arrival, Abbey Road, Rumours", got)
+	}
+	first, err := h.shelfPosition(ctx, ids["Blue"])
+	if err != nil || first.Summary != "Crate, position 1 of 4, first, before ABBA – Arrival" {
+		t.Errorf("shelfPosition(Blue) = %q, %v", first.Summary, err)
+	}
+
+	_, unknownLocation := h.updateRecordLocation(ctx, ids["Blue"], UpdateRecordLocationRequest{CurrentLocationID: crate.ID + 100})
+	_, unknownRecord := h.updateRecordLocation(ctx, ids["Blue"]+100, UpdateRecordLocationRequest{CurrentLocationID: crate.ID})
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"reorder a record from elsewhere", h.reorderShelf(ctx, def.ID, ReorderShelfRequest{RecordIDs: []int64{ids["Blue"]}}), errRecordNotOnShelf},
+		{"reorder an unknown location", h.reorderShelf(ctx, crate.ID+100, ReorderShelfRequest{RecordIDs: []int64{ids["Blue"]}}), sql.ErrNoRows},
+		{"move to an unknown location", unknownLocation, errLocationNotFound},
+		{"move an unknown record", unknownRecord, sql.ErrNoRows},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+}
+
+// TestShelf_LoanReturn tests that a record coming back from a loan is put
+// back in alphabetically rather than at the end
+func TestShelf_LoanReturn(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	crate, err := h.createLocation(ctx, CreateLocationRequest{Name: "Crate"})
+	if err != nil {
+		t.Fatalf("createLocation() error = %v", err)
+	}
+	var lent int64
+	for _, title := range []string{"Aja", "Kind of Blue", "Rumours"} {
+		record, err := h.createRecord(ctx, CreateRecordRequest{Title: title, CurrentLocationID: crate.ID, HomeLocationID: crate.ID})
+		if err != nil {
+			t.Fatalf("createRecord(%q) error = %v", title, err)
+		}
+		if title == "Kind of Blue" {
+			lent = record.ID
+		}
+	}
+
+	now := time.Now()
+	if _, err := h.lendRecord(ctx, lent, LendRequest{BorrowerName: "Sam"}, now); err != nil {
+		t.Fatalf("lendRecord() error = %v", err)
+	}
+	if pos, _ := h.shelfPosition(ctx, lent); pos.Path != "On loan" || pos.Position != 1 {
+		t.Errorf("lent position = %+v, want On loan", pos)
+	}
+	if _, err := h.returnRecord(ctx, lent, now); err != nil {
+		t.Fatalf("returnRecord() error = %v", err)
+	}
+
+	pos, err := h.shelfPosition(ctx, lent)
+	if err != nil {
+		t.Fatalf("shelfPosition() error = %v", err)
+	}
+	if want := "Crate, position 2 of 3, between Aja and Rumours"; pos.Summary != want {
+		t.Errorf("summary = %q, want %q", pos.Summary, want)
+	}
+}
+
+// TestShelf_Backfill tests that the migration adding shelf positions shelves
+// existing records alphabetically without touching when they were last
+// edited
+func TestShelf_Backfill(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
+	if err != nil {
+		t.Fatalf("Failed to create migration provider: %v", err)
+	}
+	if _, err := provider.UpTo(ctx, 20261017000000); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO records (title, current_location_id, created_at, updated_at)
+		VALUES ('Rumours', 1, '2020-01-01 00:00:00', '2021-06-01 00:00:00'),
+		       ('Aja', 1, '2020-01-01 00:00:00', '2021-06-01 00:00:00')`)
+	if err != nil {
+		t.Fatalf("Failed to insert records: %v", err)
+	}
+	if _, err := provider.UpTo(ctx, 20261017010000); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	rows, err := db.Query(`SELECT title, shelf_position, updated_at FROM records ORDER BY shelf_position`)
+	if err != nil {
+		t.Fatalf("Failed to read records: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var title string
+		var position int64
+		var updated time.Time
+		if err := rows.Scan(&title, &position, &updated); err != nil {
+			t.Fatalf("Failed to scan record: %v", err)
+		}
+		got = append(got, title)
+		if want := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC); !updated.Equal(want) {
+			t.Errorf("%s updated_at = %v, want %v", title, updated, want)
+		}
+	}
+	if strings.Join(got, ", ") != "Aja, Rumours" {
+		t.Errorf("shelf = %v, want Aja, Rumours", got)
+	}
+}
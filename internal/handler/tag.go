@@ -0,0 +1,721 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// tagKinds are the kinds a tag can have, in the order the tags page lists them
+var tagKinds = []string{"genre", "style", "mood", "custom"}
+
+// maxTagSuggestions caps autocomplete results
+const maxTagSuggestions = 10
+
+var (
+	// errDuplicateTag is returned when a tag with the same name and kind exists
+	errDuplicateTag = errors.New("a tag with that name and kind already exists; merge them instead")
+	// errMergeIntoSelf is returned when a tag is merged into itself
+	errMergeIntoSelf = errors.New("cannot merge a tag into itself")
+)
+
+// TagRequest is the body for creating or renaming a tag. Kind defaults to
+// custom.
+type TagRequest struct {
+	Name string `form:"name" json:"name" validate:"required,notblank,max=50"`
+	Kind string `form:"kind" json:"kind" validate:"omitempty,oneof=genre style mood custom"`
+}
+
+// kind returns the requested kind, or custom
+func (req TagRequest) kind() string {
+	if req.Kind == "" {
+		return "custom"
+	}
+	return req.Kind
+}
+
+// MergeTagRequest moves every record from the tag in the path onto IntoID and
+// deletes the old tag
+type MergeTagRequest struct {
+	IntoID int64 `form:"into_id" json:"into_id" validate:"required,min=1"`
+}
+
+// RecordTagRequest tags a record with an existing tag by id, or by name. A
+// name that doesn't exist yet creates the tag. Without a kind, a name matches
+// an existing tag of any kind.
+type RecordTagRequest struct {
+	TagID int64  `form:"tag_id" json:"tag_id" validate:"required_without=Name,omitempty,min=1"`
+	Name  string `form:"name" json:"name" validate:"required_without=TagID,omitempty,notblank,max=50"`
+	Kind  string `form:"kind" json:"kind" validate:"omitempty,oneof=genre style mood custom"`
+}
+
+// TagGroup is the tags of one kind
+type TagGroup struct {
+	Kind string              `json:"kind"`
+	Tags []store.ListTagsRow `json:"tags"`
+}
+
+// createTag creates a tag, mapping a name clash to errDuplicateTag
+func (h *Handler) createTag(ctx context.Context, req TagRequest) (store.Tag, error) {
+	tag, err := h.queries.CreateTag(ctx, store.CreateTagParams{
+		Name: strings.TrimSpace(req.Name),
+		Kind: req.kind(),
+	})
+	if isUniqueViolation(err) {
+		return store.Tag{}, errDuplicateTag
+	}
+	return tag, err
+}
+
+// updateTag renames a tag or changes its kind. Returns sql.ErrNoRows if the
+// tag doesn't exist.
+func (h *Handler) updateTag(ctx context.Context, tagID int64, req TagRequest) (store.Tag, error) {
+	tag, err := h.queries.UpdateTag(ctx, store.UpdateTagParams{
+		Name: strings.TrimSpace(req.Name),
+		Kind: req.kind(),
+		ID:   tagID,
+	})
+	if isUniqueViolation(err) {
+		return store.Tag{}, errDuplicateTag
+	}
+	return tag, err
+}
+
+// mergeTag moves every record tagged fromID onto intoID and deletes fromID,
+// in one transaction. Returns sql.ErrNoRows if either tag doesn't exist.
+func (h *Handler) mergeTag(ctx context.Context, fromID, intoID int64) (store.Tag, error) {
+	if fromID == intoID {
+		return store.Tag{}, errMergeIntoSelf
+	}
+
+	var into store.Tag
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		if _, err := q.GetTag(ctx, fromID); err != nil {
+			return err
+		}
+
+		var err error
+		into, err = q.GetTag(ctx, intoID)
+		if err != nil {
+			return err
+		}
+
+		if err := q.MoveRecordTags(ctx, store.MoveRecordTagsParams{IntoID: intoID, FromID: fromID}); err != nil {
+			return err
+		}
+
+		_, err = q.DeleteTag(ctx, fromID)
+		return err
+	})
+	return into, err
+}
+
+// tagRecord adds a tag to a record, finding or creating the tag by name when
+// no id is given. Returns sql.ErrNoRows if the record or tag id doesn't exist.
+func (h *Handler) tagRecord(ctx context.Context, recordID int64, req RecordTagRequest) (store.Tag, error) {
+	var tag store.Tag
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		if _, err := q.GetRecord(ctx, recordID); err != nil {
+			return err
+		}
+
+		var err error
+		name := strings.TrimSpace(req.Name)
+		switch {
+		case req.TagID > 0:
+			tag, err = q.GetTag(ctx, req.TagID)
+		case req.Kind == "":
+			tag, err = q.FindTagByName(ctx, name)
+		default:
+			tag, err = q.GetTagByName(ctx, store.GetTagByNameParams{Kind: req.Kind, Name: name})
+		}
+		if errors.Is(err, sql.ErrNoRows) && req.TagID == 0 {
+			tag, err = q.CreateTag(ctx, store.CreateTagParams{Name: name, Kind: TagRequest{Kind: req.Kind}.kind()})
+		}
+		if err != nil {
+			return err
+		}
+
+		return q.AddRecordTag(ctx, store.AddRecordTagParams{RecordID: recordID, TagID: tag.ID})
+	})
+	return tag, err
+}
+
+// tagGroups lists every tag grouped by kind. Kinds without tags are left out.
+func (h *Handler) tagGroups(ctx context.Context) ([]TagGroup, error) {
+	tags, err := h.queries.ListTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []TagGroup{}
+	for _, kind := range tagKinds {
+		var group []store.ListTagsRow
+		for _, tag := range tags {
+			if tag.Kind == kind {
+				group = append(group, tag)
+			}
+		}
+		if len(group) > 0 {
+			groups = append(groups, TagGroup{Kind: kind, Tags: group})
+		}
+	}
+	return groups, nil
+}
+
+// tagErrorStatus maps an error from saving a tag to a status and message
+func tagErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errMergeIntoSelf):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, errDuplicateTag):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Tag not found"
+	default:
+		return http.StatusInternalServerError, "Failed to save tag"
+	}
+}
+
+func pathTagID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(r.PathValue("tagID"), 10, 64)
+}
+
+// renderTagsList renders the tags page body: every tag grouped by kind with
+// its rename, merge and delete controls
+func (h *Handler) renderTagsList(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.tagGroups(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to retrieve tags", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve tags", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderer.Render(w, "tags-list", map[string]interface{}{
+		"Groups": groups,
+		"Kinds":  tagKinds,
+	})
+}
+
+// renderRecordTags renders the tag chips partial for a record
+func (h *Handler) renderRecordTags(w http.ResponseWriter, r *http.Request, recordID int64) {
+	tags, err := h.queries.ListTagsByRecord(r.Context(), recordID)
+	if err != nil {
+		h.logger.Error("Failed to retrieve record tags", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+		http.Error(w, "Failed to retrieve tags", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderer.Render(w, "record-tags", map[string]interface{}{
+		"RecordID": recordID,
+		"Tags":     tags,
+		"Kinds":    tagKinds,
+	})
+}
+
+// HTML Handlers
+
+// GET /tags
+func (h *Handler) GetTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groups, err := h.tagGroups(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve tags", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve tags", http.StatusInternalServerError)
+			return
+		}
+
+		err = h.renderer.Render(w, "tags", map[string]interface{}{
+			"Title":  "Tags",
+			"Groups": groups,
+			"Kinds":  tagKinds,
+		})
+		if err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// GET /tags/autocomplete?name=
+// Takes "name" rather than "q" so the record tag input can request it directly
+func (h *Handler) GetTagSuggestions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimSpace(r.URL.Query().Get("name"))
+		var tags []store.Tag
+		if prefix != "" {
+			var err error
+			tags, err = h.queries.SearchTagsByPrefix(r.Context(), store.SearchTagsByPrefixParams{
+				Prefix: prefix,
+				Limit:  maxTagSuggestions,
+			})
+			if err != nil {
+				h.logger.Error("Failed to search tags", slog.String("error", err.Error()))
+				http.Error(w, "Failed to search tags", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		h.renderer.Render(w, "tag-suggestions", tags)
+	}
+}
+
+// POST /tags
+func (h *Handler) CreateTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TagRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.createTag(r.Context(), req); err != nil {
+			status, message := tagErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to create tag", slog.String("error", err.Error()), slog.String("name", req.Name))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderTagsList(w, r)
+	}
+}
+
+// PUT /tags/{id}
+func (h *Handler) UpdateTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tagID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req TagRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.updateTag(r.Context(), tagID, req); err != nil {
+			status, message := tagErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update tag", slog.String("error", err.Error()), slog.Int64("tagID", tagID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderTagsList(w, r)
+	}
+}
+
+// POST /tags/{id}/merge
+func (h *Handler) MergeTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tagID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req MergeTagRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.mergeTag(r.Context(), tagID, req.IntoID); err != nil {
+			status, message := tagErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to merge tags", slog.String("error", err.Error()), slog.Int64("tagID", tagID), slog.Int64("intoID", req.IntoID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderTagsList(w, r)
+	}
+}
+
+// DELETE /tags/{id}
+func (h *Handler) DeleteTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tagID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := h.queries.DeleteTag(r.Context(), tagID)
+		if err != nil {
+			h.logger.Error("Failed to delete tag", slog.String("error", err.Error()), slog.Int64("tagID", tagID))
+			http.Error(w, "Failed to delete tag", http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			http.Error(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+
+		h.renderTagsList(w, r)
+	}
+}
+
+// POST /records/{id}/tags
+func (h *Handler) CreateRecordTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req RecordTagRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.tagRecord(r.Context(), recordID, req); err != nil {
+			status, message := tagErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to tag record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			if status == http.StatusNotFound {
+				message = "Record or tag not found"
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderRecordTags(w, r, recordID)
+	}
+}
+
+// DELETE /records/{id}/tags/{tagID}
+func (h *Handler) DeleteRecordTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		tagID, err := pathTagID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: tagID", http.StatusBadRequest)
+			return
+		}
+
+		removed, err := h.queries.RemoveRecordTag(r.Context(), store.RemoveRecordTagParams{RecordID: recordID, TagID: tagID})
+		if err != nil {
+			h.logger.Error("Failed to remove tag", slog.String("error", err.Error()), slog.Int64("recordID", recordID), slog.Int64("tagID", tagID))
+			http.Error(w, "Failed to remove tag", http.StatusInternalServerError)
+			return
+		}
+		if removed == 0 {
+			http.Error(w, "Tag not found on record", http.StatusNotFound)
+			return
+		}
+
+		h.renderRecordTags(w, r, recordID)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/tags
+// With ?q= only tags starting with q are returned, for autocomplete
+func (h *Handler) JsonGetTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if prefix := strings.TrimSpace(r.URL.Query().Get("q")); prefix != "" {
+			tags, err := h.queries.SearchTagsByPrefix(r.Context(), store.SearchTagsByPrefixParams{
+				Prefix: prefix,
+				Limit:  maxTagSuggestions,
+			})
+			if err != nil {
+				h.logger.Error("Failed to search tags", slog.String("error", err.Error()))
+				h.writeErrorJSON(w, "Failed to search tags", http.StatusInternalServerError)
+				return
+			}
+			if tags == nil {
+				tags = []store.Tag{}
+			}
+			h.writeJSON(w, tags, http.StatusOK)
+			return
+		}
+
+		tags, err := h.queries.ListTags(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to retrieve tags", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to retrieve tags", http.StatusInternalServerError)
+			return
+		}
+		if tags == nil {
+			tags = []store.ListTagsRow{}
+		}
+
+		h.writeJSON(w, tags, http.StatusOK)
+	}
+}
+
+// POST /api/v1/tags
+func (h *Handler) JsonCreateTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TagRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tag, err := h.createTag(r.Context(), req)
+		if err != nil {
+			status, message := tagErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to create tag", slog.String("error", err.Error()), slog.String("name", req.Name))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, tag, http.StatusCreated)
+	}
+}
+
+// GET /api/v1/tags/{id}
+func (h *Handler) JsonGetTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tagID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		tag, err := h.queries.GetTag(r.Context(), tagID)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeErrorJSON(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to retrieve tag", slog.String("error", err.Error()), slog.Int64("tagID", tagID))
+			h.writeErrorJSON(w, "Failed to retrieve tag", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, tag, http.StatusOK)
+	}
+}
+
+// PUT /api/v1/tags/{id}
+func (h *Handler) JsonUpdateTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tagID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req TagRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tag, err := h.updateTag(r.Context(), tagID, req)
+		if err != nil {
+			status, message := tagErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update tag", slog.String("error", err.Error()), slog.Int64("tagID", tagID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, tag, http.StatusOK)
+	}
+}
+
+// POST /api/v1/tags/{id}/merge
+func (h *Handler) JsonMergeTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tagID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req MergeTagRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tag, err := h.mergeTag(r.Context(), tagID, req.IntoID)
+		if err != nil {
+			status, message := tagErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to merge tags", slog.String("error", err.Error()), slog.Int64("tagID", tagID), slog.Int64("intoID", req.IntoID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, tag, http.StatusOK)
+	}
+}
+
+// DELETE /api/v1/tags/{id}
+func (h *Handler) JsonDeleteTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tagID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := h.queries.DeleteTag(r.Context(), tagID)
+		if err != nil {
+			h.logger.Error("Failed to delete tag", slog.String("error", err.Error()), slog.Int64("tagID", tagID))
+			h.writeErrorJSON(w, "Failed to delete tag", http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			h.writeErrorJSON(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GET /api/v1/records/{id}/tags
+func (h *Handler) JsonGetRecordTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		tags, err := h.queries.ListTagsByRecord(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve record tags", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve tags", http.StatusInternalServerError)
+			return
+		}
+		if tags == nil {
+			tags = []store.Tag{}
+		}
+
+		h.writeJSON(w, tags, http.StatusOK)
+	}
+}
+
+// POST /api/v1/records/{id}/tags
+func (h *Handler) JsonCreateRecordTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req RecordTagRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tag, err := h.tagRecord(r.Context(), recordID, req)
+		if err != nil {
+			status, message := tagErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to tag record", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			if status == http.StatusNotFound {
+				message = "Record or tag not found"
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, tag, http.StatusCreated)
+	}
+}
+
+// DELETE /api/v1/records/{id}/tags/{tagID}
+func (h *Handler) JsonDeleteRecordTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		tagID, err := pathTagID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: tagID", http.StatusBadRequest)
+			return
+		}
+
+		removed, err := h.queries.RemoveRecordTag(r.Context(), store.RemoveRecordTagParams{RecordID: recordID, TagID: tagID})
+		if err != nil {
+			h.logger.Error("Failed to remove tag", slog.String("error", err.Error()), slog.Int64("recordID", recordID), slog.Int64("tagID", tagID))
+			h.writeErrorJSON(w, "Failed to remove tag", http.StatusInternalServerError)
+			return
+		}
+		if removed == 0 {
+			h.writeErrorJSON(w, "Tag not found on record", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
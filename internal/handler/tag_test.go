@@ -0,0 +1,322 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// TestTags_CreateRenameMerge tests duplicate names, renames and merging one
+// tag into another
+func TestTags_CreateRenameMerge(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	jazz, err := h.createTag(ctx, TagRequest{Name: "Jazz", Kind: "genre"})
+	if err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	if _, err := h.createTag(ctx, TagRequest{Name: " jazz ", Kind: "genre"}); !errors.Is(err, errDuplicateTag) {
+		t.Errorf("duplicate tag error = %v, want errDuplicateTag", err)
+	}
+	// The same name is fine as a different kind
+	custom, err := h.createTag(ctx, TagRequest{Name: "Jazz"})
+	if err != nil {
+		t.Fatalf("createTag() custom error = %v", err)
+	}
+	if custom.Kind != "custom" {
+		t.Errorf("default kind = %q, want custom", custom.Kind)
+	}
+	if _, err := queries.CreateTag(ctx, store.CreateTagParams{Name: "", Kind: "custom"}); err == nil {
+		t.Error("CreateTag() with an empty name succeeded, want the CHECK constraint to reject it")
+	}
+
+	if _, err := h.updateTag(ctx, custom.ID, TagRequest{Name: "JAZZ", Kind: "genre"}); !errors.Is(err, errDuplicateTag) {
+		t.Errorf("rename into clash error = %v, want errDuplicateTag", err)
+	}
+	renamed, err := h.updateTag(ctx, custom.ID, TagRequest{Name: "Jazzy", Kind: "mood"})
+	if err != nil {
+		t.Fatalf("updateTag() error = %v", err)
+	}
+	if renamed.Name != "Jazzy" || renamed.Kind != "mood" {
+		t.Errorf("renamed tag = %+v", renamed)
+	}
+	if _, err := h.updateTag(ctx, renamed.ID+100, TagRequest{Name: "Gone"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("rename unknown tag error = %v, want sql.ErrNoRows", err)
+	}
+
+	both, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Kind of Blue"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+	onlyJazzy, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Blue Train"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+	for _, link := range []store.AddRecordTagParams{
+		{RecordID: both.ID, TagID: jazz.ID},
+		{RecordID: both.ID, TagID: renamed.ID},
+		{RecordID: onlyJazzy.ID, TagID: renamed.ID},
+	} {
+		if err := queries.AddRecordTag(ctx, link); err != nil {
+			t.Fatalf("AddRecordTag() error = %v", err)
+		}
+	}
+
+	if _, err := h.mergeTag(ctx, jazz.ID, jazz.ID); !errors.Is(err, errMergeIntoSelf) {
+		t.Errorf("merge into self error = %v, want errMergeIntoSelf", err)
+	}
+	if _, err := h.mergeTag(ctx, renamed.ID, jazz.ID+100); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("merge into unknown tag error = %v, want sql.ErrNoRows", err)
+	}
+
+	into, err := h.mergeTag(ctx, renamed.ID, jazz.ID)
+	if err != nil {
+		t.Fatalf("mergeTag() error = %v", err)
+	}
+	if into.ID != jazz.ID {
+		t.Errorf("mergeTag() = %+v, want Jazz", into)
+	}
+	if _, err := queries.GetTag(ctx, renamed.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTag() after merge error = %v, want sql.ErrNoRows", err)
+	}
+
+	// A record that had both tags keeps a single link
+	for _, record := range []store.Record{both, onlyJazzy} {
+		tags, err := queries.ListTagsByRecord(ctx, record.ID)
+		if err != nil {
+			t.Fatalf("ListTagsByRecord() error = %v", err)
+		}
+		if len(tags) != 1 || tags[0].ID != jazz.ID {
+			t.Errorf("%s tags after merge = %v, want only Jazz", record.Title, tags)
+		}
+	}
+
+	counts, err := queries.ListTags(ctx)
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if len(counts) != 1 || counts[0].RecordCount != 2 {
+		t.Errorf("ListTags() = %+v, want Jazz on 2 records", counts)
+	}
+}
+
+// TestTagRecord tests tagging by id and by name, reusing or creating tags
+func TestTagRecord(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Loveless"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+	shoegaze, err := h.createTag(ctx, TagRequest{Name: "Shoegaze", Kind: "style"})
+	if err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+
+	// Without a kind, a name matches an existing tag of any kind
+	tag, err := h.tagRecord(ctx, record.ID, RecordTagRequest{Name: "shoegaze"})
+	if err != nil {
+		t.Fatalf("tagRecord() by name error = %v", err)
+	}
+	if tag.ID != shoegaze.ID {
+		t.Errorf("tagRecord() by name = %+v, want existing Shoegaze", tag)
+	}
+
+	// A new name creates the tag
+	dreamy, err := h.tagRecord(ctx, record.ID, RecordTagRequest{Name: "Dreamy", Kind: "mood"})
+	if err != nil {
+		t.Fatalf("tagRecord() new tag error = %v", err)
+	}
+	if dreamy.Name != "Dreamy" || dreamy.Kind != "mood" {
+		t.Errorf("created tag = %+v", dreamy)
+	}
+
+	// Tagging twice is a no-op
+	if _, err := h.tagRecord(ctx, record.ID, RecordTagRequest{TagID: dreamy.ID}); err != nil {
+		t.Errorf("tagRecord() again error = %v", err)
+	}
+
+	tags, err := queries.ListTagsByRecord(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("ListTagsByRecord() error = %v", err)
+	}
+	if len(tags) != 2 {
+		t.Errorf("record tags = %v, want 2", tags)
+	}
+
+	if _, err := h.tagRecord(ctx, record.ID, RecordTagRequest{TagID: dreamy.ID + 100}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unknown tag id error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := h.tagRecord(ctx, record.ID+100, RecordTagRequest{Name: "Noise"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unknown record error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := queries.FindTagByName(ctx, "Noise"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("tag created for unknown record, error = %v", err)
+	}
+
+	// Tags go with their record
+	if err := queries.DeleteRecord(ctx, record.ID); err != nil {
+		t.Fatalf("DeleteRecord() error = %v", err)
+	}
+	tags, err = queries.ListTagsByRecord(ctx, record.ID)
+	if err != nil || len(tags) != 0 {
+		t.Errorf("ListTagsByRecord() after delete = %v, %v, want none", tags, err)
+	}
+}
+
+// TestFilterRecords_Tags tests the any-of and all-of tag filters
+func TestFilterRecords_Tags(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	jazz, err := h.createTag(ctx, TagRequest{Name: "Jazz", Kind: "genre"})
+	if err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	modal, err := h.createTag(ctx, TagRequest{Name: "Modal", Kind: "style"})
+	if err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+	rock, err := h.createTag(ctx, TagRequest{Name: "Rock", Kind: "genre"})
+	if err != nil {
+		t.Fatalf("createTag() error = %v", err)
+	}
+
+	tagged := map[string][]int64{
+		"Kind of Blue": {jazz.ID, modal.ID},
+		"Blue Train":   {jazz.ID},
+		"Abbey Road":   {rock.ID},
+		"Untagged":     nil,
+	}
+	ids := map[string]int64{}
+	for title, tagIDs := range tagged {
+		record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: title})
+		if err != nil {
+			t.Fatalf("Failed to create record: %v", err)
+		}
+		ids[title] = record.ID
+		for _, tagID := range tagIDs {
+			if err := queries.AddRecordTag(ctx, store.AddRecordTagParams{RecordID: record.ID, TagID: tagID}); err != nil {
+				t.Fatalf("AddRecordTag() error = %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter store.RecordFilter
+		want   []string
+	}{
+		{"any of one", store.RecordFilter{AnyTagIDs: []int64{jazz.ID}}, []string{"Blue Train", "Kind of Blue"}},
+		{"any of two", store.RecordFilter{AnyTagIDs: []int64{modal.ID, rock.ID}}, []string{"Abbey Road", "Kind of Blue"}},
+		{"all of two", store.RecordFilter{AllTagIDs: []int64{jazz.ID, modal.ID}}, []string{"Kind of Blue"}},
+		{"all of with repeats", store.RecordFilter{AllTagIDs: []int64{jazz.ID, jazz.ID}}, []string{"Blue Train", "Kind of Blue"}},
+		{"all of unmatched", store.RecordFilter{AllTagIDs: []int64{jazz.ID, rock.ID}}, nil},
+		{"any and all", store.RecordFilter{AnyTagIDs: []int64{jazz.ID, rock.ID}, AllTagIDs: []int64{modal.ID}}, []string{"Kind of Blue"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := queries.FilterRecords(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("FilterRecords() error = %v", err)
+			}
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Title)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FilterRecords() = %v, want %v", got, tt.want)
+			}
+
+			count, err := queries.CountFilteredRecords(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("CountFilteredRecords() error = %v", err)
+			}
+			if count != int64(len(tt.want)) {
+				t.Errorf("CountFilteredRecords() = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+// TestListRecordsRequest_TagParams tests repeated and comma-separated tag ids
+func TestListRecordsRequest_TagParams(t *testing.T) {
+	h := &Handler{validate: validator.New()}
+
+	r := httptest.NewRequest("GET", "/records?tags_any=1&tags_any=2&tags_all=3,4,+5", nil)
+	var req ListRecordsRequest
+	if err := h.bindQuery(r, &req); err != nil {
+		t.Fatalf("bindQuery() error = %v", err)
+	}
+	if !slices.Equal(req.TagsAny, []int64{1, 2}) {
+		t.Errorf("TagsAny = %v, want [1 2]", req.TagsAny)
+	}
+	if !slices.Equal(req.TagsAll, []int64{3, 4, 5}) {
+		t.Errorf("TagsAll = %v, want [3 4 5]", req.TagsAll)
+	}
+
+	filter := req.toFilter()
+	if !slices.Equal(filter.AnyTagIDs, req.TagsAny) || !slices.Equal(filter.AllTagIDs, req.TagsAll) {
+		t.Errorf("toFilter() = %+v", filter)
+	}
+}
+
+// TestSearchTagsByPrefix tests that LIKE wildcards typed into the tag
+// autocomplete match only themselves
+func TestSearchTagsByPrefix(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, name := range []string{"Jazz", "Jazz-funk", "50% off", "lo_fi", "lofi", `back\slash`} {
+		if _, err := queries.CreateTag(ctx, store.CreateTagParams{Name: name, Kind: "custom"}); err != nil {
+			t.Fatalf("CreateTag(%q) error = %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"ja", []string{"Jazz", "Jazz-funk"}},
+		{"_", nil},
+		{"%", nil},
+		{"50%", []string{"50% off"}},
+		{"lo_", []string{"lo_fi"}},
+		{`back\`, []string{`back\slash`}},
+	}
+	for _, tt := range tests {
+		tags, err := queries.SearchTagsByPrefix(ctx, store.SearchTagsByPrefixParams{Prefix: tt.prefix, Limit: maxTagSuggestions})
+		if err != nil {
+			t.Fatalf("SearchTagsByPrefix(%q) error = %v", tt.prefix, err)
+		}
+		var got []string
+		for _, tag := range tags {
+			got = append(got, tag.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("SearchTagsByPrefix(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
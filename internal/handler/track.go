@@ -0,0 +1,401 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// errInvalidDuration is returned when a track duration isn't m:ss or h:mm:ss
+	errInvalidDuration = errors.New("duration must be m:ss or h:mm:ss")
+	// errTrackArtistNotFound is returned when the per-track artist doesn't exist
+	errTrackArtistNotFound = errors.New("artist not found")
+	// errDuplicatePosition is returned when the record already has a track at
+	// the requested position
+	errDuplicatePosition = errors.New("record already has a track at that position")
+)
+
+// TrackRequest is the body for adding or editing a track. Side defaults to the
+// letters at the start of Position, so "B3" is on side B.
+type TrackRequest struct {
+	Side     string `form:"side" json:"side" validate:"omitempty,max=10,alphanum"`
+	Position string `form:"position" json:"position" validate:"required,max=10,alphanum"`
+	Title    string `form:"title" json:"title" validate:"required,min=1,max=200"`
+	Duration string `form:"duration" json:"duration" validate:"omitempty,max=8"`
+	ArtistID int64  `form:"artist_id" json:"artist_id" validate:"omitempty,min=1"`
+}
+
+// side returns the requested side, or the side implied by the position
+func (req TrackRequest) side() string {
+	if req.Side != "" {
+		return strings.ToUpper(req.Side)
+	}
+	position := strings.ToUpper(req.Position)
+	end := strings.IndexFunc(position, func(r rune) bool { return r < 'A' || r > 'Z' })
+	if end == -1 {
+		return position
+	}
+	return position[:end]
+}
+
+// parseDuration converts "m:ss" or "h:mm:ss" to seconds
+func parseDuration(s string) (int64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, errInvalidDuration
+	}
+
+	var seconds int64
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || n < 0 {
+			return 0, errInvalidDuration
+		}
+		// Everything after the leading field is base 60
+		if i > 0 && (n >= 60 || len(part) != 2) {
+			return 0, errInvalidDuration
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, nil
+}
+
+// TracklistResponse is a record's tracks in play order with running times
+// per side and in total. Durations are in seconds.
+type TracklistResponse struct {
+	Tracks      []store.ListTracksByRecordRow           `json:"tracks"`
+	Sides       []store.ListSideRunningTimesByRecordRow `json:"sides"`
+	TrackCount  int64                                   `json:"track_count"`
+	RunningTime int64                                   `json:"running_time"`
+}
+
+// tracklist loads a record's tracks and running times
+func (h *Handler) tracklist(ctx context.Context, recordID int64) (TracklistResponse, error) {
+	tracks, err := h.queries.ListTracksByRecord(ctx, recordID)
+	if err != nil {
+		return TracklistResponse{}, err
+	}
+	if tracks == nil {
+		tracks = []store.ListTracksByRecordRow{}
+	}
+
+	sides, err := h.queries.ListSideRunningTimesByRecord(ctx, recordID)
+	if err != nil {
+		return TracklistResponse{}, err
+	}
+	if sides == nil {
+		sides = []store.ListSideRunningTimesByRecordRow{}
+	}
+
+	result := TracklistResponse{Tracks: tracks, Sides: sides}
+	for _, side := range sides {
+		result.TrackCount += side.TrackCount
+		result.RunningTime += side.RunningTime
+	}
+	return result, nil
+}
+
+// saveTrack adds a track to the record, or updates trackID if it is non-zero.
+// Returns sql.ErrNoRows if the track isn't on the record.
+func (h *Handler) saveTrack(ctx context.Context, recordID, trackID int64, req TrackRequest) (store.Track, error) {
+	var duration sql.NullInt64
+	if req.Duration != "" {
+		seconds, err := parseDuration(req.Duration)
+		if err != nil {
+			return store.Track{}, err
+		}
+		duration = sql.NullInt64{Int64: seconds, Valid: true}
+	}
+
+	if req.ArtistID > 0 {
+		if _, err := h.queries.GetArtist(ctx, req.ArtistID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.Track{}, errTrackArtistNotFound
+			}
+			return store.Track{}, err
+		}
+	}
+
+	side := req.side()
+	params := store.UpdateTrackParams{
+		Side:            sql.NullString{String: side, Valid: side != ""},
+		Position:        strings.ToUpper(req.Position),
+		Title:           req.Title,
+		DurationSeconds: duration,
+		ArtistID:        sql.NullInt64{Int64: req.ArtistID, Valid: req.ArtistID > 0},
+		ID:              trackID,
+		RecordID:        recordID,
+	}
+
+	var track store.Track
+	var err error
+	if trackID == 0 {
+		track, err = h.queries.CreateTrack(ctx, store.CreateTrackParams{
+			RecordID:        params.RecordID,
+			Side:            params.Side,
+			Position:        params.Position,
+			Title:           params.Title,
+			DurationSeconds: params.DurationSeconds,
+			ArtistID:        params.ArtistID,
+		})
+	} else {
+		track, err = h.queries.UpdateTrack(ctx, params)
+	}
+	if isUniqueViolation(err) {
+		return store.Track{}, errDuplicatePosition
+	}
+	return track, err
+}
+
+// trackErrorStatus maps saveTrack errors to an HTTP status and message
+func trackErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errInvalidDuration), errors.Is(err, errTrackArtistNotFound):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, errDuplicatePosition):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Track not found"
+	default:
+		return http.StatusInternalServerError, "Failed to save track"
+	}
+}
+
+// pathTrackID parses the {trackID} path value
+func pathTrackID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(r.PathValue("trackID"), 10, 64)
+}
+
+// renderTracklist renders the editable tracklist partial for a record
+func (h *Handler) renderTracklist(w http.ResponseWriter, r *http.Request, recordID int64) {
+	tracklist, err := h.tracklist(r.Context(), recordID)
+	if err != nil {
+		h.logger.Error("Failed to retrieve tracks", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+		http.Error(w, "Failed to retrieve tracks", http.StatusInternalServerError)
+		return
+	}
+
+	artists, err := h.queries.ListArtists(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to retrieve artists", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve artists", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderer.Render(w, "record-tracklist", map[string]interface{}{
+		"RecordID":  recordID,
+		"Tracklist": tracklist,
+		"Artists":   artists,
+	})
+}
+
+// HTML Handlers
+
+// POST /records/{id}/tracks
+func (h *Handler) CreateTrack() http.HandlerFunc {
+	return h.handleSaveTrack(false)
+}
+
+// PUT /records/{id}/tracks/{trackID}
+func (h *Handler) UpdateTrack() http.HandlerFunc {
+	return h.handleSaveTrack(true)
+}
+
+// handleSaveTrack serves the tracklist add and edit forms
+func (h *Handler) handleSaveTrack(update bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var trackID int64
+		if update {
+			if trackID, err = pathTrackID(r); err != nil {
+				http.Error(w, "Invalid parameter: trackID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var req TrackRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			http.Error(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		if _, err := h.saveTrack(r.Context(), recordID, trackID, req); err != nil {
+			status, message := trackErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to save track", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderTracklist(w, r, recordID)
+	}
+}
+
+// DELETE /records/{id}/tracks/{trackID}
+func (h *Handler) DeleteTrack() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		trackID, err := pathTrackID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: trackID", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := h.queries.DeleteTrack(r.Context(), store.DeleteTrackParams{ID: trackID, RecordID: recordID})
+		if err != nil {
+			h.logger.Error("Failed to delete track", slog.String("error", err.Error()), slog.Int64("trackID", trackID))
+			http.Error(w, "Failed to delete track", http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			http.Error(w, "Track not found", http.StatusNotFound)
+			return
+		}
+
+		h.renderTracklist(w, r, recordID)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/records/{id}/tracks
+func (h *Handler) JsonGetTracks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		result, err := h.tracklist(r.Context(), recordID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve tracks", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			h.writeErrorJSON(w, "Failed to retrieve tracks", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, result, http.StatusOK)
+	}
+}
+
+// POST /api/v1/records/{id}/tracks
+func (h *Handler) JsonCreateTrack() http.HandlerFunc {
+	return h.handleJsonSaveTrack(false)
+}
+
+// PUT /api/v1/records/{id}/tracks/{trackID}
+func (h *Handler) JsonUpdateTrack() http.HandlerFunc {
+	return h.handleJsonSaveTrack(true)
+}
+
+// handleJsonSaveTrack serves the API track create and update endpoints
+func (h *Handler) handleJsonSaveTrack(update bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var trackID int64
+		if update {
+			if trackID, err = pathTrackID(r); err != nil {
+				h.writeErrorJSON(w, "Invalid parameter: trackID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var req TrackRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.GetRecord(r.Context(), recordID); err != nil {
+			h.writeErrorJSON(w, "Record not found", http.StatusNotFound)
+			return
+		}
+
+		track, err := h.saveTrack(r.Context(), recordID, trackID, req)
+		if err != nil {
+			status, message := trackErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to save track", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		status := http.StatusOK
+		if !update {
+			status = http.StatusCreated
+		}
+		h.writeJSON(w, track, status)
+	}
+}
+
+// DELETE /api/v1/records/{id}/tracks/{trackID}
+func (h *Handler) JsonDeleteTrack() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		trackID, err := pathTrackID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: trackID", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := h.queries.DeleteTrack(r.Context(), store.DeleteTrackParams{ID: trackID, RecordID: recordID})
+		if err != nil {
+			h.logger.Error("Failed to delete track", slog.String("error", err.Error()), slog.Int64("trackID", trackID))
+			h.writeErrorJSON(w, "Failed to delete track", http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			h.writeErrorJSON(w, "Track not found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
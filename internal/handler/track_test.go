@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// TestParseDuration tests m:ss and h:mm:ss track durations
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"4:33", 273, false},
+		{"0:59", 59, false},
+		{"12:00", 720, false},
+		{"1:02:03", 3723, false},
+		{"90:00", 5400, false},
+		{"4:5", 0, true},
+		{"4:60", 0, true},
+		{"1:60:00", 0, true},
+		{"273", 0, true},
+		{"-1:00", 0, true},
+		{"a:bc", 0, true},
+		{"1:2:3:4", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseDuration(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTrackRequest_Side tests that the side defaults to the position's letters
+func TestTrackRequest_Side(t *testing.T) {
+	tests := []struct {
+		request TrackRequest
+		want    string
+	}{
+		{TrackRequest{Position: "A1"}, "A"},
+		{TrackRequest{Position: "b12"}, "B"},
+		{TrackRequest{Position: "AA2"}, "AA"},
+		{TrackRequest{Position: "3"}, ""},
+		{TrackRequest{Position: "C"}, "C"},
+		{TrackRequest{Position: "A1", Side: "d"}, "D"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.request.side(); got != tt.want {
+			t.Errorf("TrackRequest{Position: %q, Side: %q}.side() = %q, want %q", tt.request.Position, tt.request.Side, got, tt.want)
+		}
+	}
+}
+
+// TestTracklist_OrderAndRunningTimes tests natural position order and the
+// per-side, total and listing running times
+func TestTracklist_OrderAndRunningTimes(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Abbey Road"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+
+	for _, req := range []TrackRequest{
+		{Position: "B1", Title: "Here Comes the Sun", Duration: "3:05"},
+		{Position: "A10", Title: "Tenth", Duration: "1:00"},
+		{Position: "A2", Title: "Something", Duration: "3:03"},
+		{Position: "A1", Title: "Come Together", Duration: "4:19"},
+		{Position: "B2", Title: "Because"},
+	} {
+		if _, err := h.saveTrack(ctx, record.ID, 0, req); err != nil {
+			t.Fatalf("saveTrack(%s) error = %v", req.Position, err)
+		}
+	}
+
+	result, err := h.tracklist(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("tracklist() error = %v", err)
+	}
+
+	wantOrder := []string{"A1", "A2", "A10", "B1", "B2"}
+	if len(result.Tracks) != len(wantOrder) {
+		t.Fatalf("len(tracks) = %d, want %d", len(result.Tracks), len(wantOrder))
+	}
+	for i, track := range result.Tracks {
+		if track.Position != wantOrder[i] {
+			t.Errorf("track %d = %s, want %s", i, track.Position, wantOrder[i])
+		}
+	}
+
+	if len(result.Sides) != 2 {
+		t.Fatalf("len(sides) = %d, want 2", len(result.Sides))
+	}
+	if result.Sides[0].Side.String != "A" || result.Sides[0].RunningTime != 259+183+60 || result.Sides[0].TrackCount != 3 {
+		t.Errorf("side A = %+v, want 3 tracks, %d seconds", result.Sides[0], 259+183+60)
+	}
+	// Tracks without a duration count towards the side but add no time
+	if result.Sides[1].Side.String != "B" || result.Sides[1].RunningTime != 185 || result.Sides[1].TrackCount != 2 {
+		t.Errorf("side B = %+v, want 2 tracks, 185 seconds", result.Sides[1])
+	}
+	if result.RunningTime != 259+183+60+185 || result.TrackCount != 5 {
+		t.Errorf("total = %d tracks, %d seconds", result.TrackCount, result.RunningTime)
+	}
+
+	// The listing carries the same totals and can sort by them
+	empty, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "No Tracks Yet"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+	records, err := queries.FilterRecords(ctx, store.RecordFilter{Sort: store.RecordSortRunningTime, Desc: true})
+	if err != nil {
+		t.Fatalf("FilterRecords() error = %v", err)
+	}
+	if len(records) != 2 || records[0].ID != record.ID || records[1].ID != empty.ID {
+		t.Fatalf("FilterRecords() by running time = %v, want Abbey Road first", records)
+	}
+	if records[0].RunningTime != result.RunningTime || records[0].TrackCount != 5 {
+		t.Errorf("listing running time = %d (%d tracks), want %d (5 tracks)", records[0].RunningTime, records[0].TrackCount, result.RunningTime)
+	}
+	if records[1].RunningTime != 0 || records[1].TrackCount != 0 {
+		t.Errorf("empty record running time = %d (%d tracks), want 0", records[1].RunningTime, records[1].TrackCount)
+	}
+}
+
+// TestSaveTrack_Errors tests duplicate positions, unknown artists and tracks
+// that belong to another record
+func TestSaveTrack_Errors(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Kind of Blue"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+	other, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Blue Train"})
+	if err != nil {
+		t.Fatalf("Failed to create record: %v", err)
+	}
+	coltrane, err := queries.CreateArtist(ctx, store.CreateArtistParams{Name: "John Coltrane", SortName: store.ArtistSortName("John Coltrane")})
+	if err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+
+	track, err := h.saveTrack(ctx, record.ID, 0, TrackRequest{Position: "a1", Title: "So What", Duration: "9:22", ArtistID: coltrane.ID})
+	if err != nil {
+		t.Fatalf("saveTrack() error = %v", err)
+	}
+	if track.Position != "A1" || track.Side.String != "A" || track.DurationSeconds.Int64 != 562 || track.ArtistID.Int64 != coltrane.ID {
+		t.Errorf("saved track = %+v", track)
+	}
+
+	if _, err := h.saveTrack(ctx, record.ID, 0, TrackRequest{Position: "A1", Title: "Freddie Freeloader"}); !errors.Is(err, errDuplicatePosition) {
+		t.Errorf("duplicate position error = %v, want errDuplicatePosition", err)
+	}
+	// The same position on another record is fine
+	if _, err := h.saveTrack(ctx, other.ID, 0, TrackRequest{Position: "A1", Title: "Blue Train"}); err != nil {
+		t.Errorf("saveTrack() on other record error = %v", err)
+	}
+	if _, err := h.saveTrack(ctx, record.ID, 0, TrackRequest{Position: "A2", Title: "Freddie Freeloader", ArtistID: coltrane.ID + 100}); !errors.Is(err, errTrackArtistNotFound) {
+		t.Errorf("unknown artist error = %v, want errTrackArtistNotFound", err)
+	}
+	if _, err := h.saveTrack(ctx, record.ID, 0, TrackRequest{Position: "A2", Title: "Freddie Freeloader", Duration: "9"}); !errors.Is(err, errInvalidDuration) {
+		t.Errorf("invalid duration error = %v, want errInvalidDuration", err)
+	}
+
+	// Editing through the wrong record doesn't touch the track
+	if _, err := h.saveTrack(ctx, other.ID, track.ID, TrackRequest{Position: "A1", Title: "Hijacked"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("update via other record error = %v, want sql.ErrNoRows", err)
+	}
+	deleted, err := queries.DeleteTrack(ctx, store.DeleteTrackParams{ID: track.ID, RecordID: other.ID})
+	if err != nil || deleted != 0 {
+		t.Errorf("DeleteTrack() via other record = %d, %v, want 0 rows", deleted, err)
+	}
+
+	updated, err := h.saveTrack(ctx, record.ID, track.ID, TrackRequest{Position: "A1", Title: "So What", Duration: "9:25"})
+	if err != nil {
+		t.Fatalf("saveTrack() update error = %v", err)
+	}
+	if updated.DurationSeconds.Int64 != 565 || updated.ArtistID.Valid {
+		t.Errorf("updated track = %+v, want 565 seconds and no artist", updated)
+	}
+
+	// Tracks go with their record
+	if err := queries.DeleteRecord(ctx, record.ID); err != nil {
+		t.Fatalf("DeleteRecord() error = %v", err)
+	}
+	if _, err := queries.GetTrack(ctx, track.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTrack() after deleting record error = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -394,7 +398,8 @@ func TestCreateRecordRequest_Validation(t *testing.T) {
 		CurrentLocationID int64  `validate:"omitempty,min=1"`
 		HomeLocationID    int64  `validate:"omitempty,min=1"`
 		CatalogNumber     string `validate:"max=100"`
-		Condition         string `validate:"omitempty,oneof=Mint 'Near Mint' 'Very Good' Good Fair Poor"`
+		MediaGrade        string `validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+		SleeveGrade       string `validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
 		Notes             string `validate:"max=1000"`
 	}
 
@@ -413,7 +418,8 @@ func TestCreateRecordRequest_Validation(t *testing.T) {
 				CurrentLocationID: 1,
 				HomeLocationID:    1,
 				CatalogNumber:     "SHVL 804",
-				Condition:         "Mint",
+				MediaGrade:        "M",
+				SleeveGrade:       "VG+",
 				Notes:             "Original pressing",
 			},
 			false,
@@ -499,38 +505,58 @@ func TestCreateRecordRequest_Validation(t *testing.T) {
 			false,
 		},
 		{
-			"valid condition: Mint",
-			CreateRecordRequest{Title: "Test", Condition: "Mint"},
+			"valid media grade: M",
+			CreateRecordRequest{Title: "Test", MediaGrade: "M"},
 			false,
 		},
 		{
-			"valid condition: Near Mint",
-			CreateRecordRequest{Title: "Test", Condition: "Near Mint"},
+			"valid media grade: NM",
+			CreateRecordRequest{Title: "Test", MediaGrade: "NM"},
 			false,
 		},
 		{
-			"valid condition: Very Good",
-			CreateRecordRequest{Title: "Test", Condition: "Very Good"},
+			"valid media grade: VG+",
+			CreateRecordRequest{Title: "Test", MediaGrade: "VG+"},
 			false,
 		},
 		{
-			"valid condition: Good",
-			CreateRecordRequest{Title: "Test", Condition: "Good"},
+			"valid media grade: VG",
+			CreateRecordRequest{Title: "Test", MediaGrade: "VG"},
 			false,
 		},
 		{
-			"valid condition: Fair",
-			CreateRecordRequest{Title: "Test", Condition: "Fair"},
+			"valid media grade: G+",
+			CreateRecordRequest{Title: "Test", MediaGrade: "G+"},
 			false,
 		},
 		{
-			"valid condition: Poor",
-			CreateRecordRequest{Title: "Test", Condition: "Poor"},
+			"valid media grade: G",
+			CreateRecordRequest{Title: "Test", MediaGrade: "G"},
 			false,
 		},
 		{
-			"invalid condition",
-			CreateRecordRequest{Title: "Test", Condition: "Excellent"},
+			"valid media grade: F",
+			CreateRecordRequest{Title: "Test", MediaGrade: "F"},
+			false,
+		},
+		{
+			"valid media grade: P",
+			CreateRecordRequest{Title: "Test", MediaGrade: "P"},
+			false,
+		},
+		{
+			"invalid media grade",
+			CreateRecordRequest{Title: "Test", MediaGrade: "Excellent"},
+			true,
+		},
+		{
+			"old condition name is not a grade",
+			CreateRecordRequest{Title: "Test", MediaGrade: "Near Mint"},
+			true,
+		},
+		{
+			"grades are case-sensitive",
+			CreateRecordRequest{Title: "Test", SleeveGrade: "vg+"},
 			true,
 		},
 		{
@@ -563,7 +589,7 @@ func TestCreateRecordRequest_Validation(t *testing.T) {
 
 // TestUpdateRecordRequest_Validation tests update record validation rules
 func TestUpdateRecordRequest_Validation(t *testing.T) {
-	validate := validator.New()
+	validate := newValidator()
 
 	tests := []struct {
 		name      string
@@ -580,7 +606,8 @@ func TestUpdateRecordRequest_Validation(t *testing.T) {
 				CurrentLocationID: 3,
 				HomeLocationID:    4,
 				CatalogNumber:     "NEW-123",
-				Condition:         "Good",
+				MediaGrade:        "G+",
+				SleeveGrade:       "G",
 				Notes:             "Updated notes",
 			},
 			false,
@@ -606,18 +633,38 @@ func TestUpdateRecordRequest_Validation(t *testing.T) {
 			false,
 		},
 		{
-			"update condition to Fair",
-			UpdateRecordRequest{Title: "Test", Condition: "Fair"},
+			"update media grade to F",
+			UpdateRecordRequest{Title: "Test", MediaGrade: "F"},
 			false,
 		},
 		{
-			"update condition to Poor",
-			UpdateRecordRequest{Title: "Test", Condition: "Poor"},
+			"update sleeve grade to P",
+			UpdateRecordRequest{Title: "Test", SleeveGrade: "P"},
 			false,
 		},
 		{
-			"invalid condition",
-			UpdateRecordRequest{Title: "Test", Condition: "Damaged"},
+			"invalid sleeve grade",
+			UpdateRecordRequest{Title: "Test", SleeveGrade: "Damaged"},
+			true,
+		},
+		{
+			"condition note too long (1001 chars)",
+			UpdateRecordRequest{Title: "Test", ConditionNote: string(make([]byte, 1001))},
+			true,
+		},
+		{
+			"valid UPC-A barcode",
+			UpdateRecordRequest{Title: "Test", Barcode: "036000291452"},
+			false,
+		},
+		{
+			"valid EAN-13 barcode with spaces",
+			UpdateRecordRequest{Title: "Test", Barcode: "4 006381 33393 1"},
+			false,
+		},
+		{
+			"barcode with wrong check digit",
+			UpdateRecordRequest{Title: "Test", Barcode: "036000291453"},
 			true,
 		},
 	}
@@ -678,7 +725,9 @@ func TestUpdateRecordConditionRequest_Validation(t *testing.T) {
 	validate := validator.New()
 
 	type UpdateRecordConditionRequest struct {
-		Condition string `validate:"required,oneof=Mint 'Near Mint' 'Very Good' Good Fair Poor"`
+		MediaGrade  string `validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+		SleeveGrade string `validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+		Note        string `validate:"max=1000"`
 	}
 
 	tests := []struct {
@@ -686,16 +735,14 @@ func TestUpdateRecordConditionRequest_Validation(t *testing.T) {
 		request   UpdateRecordConditionRequest
 		wantError bool
 	}{
-		{"Mint", UpdateRecordConditionRequest{Condition: "Mint"}, false},
-		{"Near Mint", UpdateRecordConditionRequest{Condition: "Near Mint"}, false},
-		{"Very Good", UpdateRecordConditionRequest{Condition: "Very Good"}, false},
-		{"Good", UpdateRecordConditionRequest{Condition: "Good"}, false},
-		{"Fair", UpdateRecordConditionRequest{Condition: "Fair"}, false},
-		{"Poor", UpdateRecordConditionRequest{Condition: "Poor"}, false},
-		{"empty condition", UpdateRecordConditionRequest{Condition: ""}, true},
-		{"invalid condition", UpdateRecordConditionRequest{Condition: "Excellent"}, true},
-		{"lowercase", UpdateRecordConditionRequest{Condition: "mint"}, true}, // case-sensitive
-		{"wrong casing", UpdateRecordConditionRequest{Condition: "MINT"}, true},
+		{"both grades", UpdateRecordConditionRequest{MediaGrade: "VG+", SleeveGrade: "G+"}, false},
+		{"media only", UpdateRecordConditionRequest{MediaGrade: "NM"}, false},
+		{"with note", UpdateRecordConditionRequest{MediaGrade: "VG", Note: "Cleaned"}, false},
+		{"clear both grades", UpdateRecordConditionRequest{}, false},
+		{"invalid media grade", UpdateRecordConditionRequest{MediaGrade: "Excellent"}, true},
+		{"old condition name", UpdateRecordConditionRequest{SleeveGrade: "Mint"}, true},
+		{"lowercase", UpdateRecordConditionRequest{MediaGrade: "nm"}, true}, // case-sensitive
+		{"note too long", UpdateRecordConditionRequest{Note: string(make([]byte, 1001))}, true},
 	}
 
 	for _, tt := range tests {
@@ -712,4 +759,335 @@ func TestUpdateRecordConditionRequest_Validation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// TestListRecordsRequest_Validation tests record listing query parameter validation
+func TestListRecordsRequest_Validation(t *testing.T) {
+	validate := validator.New()
+
+	tests := []struct {
+		name      string
+		request   ListRecordsRequest
+		wantError bool
+	}{
+		{"empty request", ListRecordsRequest{}, false},
+		{"all filters", ListRecordsRequest{ArtistID: 1, LocationID: 2, HomeLocationID: 3, MediaGrade: "G", MinSleeveGrade: "VG+", YearFrom: 1970, YearTo: 1979, Played: "true", CreatedSince: "2024-01-31", Query: "blue"}, false},
+		{"sort and order", ListRecordsRequest{Sort: "last_played_at", Order: "desc"}, false},
+		{"unknown sort", ListRecordsRequest{Sort: "artist_id"}, true},
+		{"unknown order", ListRecordsRequest{Order: "up"}, true},
+		{"invalid played", ListRecordsRequest{Played: "yes"}, true},
+		{"invalid created_since", ListRecordsRequest{CreatedSince: "31/01/2024"}, true},
+		{"year too early", ListRecordsRequest{YearFrom: 1850}, true},
+		{"sort by grade", ListRecordsRequest{Sort: "media_grade"}, false},
+		{"invalid media grade", ListRecordsRequest{MediaGrade: "Excellent"}, true},
+		{"invalid minimum grade", ListRecordsRequest{MinMediaGrade: "VG++"}, true},
+		{"per page too large", ListRecordsRequest{PerPage: 500}, true},
+		{"tag sets", ListRecordsRequest{TagsAny: []int64{1, 2}, TagsAll: []int64{3}}, false},
+		{"invalid tag id", ListRecordsRequest{TagsAny: []int64{0}}, true},
+		{"too many tags", ListRecordsRequest{TagsAll: make([]int64, 21)}, true},
+		{"grid view", ListRecordsRequest{View: "grid"}, false},
+		{"unknown view", ListRecordsRequest{View: "list"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.request)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Validation error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+// TestListRecordsRequest_BindGrades tests that grades in a hand-written URL
+// bind whether or not their "+" is escaped
+func TestListRecordsRequest_BindGrades(t *testing.T) {
+	h := &Handler{validate: newValidator()}
+
+	tests := []struct {
+		query     string
+		want      string
+		wantError bool
+	}{
+		{"media_grade_min=VG+", "VG+", false},
+		{"media_grade_min=VG%2B", "VG+", false},
+		{"media_grade_min=G+&sleeve_grade_min=VG+", "G+", false},
+		{"media_grade_min=NM", "NM", false},
+		{"media_grade_min=NM+", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var req ListRecordsRequest
+			err := h.bindQuery(httptest.NewRequest("GET", "/records?"+tt.query, nil), &req)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("bindQuery() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError && req.MinMediaGrade != tt.want {
+				t.Errorf("MinMediaGrade = %q, want %q", req.MinMediaGrade, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewPagination tests page counts and neighbour links
+func TestNewPagination(t *testing.T) {
+	query := url.Values{"artist_id": {"4"}, "page": {"2"}}
+
+	p := newPagination(query, 2, 10, 35)
+	if p.TotalPages != 4 {
+		t.Errorf("TotalPages = %d, want 4", p.TotalPages)
+	}
+	if !p.HasNext || p.Next != "?artist_id=4&page=3" {
+		t.Errorf("Next = %q (HasNext %v), want ?artist_id=4&page=3", p.Next, p.HasNext)
+	}
+	if !p.HasPrev || p.Prev != "?artist_id=4&page=1" {
+		t.Errorf("Prev = %q (HasPrev %v), want ?artist_id=4&page=1", p.Prev, p.HasPrev)
+	}
+
+	last := newPagination(query, 4, 10, 35)
+	if last.HasNext || last.Next != "" {
+		t.Errorf("last page should not have a next link, got %q", last.Next)
+	}
+
+	empty := newPagination(url.Values{}, 1, 10, 0)
+	if empty.HasNext || empty.HasPrev || empty.TotalPages != 0 {
+		t.Errorf("empty result pagination = %+v", empty)
+	}
+}
+
+// TestPlayRequest_Validation tests play time parsing and validation
+func TestPlayRequest_Validation(t *testing.T) {
+	validate := validator.New()
+
+	tests := []struct {
+		name      string
+		request   PlayRequest
+		wantError bool
+	}{
+		{"empty is now", PlayRequest{}, false},
+		{"backdated", PlayRequest{PlayedAt: "2025-12-24T18:30:00+01:00", Side: "B"}, false},
+		{"date without time", PlayRequest{PlayedAt: "2025-12-24"}, true},
+		{"side too long", PlayRequest{Side: "Side B continued"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.request)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Validation error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 500, time.UTC)
+
+	got, err := PlayRequest{PlayedAt: "2025-12-24T18:30:00+01:00"}.playedAt(now)
+	if err != nil {
+		t.Fatalf("playedAt() error = %v", err)
+	}
+	if want := time.Date(2025, 12, 24, 17, 30, 0, 0, time.UTC); !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("playedAt() = %v, want %v in UTC", got, want)
+	}
+
+	if got, _ := (PlayRequest{}).playedAt(now); !got.Equal(now.Truncate(time.Second)) {
+		t.Errorf("default playedAt() = %v, want %v", got, now.Truncate(time.Second))
+	}
+
+	if _, err := (PlayRequest{PlayedAt: "2026-02-01T00:00:00Z"}).playedAt(now); err != errPlayInFuture {
+		t.Errorf("future playedAt() error = %v, want %v", err, errPlayInFuture)
+	}
+}
+
+// TestPlayWindowRequest_Window tests resolving chart windows
+func TestPlayWindowRequest_Window(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		request   PlayWindowRequest
+		wantSince time.Time
+		wantUntil time.Time
+	}{
+		{"all time", PlayWindowRequest{}, time.Time{}, now.Add(time.Second)},
+		{"last 30 days", PlayWindowRequest{Days: 30}, now.AddDate(0, 0, -30), now.Add(time.Second)},
+		{"days beats since", PlayWindowRequest{Days: 7, Since: "2020-01-01"}, now.AddDate(0, 0, -7), now.Add(time.Second)},
+		{"inclusive until", PlayWindowRequest{Since: "2026-01-01", Until: "2026-01-31"},
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since, until := tt.request.window(now)
+			if !since.Equal(tt.wantSince) || !until.Equal(tt.wantUntil) {
+				t.Errorf("window() = [%v, %v), want [%v, %v)", since, until, tt.wantSince, tt.wantUntil)
+			}
+		})
+	}
+}
+
+// TestTrackRequest_Validation tests track field validation
+func TestTrackRequest_Validation(t *testing.T) {
+	validate := validator.New()
+
+	tests := []struct {
+		name      string
+		request   TrackRequest
+		wantError bool
+	}{
+		{"minimal", TrackRequest{Position: "A1", Title: "So What"}, false},
+		{"all fields", TrackRequest{Side: "A", Position: "A1", Title: "So What", Duration: "9:22", ArtistID: 1}, false},
+		{"missing position", TrackRequest{Title: "So What"}, true},
+		{"missing title", TrackRequest{Position: "A1"}, true},
+		{"position with punctuation", TrackRequest{Position: "A-1", Title: "So What"}, true},
+		{"position too long", TrackRequest{Position: "A12345678901", Title: "So What"}, true},
+		{"title too long", TrackRequest{Position: "A1", Title: string(make([]byte, 201))}, true},
+		{"duration too long", TrackRequest{Position: "A1", Title: "So What", Duration: "100:00:00"}, true},
+		{"invalid artist", TrackRequest{Position: "A1", Title: "So What", ArtistID: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.request)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Validation error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+// TestSetRecordArtistsRequest_Validation tests credit roles and artist ids
+func TestSetRecordArtistsRequest_Validation(t *testing.T) {
+	validate := validator.New()
+
+	tests := []struct {
+		name      string
+		request   SetRecordArtistsRequest
+		wantError bool
+	}{
+		{"no credits", SetRecordArtistsRequest{}, false},
+		{"default role", SetRecordArtistsRequest{Artists: []CreditRequest{{ArtistID: 1}}}, false},
+		{"every role", SetRecordArtistsRequest{Artists: []CreditRequest{
+			{ArtistID: 1, Role: "primary"},
+			{ArtistID: 2, Role: "featuring"},
+			{ArtistID: 3, Role: "producer"},
+			{ArtistID: 4, Role: "remixer"},
+		}}, false},
+		{"unknown role", SetRecordArtistsRequest{Artists: []CreditRequest{{ArtistID: 1, Role: "drummer"}}}, true},
+		{"missing artist", SetRecordArtistsRequest{Artists: []CreditRequest{{Role: "primary"}}}, true},
+		{"too many credits", SetRecordArtistsRequest{Artists: make([]CreditRequest, 51)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.request)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Validation error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestTagRequest_Validation(t *testing.T) {
+	validate := newValidator()
+
+	tests := []struct {
+		name      string
+		request   TagRequest
+		wantError bool
+	}{
+		{"default kind", TagRequest{Name: "Shoegaze"}, false},
+		{"every kind", TagRequest{Name: "Jazz", Kind: "genre"}, false},
+		{"missing name", TagRequest{Kind: "mood"}, true},
+		{"blank name", TagRequest{Name: "   "}, true},
+		{"name too long", TagRequest{Name: strings.Repeat("a", 51)}, true},
+		{"unknown kind", TagRequest{Name: "Jazz", Kind: "decade"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.request)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Validation error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestRecordTagRequest_Validation(t *testing.T) {
+	validate := newValidator()
+
+	tests := []struct {
+		name      string
+		request   RecordTagRequest
+		wantError bool
+	}{
+		{"by id", RecordTagRequest{TagID: 1}, false},
+		{"by name", RecordTagRequest{Name: "Late night"}, false},
+		{"by name and kind", RecordTagRequest{Name: "Late night", Kind: "mood"}, false},
+		{"neither id nor name", RecordTagRequest{Kind: "mood"}, true},
+		{"blank name", RecordTagRequest{Name: "   "}, true},
+		{"name too long", RecordTagRequest{Name: strings.Repeat("a", 51)}, true},
+		{"unknown kind", RecordTagRequest{Name: "Late night", Kind: "vibe"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.request)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Validation error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestWantRequest_Validation(t *testing.T) {
+	validate := newValidator()
+
+	tests := []struct {
+		name      string
+		request   WantRequest
+		wantError bool
+	}{
+		{"artist and title", WantRequest{ArtistName: "Nirvana", Title: "Bleach"}, false},
+		{"missing title", WantRequest{ArtistName: "Nirvana"}, true},
+		{"blank artist", WantRequest{ArtistName: "   ", Title: "Bleach"}, true},
+		{"blank title", WantRequest{ArtistName: "Nirvana", Title: " "}, true},
+		{"priority too low", WantRequest{ArtistName: "Nirvana", Title: "Bleach", Priority: 4}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.request)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Validation error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestReleaseSearchRequest_Validation(t *testing.T) {
+	validate := newValidator()
+
+	tests := []struct {
+		name      string
+		request   ReleaseSearchRequest
+		wantError bool
+	}{
+		{"by query", ReleaseSearchRequest{Query: "Nirvana Nevermind"}, false},
+		{"by barcode", ReleaseSearchRequest{Barcode: "720642442517"}, false},
+		{"by catalog number", ReleaseSearchRequest{CatalogNumber: "DGC-24425"}, false},
+		{"nothing to search for", ReleaseSearchRequest{}, true},
+		{"invalid barcode", ReleaseSearchRequest{Barcode: "720642442518"}, true},
+		{"query too long", ReleaseSearchRequest{Query: strings.Repeat("a", 201)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.request)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Validation error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,953 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+// defaultCurrency is the currency of prices and values given without one
+const defaultCurrency = "USD"
+
+var (
+	// errValueNotFound is returned when deleting an unknown value estimate
+	errValueNotFound = errors.New("value not found")
+
+	errPriceGuideNoValue = errors.New("no column holds values; choose one for Value")
+	errPriceGuideNoMatch = errors.New("no column identifies records; choose one for Record ID, Barcode or Title")
+)
+
+// ValueGrouping is a way the collection value can be broken down
+type ValueGrouping struct {
+	Value string
+	Name  string
+}
+
+// valueGroupings are the breakdowns the value page offers, the total first
+var valueGroupings = []ValueGrouping{
+	{store.ValueByTotal, "Total"},
+	{store.ValueByArtist, "By artist"},
+	{store.ValueByDecade, "By decade"},
+	{store.ValueByLocation, "By location"},
+}
+
+// priceGuideColumns are the fields a price guide CSV holds. A record is
+// found by its id, its barcode, or its title with the artist and catalog
+// number.
+var priceGuideColumns = []importColumn{
+	{"record_id", "Record ID", []string{"record_id", "record id", "id"}},
+	{"barcode", "Barcode", []string{"barcode", "upc", "ean"}},
+	{"artist", "Artist", []string{"artist", "artist name", "band"}},
+	{"title", "Title", []string{"title", "record", "release", "name"}},
+	{"catalog_number", "Catalog number", []string{"catalog_number", "catalog number", "catalog #", "catalog#", "catno", "cat no"}},
+	{"value", "Value", []string{"value", "price", "amount", "estimate", "median"}},
+	{"currency", "Currency", []string{"currency"}},
+	{"valued_at", "Valued on", []string{"valued_at", "valued on", "date"}},
+}
+
+// cents converts an amount, e.g. 24.99, to cents
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// PurchaseRequest is what was paid for a record, and where. Price is e.g.
+// 24.99; 0 leaves it unknown. Currency is an ISO 4217 code and defaults to
+// USD. PurchasedAt is a date, e.g. 2026-10-16.
+type PurchaseRequest struct {
+	Price       float64 `form:"price" json:"price" validate:"omitempty,min=0,max=1000000"`
+	Currency    string  `form:"currency" json:"currency" validate:"omitempty,iso4217"`
+	PurchasedAt string  `form:"purchased_at" json:"purchased_at" validate:"omitempty,datetime=2006-01-02"`
+	Seller      string  `form:"seller" json:"seller" validate:"max=200"`
+	Shop        string  `form:"shop" json:"shop" validate:"max=200"`
+}
+
+// params converts the request to a record's purchase
+func (req PurchaseRequest) params(recordID int64) store.UpsertRecordPurchaseParams {
+	price := cents(req.Price)
+	purchasedAt, err := time.Parse("2006-01-02", req.PurchasedAt)
+	return store.UpsertRecordPurchaseParams{
+		RecordID:    recordID,
+		PriceCents:  sql.NullInt64{Int64: price, Valid: price > 0},
+		Currency:    cmp.Or(req.Currency, defaultCurrency),
+		PurchasedAt: sql.NullTime{Time: purchasedAt, Valid: err == nil},
+		Seller:      sql.NullString{String: strings.TrimSpace(req.Seller), Valid: strings.TrimSpace(req.Seller) != ""},
+		Shop:        sql.NullString{String: strings.TrimSpace(req.Shop), Valid: strings.TrimSpace(req.Shop) != ""},
+	}
+}
+
+// ValueRequest is an estimate of what a record is worth. Amount is e.g.
+// 30.00. ValuedAt is a date and defaults to today; Source is where the
+// estimate came from, e.g. a price guide.
+type ValueRequest struct {
+	Amount   float64 `form:"amount" json:"amount" validate:"required,gt=0,max=1000000"`
+	Currency string  `form:"currency" json:"currency" validate:"omitempty,iso4217"`
+	ValuedAt string  `form:"valued_at" json:"valued_at" validate:"omitempty,datetime=2006-01-02"`
+	Source   string  `form:"source" json:"source" validate:"max=200"`
+}
+
+// params converts the request to a value estimate for the record
+func (req ValueRequest) params(recordID int64, now time.Time) store.CreateRecordValueParams {
+	valuedAt, err := time.Parse("2006-01-02", req.ValuedAt)
+	if err != nil {
+		valuedAt = today(now)
+	}
+	return store.CreateRecordValueParams{
+		RecordID:    recordID,
+		ValuedAt:    valuedAt,
+		AmountCents: cents(req.Amount),
+		Currency:    cmp.Or(req.Currency, defaultCurrency),
+		Source:      sql.NullString{String: strings.TrimSpace(req.Source), Valid: strings.TrimSpace(req.Source) != ""},
+	}
+}
+
+// ValueSummaryRequest chooses how the collection value is broken down:
+// artist, decade or location. Blank is only the total.
+type ValueSummaryRequest struct {
+	By string `form:"by" validate:"omitempty,oneof=artist decade location"`
+}
+
+// PriceGuideRequest is sent with a price guide upload. The columns are
+// chosen with column_<field>, as for a CSV import. Currency and ValuedAt are
+// used for rows that don't have their own; Source is noted on every value.
+// DryRun reports what would be valued without saving anything.
+type PriceGuideRequest struct {
+	DryRun   bool   `form:"dry_run" json:"dry_run"`
+	Source   string `form:"source" json:"source" validate:"max=200"`
+	Currency string `form:"currency" json:"currency" validate:"omitempty,iso4217"`
+	ValuedAt string `form:"valued_at" json:"valued_at" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// RecordValueResponse is what a record cost and what it's worth. Current is
+// the latest value estimate; GainCents is Current less the price, when both
+// are known in the same currency.
+type RecordValueResponse struct {
+	Purchase  *store.RecordPurchase `json:"purchase"`
+	Values    []store.RecordValue   `json:"values"`
+	Current   *store.RecordValue    `json:"current"`
+	GainCents *int64                `json:"gain_cents"`
+}
+
+// Loss reports whether the record is worth less than was paid for it
+func (v RecordValueResponse) Loss() bool {
+	return v.GainCents != nil && *v.GainCents < 0
+}
+
+// ValueSummaryResponse is the collection's value in total, with a row per
+// currency, and broken down By artist, decade or location
+type ValueSummaryResponse struct {
+	By     string                  `json:"by"`
+	Total  []store.ValueSummaryRow `json:"total"`
+	Groups []store.ValueSummaryRow `json:"groups"`
+}
+
+// ValuedRow is a price guide row that valued a record, or would in a dry run
+type ValuedRow struct {
+	Line        int    `json:"line"`
+	RecordID    int64  `json:"record_id"`
+	Title       string `json:"title"`
+	AmountCents int64  `json:"amount_cents"`
+	Currency    string `json:"currency"`
+}
+
+// PriceGuideReport is the outcome of a price guide import. Rows valued a
+// record; Unmatched rows were read but match no record in the collection,
+// and Rejected rows couldn't be read. In a dry run nothing is saved.
+type PriceGuideReport struct {
+	DryRun    bool              `json:"dry_run"`
+	Columns   map[string]string `json:"columns"`
+	Total     int               `json:"total"`
+	Valued    int               `json:"valued"`
+	Rows      []ValuedRow       `json:"rows"`
+	Unmatched []RejectedRow     `json:"unmatched"`
+	Rejected  []RejectedRow     `json:"rejected"`
+}
+
+// priceGuideRow is a price guide entry read into a value estimate and what
+// identifies its record
+type priceGuideRow struct {
+	line     int
+	values   []string
+	recordID int64
+	artist   string
+	match    CreateRecordRequest
+	value    store.CreateRecordValueParams
+	// titleOnly is set when the guide has neither an artist nor a catalog
+	// number column, so the title is all there is to match on
+	titleOnly bool
+}
+
+// recordValue gathers a record's purchase and value estimates. Returns
+// sql.ErrNoRows if the record doesn't exist.
+func (h *Handler) recordValue(ctx context.Context, recordID int64) (RecordValueResponse, error) {
+	resp := RecordValueResponse{Values: []store.RecordValue{}}
+	if _, err := h.queries.GetRecord(ctx, recordID); err != nil {
+		return resp, err
+	}
+
+	purchase, err := h.queries.GetRecordPurchase(ctx, recordID)
+	if err == nil {
+		resp.Purchase = &purchase
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return resp, err
+	}
+
+	values, err := h.queries.ListRecordValues(ctx, recordID)
+	if err != nil {
+		return resp, err
+	}
+	if len(values) > 0 {
+		resp.Values = values
+		resp.Current = &values[0]
+	}
+
+	if p, v := resp.Purchase, resp.Current; p != nil && v != nil && p.PriceCents.Valid && p.Currency == v.Currency {
+		gain := v.AmountCents - p.PriceCents.Int64
+		resp.GainCents = &gain
+	}
+	return resp, nil
+}
+
+// setPurchase sets what was paid for a record. Returns sql.ErrNoRows if the
+// record doesn't exist.
+func (h *Handler) setPurchase(ctx context.Context, recordID int64, req PurchaseRequest) (store.RecordPurchase, error) {
+	if _, err := h.queries.GetRecord(ctx, recordID); err != nil {
+		return store.RecordPurchase{}, err
+	}
+	return h.queries.UpsertRecordPurchase(ctx, req.params(recordID))
+}
+
+// clearPurchase forgets what was paid for a record. Returns sql.ErrNoRows if
+// the record doesn't exist.
+func (h *Handler) clearPurchase(ctx context.Context, recordID int64) error {
+	if _, err := h.queries.GetRecord(ctx, recordID); err != nil {
+		return err
+	}
+	_, err := h.queries.DeleteRecordPurchase(ctx, recordID)
+	return err
+}
+
+// addValue adds a value estimate for a record. Returns sql.ErrNoRows if the
+// record doesn't exist.
+func (h *Handler) addValue(ctx context.Context, recordID int64, req ValueRequest, now time.Time) (store.RecordValue, error) {
+	if _, err := h.queries.GetRecord(ctx, recordID); err != nil {
+		return store.RecordValue{}, err
+	}
+	return h.queries.CreateRecordValue(ctx, req.params(recordID, now))
+}
+
+// deleteValue deletes one of a record's value estimates. Returns
+// errValueNotFound if the record hasn't got it.
+func (h *Handler) deleteValue(ctx context.Context, recordID, valueID int64) error {
+	deleted, err := h.queries.DeleteRecordValue(ctx, store.DeleteRecordValueParams{ID: valueID, RecordID: recordID})
+	if err == nil && deleted == 0 {
+		return errValueNotFound
+	}
+	return err
+}
+
+// valueSummary adds up the collection's value, in total and broken down by
+// the grouping, if one is given
+func (h *Handler) valueSummary(ctx context.Context, by string) (ValueSummaryResponse, error) {
+	resp := ValueSummaryResponse{By: by, Total: []store.ValueSummaryRow{}, Groups: []store.ValueSummaryRow{}}
+
+	total, err := h.queries.ValueSummary(ctx, store.ValueByTotal)
+	if err != nil {
+		return resp, err
+	}
+	if total != nil {
+		resp.Total = total
+	}
+
+	if by == store.ValueByTotal {
+		return resp, nil
+	}
+	groups, err := h.queries.ValueSummary(ctx, by)
+	if err != nil {
+		return resp, err
+	}
+	if groups != nil {
+		resp.Groups = groups
+	}
+	return resp, nil
+}
+
+// thousandsAmount matches an amount with comma thousands separators, e.g.
+// "1,024.50"
+var thousandsAmount = regexp.MustCompile(`^\d{1,3}(,\d{3})+(\.\d+)?$`)
+
+// parseAmount reads an amount as a price guide writes it, e.g. "24.99",
+// "$1,024.50" or "€12", into cents. A comma that isn't a thousands separator
+// is rejected rather than dropped, so "24,99" isn't read as 2499.
+func parseAmount(s string) (int64, bool) {
+	s = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(s), "$£€¥"))
+	if strings.Contains(s, ",") {
+		if !thousandsAmount.MatchString(s) {
+			return 0, false
+		}
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || f < 0 || f > 1000000 {
+		return 0, false
+	}
+	return cents(f), true
+}
+
+// readPriceGuide reads a price guide CSV whose columns are chosen by the
+// user, or matched by their usual names. A value column is required, and one
+// that identifies records.
+func readPriceGuide(r io.Reader, chosen map[string]string) (importUpload, error) {
+	header, values, lines, err := readCSV(r)
+	if err != nil {
+		return importUpload{}, err
+	}
+	columns, err := mapColumns(header, priceGuideColumns, chosen)
+	if err != nil {
+		return importUpload{}, err
+	}
+	if _, ok := columns["value"]; !ok {
+		return importUpload{}, errPriceGuideNoValue
+	}
+	_, byID := columns["record_id"]
+	_, byBarcode := columns["barcode"]
+	_, byTitle := columns["title"]
+	if !byID && !byBarcode && !byTitle {
+		return importUpload{}, errPriceGuideNoMatch
+	}
+	return mappedUpload("price_guide", header, values, lines, columns), nil
+}
+
+// parsePriceGuideRow reads an entry into a value estimate, returning every
+// problem found
+func (h *Handler) parsePriceGuideRow(entry importSource, req PriceGuideRequest, now time.Time) (priceGuideRow, []string) {
+	get := func(field string) string {
+		return strings.TrimSpace(entry.fields[field])
+	}
+
+	row := priceGuideRow{
+		line:   entry.line,
+		values: entry.values,
+		artist: get("artist"),
+		match: CreateRecordRequest{
+			Title:         get("title"),
+			CatalogNumber: get("catalog_number"),
+			Barcode:       get("barcode"),
+		},
+		value: store.CreateRecordValueParams{
+			Currency: cmp.Or(strings.ToUpper(get("currency")), req.Currency, defaultCurrency),
+			Source:   sql.NullString{String: strings.TrimSpace(req.Source), Valid: strings.TrimSpace(req.Source) != ""},
+		},
+	}
+	_, hasArtist := entry.fields["artist"]
+	_, hasCatalog := entry.fields["catalog_number"]
+	row.titleOnly = !hasArtist && !hasCatalog
+
+	var problems []string
+	if id := get("record_id"); id != "" {
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil || n < 1 {
+			problems = append(problems, fmt.Sprintf("record ID %q is not a number", id))
+		}
+		row.recordID = n
+	} else if row.match.Barcode == "" && row.match.Title == "" {
+		problems = append(problems, "nothing identifies the record; give a record ID, barcode or title")
+	}
+
+	if amount := get("value"); amount == "" {
+		problems = append(problems, "the value is missing")
+	} else if c, ok := parseAmount(amount); !ok {
+		problems = append(problems, fmt.Sprintf("value %q is not an amount", amount))
+	} else {
+		row.value.AmountCents = c
+	}
+
+	if err := h.validate.Var(row.value.Currency, "iso4217"); err != nil {
+		problems = append(problems, fmt.Sprintf("currency %q is not an ISO 4217 code", row.value.Currency))
+	}
+
+	valuedAt := cmp.Or(get("valued_at"), req.ValuedAt)
+	if valuedAt == "" {
+		row.value.ValuedAt = today(now)
+	} else if t, err := time.Parse("2006-01-02", valuedAt); err != nil {
+		problems = append(problems, fmt.Sprintf("date %q is not a date like 2026-10-16", valuedAt))
+	} else {
+		row.value.ValuedAt = t
+	}
+	return row, problems
+}
+
+// priceGuideRecord returns the id of the record a price guide row values, or
+// 0 if there's none: the record with its id, or one matching its barcode, or
+// its title, artist and catalog number. A guide without artists or catalog
+// numbers matches on the title alone, when only one record has it.
+func priceGuideRecord(ctx context.Context, q *store.Queries, row priceGuideRow) (int64, error) {
+	if row.recordID > 0 {
+		_, err := q.GetRecord(ctx, row.recordID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return row.recordID, err
+	}
+
+	match := row.match
+	if row.titleOnly && match.Title != "" {
+		if id, err := matchBarcode(ctx, q, match.Barcode); err != nil || id > 0 {
+			return id, err
+		}
+		ids, err := q.ListRecordIDsByTitle(ctx, match.Title)
+		if err != nil || len(ids) != 1 {
+			return 0, err
+		}
+		return ids[0], nil
+	}
+	if row.artist != "" {
+		artist, err := q.GetArtistByName(ctx, row.artist)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// No record is by an artist that isn't in the collection; only
+			// the barcode can match
+			match.Title = ""
+		case err != nil:
+			return 0, err
+		default:
+			match.ArtistID = artist.ID
+		}
+	}
+	if match.Barcode == "" && match.Title == "" {
+		return 0, nil
+	}
+	return matchRecord(ctx, q, match)
+}
+
+// importPriceGuide adds a value estimate for every record a price guide
+// matches, in a single transaction. Rows that can't be read are rejected and
+// rows matching no record are reported as unmatched. A dry run does the same
+// and rolls it all back.
+func (h *Handler) importPriceGuide(ctx context.Context, upload importUpload, req PriceGuideRequest, now time.Time) (PriceGuideReport, error) {
+	report := PriceGuideReport{
+		DryRun:    req.DryRun,
+		Columns:   upload.columns,
+		Total:     len(upload.entries),
+		Rows:      []ValuedRow{},
+		Unmatched: []RejectedRow{},
+		Rejected:  []RejectedRow{},
+	}
+
+	var rows []priceGuideRow
+	for _, entry := range upload.entries {
+		row, problems := h.parsePriceGuideRow(entry, req, now)
+		if len(problems) > 0 {
+			report.Rejected = append(report.Rejected, RejectedRow{Line: entry.line, Errors: problems, Values: entry.values})
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	err := h.withTx(ctx, func(q *store.Queries) error {
+		for _, row := range rows {
+			recordID, err := priceGuideRecord(ctx, q, row)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", row.line, err)
+			}
+			if recordID == 0 {
+				report.Unmatched = append(report.Unmatched, RejectedRow{
+					Line:   row.line,
+					Errors: []string{"no record in the collection matches"},
+					Values: row.values,
+				})
+				continue
+			}
+
+			record, err := q.GetRecord(ctx, recordID)
+			if err != nil {
+				return err
+			}
+			row.value.RecordID = recordID
+			if _, err := q.CreateRecordValue(ctx, row.value); err != nil {
+				return fmt.Errorf("line %d: %w", row.line, err)
+			}
+			report.Rows = append(report.Rows, ValuedRow{
+				Line:        row.line,
+				RecordID:    recordID,
+				Title:       record.Title,
+				AmountCents: row.value.AmountCents,
+				Currency:    row.value.Currency,
+			})
+		}
+
+		if req.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return PriceGuideReport{}, err
+	}
+
+	report.Valued = len(report.Rows)
+	return report, nil
+}
+
+// valueErrorStatus maps an error from saving a purchase or value to a status
+// and message
+func valueErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Record not found"
+	case errors.Is(err, errValueNotFound):
+		return http.StatusNotFound, "Value not found"
+	default:
+		return http.StatusInternalServerError, "Failed to save value"
+	}
+}
+
+// priceGuideErrorStatus maps a price guide import error to a status and
+// message
+func priceGuideErrorStatus(err error) (int, string) {
+	if errors.Is(err, errPriceGuideNoValue) || errors.Is(err, errPriceGuideNoMatch) {
+		return http.StatusBadRequest, err.Error()
+	}
+	status, message := importErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		message = "Failed to import values"
+	}
+	return status, message
+}
+
+// pathValueID parses the {valueID} path value
+func pathValueID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(r.PathValue("valueID"), 10, 64)
+}
+
+// renderRecordValue renders the record page's purchase and value section
+func (h *Handler) renderRecordValue(w http.ResponseWriter, r *http.Request, recordID int64) {
+	value, err := h.recordValue(r.Context(), recordID)
+	if err != nil {
+		status, message := valueErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to retrieve value", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+		}
+		http.Error(w, message, status)
+		return
+	}
+
+	h.renderer.Render(w, "record-value", map[string]interface{}{
+		"RecordID": recordID,
+		"Value":    value,
+	})
+}
+
+// HTML Handlers
+
+// GET /value
+// ?by=artist|decade|location breaks the total down
+func (h *Handler) GetValue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ValueSummaryRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			http.Error(w, "Invalid parameter: by", http.StatusBadRequest)
+			return
+		}
+
+		summary, err := h.valueSummary(r.Context(), req.By)
+		if err != nil {
+			h.logger.Error("Failed to add up value", slog.String("error", err.Error()))
+			http.Error(w, "Failed to add up value", http.StatusInternalServerError)
+			return
+		}
+
+		err = h.renderer.Render(w, "value", map[string]interface{}{
+			"Title":     "Collection value",
+			"Summary":   summary,
+			"Groupings": valueGroupings,
+			"Columns":   priceGuideColumns,
+			"MaxRows":   maxImportRows,
+		})
+		if err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// PUT /records/{id}/purchase
+func (h *Handler) UpdateRecordPurchase() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req PurchaseRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.setPurchase(r.Context(), recordID, req); err != nil {
+			status, message := valueErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to save purchase", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderRecordValue(w, r, recordID)
+	}
+}
+
+// DELETE /records/{id}/purchase
+func (h *Handler) DeleteRecordPurchase() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.clearPurchase(r.Context(), recordID); err != nil {
+			status, message := valueErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to clear purchase", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderRecordValue(w, r, recordID)
+	}
+}
+
+// POST /records/{id}/values
+func (h *Handler) CreateRecordValue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req ValueRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.addValue(r.Context(), recordID, req, time.Now()); err != nil {
+			status, message := valueErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to add value", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderRecordValue(w, r, recordID)
+	}
+}
+
+// DELETE /records/{id}/values/{valueID}
+func (h *Handler) DeleteRecordValue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		valueID, err := pathValueID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: valueID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.deleteValue(r.Context(), recordID, valueID); err != nil {
+			status, message := valueErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to delete value", slog.String("error", err.Error()), slog.Int64("valueID", valueID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderRecordValue(w, r, recordID)
+	}
+}
+
+// POST /value/import
+func (h *Handler) ImportPriceGuide() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The file is read first so the multipart form is parsed before binding
+		file, err := uploadedFile(r)
+		if err != nil {
+			status, message := priceGuideErrorStatus(err)
+			http.Error(w, message, status)
+			return
+		}
+		defer file.Close()
+
+		var req PriceGuideRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := h.priceGuide(r.Context(), file, chosenColumns(r, priceGuideColumns), req)
+		if err != nil {
+			status, message := priceGuideErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to import values", slog.String("error", err.Error()))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderer.Render(w, "price-guide-report", map[string]interface{}{
+			"Report":  report,
+			"Preview": report.Rows[:min(len(report.Rows), maxPreviewRows)],
+		})
+	}
+}
+
+// priceGuide reads a price guide upload and imports it
+func (h *Handler) priceGuide(ctx context.Context, r io.Reader, chosen map[string]string, req PriceGuideRequest) (PriceGuideReport, error) {
+	upload, err := readPriceGuide(r, chosen)
+	if err != nil {
+		return PriceGuideReport{}, err
+	}
+	return h.importPriceGuide(ctx, upload, req, time.Now())
+}
+
+// API Handlers
+
+// GET /api/v1/value
+// What the collection cost and is worth, per currency. ?by=artist, decade
+// or location breaks it down.
+func (h *Handler) JsonGetValue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ValueSummaryRequest
+		if err := h.bindQuery(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		summary, err := h.valueSummary(r.Context(), req.By)
+		if err != nil {
+			h.logger.Error("Failed to add up value", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to add up value", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, summary, http.StatusOK)
+	}
+}
+
+// GET /api/v1/records/{id}/value
+func (h *Handler) JsonGetRecordValue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		value, err := h.recordValue(r.Context(), recordID)
+		if err != nil {
+			status, message := valueErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to retrieve value", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, value, http.StatusOK)
+	}
+}
+
+// PUT /api/v1/records/{id}/purchase
+func (h *Handler) JsonUpdateRecordPurchase() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req PurchaseRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		purchase, err := h.setPurchase(r.Context(), recordID, req)
+		if err != nil {
+			status, message := valueErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to save purchase", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, purchase, http.StatusOK)
+	}
+}
+
+// DELETE /api/v1/records/{id}/purchase
+func (h *Handler) JsonDeleteRecordPurchase() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.clearPurchase(r.Context(), recordID); err != nil {
+			status, message := valueErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to clear purchase", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// POST /api/v1/records/{id}/values
+func (h *Handler) JsonCreateRecordValue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req ValueRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		value, err := h.addValue(r.Context(), recordID, req, time.Now())
+		if err != nil {
+			status, message := valueErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to add value", slog.String("error", err.Error()), slog.Int64("recordID", recordID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		h.writeJSON(w, value, http.StatusCreated)
+	}
+}
+
+// DELETE /api/v1/records/{id}/values/{valueID}
+func (h *Handler) JsonDeleteRecordValue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+		valueID, err := pathValueID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: valueID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.deleteValue(r.Context(), recordID, valueID); err != nil {
+			status, message := valueErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to delete value", slog.String("error", err.Error()), slog.Int64("valueID", valueID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// POST /api/v1/values/import
+func (h *Handler) JsonImportPriceGuide() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		file, err := uploadedFile(r)
+		if err != nil {
+			status, message := priceGuideErrorStatus(err)
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+		defer file.Close()
+
+		var req PriceGuideRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := h.priceGuide(r.Context(), file, chosenColumns(r, priceGuideColumns), req)
+		if err != nil {
+			status, message := priceGuideErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to import values", slog.String("error", err.Error()))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		status := http.StatusCreated
+		if report.DryRun {
+			status = http.StatusOK
+		}
+		h.writeJSON(w, report, status)
+	}
+}
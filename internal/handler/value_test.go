@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/dd/internal/store"
+)
+
+// TestValue_RecordValue tests that a record's current value is its latest
+// estimate, and that the gain is only worked out in the purchase currency
+func TestValue_RecordValue(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	record, err := h.createRecord(ctx, CreateRecordRequest{Title: "Bleach", ArtistName: "Nirvana"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+
+	purchase, err := h.setPurchase(ctx, record.ID, PurchaseRequest{Price: 12.499, PurchasedAt: "2026-01-02", Shop: " Amoeba "})
+	if err != nil {
+		t.Fatalf("setPurchase() error = %v", err)
+	}
+	if purchase.PriceCents.Int64 != 1250 || purchase.Currency != defaultCurrency || purchase.Shop.String != "Amoeba" {
+		t.Errorf("purchase = %+v, want 1250 USD cents from Amoeba", purchase)
+	}
+
+	if _, err := h.addValue(ctx, record.ID, ValueRequest{Amount: 30, ValuedAt: "2026-03-01"}, now); err != nil {
+		t.Fatalf("addValue() error = %v", err)
+	}
+	latest, err := h.addValue(ctx, record.ID, ValueRequest{Amount: 10, Source: "Discogs"}, now)
+	if err != nil {
+		t.Fatalf("addValue() error = %v", err)
+	}
+	if !latest.ValuedAt.Equal(today(now)) {
+		t.Errorf("valued at = %v, want today", latest.ValuedAt)
+	}
+
+	value, err := h.recordValue(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("recordValue() error = %v", err)
+	}
+	if len(value.Values) != 2 || value.Current == nil || value.Current.ID != latest.ID {
+		t.Fatalf("value = %+v, want 2 estimates with today's current", value)
+	}
+	if value.GainCents == nil || *value.GainCents != -250 || !value.Loss() {
+		t.Errorf("gain = %v, want a loss of 250 cents", value.GainCents)
+	}
+
+	// A value in another currency can't be compared to the price
+	if _, err := h.addValue(ctx, record.ID, ValueRequest{Amount: 20, Currency: "EUR", ValuedAt: "2026-10-17"}, now); err != nil {
+		t.Fatalf("addValue() error = %v", err)
+	}
+	if value, err = h.recordValue(ctx, record.ID); err != nil {
+		t.Fatalf("recordValue() error = %v", err)
+	}
+	if value.Current.Currency != "EUR" || value.GainCents != nil {
+		t.Errorf("value = %+v, want a EUR current value and no gain", value)
+	}
+
+	if err := h.deleteValue(ctx, record.ID+100, latest.ID); !errors.Is(err, errValueNotFound) {
+		t.Errorf("delete another record's value error = %v, want errValueNotFound", err)
+	}
+	if err := h.clearPurchase(ctx, record.ID); err != nil {
+		t.Errorf("clearPurchase() error = %v", err)
+	}
+	if _, err := h.recordValue(ctx, record.ID+100); err == nil {
+		t.Error("recordValue() of an unknown record error = nil, want sql.ErrNoRows")
+	}
+}
+
+// TestValue_Summary tests the collection value in total and broken down,
+// with each currency kept apart
+func TestValue_Summary(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+	now := time.Now()
+
+	shelf, err := queries.CreateLocation(ctx, store.CreateLocationParams{Name: "Shelf A"})
+	if err != nil {
+		t.Fatalf("CreateLocation() error = %v", err)
+	}
+
+	for _, r := range []struct {
+		title, artist string
+		year          int32
+		paid, worth   float64
+		currency      string
+	}{
+		{"Bleach", "Nirvana", 1989, 10, 25, ""},
+		{"Nevermind", "Nirvana", 1991, 20, 15, ""},
+		{"Dry", "PJ Harvey", 1992, 0, 40, ""},
+		{"Rid of Me", "PJ Harvey", 1993, 18, 0, ""},
+		{"Unknown Pleasures", "Joy Division", 1979, 30, 50, "GBP"},
+	} {
+		record, err := h.createRecord(ctx, CreateRecordRequest{Title: r.title, ArtistName: r.artist, ReleaseYear: r.year, CurrentLocationID: shelf.ID})
+		if err != nil {
+			t.Fatalf("createRecord() error = %v", err)
+		}
+		if r.paid > 0 {
+			if _, err := h.setPurchase(ctx, record.ID, PurchaseRequest{Price: r.paid, Currency: r.currency}); err != nil {
+				t.Fatalf("setPurchase() error = %v", err)
+			}
+		}
+		if r.worth > 0 {
+			// An older estimate is ignored
+			if _, err := h.addValue(ctx, record.ID, ValueRequest{Amount: 1000, Currency: r.currency, ValuedAt: "2020-01-01"}, now); err != nil {
+				t.Fatalf("addValue() error = %v", err)
+			}
+			if _, err := h.addValue(ctx, record.ID, ValueRequest{Amount: r.worth, Currency: r.currency}, now); err != nil {
+				t.Fatalf("addValue() error = %v", err)
+			}
+		}
+	}
+
+	summary, err := h.valueSummary(ctx, store.ValueByArtist)
+	if err != nil {
+		t.Fatalf("valueSummary() error = %v", err)
+	}
+	if len(summary.Total) != 2 {
+		t.Fatalf("total = %+v, want a GBP and a USD row", summary.Total)
+	}
+	usd := summary.Total[1]
+	if usd.Currency != "USD" || usd.Records != 4 || usd.PaidCents != 4800 || usd.ValueCents != 8000 || usd.GainCents != 1000 {
+		t.Errorf("USD total = %+v, want 4 records, 4800 paid, 8000 worth and 1000 gained", usd)
+	}
+
+	groups := map[string]store.ValueSummaryRow{}
+	for _, g := range summary.Groups {
+		groups[g.Group.String+" "+g.Currency] = g
+	}
+	if len(groups) != 3 || groups["Nirvana USD"].GainCents != 1000 || groups["PJ Harvey USD"].GainCents != 0 || groups["Joy Division GBP"].ValueCents != 5000 {
+		t.Errorf("groups by artist = %+v", summary.Groups)
+	}
+
+	if summary, err = h.valueSummary(ctx, store.ValueByDecade); err != nil {
+		t.Fatalf("valueSummary() error = %v", err)
+	}
+	var decades []string
+	for _, g := range summary.Groups {
+		decades = append(decades, g.Group.String+" "+g.Currency)
+	}
+	if strings.Join(decades, ",") != "1970s GBP,1980s USD,1990s USD" {
+		t.Errorf("decades = %v, want the 1970s, 1980s and 1990s", decades)
+	}
+
+	if summary, err = h.valueSummary(ctx, store.ValueByLocation); err != nil {
+		t.Fatalf("valueSummary() error = %v", err)
+	}
+	if len(summary.Groups) != 2 || summary.Groups[0].Group.String != "Shelf A" {
+		t.Errorf("groups by location = %+v, want Shelf A in both currencies", summary.Groups)
+	}
+}
+
+// TestValue_ImportPriceGuide tests matching price guide rows to records by
+// id, barcode, and artist and title, and that a dry run saves nothing
+func TestValue_ImportPriceGuide(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries, validate: newValidator()}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	bleach, err := h.createRecord(ctx, CreateRecordRequest{Title: "Bleach", ArtistName: "Nirvana", CatalogNumber: "SP34"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	nevermind, err := h.createRecord(ctx, CreateRecordRequest{Title: "Nevermind", ArtistName: "Nirvana", Barcode: "720642442525"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	dry, err := h.createRecord(ctx, CreateRecordRequest{Title: "Dry", ArtistName: "PJ Harvey"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+
+	guide := "Record ID,UPC,Artist,Title,Cat No,Median,Currency,Date\n" +
+		strconv.FormatInt(dry.ID, 10) + ",,,,,£12,GBP,2026-09-01\n" +
+		",0 720642 44252 5,,,,\"$1,024.50\",,\n" +
+		",,Nirvana,bleach,SP34,25,,\n" +
+		",,Nirvana,In Utero,,30,,\n" +
+		",,Mudhoney,Bleach,SP34,30,,\n" +
+		",,Nirvana,Bleach,,lots,ZZZ,yesterday\n" +
+		",,,,,5,,\n"
+
+	importGuide := func(dryRun bool) PriceGuideReport {
+		upload, err := readPriceGuide(strings.NewReader(guide), nil)
+		if err != nil {
+			t.Fatalf("readPriceGuide() error = %v", err)
+		}
+		report, err := h.importPriceGuide(ctx, upload, PriceGuideRequest{DryRun: dryRun, Source: "Discogs"}, now)
+		if err != nil {
+			t.Fatalf("importPriceGuide() error = %v", err)
+		}
+		return report
+	}
+
+	preview := importGuide(true)
+	if preview.Valued != 3 || len(preview.Unmatched) != 2 || len(preview.Rejected) != 2 {
+		t.Fatalf("preview = %+v, want 3 valued, 2 unmatched and 2 rejected", preview)
+	}
+	if errs := preview.Rejected[0].Errors; len(errs) != 3 {
+		t.Errorf("rejected errors = %v, want the value, currency and date", errs)
+	}
+	values, err := queries.ListRecordValues(ctx, bleach.ID)
+	if err != nil {
+		t.Fatalf("ListRecordValues() error = %v", err)
+	}
+	if len(values) != 0 {
+		t.Errorf("values after a dry run = %d, want 0", len(values))
+	}
+
+	report := importGuide(false)
+	want := map[int64]int64{dry.ID: 1200, nevermind.ID: 102450, bleach.ID: 2500}
+	for _, row := range report.Rows {
+		if want[row.RecordID] != row.AmountCents {
+			t.Errorf("line %d valued record %d at %d, want %d", row.Line, row.RecordID, row.AmountCents, want[row.RecordID])
+		}
+	}
+
+	values, err = queries.ListRecordValues(ctx, dry.ID)
+	if err != nil {
+		t.Fatalf("ListRecordValues() error = %v", err)
+	}
+	if len(values) != 1 || values[0].Currency != "GBP" || values[0].Source.String != "Discogs" || values[0].ValuedAt.Format("2006-01-02") != "2026-09-01" {
+		t.Errorf("values = %+v, want one GBP Discogs value from 2026-09-01", values)
+	}
+
+	if _, err := readPriceGuide(strings.NewReader("Artist,Title\nNirvana,Bleach\n"), nil); !errors.Is(err, errPriceGuideNoValue) {
+		t.Errorf("no value column error = %v, want errPriceGuideNoValue", err)
+	}
+	if _, err := readPriceGuide(strings.NewReader("Artist,Value\nNirvana,10\n"), nil); !errors.Is(err, errPriceGuideNoMatch) {
+		t.Errorf("no record column error = %v, want errPriceGuideNoMatch", err)
+	}
+}
+
+// TestValue_ImportPriceGuideByTitle tests that a guide without artists or
+// catalog numbers matches on the title alone when only one record has it,
+// and that a guide with an artist column still needs the artist to match
+func TestValue_ImportPriceGuideByTitle(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries, validate: newValidator()}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	bleach, err := h.createRecord(ctx, CreateRecordRequest{Title: "Bleach", ArtistName: "Nirvana", CatalogNumber: "SP34"})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	for _, artist := range []string{"Weezer", "Blind Melon"} {
+		if _, err := h.createRecord(ctx, CreateRecordRequest{Title: "Blue Album", ArtistName: artist}); err != nil {
+			t.Fatalf("createRecord() error = %v", err)
+		}
+	}
+
+	importGuide := func(guide string) PriceGuideReport {
+		t.Helper()
+		upload, err := readPriceGuide(strings.NewReader(guide), nil)
+		if err != nil {
+			t.Fatalf("readPriceGuide() error = %v", err)
+		}
+		report, err := h.importPriceGuide(ctx, upload, PriceGuideRequest{DryRun: true}, now)
+		if err != nil {
+			t.Fatalf("importPriceGuide() error = %v", err)
+		}
+		return report
+	}
+
+	// Only titles: a title one record has matches it, one two records share
+	// doesn't
+	report := importGuide("Title,Value\nbleach,25\nBlue Album,10\n")
+	if len(report.Rows) != 1 || report.Rows[0].RecordID != bleach.ID || len(report.Unmatched) != 1 {
+		t.Errorf("title-only report = %+v, want Bleach matched and Blue Album unmatched", report)
+	}
+
+	// With an artist column, a row missing the artist doesn't match on its
+	// title
+	report = importGuide("Artist,Title,Value\n,Bleach,25\nNirvana,Bleach,30\n")
+	if len(report.Rows) != 0 || len(report.Unmatched) != 2 {
+		t.Errorf("artist column report = %+v, want both unmatched without the catalog number", report)
+	}
+	report = importGuide("Artist,Title,Cat No,Value\nNirvana,Bleach,SP34,25\n")
+	if len(report.Rows) != 1 || report.Rows[0].RecordID != bleach.ID {
+		t.Errorf("full match report = %+v, want Bleach matched", report)
+	}
+}
+
+// TestParseAmount tests reading amounts the way price guides write them
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   int64
+		wantOK bool
+	}{
+		{"24.99", 2499, true},
+		{"$1,024.50", 102450, true},
+		{"€12", 1200, true},
+		{"1,000,000", 100000000, true},
+		{"24,99", 0, false},
+		{"1,5", 0, false},
+		{"1,0245.00", 0, false},
+		{"1.024,50", 0, false},
+		{"lots", 0, false},
+		{"-5", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseAmount(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseAmount(%q) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// TestJsonCreateRecordValue tests the value endpoint's validation and statuses
+func TestJsonCreateRecordValue(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{
+		db:       db,
+		queries:  queries,
+		logger:   slog.New(slog.DiscardHandler),
+		validate: newValidator(),
+	}
+
+	record, err := queries.CreateRecord(ctx, store.CreateRecordParams{Title: "Bleach"})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+
+	add := func(id int64, body string) int {
+		r := httptest.NewRequest("POST", "/api/v1/records/"+strconv.FormatInt(id, 10)+"/values", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.SetPathValue("id", strconv.FormatInt(id, 10))
+		w := httptest.NewRecorder()
+		h.JsonCreateRecordValue()(w, r)
+		return w.Code
+	}
+
+	tests := []struct {
+		name string
+		id   int64
+		body string
+		want int
+	}{
+		{"no amount", record.ID, `{}`, http.StatusBadRequest},
+		{"bad currency", record.ID, `{"amount": 10, "currency": "dollars"}`, http.StatusBadRequest},
+		{"bad date", record.ID, `{"amount": 10, "valued_at": "last week"}`, http.StatusBadRequest},
+		{"unknown record", record.ID + 100, `{"amount": 10}`, http.StatusNotFound},
+		{"valued", record.ID, `{"amount": 10, "currency": "EUR", "source": "Discogs"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		if got := add(tt.id, tt.body); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,566 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/dukerupert/dd/internal/store"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// errWantNotFound is returned when a record is created for an unknown want
+	errWantNotFound = errors.New("want not found")
+	// errWantAcquired is returned when a want has already been acquired
+	errWantAcquired = errors.New("want has already been acquired")
+)
+
+// WantPriority is a priority a want can have
+type WantPriority struct {
+	Value int64
+	Name  string
+}
+
+// wantPriorities are the priorities, highest first
+var wantPriorities = []WantPriority{{1, "High"}, {2, "Medium"}, {3, "Low"}}
+
+// defaultWantPriority is the priority of a want that isn't given one
+const defaultWantPriority = 2
+
+// WantRequest is the body for adding or editing a want. MaxPrice is what
+// it's worth paying, e.g. 24.99; 0 means no limit. Priority is 1 (high) to 3
+// (low) and defaults to 2.
+type WantRequest struct {
+	ArtistName    string  `form:"artist_name" json:"artist_name" validate:"required,notblank,max=100"`
+	Title         string  `form:"title" json:"title" validate:"required,notblank,max=200"`
+	Pressing      string  `form:"pressing" json:"pressing" validate:"max=200"`
+	CatalogNumber string  `form:"catalog_number" json:"catalog_number" validate:"max=100"`
+	Barcode       string  `form:"barcode" json:"barcode" validate:"omitempty,barcode"`
+	MaxPrice      float64 `form:"max_price" json:"max_price" validate:"omitempty,min=0,max=1000000"`
+	Priority      int64   `form:"priority" json:"priority" validate:"omitempty,min=1,max=3"`
+	Notes         string  `form:"notes" json:"notes" validate:"max=1000"`
+}
+
+// params converts the request to the columns shared by creating and
+// updating a want
+func (req WantRequest) params() store.CreateWantParams {
+	priority := req.Priority
+	if priority == 0 {
+		priority = defaultWantPriority
+	}
+	cents := int64(math.Round(req.MaxPrice * 100))
+	return store.CreateWantParams{
+		ArtistName:    strings.TrimSpace(req.ArtistName),
+		Title:         strings.TrimSpace(req.Title),
+		Pressing:      sql.NullString{String: req.Pressing, Valid: req.Pressing != ""},
+		CatalogNumber: sql.NullString{String: req.CatalogNumber, Valid: req.CatalogNumber != ""},
+		Barcode:       sql.NullString{String: normalizeBarcode(req.Barcode), Valid: req.Barcode != ""},
+		MaxPriceCents: sql.NullInt64{Int64: cents, Valid: cents > 0},
+		Priority:      priority,
+		Notes:         sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+	}
+}
+
+// AcquireWantRequest is what's known about a want once it's found. The
+// record takes its title, artist, catalog number, barcode and notes from the
+// want unless they're given here.
+type AcquireWantRequest struct {
+	Title             string `form:"title" json:"title" validate:"max=200"`
+	AlbumTitle        string `form:"album_title" json:"album_title" validate:"max=200"`
+	ReleaseYear       int32  `form:"release_year" json:"release_year" validate:"omitempty,min=1900,max=2100"`
+	CurrentLocationID int64  `form:"current_location_id" json:"current_location_id" validate:"omitempty,min=1"`
+	HomeLocationID    int64  `form:"home_location_id" json:"home_location_id" validate:"omitempty,min=1"`
+	CatalogNumber     string `form:"catalog_number" json:"catalog_number" validate:"max=100"`
+	MediaGrade        string `form:"media_grade" json:"media_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	SleeveGrade       string `form:"sleeve_grade" json:"sleeve_grade" validate:"omitempty,oneof=M NM VG+ VG G+ G F P"`
+	Notes             string `form:"notes" json:"notes" validate:"max=1000"`
+	Barcode           string `form:"barcode" json:"barcode" validate:"omitempty,barcode"`
+	ReleaseID         string `form:"release_id" json:"release_id" validate:"omitempty,max=50,alphanum"`
+}
+
+// record merges the request over the want into the record to create
+func (req AcquireWantRequest) record(want store.Want) CreateRecordRequest {
+	rec := wantRecord(want)
+	rec.Title = cmp.Or(req.Title, rec.Title)
+	rec.AlbumTitle = req.AlbumTitle
+	rec.ReleaseYear = req.ReleaseYear
+	rec.CurrentLocationID = req.CurrentLocationID
+	rec.HomeLocationID = req.HomeLocationID
+	rec.CatalogNumber = cmp.Or(req.CatalogNumber, rec.CatalogNumber)
+	rec.MediaGrade = req.MediaGrade
+	rec.SleeveGrade = req.SleeveGrade
+	rec.Notes = cmp.Or(req.Notes, rec.Notes)
+	rec.Barcode = cmp.Or(req.Barcode, rec.Barcode)
+	rec.ReleaseID = req.ReleaseID
+	return rec
+}
+
+// wantRecord is the record a want becomes: its title, artist, catalog
+// number, barcode and notes
+func wantRecord(want store.Want) CreateRecordRequest {
+	return CreateRecordRequest{
+		Title:         want.Title,
+		ArtistName:    want.ArtistName,
+		CatalogNumber: want.CatalogNumber.String,
+		Barcode:       want.Barcode.String,
+		Notes:         want.Notes.String,
+		WantID:        want.ID,
+	}
+}
+
+// WantResponse is a want with the records already in the collection that it
+// matches, so a second copy isn't bought by mistake. Only open wants have
+// matches.
+type WantResponse struct {
+	store.Want
+	Matches []store.ListWantMatchesRow `json:"matches"`
+}
+
+// AcquireWantResponse is an acquired want and the record it became
+type AcquireWantResponse struct {
+	Want   store.Want   `json:"want"`
+	Record store.Record `json:"record"`
+}
+
+// wants lists the open wants with their matches, or the acquired ones
+func (h *Handler) wants(ctx context.Context, acquired bool) ([]WantResponse, error) {
+	list := h.queries.ListWants
+	if acquired {
+		list = h.queries.ListAcquiredWants
+	}
+	wants, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make(map[int64][]store.ListWantMatchesRow)
+	if !acquired {
+		rows, err := h.queries.ListWantMatches(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			matches[row.WantID] = append(matches[row.WantID], row)
+		}
+	}
+
+	resp := make([]WantResponse, 0, len(wants))
+	for _, want := range wants {
+		resp = append(resp, newWantResponse(want, matches[want.ID]))
+	}
+	return resp, nil
+}
+
+// want returns a want with its matches. Returns sql.ErrNoRows if it doesn't
+// exist.
+func (h *Handler) want(ctx context.Context, wantID int64) (WantResponse, error) {
+	want, err := h.queries.GetWant(ctx, wantID)
+	if err != nil {
+		return WantResponse{}, err
+	}
+	return h.withMatches(ctx, want)
+}
+
+// withMatches looks up the records an open want matches
+func (h *Handler) withMatches(ctx context.Context, want store.Want) (WantResponse, error) {
+	if want.AcquiredAt.Valid {
+		return newWantResponse(want, nil), nil
+	}
+	rows, err := h.queries.ListWantMatches(ctx)
+	if err != nil {
+		return WantResponse{}, err
+	}
+	var matches []store.ListWantMatchesRow
+	for _, row := range rows {
+		if row.WantID == want.ID {
+			matches = append(matches, row)
+		}
+	}
+	return newWantResponse(want, matches), nil
+}
+
+func newWantResponse(want store.Want, matches []store.ListWantMatchesRow) WantResponse {
+	if matches == nil {
+		matches = []store.ListWantMatchesRow{}
+	}
+	return WantResponse{Want: want, Matches: matches}
+}
+
+// updateWant replaces a want's fields. Returns sql.ErrNoRows if the want
+// doesn't exist.
+func (h *Handler) updateWant(ctx context.Context, wantID int64, req WantRequest) (store.Want, error) {
+	p := req.params()
+	return h.queries.UpdateWant(ctx, store.UpdateWantParams{
+		ArtistName:    p.ArtistName,
+		Title:         p.Title,
+		Pressing:      p.Pressing,
+		CatalogNumber: p.CatalogNumber,
+		Barcode:       p.Barcode,
+		MaxPriceCents: p.MaxPriceCents,
+		Priority:      p.Priority,
+		Notes:         p.Notes,
+		ID:            wantID,
+	})
+}
+
+// acquireWant marks a want acquired as the record with q, in the record's
+// transaction. Returns errWantNotFound or errWantAcquired.
+func acquireWant(ctx context.Context, q *store.Queries, wantID, recordID int64) error {
+	want, err := q.GetWant(ctx, wantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errWantNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if want.AcquiredAt.Valid {
+		return errWantAcquired
+	}
+
+	_, err = q.MarkWantAcquired(ctx, store.MarkWantAcquiredParams{
+		RecordID: sql.NullInt64{Int64: recordID, Valid: true},
+		ID:       wantID,
+	})
+	return err
+}
+
+// renderWantsList renders the wishlist body: the open wants with their edit,
+// "found it" and delete controls, or the acquired ones
+func (h *Handler) renderWantsList(w http.ResponseWriter, r *http.Request, acquired bool) {
+	wants, err := h.wants(r.Context(), acquired)
+	if err != nil {
+		h.logger.Error("Failed to retrieve wants", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve wants", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderer.Render(w, "wants-list", map[string]interface{}{
+		"Wants":      wants,
+		"Acquired":   acquired,
+		"Priorities": wantPriorities,
+	})
+}
+
+// wantErrorStatus maps an error from saving a want to a status and message
+func wantErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Want not found"
+	case errors.Is(err, errWantAcquired):
+		return http.StatusConflict, err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to save want"
+	}
+}
+
+// HTML Handlers
+
+// GET /wants
+// ?acquired=true lists the wants that have been found instead
+func (h *Handler) GetWants() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		acquired := r.URL.Query().Get("acquired") == "true"
+		wants, err := h.wants(r.Context(), acquired)
+		if err != nil {
+			h.logger.Error("Failed to retrieve wants", slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve wants", http.StatusInternalServerError)
+			return
+		}
+
+		err = h.renderer.Render(w, "wants", map[string]interface{}{
+			"Title":      "Wishlist",
+			"Wants":      wants,
+			"Acquired":   acquired,
+			"Priorities": wantPriorities,
+		})
+		if err != nil {
+			h.logger.Error("Failed to render template", slog.String("error", err.Error()))
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+	}
+}
+
+// POST /wants
+func (h *Handler) CreateWant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req WantRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.queries.CreateWant(r.Context(), req.params()); err != nil {
+			h.logger.Error("Failed to create want", slog.String("error", err.Error()), slog.String("title", req.Title))
+			http.Error(w, "Failed to save want", http.StatusInternalServerError)
+			return
+		}
+
+		h.renderWantsList(w, r, false)
+	}
+}
+
+// PUT /wants/{id}
+func (h *Handler) UpdateWant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wantID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req WantRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(h.formatValidationErrorsHTML(validationErrs)))
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		want, err := h.updateWant(r.Context(), wantID, req)
+		if err != nil {
+			status, message := wantErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update want", slog.String("error", err.Error()), slog.Int64("wantID", wantID))
+			}
+			http.Error(w, message, status)
+			return
+		}
+
+		h.renderWantsList(w, r, want.AcquiredAt.Valid)
+	}
+}
+
+// DELETE /wants/{id}
+func (h *Handler) DeleteWant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wantID, err := pathID(r)
+		if err != nil {
+			http.Error(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		want, err := h.queries.GetWant(r.Context(), wantID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Want not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to retrieve want", slog.String("error", err.Error()), slog.Int64("wantID", wantID))
+			http.Error(w, "Failed to delete want", http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := h.queries.DeleteWant(r.Context(), wantID); err != nil {
+			h.logger.Error("Failed to delete want", slog.String("error", err.Error()), slog.Int64("wantID", wantID))
+			http.Error(w, "Failed to delete want", http.StatusInternalServerError)
+			return
+		}
+
+		h.renderWantsList(w, r, want.AcquiredAt.Valid)
+	}
+}
+
+// API Handlers
+
+// GET /api/v1/wants
+// ?acquired=true lists the wants that have been found instead
+func (h *Handler) JsonGetWants() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wants, err := h.wants(r.Context(), r.URL.Query().Get("acquired") == "true")
+		if err != nil {
+			h.logger.Error("Failed to retrieve wants", slog.String("error", err.Error()))
+			h.writeErrorJSON(w, "Failed to retrieve wants", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, wants, http.StatusOK)
+	}
+}
+
+// POST /api/v1/wants
+// The response lists any records it matches that are already in the
+// collection.
+func (h *Handler) JsonCreateWant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req WantRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		want, err := h.queries.CreateWant(r.Context(), req.params())
+		if err != nil {
+			h.logger.Error("Failed to create want", slog.String("error", err.Error()), slog.String("title", req.Title))
+			h.writeErrorJSON(w, "Failed to save want", http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := h.withMatches(r.Context(), want)
+		if err != nil {
+			h.logger.Error("Failed to match want", slog.String("error", err.Error()), slog.Int64("wantID", want.ID))
+			h.writeErrorJSON(w, "Failed to match want", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, resp, http.StatusCreated)
+	}
+}
+
+// GET /api/v1/wants/{id}
+func (h *Handler) JsonGetWant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wantID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		want, err := h.want(r.Context(), wantID)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeErrorJSON(w, "Want not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to retrieve want", slog.String("error", err.Error()), slog.Int64("wantID", wantID))
+			h.writeErrorJSON(w, "Failed to retrieve want", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, want, http.StatusOK)
+	}
+}
+
+// PUT /api/v1/wants/{id}
+func (h *Handler) JsonUpdateWant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wantID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req WantRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		want, err := h.updateWant(r.Context(), wantID, req)
+		if err != nil {
+			status, message := wantErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to update want", slog.String("error", err.Error()), slog.Int64("wantID", wantID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		resp, err := h.withMatches(r.Context(), want)
+		if err != nil {
+			h.logger.Error("Failed to match want", slog.String("error", err.Error()), slog.Int64("wantID", want.ID))
+			h.writeErrorJSON(w, "Failed to match want", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, resp, http.StatusOK)
+	}
+}
+
+// DELETE /api/v1/wants/{id}
+func (h *Handler) JsonDeleteWant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wantID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := h.queries.DeleteWant(r.Context(), wantID)
+		if err != nil {
+			h.logger.Error("Failed to delete want", slog.String("error", err.Error()), slog.Int64("wantID", wantID))
+			h.writeErrorJSON(w, "Failed to delete want", http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			h.writeErrorJSON(w, "Want not found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// POST /api/v1/wants/{id}/acquire
+// Creates the record the want has become and marks the want acquired, in one
+// transaction. Send {} to take everything from the want.
+func (h *Handler) JsonAcquireWant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wantID, err := pathID(r)
+		if err != nil {
+			h.writeErrorJSON(w, "Invalid parameter: id", http.StatusBadRequest)
+			return
+		}
+
+		var req AcquireWantRequest
+		if err := h.bind(r, &req); err != nil {
+			if validationErrs, ok := err.(validator.ValidationErrors); ok {
+				h.writeValidationErrorJSON(w, validationErrs, "Please check your input")
+				return
+			}
+			h.writeErrorJSON(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		want, err := h.queries.GetWant(r.Context(), wantID)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeErrorJSON(w, "Want not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to retrieve want", slog.String("error", err.Error()), slog.Int64("wantID", wantID))
+			h.writeErrorJSON(w, "Failed to retrieve want", http.StatusInternalServerError)
+			return
+		}
+
+		record, err := h.createRecord(r.Context(), req.record(want))
+		if err != nil {
+			status, message := createRecordErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				h.logger.Error("Failed to acquire want", slog.String("error", err.Error()), slog.Int64("wantID", wantID))
+			}
+			h.writeErrorJSON(w, message, status)
+			return
+		}
+
+		want, err = h.queries.GetWant(r.Context(), wantID)
+		if err != nil {
+			h.logger.Error("Failed to retrieve want", slog.String("error", err.Error()), slog.Int64("wantID", wantID))
+			h.writeErrorJSON(w, "Failed to retrieve want", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSON(w, AcquireWantResponse{Want: want, Record: record}, http.StatusCreated)
+	}
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestWants_Matches tests that open wants are matched to records already in
+// the collection by barcode, or by artist and title ignoring case
+func TestWants_Matches(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	byTitle, err := queries.CreateWant(ctx, WantRequest{ArtistName: " nirvana ", Title: "bleach", MaxPrice: 24.999}.params())
+	if err != nil {
+		t.Fatalf("CreateWant() error = %v", err)
+	}
+	if byTitle.ArtistName != "nirvana" || byTitle.Priority != defaultWantPriority {
+		t.Errorf("want = %+v, want trimmed artist and default priority", byTitle)
+	}
+	if byTitle.MaxPriceCents.Int64 != 2500 {
+		t.Errorf("max price = %d cents, want 2500", byTitle.MaxPriceCents.Int64)
+	}
+	byBarcode, err := queries.CreateWant(ctx, WantRequest{ArtistName: "Someone", Title: "Something", Barcode: "0 720642 44252 5", Priority: 1}.params())
+	if err != nil {
+		t.Fatalf("CreateWant() error = %v", err)
+	}
+	none, err := queries.CreateWant(ctx, WantRequest{ArtistName: "Nirvana", Title: "In Utero"}.params())
+	if err != nil {
+		t.Fatalf("CreateWant() error = %v", err)
+	}
+
+	if _, err := h.createRecord(ctx, CreateRecordRequest{Title: "Bleach", ArtistName: "Nirvana"}); err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	if _, err := h.createRecord(ctx, CreateRecordRequest{Title: "Nevermind", ArtistName: "Nirvana", Barcode: "720642442525"}); err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+
+	wants, err := h.wants(ctx, false)
+	if err != nil {
+		t.Fatalf("wants() error = %v", err)
+	}
+	if len(wants) != 3 || wants[0].ID != byBarcode.ID {
+		t.Fatalf("wants = %+v, want 3 with the high priority one first", wants)
+	}
+	matches := map[int64]int{}
+	for _, want := range wants {
+		matches[want.ID] = len(want.Matches)
+	}
+	if matches[byTitle.ID] != 1 || matches[byBarcode.ID] != 1 || matches[none.ID] != 0 {
+		t.Errorf("matches = %v, want 1 by title, 1 by barcode and none for In Utero", matches)
+	}
+
+	got, err := h.want(ctx, byBarcode.ID)
+	if err != nil {
+		t.Fatalf("want() error = %v", err)
+	}
+	if len(got.Matches) != 1 || got.Matches[0].Title != "Nevermind" {
+		t.Errorf("matches = %+v, want Nevermind", got.Matches)
+	}
+}
+
+// TestWants_Acquire tests that creating a record for a want crosses it off,
+// once, and that a bad want rolls the record back
+func TestWants_Acquire(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{db: db, queries: queries}
+
+	want, err := queries.CreateWant(ctx, WantRequest{ArtistName: "Nirvana", Title: "Bleach", CatalogNumber: "SP34"}.params())
+	if err != nil {
+		t.Fatalf("CreateWant() error = %v", err)
+	}
+
+	record, err := h.createRecord(ctx, wantRecord(want))
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	if record.CatalogNumber.String != "SP34" || !record.ArtistID.Valid {
+		t.Errorf("record = %+v, want the want's catalog number and artist", record)
+	}
+
+	acquired, err := queries.GetWant(ctx, want.ID)
+	if err != nil {
+		t.Fatalf("GetWant() error = %v", err)
+	}
+	if !acquired.AcquiredAt.Valid || acquired.RecordID.Int64 != record.ID {
+		t.Errorf("want = %+v, want acquired as record %d", acquired, record.ID)
+	}
+	open, err := h.wants(ctx, false)
+	if err != nil {
+		t.Fatalf("wants() error = %v", err)
+	}
+	if len(open) != 0 {
+		t.Errorf("open wants = %d, want 0", len(open))
+	}
+
+	before, err := queries.ListRecords(ctx)
+	if err != nil {
+		t.Fatalf("ListRecords() error = %v", err)
+	}
+	if _, err := h.createRecord(ctx, wantRecord(want)); !errors.Is(err, errWantAcquired) {
+		t.Errorf("second acquire error = %v, want errWantAcquired", err)
+	}
+	if _, err := h.createRecord(ctx, CreateRecordRequest{Title: "Gone", WantID: want.ID + 100}); !errors.Is(err, errWantNotFound) {
+		t.Errorf("unknown want error = %v, want errWantNotFound", err)
+	}
+	after, err := queries.ListRecords(ctx)
+	if err != nil {
+		t.Fatalf("ListRecords() error = %v", err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("records = %d after failed acquires, want %d", len(after), len(before))
+	}
+
+	// Deleting the record keeps the want as acquired
+	if err := queries.DeleteRecord(ctx, record.ID); err != nil {
+		t.Fatalf("DeleteRecord() error = %v", err)
+	}
+	acquired, err = queries.GetWant(ctx, want.ID)
+	if err != nil {
+		t.Fatalf("GetWant() error = %v", err)
+	}
+	if !acquired.AcquiredAt.Valid || acquired.RecordID.Valid {
+		t.Errorf("want = %+v, want still acquired without a record", acquired)
+	}
+}
+
+// TestJsonAcquireWant tests acquiring a want over the API, with overrides and
+// the status when it's already been found
+func TestJsonAcquireWant(t *testing.T) {
+	db, queries := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	h := &Handler{
+		db:       db,
+		queries:  queries,
+		logger:   slog.New(slog.DiscardHandler),
+		validate: newValidator(),
+	}
+
+	want, err := queries.CreateWant(ctx, WantRequest{ArtistName: "Nirvana", Title: "Bleach", Notes: "first pressing"}.params())
+	if err != nil {
+		t.Fatalf("CreateWant() error = %v", err)
+	}
+
+	acquire := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/v1/wants/"+strconv.FormatInt(want.ID, 10)+"/acquire", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.SetPathValue("id", strconv.FormatInt(want.ID, 10))
+		w := httptest.NewRecorder()
+		h.JsonAcquireWant()(w, r)
+		return w
+	}
+
+	w := acquire(`{"release_year": 1989, "media_grade": "VG+"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body.String())
+	}
+	var resp AcquireWantResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Record.Title != "Bleach" || resp.Record.ReleaseYear.Int64 != 1989 || resp.Record.Notes.String != "first pressing" {
+		t.Errorf("record = %+v, want the want merged with the request", resp.Record)
+	}
+	if resp.Want.RecordID.Int64 != resp.Record.ID || !resp.Want.AcquiredAt.Valid {
+		t.Errorf("want = %+v, want acquired as record %d", resp.Want, resp.Record.ID)
+	}
+
+	if w := acquire(`{}`); w.Code != http.StatusConflict {
+		t.Errorf("second acquire status = %d, want 409", w.Code)
+	}
+	if w := acquire(`{"media_grade": "great"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bad grade status = %d, want 400", w.Code)
+	}
+}
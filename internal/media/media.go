@@ -0,0 +1,209 @@
+// Package media stores record cover art on local disk and generates its
+// thumbnails.
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register GIF decoding
+	"image/jpeg"
+	_ "image/png" // register PNG decoding
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// maxPixels caps decoded image area so a small, highly compressed upload
+// can't expand into gigabytes of memory
+const maxPixels = 50_000_000
+
+// thumbnailQuality is the JPEG quality used for thumbnails
+const thumbnailQuality = 85
+
+// Thumbnail sizes, as the longest edge in pixels. Small is used in the record
+// table, medium in the grid and on the record page.
+const (
+	SizeSmall  = "sm"
+	SizeMedium = "md"
+)
+
+// ThumbnailSizes maps each thumbnail size to its longest edge in pixels
+var ThumbnailSizes = map[string]int{
+	SizeSmall:  96,
+	SizeMedium: 320,
+}
+
+// contentTypes are the accepted image types, as sniffed from the upload
+var contentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+var (
+	// ErrTooLarge is returned when an upload is over the size limit
+	ErrTooLarge = errors.New("image is too large")
+	// ErrUnsupportedType is returned when an upload isn't a JPEG, PNG or GIF
+	ErrUnsupportedType = errors.New("unsupported image type; upload a JPEG, PNG or GIF")
+	// ErrTooManyPixels is returned when an image's dimensions are too large
+	// to decode safely
+	ErrTooManyPixels = errors.New("image dimensions are too large")
+)
+
+// Image describes a stored original
+type Image struct {
+	ContentType string
+	Width       int
+	Height      int
+	Size        int64
+	// Checksum is the hex SHA-256 of the original, used as its ETag
+	Checksum string
+}
+
+// Store keeps images under a directory, one folder per record:
+// records/{id}/{kind} for the original and records/{id}/{kind}_{size}.jpg for
+// its thumbnails
+type Store struct {
+	dir      string
+	maxBytes int64
+}
+
+// NewStore creates a store rooted at dir that accepts uploads of up to
+// maxBytes
+func NewStore(dir string, maxBytes int64) *Store {
+	return &Store{dir: dir, maxBytes: maxBytes}
+}
+
+// MaxBytes returns the upload size limit
+func (s *Store) MaxBytes() int64 {
+	return s.maxBytes
+}
+
+// Path returns the file for a record's image. An empty size is the original.
+func (s *Store) Path(recordID int64, kind, size string) string {
+	name := kind
+	if size != "" {
+		name = kind + "_" + size + ".jpg"
+	}
+	return filepath.Join(s.dir, "records", strconv.FormatInt(recordID, 10), name)
+}
+
+// Save checks an upload, writes it and its thumbnails, replacing any previous
+// image of the same kind, and returns its metadata
+func (s *Store) Save(recordID int64, kind string, r io.Reader) (Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxBytes+1))
+	if err != nil {
+		return Image{}, err
+	}
+	if int64(len(data)) > s.maxBytes {
+		return Image{}, ErrTooLarge
+	}
+
+	// Trust the bytes, not the client's Content-Type
+	contentType := http.DetectContentType(data)
+	if !contentTypes[contentType] {
+		return Image{}, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrUnsupportedType
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return Image{}, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrUnsupportedType
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path(recordID, kind, "")), 0o755); err != nil {
+		return Image{}, err
+	}
+
+	for size, edge := range ThumbnailSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, Thumbnail(img, edge), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return Image{}, fmt.Errorf("encode %s thumbnail: %w", size, err)
+		}
+		if err := writeFile(s.Path(recordID, kind, size), buf.Bytes()); err != nil {
+			return Image{}, err
+		}
+	}
+	if err := writeFile(s.Path(recordID, kind, ""), data); err != nil {
+		return Image{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	return Image{
+		ContentType: contentType,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		Size:        int64(len(data)),
+		Checksum:    hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// Delete removes a record's image and its thumbnails. Missing files are not
+// an error.
+func (s *Store) Delete(recordID int64, kind string) error {
+	paths := []string{s.Path(recordID, kind, "")}
+	for size := range ThumbnailSizes {
+		paths = append(paths, s.Path(recordID, kind, size))
+	}
+
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Move renames a record's image and its thumbnails to another record,
+// replacing any image of the same kind it had. Missing files are not an
+// error.
+func (s *Store) Move(fromID, toID int64, kind string) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path(toID, kind, "")), 0o755); err != nil {
+		return err
+	}
+
+	sizes := []string{""}
+	for size := range ThumbnailSizes {
+		sizes = append(sizes, size)
+	}
+
+	for _, size := range sizes {
+		err := os.Rename(s.Path(fromID, kind, size), s.Path(toID, kind, size))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes data to a temporary file next to path and renames it into
+// place, so readers never see a half-written image
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
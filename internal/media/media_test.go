@@ -0,0 +1,168 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+)
+
+// encodePNG returns a w x h PNG filled with c
+func encodePNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestThumbnail tests thumbnail dimensions, averaging and flattening
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h, edge   int
+		wantW, wantH int
+	}{
+		{"landscape", 400, 200, 100, 100, 50},
+		{"portrait", 200, 400, 100, 50, 100},
+		{"square", 300, 300, 96, 96, 96},
+		{"already small", 50, 40, 96, 50, 40},
+		{"very thin", 1000, 2, 100, 100, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Thumbnail(image.NewRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.edge)
+			if got.Bounds().Dx() != tt.wantW || got.Bounds().Dy() != tt.wantH {
+				t.Errorf("Thumbnail(%dx%d, %d) = %v, want %dx%d", tt.w, tt.h, tt.edge, got.Bounds(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+
+	// Alternating black and white columns average to grey
+	stripes := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if x%2 == 0 {
+				stripes.Set(x, y, color.White)
+			} else {
+				stripes.Set(x, y, color.Black)
+			}
+		}
+	}
+	if got := Thumbnail(stripes, 2).RGBAAt(0, 0); got.R != 127 || got.A != 255 {
+		t.Errorf("averaged pixel = %v, want grey", got)
+	}
+
+	// Transparency is flattened onto white
+	clear := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	if got := Thumbnail(clear, 5).RGBAAt(0, 0); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("transparent pixel = %v, want white", got)
+	}
+}
+
+// TestStore_Save tests sniffing, limits, thumbnails and replacing an image
+func TestStore_Save(t *testing.T) {
+	s := NewStore(t.TempDir(), 1<<20)
+
+	img, err := s.Save(7, "front", bytes.NewReader(encodePNG(t, 640, 480, color.RGBA{200, 0, 0, 255})))
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if img.ContentType != "image/png" || img.Width != 640 || img.Height != 480 || len(img.Checksum) != 64 {
+		t.Errorf("Save() = %+v", img)
+	}
+
+	if _, err := os.Stat(s.Path(7, "front", "")); err != nil {
+		t.Errorf("original not written: %v", err)
+	}
+	for size, edge := range ThumbnailSizes {
+		f, err := os.Open(s.Path(7, "front", size))
+		if err != nil {
+			t.Fatalf("%s thumbnail not written: %v", size, err)
+		}
+		cfg, format, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil || format != "jpeg" || cfg.Width != edge {
+			t.Errorf("%s thumbnail = %s %dx%d (%v), want %d wide JPEG", size, format, cfg.Width, cfg.Height, err, edge)
+		}
+	}
+
+	// Replacing writes a new checksum over the same files
+	replaced, err := s.Save(7, "front", bytes.NewReader(encodePNG(t, 100, 100, color.White)))
+	if err != nil {
+		t.Fatalf("Save() replace error = %v", err)
+	}
+	if replaced.Checksum == img.Checksum || replaced.Width != 100 {
+		t.Errorf("replaced image = %+v", replaced)
+	}
+
+	if _, err := s.Save(7, "back", strings.NewReader("<html>not an image</html>")); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("HTML upload error = %v, want ErrUnsupportedType", err)
+	}
+	// A PNG signature alone isn't enough
+	if _, err := s.Save(7, "back", strings.NewReader("\x89PNG\r\n\x1a\ngarbage")); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("corrupt PNG error = %v, want ErrUnsupportedType", err)
+	}
+	if _, err := NewStore(t.TempDir(), 100).Save(7, "back", bytes.NewReader(encodePNG(t, 64, 64, color.Black))); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("oversized upload error = %v, want ErrTooLarge", err)
+	}
+
+	// A tiny file can claim enormous dimensions; it's rejected before decoding
+	bomb := encodePNG(t, 1, 1, color.Black)
+	binary.BigEndian.PutUint32(bomb[16:], 100_000)
+	binary.BigEndian.PutUint32(bomb[20:], 100_000)
+	binary.BigEndian.PutUint32(bomb[29:], crc32.ChecksumIEEE(bomb[12:29]))
+	if _, err := s.Save(7, "back", bytes.NewReader(bomb)); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("oversized dimensions error = %v, want ErrTooManyPixels", err)
+	}
+
+	if err := s.Delete(7, "front"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(s.Path(7, "front", SizeSmall)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("thumbnail still exists after Delete(): %v", err)
+	}
+	// Deleting again is fine
+	if err := s.Delete(7, "front"); err != nil {
+		t.Errorf("Delete() again error = %v", err)
+	}
+}
+
+func TestStore_Move(t *testing.T) {
+	s := NewStore(t.TempDir(), 1<<20)
+
+	if _, err := s.Save(7, "front", bytes.NewReader(encodePNG(t, 64, 64, color.Black))); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if err := s.Move(7, 9, "front"); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	for _, size := range []string{"", SizeSmall} {
+		if _, err := os.Stat(s.Path(9, "front", size)); err != nil {
+			t.Errorf("%q not moved: %v", size, err)
+		}
+		if _, err := os.Stat(s.Path(7, "front", size)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%q still exists after Move(): %v", size, err)
+		}
+	}
+
+	// Moving an image that isn't there is fine
+	if err := s.Move(7, 9, "back"); err != nil {
+		t.Errorf("Move() missing image error = %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package media
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Thumbnail scales img so its longest edge is at most edge pixels, keeping the
+// aspect ratio. Each output pixel is the average of the source pixels it
+// covers (a box filter), which is sharp enough for downscaling and needs
+// nothing beyond the standard library. Transparent areas are flattened onto
+// white since thumbnails are stored as JPEG. Images already small enough are
+// only flattened.
+func Thumbnail(img image.Image, edge int) *image.RGBA {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+
+	dstW, dstH := srcW, srcH
+	if srcW > edge || srcH > edge {
+		if srcW >= srcH {
+			dstW, dstH = edge, max(1, srcH*edge/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*edge/srcH), edge
+		}
+	}
+
+	// Flatten onto white in a plain RGBA buffer so the loop below can read
+	// pixels directly instead of going through color.Color
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Over)
+	if dstW == srcW && dstH == srcH {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					bl += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
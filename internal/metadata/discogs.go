@@ -0,0 +1,305 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// discogsUserAgent identifies the app, which Discogs requires of every client
+const discogsUserAgent = "DoxieDiscs/1.0 +https://github.com/dukerupert/dd"
+
+// maxResponseBytes caps how much of a response is read
+const maxResponseBytes = 4 << 20
+
+// maxRetryAfter caps how long a Retry-After header can make a lookup wait
+const maxRetryAfter = time.Minute
+
+// disambiguation matches the " (2)" Discogs appends to artists sharing a name
+var disambiguation = regexp.MustCompile(`\s\(\d+\)$`)
+
+// StripDisambiguation removes the " (2)" Discogs appends to an artist or
+// label name shared with others
+func StripDisambiguation(name string) string {
+	return disambiguation.ReplaceAllString(name, "")
+}
+
+// DiscogsClient is a MetadataProvider backed by the Discogs API, or anything
+// that speaks it. Requests are spaced out to stay under the rate limit, and
+// 429 or 5xx responses are retried with exponential backoff, honouring
+// Retry-After.
+type DiscogsClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+
+	// interval is the minimum gap between requests; Discogs allows 60 a
+	// minute with a token
+	interval time.Duration
+	// window is how long to hold off once Discogs reports no requests left
+	window     time.Duration
+	maxRetries int
+	backoff    time.Duration
+
+	mu   sync.Mutex
+	next time.Time // earliest time the next request may start
+}
+
+// NewDiscogsClient creates a client for the API at baseURL, authenticating
+// with a personal access token
+func NewDiscogsClient(baseURL, token string) *DiscogsClient {
+	return &DiscogsClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		interval:   time.Second,
+		window:     time.Minute,
+		maxRetries: 3,
+		backoff:    time.Second,
+	}
+}
+
+// Name implements MetadataProvider
+func (c *DiscogsClient) Name() string {
+	return "discogs"
+}
+
+// discogsSearchResponse is the part of GET /database/search we use
+type discogsSearchResponse struct {
+	Results []struct {
+		ID      int64    `json:"id"`
+		Title   string   `json:"title"` // "Artist - Title"
+		Year    string   `json:"year"`
+		Label   []string `json:"label"`
+		CatNo   string   `json:"catno"`
+		Format  []string `json:"format"`
+		Country string   `json:"country"`
+		Thumb   string   `json:"thumb"`
+	} `json:"results"`
+}
+
+// discogsRelease is the part of GET /releases/{id} we use
+type discogsRelease struct {
+	ID      int64  `json:"id"`
+	Title   string `json:"title"`
+	Year    int    `json:"year"`
+	Artists []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Labels []struct {
+		Name  string `json:"name"`
+		CatNo string `json:"catno"`
+	} `json:"labels"`
+	Identifiers []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"identifiers"`
+	Genres    []string `json:"genres"`
+	Styles    []string `json:"styles"`
+	Tracklist []struct {
+		Position string `json:"position"`
+		Type     string `json:"type_"` // "track", or "heading"/"index" for sub-titles
+		Title    string `json:"title"`
+		Duration string `json:"duration"`
+	} `json:"tracklist"`
+}
+
+// Search implements MetadataProvider, returning vinyl and other releases
+// matching the query
+func (c *DiscogsClient) Search(ctx context.Context, q SearchQuery) ([]SearchResult, error) {
+	params := url.Values{"type": {"release"}, "per_page": {"20"}}
+	if q.Query != "" {
+		params.Set("q", q.Query)
+	}
+	if q.Barcode != "" {
+		params.Set("barcode", q.Barcode)
+	}
+	if q.CatalogNumber != "" {
+		params.Set("catno", q.CatalogNumber)
+	}
+
+	var resp discogsSearchResponse
+	if err := c.get(ctx, "/database/search", params, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		artist, title, found := strings.Cut(r.Title, " - ")
+		if !found {
+			artist, title = "", r.Title
+		}
+		year, _ := strconv.Atoi(r.Year)
+		result := SearchResult{
+			ID:            strconv.FormatInt(r.ID, 10),
+			Artist:        StripDisambiguation(artist),
+			Title:         title,
+			Year:          year,
+			CatalogNumber: r.CatNo,
+			Format:        strings.Join(r.Format, ", "),
+			Country:       r.Country,
+			Thumbnail:     r.Thumb,
+		}
+		if len(r.Label) > 0 {
+			result.Label = r.Label[0]
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Release implements MetadataProvider. Headings in the tracklist are left
+// out.
+func (c *DiscogsClient) Release(ctx context.Context, id string) (Release, error) {
+	// Ids are numeric; anything else can't exist and mustn't reach the path
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return Release{}, ErrNotFound
+	}
+
+	var r discogsRelease
+	if err := c.get(ctx, "/releases/"+id, nil, &r); err != nil {
+		return Release{}, err
+	}
+
+	release := Release{
+		ID:      strconv.FormatInt(r.ID, 10),
+		Title:   r.Title,
+		Year:    r.Year,
+		Artists: []string{},
+		Genres:  r.Genres,
+		Styles:  r.Styles,
+		Tracks:  []Track{},
+	}
+	for _, a := range r.Artists {
+		release.Artists = append(release.Artists, StripDisambiguation(a.Name))
+	}
+	if len(r.Labels) > 0 {
+		release.Label = StripDisambiguation(r.Labels[0].Name)
+		release.CatalogNumber = r.Labels[0].CatNo
+	}
+	for _, ident := range r.Identifiers {
+		if ident.Type == "Barcode" {
+			release.Barcode = ident.Value
+			break
+		}
+	}
+	for _, t := range r.Tracklist {
+		if t.Type != "" && t.Type != "track" {
+			continue
+		}
+		release.Tracks = append(release.Tracks, Track{Position: t.Position, Title: t.Title, Duration: t.Duration})
+	}
+	return release, nil
+}
+
+// get fetches path and decodes the JSON response into v, retrying when
+// Discogs is rate limiting or failing
+func (c *DiscogsClient) get(ctx context.Context, path string, params url.Values, v any) error {
+	u := c.baseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx); err != nil {
+			return err
+		}
+
+		retryable, retryAfter, err := c.do(ctx, u, v)
+		if !retryable || attempt >= c.maxRetries {
+			return err
+		}
+
+		// Back off exponentially unless the server said how long to wait
+		delay := c.backoff << attempt
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+		c.holdOff(delay)
+	}
+}
+
+// do makes a single request. retryable reports whether it was refused in a
+// way worth retrying, and retryAfter is the server's Retry-After, if any.
+func (c *DiscogsClient) do(ctx context.Context, u string, v any) (retryable bool, retryAfter time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header.Set("User-Agent", discogsUserAgent)
+	req.Header.Set("Accept", "application/vnd.discogs.v2.discogs+json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Discogs token="+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("X-Discogs-Ratelimit-Remaining") == "0" {
+		c.holdOff(c.window)
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = min(time.Duration(seconds)*time.Second, maxRetryAfter)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, retryAfter, ErrRateLimited
+	case resp.StatusCode >= 500:
+		return true, retryAfter, fmt.Errorf("discogs: %s", resp.Status)
+	case resp.StatusCode == http.StatusNotFound:
+		return false, 0, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return false, 0, fmt.Errorf("discogs: %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(v); err != nil {
+		return false, 0, fmt.Errorf("discogs: decode response: %w", err)
+	}
+	return false, 0, nil
+}
+
+// wait blocks until the client may send its next request, reserving the slot
+// after it for whoever asks next
+func (c *DiscogsClient) wait(ctx context.Context) error {
+	c.mu.Lock()
+	start := time.Now()
+	if c.next.After(start) {
+		start = c.next
+	}
+	c.next = start.Add(c.interval)
+	c.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// holdOff delays every request for at least d from now
+func (c *DiscogsClient) holdOff(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t := time.Now().Add(d); t.After(c.next) {
+		c.next = t
+	}
+}
@@ -0,0 +1,205 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient returns a client for srv that doesn't wait between requests
+func testClient(srv *httptest.Server) *DiscogsClient {
+	c := NewDiscogsClient(srv.URL, "secret")
+	c.interval = 0
+	c.backoff = time.Millisecond
+	return c
+}
+
+// TestDiscogsClient_Search tests the query sent and how results are read
+func TestDiscogsClient_Search(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/database/search" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Discogs token=secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if r.Header.Get("User-Agent") == "" {
+			t.Error("User-Agent not sent")
+		}
+		q := r.URL.Query()
+		if q.Get("q") != "nevermind" || q.Get("barcode") != "720642442517" || q.Get("type") != "release" {
+			t.Errorf("query = %v", q)
+		}
+		w.Write([]byte(`{"pagination": {"items": 2}, "results": [
+			{"id": 367084, "title": "Nirvana - Nevermind", "year": "1991", "label": ["DGC", "Sub Pop"], "catno": "DGC-24425", "format": ["Vinyl", "LP"], "country": "US", "thumb": "https://img/t.jpg"},
+			{"id": 9, "title": "Untitled", "year": "", "label": []}
+		]}`))
+	}))
+	defer srv.Close()
+
+	results, err := testClient(srv).Search(context.Background(), SearchQuery{Query: "nevermind", Barcode: "720642442517"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	want := SearchResult{
+		ID:            "367084",
+		Artist:        "Nirvana",
+		Title:         "Nevermind",
+		Year:          1991,
+		Label:         "DGC",
+		CatalogNumber: "DGC-24425",
+		Format:        "Vinyl, LP",
+		Country:       "US",
+		Thumbnail:     "https://img/t.jpg",
+	}
+	if len(results) != 2 || results[0] != want {
+		t.Fatalf("Search() = %+v, want first %+v", results, want)
+	}
+	if results[1].Title != "Untitled" || results[1].Artist != "" || results[1].Year != 0 {
+		t.Errorf("result without artist = %+v", results[1])
+	}
+}
+
+// TestDiscogsClient_Release tests reading a release's details and tracklist
+func TestDiscogsClient_Release(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/releases/367084" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+			"id": 367084, "title": "Nevermind", "year": 1991,
+			"artists": [{"name": "Nirvana (2)"}],
+			"labels": [{"name": "DGC", "catno": "DGC-24425"}],
+			"identifiers": [{"type": "Matrix / Runout", "value": "DGC-24425-A"}, {"type": "Barcode", "value": "7 20642 44251 7"}],
+			"genres": ["Rock"], "styles": ["Grunge"],
+			"tracklist": [
+				{"position": "", "type_": "heading", "title": "Side One"},
+				{"position": "A1", "type_": "track", "title": "Smells Like Teen Spirit", "duration": "5:01"},
+				{"position": "A2", "type_": "track", "title": "In Bloom", "duration": ""}
+			]
+		}`))
+	}))
+	defer srv.Close()
+
+	c := testClient(srv)
+	release, err := c.Release(context.Background(), "367084")
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if release.ID != "367084" || release.Title != "Nevermind" || release.Year != 1991 {
+		t.Errorf("Release() = %+v", release)
+	}
+	if len(release.Artists) != 1 || release.Artists[0] != "Nirvana" {
+		t.Errorf("artists = %v, want the disambiguation stripped", release.Artists)
+	}
+	if release.Label != "DGC" || release.CatalogNumber != "DGC-24425" || release.Barcode != "7 20642 44251 7" {
+		t.Errorf("label = %q, catno = %q, barcode = %q", release.Label, release.CatalogNumber, release.Barcode)
+	}
+	want := []Track{{"A1", "Smells Like Teen Spirit", "5:01"}, {"A2", "In Bloom", ""}}
+	if len(release.Tracks) != len(want) || release.Tracks[0] != want[0] || release.Tracks[1] != want[1] {
+		t.Errorf("tracks = %+v, want %+v", release.Tracks, want)
+	}
+
+	if _, err := c.Release(context.Background(), "1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown release error = %v, want ErrNotFound", err)
+	}
+	if _, err := c.Release(context.Background(), "../users/me"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("non-numeric id error = %v, want ErrNotFound", err)
+	}
+}
+
+// TestDiscogsClient_Retry tests backing off from 429 and 5xx responses
+func TestDiscogsClient_Retry(t *testing.T) {
+	t.Run("recovers after being rate limited", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch calls.Add(1) {
+			case 1:
+				w.WriteHeader(http.StatusTooManyRequests)
+			case 2:
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				w.Write([]byte(`{"id": 1, "title": "Bleach", "tracklist": []}`))
+			}
+		}))
+		defer srv.Close()
+
+		release, err := testClient(srv).Release(context.Background(), "1")
+		if err != nil || release.Title != "Bleach" {
+			t.Fatalf("Release() = %+v, %v", release, err)
+		}
+		if calls.Load() != 3 {
+			t.Errorf("requests = %d, want 3", calls.Load())
+		}
+	})
+
+	t.Run("gives up after the last retry", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer srv.Close()
+
+		c := testClient(srv)
+		if _, err := c.Release(context.Background(), "1"); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Release() error = %v, want ErrRateLimited", err)
+		}
+		if int(calls.Load()) != c.maxRetries+1 {
+			t.Errorf("requests = %d, want %d", calls.Load(), c.maxRetries+1)
+		}
+	})
+
+	t.Run("honours Retry-After", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"results": []}`))
+		}))
+		defer srv.Close()
+
+		start := time.Now()
+		if _, err := testClient(srv).Search(context.Background(), SearchQuery{Query: "x"}); err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+		}
+	})
+
+	t.Run("stops waiting when the context ends", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := testClient(srv).Release(ctx, "1"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Release() error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("client errors aren't retried", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer srv.Close()
+
+		if _, err := testClient(srv).Release(context.Background(), "1"); err == nil || calls.Load() != 1 {
+			t.Errorf("Release() error = %v after %d requests, want an error after 1", err, calls.Load())
+		}
+	})
+}
@@ -0,0 +1,69 @@
+// Package metadata looks up release details (year, label, catalog number,
+// tracklist) in an online database so records don't have to be typed in by
+// hand.
+package metadata
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotFound is returned when the provider has no release with the id
+	ErrNotFound = errors.New("release not found")
+	// ErrRateLimited is returned when the provider is still refusing requests
+	// after every retry
+	ErrRateLimited = errors.New("release lookups are rate limited; try again shortly")
+)
+
+// SearchQuery is a release search. Barcode and CatalogNumber narrow the
+// results when given.
+type SearchQuery struct {
+	Query         string
+	Barcode       string
+	CatalogNumber string
+}
+
+// SearchResult is one release in a search, with enough detail to tell
+// pressings apart
+type SearchResult struct {
+	ID            string `json:"id"`
+	Artist        string `json:"artist"`
+	Title         string `json:"title"`
+	Year          int    `json:"year,omitempty"`
+	Label         string `json:"label,omitempty"`
+	CatalogNumber string `json:"catalog_number,omitempty"`
+	Format        string `json:"format,omitempty"`
+	Country       string `json:"country,omitempty"`
+	Thumbnail     string `json:"thumbnail,omitempty"`
+}
+
+// Track is one entry in a release's tracklist. Duration is as printed, e.g.
+// "4:31", and may be empty.
+type Track struct {
+	Position string `json:"position"`
+	Title    string `json:"title"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Release is a single release's details
+type Release struct {
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	Artists       []string `json:"artists"`
+	Year          int      `json:"year,omitempty"`
+	Label         string   `json:"label,omitempty"`
+	CatalogNumber string   `json:"catalog_number,omitempty"`
+	Barcode       string   `json:"barcode,omitempty"`
+	Genres        []string `json:"genres,omitempty"`
+	Styles        []string `json:"styles,omitempty"`
+	Tracks        []Track  `json:"tracks"`
+}
+
+// MetadataProvider searches an online release database. Name identifies the
+// provider in the local cache, so ids from different providers never mix.
+type MetadataProvider interface {
+	Name() string
+	Search(ctx context.Context, q SearchQuery) ([]SearchResult, error)
+	Release(ctx context.Context, id string) (Release, error)
+}
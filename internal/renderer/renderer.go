@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net/http"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
@@ -31,6 +32,11 @@ func New(templateFS embed.FS) *Renderer {
 			"formatDate": func(t time.Time) string {
 				return t.Format("2006-01-02")
 			},
+			"formatDuration": formatDuration,
+			"formatPrice":    formatPrice,
+			"containsID": func(ids []int64, id int64) bool {
+				return slices.Contains(ids, id)
+			},
 		},
 	}
 }
@@ -53,11 +59,13 @@ func (r *Renderer) LoadTemplates() error {
 		return err
 	}
 
-	// Parse all layouts and partials
+	// Parse all layouts and partials. Each one is parsed alongside every
+	// partial so partials can be rendered on their own (e.g. for HTMX swaps)
+	// and still include each other.
 	templates := append(layouts, partials...)
 	for _, t := range templates {
 		name := strings.TrimSuffix(filepath.Base(t), filepath.Ext(filepath.Base(t)))
-		tmpl := template.Must(template.New(name).ParseFS(r.fs, t))
+		tmpl := template.Must(template.New(name).Funcs(r.funcMap).ParseFS(r.fs, append([]string{t}, partials...)...))
 		r.templates[name] = tmpl
 	}
 
@@ -85,3 +93,20 @@ func (r *Renderer) Render(w http.ResponseWriter, name string, data interface{})
 
 	return tmpl.ExecuteTemplate(w, name, data)
 }
+
+// formatDuration formats a number of seconds as m:ss, or h:mm:ss from an hour
+func formatDuration(seconds int64) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// formatPrice formats an amount in cents with two decimal places
+func formatPrice(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,429 @@
+// Package replica replicates the SQLite database continuously to a local
+// directory, the way Litestream does, and restores it as of any moment.
+//
+// The replica is a generation per server start. Each holds snapshots of the
+// database file and the WAL's committed frames, shipped in segments as
+// they're written:
+//
+//	<dir>/<generation>/snapshots/<index>-<time>.db.gz
+//	<dir>/<generation>/wal/<index>-<offset>-<time>.wal
+//
+// index counts the WAL's restarts within the generation and offset is where
+// the segment starts in the WAL file. A snapshot taken at index i is brought
+// forward by replaying the segments of WAL i onwards.
+//
+// The app's connections must not checkpoint (see DSN). Only the replicator
+// does, holding the write lock once every frame is shipped, so the WAL never
+// restarts over frames that haven't been.
+package replica
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// timeFormat is the timestamp in generation, snapshot and segment names;
+// it's fixed width, so names sort in time order
+const timeFormat = "20060102T150405.000000000Z"
+
+// checkpointBytes is how large the WAL grows before it's checkpointed
+const checkpointBytes = 4 << 20
+
+// DSN returns the data source name to open the database at path with for
+// replication: WAL mode, no automatic checkpoints, and a busy timeout to wait
+// out the replicator's write lock. Foreign keys are enforced on every
+// connection, as for any other.
+func DSN(path string) string {
+	return path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=wal_autocheckpoint(0)"
+}
+
+// Options tune a Replicator. New frames are shipped every SyncInterval and
+// the database snapshotted every SnapshotInterval; the replica keeps enough
+// to restore to any moment in the last Retention.
+type Options struct {
+	SyncInterval     time.Duration
+	SnapshotInterval time.Duration
+	Retention        time.Duration
+}
+
+// Replicator ships the database's WAL to a replica directory
+type Replicator struct {
+	db     *sql.DB
+	logger *slog.Logger
+	path   string
+	dir    string
+	opts   Options
+
+	// Where shipping has got to: the generation, which WAL since it
+	// started, and the offset and running checksum in it
+	generation     string
+	index          int
+	header         walHeader
+	hasHeader      bool
+	offset         int64
+	sum            [2]uint32
+	checkpointedAt int64
+	lastSnapshot   time.Time
+}
+
+// New creates a replicator for the database at path, open as db with DSN,
+// into dir. The pool needs room for two connections besides the app's: one
+// holds the write lock while the other checkpoints.
+func New(db *sql.DB, logger *slog.Logger, path, dir string, opts Options) *Replicator {
+	return &Replicator{db: db, logger: logger, path: path, dir: dir, opts: opts}
+}
+
+// Run starts a generation and replicates until ctx is done
+func (r *Replicator) Run(ctx context.Context) error {
+	if err := r.start(ctx); err != nil {
+		return err
+	}
+	r.logger.Info("Replication started", slog.String("dir", r.dir), slog.String("generation", r.generation))
+
+	ticker := time.NewTicker(r.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := r.step(ctx); err != nil {
+			r.logger.Error("Replication failed", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// start begins a new generation with a snapshot. Whatever happened to the
+// WAL while the server was down, the generation starts from here.
+func (r *Replicator) start(ctx context.Context) error {
+	var mode string
+	if err := r.db.QueryRowContext(ctx, "PRAGMA journal_mode=WAL").Scan(&mode); err != nil {
+		return err
+	}
+	if mode != "wal" {
+		return fmt.Errorf("can't replicate a database in %s journal mode", mode)
+	}
+
+	r.generation = time.Now().UTC().Format(timeFormat)
+	r.index, r.hasHeader, r.offset, r.checkpointedAt = 0, false, 0, 0
+	for _, sub := range []string{"snapshots", "wal"} {
+		if err := os.MkdirAll(filepath.Join(r.dir, r.generation, sub), 0o755); err != nil {
+			return err
+		}
+	}
+	return r.snapshot(ctx)
+}
+
+// step ships new frames, then snapshots, checkpoints and prunes when due
+func (r *Replicator) step(ctx context.Context) error {
+	if time.Since(r.lastSnapshot) >= r.opts.SnapshotInterval {
+		if err := r.snapshot(ctx); err != nil {
+			return err
+		}
+		return r.prune(time.Now())
+	}
+	if err := r.sync(); err != nil {
+		return err
+	}
+	if r.offset >= checkpointBytes && r.offset != r.checkpointedAt {
+		return r.checkpoint(ctx)
+	}
+	return nil
+}
+
+// withWriteLock runs fn holding the database's write lock, so nothing can
+// be committed, and the WAL can't restart, meanwhile
+func (r *Replicator) withWriteLock(ctx context.Context, fn func() error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("take the write lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "ROLLBACK")
+	return fn()
+}
+
+// sync ships the frames committed since the last sync as a segment. A WAL
+// that has restarted since is a new index, shipped from its header.
+func (r *Replicator) sync() error {
+	f, err := os.Open(r.path + "-wal")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		return err
+	}
+	h, err := readWALHeader(buf)
+	if errors.Is(err, errNoWAL) {
+		return nil
+	}
+
+	index, start, sum := r.index, r.offset, r.sum
+	var data []byte
+	if !r.hasHeader || h.salt1 != r.header.salt1 || h.salt2 != r.header.salt2 {
+		if r.hasHeader {
+			index++
+		}
+		start, sum = 0, h.sum
+		data = buf
+	}
+
+	if _, err := f.Seek(max(start, walHeaderSize), io.SeekStart); err != nil {
+		return err
+	}
+	frames, sum, err := readCommitted(f, h, sum)
+	if err != nil {
+		return err
+	}
+	data = append(data, frames...)
+	if len(data) == 0 {
+		return nil
+	}
+
+	name := fmt.Sprintf("%08d-%016x-%s.wal", index, start, time.Now().UTC().Format(timeFormat))
+	if err := writeFile(filepath.Join(r.dir, r.generation, "wal", name), data, false); err != nil {
+		return err
+	}
+	r.index, r.header, r.hasHeader = index, h, true
+	r.offset, r.sum = start+int64(len(data)), sum
+	return nil
+}
+
+// checkpoint ships every frame, then copies them into the database file.
+// The WAL restarts with the next write.
+func (r *Replicator) checkpoint(ctx context.Context) error {
+	return r.withWriteLock(ctx, func() error {
+		if err := r.sync(); err != nil {
+			return err
+		}
+		var busy, frames, checkpointed int
+		if err := r.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)").Scan(&busy, &frames, &checkpointed); err != nil {
+			return err
+		}
+		r.checkpointedAt = r.offset
+		r.logger.Debug("Checkpointed the WAL", slog.Int("frames", frames), slog.Int("checkpointed", checkpointed))
+		return nil
+	})
+}
+
+// snapshot ships every frame, then copies the database file. Nothing
+// writes the file but a checkpoint, so it's the database as of the last
+// one, and replaying the current WAL over it brings it up to date.
+func (r *Replicator) snapshot(ctx context.Context) error {
+	return r.withWriteLock(ctx, func() error {
+		if err := r.sync(); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(r.path)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		name := fmt.Sprintf("%08d-%s.db.gz", r.index, now.Format(timeFormat))
+		if err := writeFile(filepath.Join(r.dir, r.generation, "snapshots", name), data, true); err != nil {
+			return err
+		}
+		r.lastSnapshot = now
+		return nil
+	})
+}
+
+// prune deletes what isn't needed to restore to any moment since the
+// retention period began: generations that ended before it, and in the
+// rest, snapshots older than the last one before it and the WAL before that
+func (r *Replicator) prune(now time.Time) error {
+	cutoff := now.Add(-r.opts.Retention)
+	generations, err := listGenerations(r.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, gen := range generations {
+		snapshots, segments, err := readGeneration(filepath.Join(r.dir, gen))
+		if err != nil {
+			return err
+		}
+
+		last := time.Time{}
+		if len(snapshots) > 0 {
+			last = snapshots[len(snapshots)-1].at
+		}
+		if len(segments) > 0 && segments[len(segments)-1].at.After(last) {
+			last = segments[len(segments)-1].at
+		}
+		if gen != r.generation && last.Before(cutoff) {
+			if err := os.RemoveAll(filepath.Join(r.dir, gen)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The newest snapshot from before the cutoff is the oldest kept
+		keep := 0
+		for i, snap := range snapshots {
+			if !snap.at.After(cutoff) {
+				keep = i
+			}
+		}
+		for _, snap := range snapshots[:keep] {
+			if err := os.Remove(filepath.Join(r.dir, gen, "snapshots", snap.name)); err != nil {
+				return err
+			}
+		}
+		if len(snapshots) == 0 {
+			continue
+		}
+		for _, seg := range segments {
+			if seg.index >= snapshots[keep].index {
+				break
+			}
+			if err := os.Remove(filepath.Join(r.dir, gen, "wal", seg.name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeFile writes data to path through a temporary file, compressed if
+// asked, so a replica never holds half a file
+func writeFile(path string, data []byte, compress bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	var w io.Writer = tmp
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(tmp)
+		w = gz
+	}
+	_, err = w.Write(data)
+	if gz != nil && err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// snapshotFile is a snapshot in a generation
+type snapshotFile struct {
+	name  string
+	index int
+	at    time.Time
+}
+
+// segmentFile is a WAL segment in a generation
+type segmentFile struct {
+	name   string
+	index  int
+	offset int64
+	at     time.Time
+}
+
+// listGenerations returns the generations in the replica, oldest first
+func listGenerations(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var generations []string
+	for _, e := range entries {
+		if _, err := time.Parse(timeFormat, e.Name()); err == nil && e.IsDir() {
+			generations = append(generations, e.Name())
+		}
+	}
+	slices.Sort(generations)
+	return generations, nil
+}
+
+// readGeneration lists a generation's snapshots and segments, oldest first.
+// Files that aren't either are ignored.
+func readGeneration(dir string) ([]snapshotFile, []segmentFile, error) {
+	var snapshots []snapshotFile
+	entries, err := os.ReadDir(filepath.Join(dir, "snapshots"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		var snap snapshotFile
+		var stamp string
+		name, ok := strings.CutSuffix(e.Name(), ".db.gz")
+		if !ok {
+			continue
+		}
+		if _, err := fmt.Sscanf(name, "%08d-%s", &snap.index, &stamp); err != nil {
+			continue
+		}
+		if snap.at, err = time.Parse(timeFormat, stamp); err != nil {
+			continue
+		}
+		snap.name = e.Name()
+		snapshots = append(snapshots, snap)
+	}
+
+	var segments []segmentFile
+	entries, err = os.ReadDir(filepath.Join(dir, "wal"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		var seg segmentFile
+		var stamp string
+		name, ok := strings.CutSuffix(e.Name(), ".wal")
+		if !ok {
+			continue
+		}
+		if _, err := fmt.Sscanf(name, "%08d-%016x-%s", &seg.index, &seg.offset, &stamp); err != nil {
+			continue
+		}
+		if seg.at, err = time.Parse(timeFormat, stamp); err != nil {
+			continue
+		}
+		seg.name = e.Name()
+		segments = append(segments, seg)
+	}
+
+	// The names sort by index, then offset or time
+	slices.SortFunc(snapshots, func(a, b snapshotFile) int { return strings.Compare(a.name, b.name) })
+	slices.SortFunc(segments, func(a, b segmentFile) int { return strings.Compare(a.name, b.name) })
+	return snapshots, segments, nil
+}
@@ -0,0 +1,256 @@
+package replica
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/dd/data/sql/migrations"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+// setupReplicator creates a migrated database file opened for replication,
+// and a replicator for it with a started generation
+func setupReplicator(t *testing.T) (*sql.DB, *Replicator) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "database.sqlite")
+	db, err := sql.Open("sqlite", DSN(path))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider, err := goose.NewProvider(database.DialectSQLite3, db, migrations.Embed)
+	if err != nil {
+		t.Fatalf("Failed to create migration provider: %v", err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	r := New(db, slog.New(slog.DiscardHandler), path, filepath.Join(dir, "replica"), Options{
+		SyncInterval:     time.Second,
+		SnapshotInterval: time.Hour,
+		Retention:        time.Hour,
+	})
+	if err := r.start(context.Background()); err != nil {
+		t.Fatalf("start() error = %v", err)
+	}
+	return db, r
+}
+
+// addArtist creates an artist, then ships it
+func addArtist(t *testing.T, db *sql.DB, r *Replicator, name string) time.Time {
+	t.Helper()
+	if _, err := db.Exec("INSERT INTO artists (name) VALUES (?)", name); err != nil {
+		t.Fatalf("Failed to create artist: %v", err)
+	}
+	if err := r.sync(); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	return time.Now()
+}
+
+// countArtists restores the replica as of at into a new file and counts
+// the artists in it
+func countArtists(t *testing.T, r *Replicator, at time.Time) (int, error) {
+	t.Helper()
+	target := filepath.Join(t.TempDir(), "restored.sqlite")
+	if _, err := Restore(context.Background(), r.dir, target, at); err != nil {
+		return 0, err
+	}
+	db, err := sql.Open("sqlite", target)
+	if err != nil {
+		t.Fatalf("Failed to open restored database: %v", err)
+	}
+	defer db.Close()
+
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM artists").Scan(&n); err != nil {
+		t.Fatalf("Failed to count artists: %v", err)
+	}
+	return n, nil
+}
+
+// walSize returns the size of the replicated database's WAL
+func walSize(t *testing.T, r *Replicator) int64 {
+	t.Helper()
+	info, err := os.Stat(r.path + "-wal")
+	if err != nil {
+		t.Fatalf("Failed to stat the WAL: %v", err)
+	}
+	return info.Size()
+}
+
+func TestRestoreToTime(t *testing.T) {
+	ctx := context.Background()
+	db, r := setupReplicator(t)
+	started := time.Now()
+
+	one := addArtist(t, db, r, "Nirvana")
+	addArtist(t, db, r, "Pixies")
+
+	// A checkpoint lets the WAL restart, which the next write does
+	if err := r.checkpoint(ctx); err != nil {
+		t.Fatalf("checkpoint() error = %v", err)
+	}
+	three := addArtist(t, db, r, "Hole")
+	if r.index != 1 {
+		t.Fatalf("index after checkpoint = %d, want 1", r.index)
+	}
+
+	if err := r.snapshot(ctx); err != nil {
+		t.Fatalf("snapshot() error = %v", err)
+	}
+	four := addArtist(t, db, r, "Mudhoney")
+
+	for _, tt := range []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"generation start", started, 0},
+		{"first WAL", one, 1},
+		{"after a restart", three, 3},
+		{"after the second snapshot", four, 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := countArtists(t, r, tt.at)
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("artists = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := countArtists(t, r, started.Add(-time.Hour)); !errors.Is(err, ErrNoReplica) {
+		t.Errorf("Restore() before the replica error = %v, want ErrNoReplica", err)
+	}
+}
+
+func TestRestoreIgnoresUncommitted(t *testing.T) {
+	db, r := setupReplicator(t)
+	addArtist(t, db, r, "Nirvana")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	defer tx.Rollback()
+
+	// A transaction too big for the page cache spills into the WAL before
+	// it commits
+	before := walSize(t, r)
+	if _, err := tx.Exec("PRAGMA cache_size = 10"); err != nil {
+		t.Fatalf("Failed to shrink the cache: %v", err)
+	}
+	for i := range 200 {
+		if _, err := tx.Exec("INSERT INTO artists (name) VALUES (?)", fmt.Sprintf("%d %s", i, strings.Repeat("x", 500))); err != nil {
+			t.Fatalf("Failed to create artist: %v", err)
+		}
+	}
+	if walSize(t, r) == before {
+		t.Fatal("transaction didn't spill into the WAL")
+	}
+	if err := r.sync(); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+
+	got, err := countArtists(t, r, time.Now())
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got != 1 {
+		t.Errorf("artists = %d, want 1", got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	db, r := setupReplicator(t)
+
+	one := addArtist(t, db, r, "Nirvana")
+	if err := r.checkpoint(ctx); err != nil {
+		t.Fatalf("checkpoint() error = %v", err)
+	}
+	addArtist(t, db, r, "Pixies")
+	if err := r.snapshot(ctx); err != nil {
+		t.Fatalf("snapshot() error = %v", err)
+	}
+	three := addArtist(t, db, r, "Hole")
+
+	// An old generation that's out of retention goes entirely
+	old := filepath.Join(r.dir, time.Now().Add(-48*time.Hour).UTC().Format(timeFormat))
+	if err := os.MkdirAll(old, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// With the cutoff after everything, only the newest snapshot and the
+	// WAL after it are needed
+	if err := r.prune(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatalf("prune() error = %v", err)
+	}
+	if _, err := os.Stat(old); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("old generation still exists: %v", err)
+	}
+	snapshots, segments, err := readGeneration(filepath.Join(r.dir, r.generation))
+	if err != nil {
+		t.Fatalf("readGeneration() error = %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].index != 1 {
+		t.Errorf("snapshots = %+v, want just the one at index 1", snapshots)
+	}
+	for _, seg := range segments {
+		if seg.index < 1 {
+			t.Errorf("segment %s from before the snapshot wasn't pruned", seg.name)
+		}
+	}
+
+	if got, err := countArtists(t, r, three); err != nil || got != 3 {
+		t.Errorf("Restore() after prune = %d, %v; want 3", got, err)
+	}
+	if _, err := countArtists(t, r, one); !errors.Is(err, ErrNoReplica) {
+		t.Errorf("Restore() to a pruned time error = %v, want ErrNoReplica", err)
+	}
+}
+
+// TestDSN_ForeignKeys tests that every pooled connection enforces foreign
+// keys, not just the first
+func TestDSN_ForeignKeys(t *testing.T) {
+	db, err := sql.Open("sqlite", DSN(filepath.Join(t.TempDir(), "database.sqlite")))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for range 3 {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn() error = %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	for i, conn := range conns {
+		var on int
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&on); err != nil {
+			t.Fatalf("PRAGMA foreign_keys error = %v", err)
+		}
+		if on != 1 {
+			t.Errorf("connection %d foreign_keys = %d, want 1", i, on)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package replica
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dukerupert/dd/internal/backup"
+)
+
+// ErrNoReplica is returned when the replica has nothing to restore from as
+// of the requested time
+var ErrNoReplica = errors.New("no replica old enough")
+
+// Restored is what a restore was rebuilt from
+type Restored struct {
+	Generation string
+	Snapshot   time.Time
+	Segments   int
+	// At is when the last commit restored was shipped, or the snapshot was
+	// taken if that's later
+	At time.Time
+}
+
+// Restore rebuilds the database as it was at t from the replica in dir and
+// swaps it in at dbPath. It's the newest snapshot taken by t with the WAL
+// shipped after it, up to t, replayed over it. Stop the server first.
+func Restore(ctx context.Context, dir, dbPath string, t time.Time) (Restored, error) {
+	gen, snap, segments, err := plan(dir, t)
+	if err != nil {
+		return Restored{}, err
+	}
+	restored := Restored{Generation: gen, Snapshot: snap.at, Segments: len(segments), At: snap.at}
+
+	staging, err := os.MkdirTemp(filepath.Dir(dbPath), ".restore-*")
+	if err != nil {
+		return Restored{}, err
+	}
+	defer os.RemoveAll(staging)
+	tmp := filepath.Join(staging, "database.sqlite")
+
+	if err := unzipFile(filepath.Join(dir, gen, "snapshots", snap.name), tmp); err != nil {
+		return Restored{}, fmt.Errorf("%w: snapshot %s: %v", backup.ErrCorrupt, snap.name, err)
+	}
+
+	// Replay each WAL in turn: write it out beside the database and
+	// checkpoint it in
+	for start := 0; start < len(segments); {
+		end := start
+		for end < len(segments) && segments[end].index == segments[start].index {
+			end++
+		}
+		if err := replay(ctx, dir, gen, tmp, segments[start:end]); err != nil {
+			return Restored{}, err
+		}
+		if last := segments[end-1].at; last.After(restored.At) {
+			restored.At = last
+		}
+		start = end
+	}
+
+	if err := finish(ctx, tmp); err != nil {
+		return Restored{}, err
+	}
+	if err := backup.IntegrityCheck(ctx, tmp); err != nil {
+		return Restored{}, err
+	}
+	return restored, backup.SwapIn(tmp, dbPath)
+}
+
+// plan picks what to restore t from: the newest snapshot taken by then, and
+// the segments shipped after it up to then, in the order to replay them
+func plan(dir string, t time.Time) (string, snapshotFile, []segmentFile, error) {
+	generations, err := listGenerations(dir)
+	if err != nil {
+		return "", snapshotFile{}, nil, err
+	}
+
+	for i := len(generations) - 1; i >= 0; i-- {
+		gen := generations[i]
+		snapshots, segments, err := readGeneration(filepath.Join(dir, gen))
+		if err != nil {
+			return "", snapshotFile{}, nil, err
+		}
+
+		var snap *snapshotFile
+		for j := range snapshots {
+			if !snapshots[j].at.After(t) {
+				snap = &snapshots[j]
+			}
+		}
+		if snap == nil {
+			continue
+		}
+
+		// Each WAL must be shipped from its header without gaps; it stops at
+		// the first segment after t
+		var replay []segmentFile
+		next := map[int]int64{}
+		for _, seg := range segments {
+			if seg.index < snap.index {
+				continue
+			}
+			if seg.at.After(t) {
+				break
+			}
+			if seg.offset != next[seg.index] {
+				return "", snapshotFile{}, nil, fmt.Errorf("%w: generation %s is missing the WAL before %s", backup.ErrCorrupt, gen, seg.name)
+			}
+			info, err := os.Stat(filepath.Join(dir, gen, "wal", seg.name))
+			if err != nil {
+				return "", snapshotFile{}, nil, err
+			}
+			next[seg.index] = seg.offset + info.Size()
+			replay = append(replay, seg)
+		}
+		return gen, *snap, replay, nil
+	}
+	return "", snapshotFile{}, nil, fmt.Errorf("%w: nothing in %s from before %s", ErrNoReplica, dir, t.Format(time.RFC3339))
+}
+
+// replay checkpoints one WAL's segments into the database at path
+func replay(ctx context.Context, dir, gen, path string, segments []segmentFile) error {
+	wal, err := os.Create(path + "-wal")
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		f, err := os.Open(filepath.Join(dir, gen, "wal", seg.name))
+		if err != nil {
+			wal.Close()
+			return err
+		}
+		_, err = io.Copy(wal, f)
+		f.Close()
+		if err != nil {
+			wal.Close()
+			return err
+		}
+	}
+	if err := wal.Close(); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var busy, frames, checkpointed int
+	if err := db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &frames, &checkpointed); err != nil {
+		return fmt.Errorf("%w: replay %s: %v", backup.ErrCorrupt, segments[0].name, err)
+	}
+	if busy != 0 || frames != checkpointed {
+		return fmt.Errorf("%w: replay %s: checkpointed %d of %d frames", backup.ErrCorrupt, segments[0].name, checkpointed, frames)
+	}
+	return nil
+}
+
+// finish takes the restored database out of WAL mode, so it's a single file
+// to swap in. The server switches it back when it starts replicating.
+func finish(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var mode string
+	if err := db.QueryRowContext(ctx, "PRAGMA journal_mode=DELETE").Scan(&mode); err != nil {
+		return err
+	}
+	if mode != "delete" {
+		return fmt.Errorf("restored database is still in %s journal mode", mode)
+	}
+	return nil
+}
+
+// unzipFile decompresses the gzip file at src to dst
+func unzipFile(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, gz)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package replica
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// WAL file layout; see https://www.sqlite.org/fileformat2.html#walformat
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+	walMagicLE         = 0x377f0682
+	walMagicBE         = 0x377f0683
+)
+
+// errNoWAL is returned when the WAL has no valid header yet
+var errNoWAL = errors.New("no WAL header")
+
+// walHeader is the part of a WAL header replication needs
+type walHeader struct {
+	pageSize  int
+	bigEndian bool
+	salt1     uint32
+	salt2     uint32
+	// sum is the header's checksum, which the first frame's continues from
+	sum [2]uint32
+}
+
+// readWALHeader reads and checks the header at the start of a WAL
+func readWALHeader(b []byte) (walHeader, error) {
+	if len(b) < walHeaderSize {
+		return walHeader{}, errNoWAL
+	}
+	magic := binary.BigEndian.Uint32(b[0:])
+	if magic != walMagicLE && magic != walMagicBE {
+		return walHeader{}, errNoWAL
+	}
+	h := walHeader{
+		pageSize:  int(binary.BigEndian.Uint32(b[8:])),
+		bigEndian: magic == walMagicBE,
+		salt1:     binary.BigEndian.Uint32(b[16:]),
+		salt2:     binary.BigEndian.Uint32(b[20:]),
+	}
+	// A page size of 65536 is stored as 1
+	if h.pageSize == 1 {
+		h.pageSize = 65536
+	}
+	h.sum = walChecksum(h.bigEndian, [2]uint32{}, b[:24])
+	if h.sum[0] != binary.BigEndian.Uint32(b[24:]) || h.sum[1] != binary.BigEndian.Uint32(b[28:]) {
+		return walHeader{}, errNoWAL
+	}
+	return h, nil
+}
+
+// frameSize is the size of a frame: its header and a page
+func (h walHeader) frameSize() int {
+	return walFrameHeaderSize + h.pageSize
+}
+
+// walChecksum continues the WAL checksum sum over b, a multiple of 8 bytes,
+// read as 32-bit words in the byte order the WAL was written with
+func walChecksum(bigEndian bool, sum [2]uint32, b []byte) [2]uint32 {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	s0, s1 := sum[0], sum[1]
+	for i := 0; i+8 <= len(b); i += 8 {
+		s0 += order.Uint32(b[i:]) + s1
+		s1 += order.Uint32(b[i+4:]) + s0
+	}
+	return [2]uint32{s0, s1}
+}
+
+// readCommitted reads the frames from r, which starts at a frame boundary,
+// and returns those up to the last commit that are valid for the header,
+// continuing the checksum from sum. It stops at the first frame that's short
+// or doesn't check out, which is where SQLite itself stops. The checksum at
+// the end of what's returned is returned with it.
+func readCommitted(r io.Reader, h walHeader, sum [2]uint32) ([]byte, [2]uint32, error) {
+	var out []byte
+	committed, committedSum := 0, sum
+	frame := make([]byte, h.frameSize())
+	for {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, sum, err
+		}
+		if binary.BigEndian.Uint32(frame[0:]) == 0 ||
+			binary.BigEndian.Uint32(frame[8:]) != h.salt1 || binary.BigEndian.Uint32(frame[12:]) != h.salt2 {
+			break
+		}
+		next := walChecksum(h.bigEndian, sum, frame[:8])
+		next = walChecksum(h.bigEndian, next, frame[walFrameHeaderSize:])
+		if next[0] != binary.BigEndian.Uint32(frame[16:]) || next[1] != binary.BigEndian.Uint32(frame[20:]) {
+			break
+		}
+		sum = next
+		out = append(out, frame...)
+		// A frame recording the database size ends a transaction
+		if binary.BigEndian.Uint32(frame[4:]) != 0 {
+			committed, committedSum = len(out), sum
+		}
+	}
+	return out[:committed], committedSum, nil
+}
@@ -15,6 +15,7 @@ func New(h *handler.Handler, queries *store.Queries, sessionCookieName string) h
 	// API routes
 	apiMux := http.NewServeMux()
 	addAPIRoutes(apiMux, h)
+	addAdminRoutes(apiMux, h, queries)
 
 	apiHandler := http.StripPrefix("/api", apiMux)
 	apiHandler = middleware.RateLimit(apiHandler, 100)
@@ -27,6 +28,7 @@ func New(h *handler.Handler, queries *store.Queries, sessionCookieName string) h
 	// HTML routes
 	htmlMux := http.NewServeMux()
 	addHTMLRoutes(htmlMux, h)
+	addAdminHTMLRoutes(htmlMux, h, queries)
 
 	htmlHandler := http.Handler(htmlMux)
 	htmlHandler = middleware.RateLimit(htmlHandler, 1000)
@@ -36,9 +38,49 @@ func New(h *handler.Handler, queries *store.Queries, sessionCookieName string) h
 	htmlHandler = middleware.RequestID(htmlHandler)
 	mux.Handle("/", htmlHandler)
 
+	// Image and import uploads need a larger body limit than the rest of the
+	// app, so they get their own chain. These patterns are more specific than
+	// "/" and "/api/" and take precedence.
+	uploadMux := http.NewServeMux()
+	addUploadRoutes(uploadMux, h)
+
+	uploadHandler := http.Handler(uploadMux)
+	uploadHandler = middleware.RateLimit(uploadHandler, 100)
+	uploadHandler = middleware.MaxBytes(h.UploadLimit())(uploadHandler)
+	uploadHandler = middleware.Auth(queries, sessionCookieName)(uploadHandler)
+	uploadHandler = middleware.Logging(uploadHandler, h.Logger())
+	uploadHandler = middleware.RequestID(uploadHandler)
+	mux.Handle("POST /records/{id}/images/{kind}", uploadHandler)
+	mux.Handle("POST /api/v1/records/{id}/images/{kind}", uploadHandler)
+	mux.Handle("POST /imports/{source}", uploadHandler)
+	mux.Handle("POST /api/v1/imports/{source}", uploadHandler)
+	mux.Handle("POST /value/import", uploadHandler)
+	mux.Handle("POST /api/v1/values/import", uploadHandler)
+
 	return mux
 }
 
+func addAdminRoutes(mux *http.ServeMux, h *handler.Handler, queries *store.Queries) {
+	admin := middleware.RequireRole(queries, "admin")
+	mux.Handle("GET /v1/admin/backup", admin(h.JsonBackup()))
+	mux.Handle("GET /v1/admin/health", admin(h.JsonAdminHealth()))
+	mux.Handle("POST /v1/admin/artists/{id}/merge", admin(h.JsonMergeArtist()))
+}
+
+func addAdminHTMLRoutes(mux *http.ServeMux, h *handler.Handler, queries *store.Queries) {
+	admin := middleware.RequireRole(queries, "admin")
+	mux.Handle("POST /artists/{id}/merge", admin(h.MergeArtist()))
+}
+
+func addUploadRoutes(mux *http.ServeMux, h *handler.Handler) {
+	mux.HandleFunc("POST /records/{id}/images/{kind}", h.UploadRecordImage())
+	mux.HandleFunc("POST /api/v1/records/{id}/images/{kind}", h.JsonUploadRecordImage())
+	mux.HandleFunc("POST /imports/{source}", h.ImportRecords())
+	mux.HandleFunc("POST /api/v1/imports/{source}", h.JsonImportRecords())
+	mux.HandleFunc("POST /value/import", h.ImportPriceGuide())
+	mux.HandleFunc("POST /api/v1/values/import", h.JsonImportPriceGuide())
+}
+
 func addHTMLRoutes(mux *http.ServeMux, h *handler.Handler) {
 	// Public routes
 	mux.HandleFunc("GET /", h.Landing())
@@ -60,16 +102,71 @@ func addHTMLRoutes(mux *http.ServeMux, h *handler.Handler) {
 	mux.HandleFunc("PUT /artists/{id}", h.UpdateArtist())
 	mux.HandleFunc("GET /artists/{id}/edit", h.GetUpdateArtistForm())
 	mux.HandleFunc("DELETE /artists/{id}", h.DeleteArtist())
+	mux.HandleFunc("POST /artists/{id}/aliases", h.CreateArtistAlias())
+	mux.HandleFunc("DELETE /artists/{id}/aliases/{aliasID}", h.DeleteArtistAlias())
+
+	// Tags
+	mux.HandleFunc("GET /tags", h.GetTags())
+	mux.HandleFunc("GET /tags/autocomplete", h.GetTagSuggestions())
+	mux.HandleFunc("POST /tags", h.CreateTag())
+	mux.HandleFunc("PUT /tags/{id}", h.UpdateTag())
+	mux.HandleFunc("POST /tags/{id}/merge", h.MergeTag())
+	mux.HandleFunc("DELETE /tags/{id}", h.DeleteTag())
 
 	// Records
 	mux.HandleFunc("GET /records", h.GetRecords())
 	mux.HandleFunc("GET /records/new", h.GetCreateRecordForm())
+	mux.HandleFunc("GET /records/new/releases", h.SearchReleases())
 	mux.HandleFunc("POST /records", h.CreateRecord())
+	mux.HandleFunc("GET /records/away", h.GetRecordsAway())
+	mux.HandleFunc("POST /records/put-away", h.PutAwayRecords())
 	mux.HandleFunc("GET /records/{id}", h.GetRecord())
 	mux.HandleFunc("PUT /records/{id}", h.UpdateRecord())
 	mux.HandleFunc("GET /records/{id}/edit", h.GetUpdateRecordForm())
 	mux.HandleFunc("DELETE /records/{id}", h.DeleteRecord())
 	mux.HandleFunc("POST /records/{id}/play", h.PlayRecord())
+	mux.HandleFunc("PUT /records/{id}/condition", h.UpdateRecordCondition())
+	mux.HandleFunc("POST /records/{id}/tracks", h.CreateTrack())
+	mux.HandleFunc("PUT /records/{id}/tracks/{trackID}", h.UpdateTrack())
+	mux.HandleFunc("DELETE /records/{id}/tracks/{trackID}", h.DeleteTrack())
+	mux.HandleFunc("POST /records/{id}/artists", h.CreateRecordArtist())
+	mux.HandleFunc("DELETE /records/{id}/artists/{artistID}/{role}", h.DeleteRecordArtist())
+	mux.HandleFunc("POST /records/{id}/tags", h.CreateRecordTag())
+	mux.HandleFunc("DELETE /records/{id}/tags/{tagID}", h.DeleteRecordTag())
+	mux.HandleFunc("GET /records/{id}/images/{kind}", h.ServeRecordImage())
+	mux.HandleFunc("GET /records/{id}/images/{kind}/{size}", h.ServeRecordImage())
+	mux.HandleFunc("DELETE /records/{id}/images/{kind}", h.DeleteRecordImage())
+	mux.HandleFunc("POST /records/{id}/lend", h.LendRecord())
+	mux.HandleFunc("POST /records/{id}/return", h.ReturnRecord())
+	mux.HandleFunc("PUT /records/{id}/purchase", h.UpdateRecordPurchase())
+	mux.HandleFunc("DELETE /records/{id}/purchase", h.DeleteRecordPurchase())
+	mux.HandleFunc("POST /records/{id}/values", h.CreateRecordValue())
+	mux.HandleFunc("DELETE /records/{id}/values/{valueID}", h.DeleteRecordValue())
+	mux.HandleFunc("POST /records/{id}/merge", h.MergeRecord())
+	mux.HandleFunc("PUT /records/{id}/location", h.UpdateRecordLocation())
+	mux.HandleFunc("POST /records/{id}/put-away", h.PutAwayRecord())
+
+	// Imports; the uploads are handled in addUploadRoutes
+	mux.HandleFunc("GET /imports", h.GetImports())
+
+	// Wishlist
+	mux.HandleFunc("GET /wants", h.GetWants())
+	mux.HandleFunc("POST /wants", h.CreateWant())
+	mux.HandleFunc("PUT /wants/{id}", h.UpdateWant())
+	mux.HandleFunc("DELETE /wants/{id}", h.DeleteWant())
+
+	// Loans
+	mux.HandleFunc("GET /loans", h.GetLoans())
+	mux.HandleFunc("POST /borrowers", h.CreateBorrower())
+	mux.HandleFunc("PUT /borrowers/{id}", h.UpdateBorrower())
+	mux.HandleFunc("DELETE /borrowers/{id}", h.DeleteBorrower())
+
+	// Value; the price guide upload is handled in addUploadRoutes
+	mux.HandleFunc("GET /value", h.GetValue())
+
+	// Duplicates; merging is POST /records/{id}/merge
+	mux.HandleFunc("GET /duplicates", h.GetDuplicates())
+	mux.HandleFunc("POST /duplicates/dismiss", h.DismissDuplicate())
 
 	// Locations
 	mux.HandleFunc("GET /locations", h.GetLocations())
@@ -78,9 +175,13 @@ func addHTMLRoutes(mux *http.ServeMux, h *handler.Handler) {
 	mux.HandleFunc("GET /locations/{id}", h.GetLocation())
 	mux.HandleFunc("PUT /locations/{id}", h.UpdateLocation())
 	mux.HandleFunc("GET /locations/{id}/edit", h.GetUpdateLocationForm())
+	mux.HandleFunc("PUT /locations/{id}/shelf", h.ReorderShelf())
 	mux.HandleFunc("DELETE /locations/{id}", h.DeleteLocation())
 	mux.HandleFunc("POST /locations/default/{id}", h.SetDefaultLocation())
 
+	// Search
+	mux.HandleFunc("GET /search", h.Search())
+
 	// Profile
 	mux.HandleFunc("GET /profile", h.GetProfile())
 	mux.HandleFunc("PUT /profile", h.UpdateProfile())
@@ -95,6 +196,7 @@ func addAPIRoutes(mux *http.ServeMux, h *handler.Handler) {
 	mux.HandleFunc("POST /v1/auth/signup", h.JsonSignup())
 	mux.HandleFunc("POST /v1/auth/login", h.JsonLogin())
 	mux.HandleFunc("POST /v1/auth/logout", h.JsonLogout())
+	mux.HandleFunc("GET /v1/health", h.JsonHealth())
 
 	// Protected Api
 
@@ -105,6 +207,9 @@ func addAPIRoutes(mux *http.ServeMux, h *handler.Handler) {
 	mux.HandleFunc("PUT /v1/artists/{id}", h.JsonUpdateArtist())
 	mux.HandleFunc("DELETE /v1/artists/{id}", h.JsonDeleteArtist())
 	mux.HandleFunc("GET /v1/artists/{id}/records", h.JsonGetRecordsByArtist())
+	mux.HandleFunc("GET /v1/artists/{id}/aliases", h.JsonGetArtistAliases())
+	mux.HandleFunc("POST /v1/artists/{id}/aliases", h.JsonCreateArtistAlias())
+	mux.HandleFunc("DELETE /v1/artists/{id}/aliases/{aliasID}", h.JsonDeleteArtistAlias())
 
 	// Records
 	mux.HandleFunc("GET /v1/records", h.JsonGetRecords())
@@ -112,9 +217,78 @@ func addAPIRoutes(mux *http.ServeMux, h *handler.Handler) {
 	mux.HandleFunc("GET /v1/records/{id}", h.JsonGetRecord())
 	mux.HandleFunc("DELETE /v1/records/{id}", h.JsonDeleteRecord())
 	mux.HandleFunc("PUT /v1/records/{id}", h.JsonUpdateRecord())
-	mux.HandleFunc("GET /v1/records/{id}/play", h.JsonPlayRecord())
+	mux.HandleFunc("POST /v1/records/{id}/play", h.JsonPlayRecord())
+	mux.HandleFunc("GET /v1/records/{id}/plays", h.JsonGetRecordPlays())
+	mux.HandleFunc("POST /v1/records/{id}/plays", h.JsonCreatePlay())
+	mux.HandleFunc("PUT /v1/records/{id}/condition", h.JsonUpdateRecordCondition())
+	mux.HandleFunc("GET /v1/records/{id}/condition-history", h.JsonGetConditionHistory())
+	mux.HandleFunc("GET /v1/records/{id}/tracks", h.JsonGetTracks())
+	mux.HandleFunc("POST /v1/records/{id}/tracks", h.JsonCreateTrack())
+	mux.HandleFunc("PUT /v1/records/{id}/tracks/{trackID}", h.JsonUpdateTrack())
+	mux.HandleFunc("DELETE /v1/records/{id}/tracks/{trackID}", h.JsonDeleteTrack())
+	mux.HandleFunc("GET /v1/records/{id}/artists", h.JsonGetRecordArtists())
+	mux.HandleFunc("PUT /v1/records/{id}/artists", h.JsonSetRecordArtists())
+	mux.HandleFunc("GET /v1/records/{id}/tags", h.JsonGetRecordTags())
+	mux.HandleFunc("POST /v1/records/{id}/tags", h.JsonCreateRecordTag())
+	mux.HandleFunc("DELETE /v1/records/{id}/tags/{tagID}", h.JsonDeleteRecordTag())
+	mux.HandleFunc("GET /v1/records/{id}/loans", h.JsonGetRecordLoans())
+	mux.HandleFunc("POST /v1/records/{id}/lend", h.JsonLendRecord())
+	mux.HandleFunc("POST /v1/records/{id}/return", h.JsonReturnRecord())
+	mux.HandleFunc("GET /v1/records/{id}/value", h.JsonGetRecordValue())
+	mux.HandleFunc("PUT /v1/records/{id}/purchase", h.JsonUpdateRecordPurchase())
+	mux.HandleFunc("DELETE /v1/records/{id}/purchase", h.JsonDeleteRecordPurchase())
+	mux.HandleFunc("POST /v1/records/{id}/values", h.JsonCreateRecordValue())
+	mux.HandleFunc("DELETE /v1/records/{id}/values/{valueID}", h.JsonDeleteRecordValue())
+	mux.HandleFunc("POST /v1/records/{id}/merge", h.JsonMergeRecord())
+	mux.HandleFunc("PUT /v1/records/{id}/location", h.JsonUpdateRecordLocation())
+	mux.HandleFunc("GET /v1/records/{id}/position", h.JsonGetRecordPosition())
+	mux.HandleFunc("POST /v1/records/{id}/put-away", h.JsonPutAwayRecord())
+	mux.HandleFunc("GET /v1/records/{id}/movements", h.JsonGetRecordMovements())
+	mux.HandleFunc("GET /v1/records/{id}/images", h.JsonGetRecordImages())
+	mux.HandleFunc("GET /v1/records/{id}/images/{kind}", h.ServeRecordImage())
+	mux.HandleFunc("GET /v1/records/{id}/images/{kind}/{size}", h.ServeRecordImage())
+	mux.HandleFunc("DELETE /v1/records/{id}/images/{kind}", h.JsonDeleteRecordImage())
 	mux.HandleFunc("GET /v1/records/recent", h.JsonGetRecordsByRecent())
 	mux.HandleFunc("GET /v1/records/popular", h.JsonGetRecordsByPopular())
+	mux.HandleFunc("GET /v1/records/lookup", h.JsonLookupRecordByBarcode())
+	mux.HandleFunc("GET /v1/records/away", h.JsonGetRecordsAway())
+	mux.HandleFunc("POST /v1/records/put-away", h.JsonPutAwayRecords())
+	mux.HandleFunc("GET /v1/export", h.JsonExportRecords())
+
+	// Tags
+	mux.HandleFunc("GET /v1/tags", h.JsonGetTags())
+	mux.HandleFunc("POST /v1/tags", h.JsonCreateTag())
+	mux.HandleFunc("GET /v1/tags/{id}", h.JsonGetTag())
+	mux.HandleFunc("PUT /v1/tags/{id}", h.JsonUpdateTag())
+	mux.HandleFunc("DELETE /v1/tags/{id}", h.JsonDeleteTag())
+	mux.HandleFunc("POST /v1/tags/{id}/merge", h.JsonMergeTag())
+
+	// Plays
+	mux.HandleFunc("PUT /v1/plays/{id}", h.JsonUpdatePlay())
+	mux.HandleFunc("DELETE /v1/plays/{id}", h.JsonDeletePlay())
+
+	// Wishlist
+	mux.HandleFunc("GET /v1/wants", h.JsonGetWants())
+	mux.HandleFunc("POST /v1/wants", h.JsonCreateWant())
+	mux.HandleFunc("GET /v1/wants/{id}", h.JsonGetWant())
+	mux.HandleFunc("PUT /v1/wants/{id}", h.JsonUpdateWant())
+	mux.HandleFunc("DELETE /v1/wants/{id}", h.JsonDeleteWant())
+	mux.HandleFunc("POST /v1/wants/{id}/acquire", h.JsonAcquireWant())
+
+	// Loans
+	mux.HandleFunc("GET /v1/loans", h.JsonGetLoans())
+	mux.HandleFunc("GET /v1/loans/overdue", h.JsonGetOverdueLoans())
+	mux.HandleFunc("GET /v1/borrowers", h.JsonGetBorrowers())
+	mux.HandleFunc("POST /v1/borrowers", h.JsonCreateBorrower())
+	mux.HandleFunc("PUT /v1/borrowers/{id}", h.JsonUpdateBorrower())
+	mux.HandleFunc("DELETE /v1/borrowers/{id}", h.JsonDeleteBorrower())
+
+	// Value
+	mux.HandleFunc("GET /v1/value", h.JsonGetValue())
+
+	// Duplicates
+	mux.HandleFunc("GET /v1/duplicates", h.JsonGetDuplicates())
+	mux.HandleFunc("POST /v1/duplicates/dismiss", h.JsonDismissDuplicate())
 
 	// Locations
 	mux.HandleFunc("GET /v1/locations", h.JsonGetLocations())
@@ -123,8 +297,17 @@ func addAPIRoutes(mux *http.ServeMux, h *handler.Handler) {
 	mux.HandleFunc("PUT /v1/locations/{id}", h.JsonUpdateLocation())
 	mux.HandleFunc("DELETE /v1/locations/{id}", h.JsonDeleteLocation())
 	mux.HandleFunc("GET /v1/locations/{id}/records", h.JsonGetRecordsByLocation())
+	mux.HandleFunc("GET /v1/locations/{id}/shelf", h.JsonGetShelf())
+	mux.HandleFunc("PUT /v1/locations/{id}/shelf", h.JsonReorderShelf())
 	mux.HandleFunc("POST /v1/locations/default/{id}", h.JsonSetDefaultLocation())
 
+	// Search
+	mux.HandleFunc("GET /v1/search", h.JsonSearch())
+
+	// Release lookups
+	mux.HandleFunc("GET /v1/metadata/search", h.JsonSearchReleases())
+	mux.HandleFunc("GET /v1/metadata/releases/{id}", h.JsonGetRelease())
+
 	// User
 	mux.HandleFunc("GET /v1/profile", h.JsonGetProfile())
 	mux.HandleFunc("PUT /v1/profile", h.JsonUpdateProfile())
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// sortArticles are the leading articles moved to the end of a sort name, in
+// English, French, Spanish, German, Italian, Dutch and Portuguese. Articles
+// that are also common words or particles at the start of a name ("As", "I",
+// the "De" of "De La Soul") are left out.
+var sortArticles = map[string]bool{
+	"the": true, "a": true, "an": true,
+	"le": true, "la": true, "les": true,
+	"el": true, "los": true, "las": true,
+	"der": true, "die": true, "das": true,
+	"il": true, "gli": true,
+	"het": true,
+	"os":  true,
+}
+
+// sortElisions are articles joined to the next word by an apostrophe, as in
+// "L'Impératrice"
+var sortElisions = []string{"l'", "l’"}
+
+// sortLetters spells out letters that don't decompose into a base letter
+// and a diacritic
+var sortLetters = strings.NewReplacer(
+	"ß", "ss", "Æ", "AE", "æ", "ae", "Œ", "OE", "œ", "oe",
+	"Ø", "O", "ø", "o", "Ł", "L", "ł", "l", "Đ", "D", "đ", "d",
+	"Þ", "Th", "þ", "th", "ı", "i",
+)
+
+// ArtistSortName derives the name an artist is alphabetized by: diacritics
+// are folded and a leading article moves to the end, so "The Rolling Stones"
+// sorts as "Rolling Stones, The" and "Ólafur Arnalds" as "Olafur Arnalds".
+// A name that is only an article is left alone.
+func ArtistSortName(name string) string {
+	name = strings.Join(strings.Fields(foldDiacritics(name)), " ")
+
+	first, rest, ok := strings.Cut(name, " ")
+	if ok && sortArticles[strings.ToLower(first)] {
+		return rest + ", " + first
+	}
+
+	lower := strings.ToLower(name)
+	for _, elision := range sortElisions {
+		if strings.HasPrefix(lower, elision) && len(name) > len(elision) {
+			return name[len(elision):] + ", " + name[:len(elision)]
+		}
+	}
+	return name
+}
+
+// foldDiacritics strips accents and other combining marks from s
+func foldDiacritics(s string) string {
+	decomposed := norm.NFD.String(sortLetters.Replace(s))
+	return norm.NFC.String(strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, decomposed))
+}
@@ -22,19 +22,27 @@ func (q *Queries) CountArtists(ctx context.Context) (int64, error) {
 }
 
 const createArtist = `-- name: CreateArtist :one
-INSERT INTO artists (name)
-VALUES (?)
-RETURNING id, name, created_at, updated_at
+INSERT INTO artists (name, sort_name)
+VALUES (?, ?)
+RETURNING id, name, created_at, updated_at, sort_name, sort_name_manual
 `
 
-func (q *Queries) CreateArtist(ctx context.Context, name string) (Artist, error) {
-	row := q.db.QueryRowContext(ctx, createArtist, name)
+type CreateArtistParams struct {
+	Name     string
+	SortName string
+}
+
+// The sort name is derived from the name with ArtistSortName
+func (q *Queries) CreateArtist(ctx context.Context, arg CreateArtistParams) (Artist, error) {
+	row := q.db.QueryRowContext(ctx, createArtist, arg.Name, arg.SortName)
 	var i Artist
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SortName,
+		&i.SortNameManual,
 	)
 	return i, err
 }
@@ -50,7 +58,7 @@ func (q *Queries) DeleteArtist(ctx context.Context, id int64) error {
 }
 
 const getArtist = `-- name: GetArtist :one
-SELECT id, name, created_at, updated_at
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
 WHERE id = ?
 `
@@ -63,16 +71,23 @@ func (q *Queries) GetArtist(ctx context.Context, id int64) (Artist, error) {
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SortName,
+		&i.SortNameManual,
 	)
 	return i, err
 }
 
 const getArtistByName = `-- name: GetArtistByName :one
-SELECT id, name, created_at, updated_at
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
-WHERE name = ?
+WHERE name = ?1
+   OR id = (SELECT artist_id FROM artist_aliases WHERE artist_aliases.name = ?1)
+ORDER BY name = ?1 DESC
+LIMIT 1
 `
 
+// The artist with the name, or else the artist with an alias of that name
+// (ignoring case)
 func (q *Queries) GetArtistByName(ctx context.Context, name string) (Artist, error) {
 	row := q.db.QueryRowContext(ctx, getArtistByName, name)
 	var i Artist
@@ -81,14 +96,16 @@ func (q *Queries) GetArtistByName(ctx context.Context, name string) (Artist, err
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SortName,
+		&i.SortNameManual,
 	)
 	return i, err
 }
 
 const listArtists = `-- name: ListArtists :many
-SELECT id, name, created_at, updated_at
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
-ORDER BY name ASC
+ORDER BY sort_name ASC, name ASC
 `
 
 func (q *Queries) ListArtists(ctx context.Context) ([]Artist, error) {
@@ -105,6 +122,8 @@ func (q *Queries) ListArtists(ctx context.Context) ([]Artist, error) {
 			&i.Name,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SortName,
+			&i.SortNameManual,
 		); err != nil {
 			return nil, err
 		}
@@ -120,9 +139,9 @@ func (q *Queries) ListArtists(ctx context.Context) ([]Artist, error) {
 }
 
 const listArtistsWithPagination = `-- name: ListArtistsWithPagination :many
-SELECT id, name, created_at, updated_at
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
-ORDER BY name ASC
+ORDER BY sort_name ASC, name ASC
 LIMIT ? OFFSET ?
 `
 
@@ -145,6 +164,8 @@ func (q *Queries) ListArtistsWithPagination(ctx context.Context, arg ListArtists
 			&i.Name,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SortName,
+			&i.SortNameManual,
 		); err != nil {
 			return nil, err
 		}
@@ -160,10 +181,10 @@ func (q *Queries) ListArtistsWithPagination(ctx context.Context, arg ListArtists
 }
 
 const searchArtistsByName = `-- name: SearchArtistsByName :many
-SELECT id, name, created_at, updated_at
+SELECT id, name, created_at, updated_at, sort_name, sort_name_manual
 FROM artists
 WHERE name LIKE '%' || ? || '%'
-ORDER BY name ASC
+ORDER BY sort_name ASC, name ASC
 `
 
 func (q *Queries) SearchArtistsByName(ctx context.Context, dollar_1 sql.NullString) ([]Artist, error) {
@@ -180,6 +201,8 @@ func (q *Queries) SearchArtistsByName(ctx context.Context, dollar_1 sql.NullStri
 			&i.Name,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SortName,
+			&i.SortNameManual,
 		); err != nil {
 			return nil, err
 		}
@@ -198,7 +221,7 @@ const updateArtist = `-- name: UpdateArtist :one
 UPDATE artists
 SET name = ?
 WHERE id = ?
-RETURNING id, name, created_at, updated_at
+RETURNING id, name, created_at, updated_at, sort_name, sort_name_manual
 `
 
 type UpdateArtistParams struct {
@@ -214,6 +237,35 @@ func (q *Queries) UpdateArtist(ctx context.Context, arg UpdateArtistParams) (Art
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SortName,
+		&i.SortNameManual,
+	)
+	return i, err
+}
+
+const updateArtistSortName = `-- name: UpdateArtistSortName :one
+UPDATE artists
+SET sort_name = ?, sort_name_manual = ?
+WHERE id = ?
+RETURNING id, name, created_at, updated_at, sort_name, sort_name_manual
+`
+
+type UpdateArtistSortNameParams struct {
+	SortName       string
+	SortNameManual bool
+	ID             int64
+}
+
+func (q *Queries) UpdateArtistSortName(ctx context.Context, arg UpdateArtistSortNameParams) (Artist, error) {
+	row := q.db.QueryRowContext(ctx, updateArtistSortName, arg.SortName, arg.SortNameManual, arg.ID)
+	var i Artist
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SortName,
+		&i.SortNameManual,
 	)
 	return i, err
 }
@@ -21,20 +21,52 @@ func (q *Queries) CountLocations(ctx context.Context) (int64, error) {
 	return count, err
 }
 
+const createLoanLocation = `-- name: CreateLoanLocation :one
+INSERT INTO locations (name, description, is_default, is_loan)
+VALUES ('On loan', 'Records lent out to borrowers', 0, 1)
+RETURNING id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
+`
+
+// Adds the "On loan" location that records are moved to while they're lent
+func (q *Queries) CreateLoanLocation(ctx context.Context) (Location, error) {
+	row := q.db.QueryRowContext(ctx, createLoanLocation)
+	var i Location
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.IsDefault,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsLoan,
+		&i.ParentID,
+		&i.Capacity,
+	)
+	return i, err
+}
+
 const createLocation = `-- name: CreateLocation :one
-INSERT INTO locations (name, description, is_default)
-VALUES (?, ?, ?)
-RETURNING id, name, description, is_default, created_at, updated_at
+INSERT INTO locations (name, description, is_default, parent_id, capacity)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 `
 
 type CreateLocationParams struct {
 	Name        string
 	Description sql.NullString
 	IsDefault   sql.NullBool
+	ParentID    sql.NullInt64
+	Capacity    sql.NullInt64
 }
 
 func (q *Queries) CreateLocation(ctx context.Context, arg CreateLocationParams) (Location, error) {
-	row := q.db.QueryRowContext(ctx, createLocation, arg.Name, arg.Description, arg.IsDefault)
+	row := q.db.QueryRowContext(ctx, createLocation,
+		arg.Name,
+		arg.Description,
+		arg.IsDefault,
+		arg.ParentID,
+		arg.Capacity,
+	)
 	var i Location
 	err := row.Scan(
 		&i.ID,
@@ -43,6 +75,9 @@ func (q *Queries) CreateLocation(ctx context.Context, arg CreateLocationParams)
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsLoan,
+		&i.ParentID,
+		&i.Capacity,
 	)
 	return i, err
 }
@@ -57,8 +92,40 @@ func (q *Queries) DeleteLocation(ctx context.Context, id int64) error {
 	return err
 }
 
+const getChildLocationByName = `-- name: GetChildLocationByName :one
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
+FROM locations
+WHERE name = ?1 AND parent_id IS ?2
+ORDER BY id
+LIMIT 1
+`
+
+type GetChildLocationByNameParams struct {
+	Name     string
+	ParentID sql.NullInt64
+}
+
+// The location with the name directly inside the parent, or at the top level
+// when parent_id is null
+func (q *Queries) GetChildLocationByName(ctx context.Context, arg GetChildLocationByNameParams) (Location, error) {
+	row := q.db.QueryRowContext(ctx, getChildLocationByName, arg.Name, arg.ParentID)
+	var i Location
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.IsDefault,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsLoan,
+		&i.ParentID,
+		&i.Capacity,
+	)
+	return i, err
+}
+
 const getDefaultLocation = `-- name: GetDefaultLocation :one
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 WHERE is_default = 1
 LIMIT 1
@@ -74,12 +141,38 @@ func (q *Queries) GetDefaultLocation(ctx context.Context) (Location, error) {
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsLoan,
+		&i.ParentID,
+		&i.Capacity,
+	)
+	return i, err
+}
+
+const getLoanLocation = `-- name: GetLoanLocation :one
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
+FROM locations
+WHERE is_loan = 1
+`
+
+func (q *Queries) GetLoanLocation(ctx context.Context) (Location, error) {
+	row := q.db.QueryRowContext(ctx, getLoanLocation)
+	var i Location
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.IsDefault,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsLoan,
+		&i.ParentID,
+		&i.Capacity,
 	)
 	return i, err
 }
 
 const getLocation = `-- name: GetLocation :one
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 WHERE id = ?
 `
@@ -94,12 +187,15 @@ func (q *Queries) GetLocation(ctx context.Context, id int64) (Location, error) {
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsLoan,
+		&i.ParentID,
+		&i.Capacity,
 	)
 	return i, err
 }
 
 const getLocationByName = `-- name: GetLocationByName :one
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 WHERE name = ?
 `
@@ -114,12 +210,48 @@ func (q *Queries) GetLocationByName(ctx context.Context, name string) (Location,
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsLoan,
+		&i.ParentID,
+		&i.Capacity,
 	)
 	return i, err
 }
 
+const getLocationPath = `-- name: GetLocationPath :one
+SELECT path FROM location_paths
+WHERE id = ?
+`
+
+// A location's full path from the top, e.g. "Living room / Kallax"
+func (q *Queries) GetLocationPath(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getLocationPath, id)
+	var path string
+	err := row.Scan(&path)
+	return path, err
+}
+
+const isLocationWithin = `-- name: IsLocationWithin :one
+SELECT EXISTS (
+    SELECT 1 FROM location_closure
+    WHERE ancestor_id = ?1 AND location_id = ?2
+)
+`
+
+type IsLocationWithinParams struct {
+	AncestorID int64
+	LocationID int64
+}
+
+// Whether the location is the ancestor or somewhere inside it
+func (q *Queries) IsLocationWithin(ctx context.Context, arg IsLocationWithinParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, isLocationWithin, arg.AncestorID, arg.LocationID)
+	var exists int64
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const listLocations = `-- name: ListLocations :many
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 ORDER BY name ASC
 `
@@ -140,6 +272,114 @@ func (q *Queries) ListLocations(ctx context.Context) ([]Location, error) {
 			&i.IsDefault,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsLoan,
+			&i.ParentID,
+			&i.Capacity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLocationsByName = `-- name: ListLocationsByName :many
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
+FROM locations
+WHERE name = ?
+ORDER BY parent_id IS NOT NULL, id
+LIMIT 2
+`
+
+// Up to two locations with the name, top-level ones first: enough to tell
+// whether a bare name is ambiguous
+func (q *Queries) ListLocationsByName(ctx context.Context, name string) ([]Location, error) {
+	rows, err := q.db.QueryContext(ctx, listLocationsByName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Location
+	for rows.Next() {
+		var i Location
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.IsDefault,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsLoan,
+			&i.ParentID,
+			&i.Capacity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLocationTree = `-- name: ListLocationTree :many
+SELECT l.id, l.name, l.description, l.is_default, l.is_loan, l.parent_id, l.capacity,
+       p.path,
+       COUNT(CASE WHEN r.current_location_id = l.id THEN 1 END) AS record_count,
+       COUNT(r.id) AS total_record_count
+FROM locations l
+JOIN location_paths p ON p.id = l.id
+LEFT JOIN location_closure c ON c.ancestor_id = l.id
+LEFT JOIN records r ON r.current_location_id = c.location_id
+GROUP BY l.id
+ORDER BY l.name COLLATE NOCASE, l.id
+`
+
+type ListLocationTreeRow struct {
+	ID               int64
+	Name             string
+	Description      sql.NullString
+	IsDefault        sql.NullBool
+	IsLoan           bool
+	ParentID         sql.NullInt64
+	Capacity         sql.NullInt64
+	Path             string
+	RecordCount      int64
+	TotalRecordCount int64
+}
+
+// Every location with its full path, the records in it and the records in it
+// or anywhere inside it. Children are put under their parents by the caller.
+func (q *Queries) ListLocationTree(ctx context.Context) ([]ListLocationTreeRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLocationTree)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLocationTreeRow
+	for rows.Next() {
+		var i ListLocationTreeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.IsDefault,
+			&i.IsLoan,
+			&i.ParentID,
+			&i.Capacity,
+			&i.Path,
+			&i.RecordCount,
+			&i.TotalRecordCount,
 		); err != nil {
 			return nil, err
 		}
@@ -155,7 +395,7 @@ func (q *Queries) ListLocations(ctx context.Context) ([]Location, error) {
 }
 
 const listLocationsWithPagination = `-- name: ListLocationsWithPagination :many
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 ORDER BY name ASC
 LIMIT ? OFFSET ?
@@ -182,6 +422,9 @@ func (q *Queries) ListLocationsWithPagination(ctx context.Context, arg ListLocat
 			&i.IsDefault,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsLoan,
+			&i.ParentID,
+			&i.Capacity,
 		); err != nil {
 			return nil, err
 		}
@@ -197,7 +440,7 @@ func (q *Queries) ListLocationsWithPagination(ctx context.Context, arg ListLocat
 }
 
 const searchLocationsByName = `-- name: SearchLocationsByName :many
-SELECT id, name, description, is_default, created_at, updated_at
+SELECT id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 FROM locations
 WHERE name LIKE '%' || ? || '%'
 ORDER BY name ASC
@@ -219,6 +462,9 @@ func (q *Queries) SearchLocationsByName(ctx context.Context, dollar_1 sql.NullSt
 			&i.IsDefault,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsLoan,
+			&i.ParentID,
+			&i.Capacity,
 		); err != nil {
 			return nil, err
 		}
@@ -248,15 +494,17 @@ func (q *Queries) SetDefaultLocation(ctx context.Context, id int64) error {
 
 const updateLocation = `-- name: UpdateLocation :one
 UPDATE locations
-SET name = ?, description = ?, is_default = ?
+SET name = ?, description = ?, is_default = ?, parent_id = ?, capacity = ?
 WHERE id = ?
-RETURNING id, name, description, is_default, created_at, updated_at
+RETURNING id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 `
 
 type UpdateLocationParams struct {
 	Name        string
 	Description sql.NullString
 	IsDefault   sql.NullBool
+	ParentID    sql.NullInt64
+	Capacity    sql.NullInt64
 	ID          int64
 }
 
@@ -265,6 +513,8 @@ func (q *Queries) UpdateLocation(ctx context.Context, arg UpdateLocationParams)
 		arg.Name,
 		arg.Description,
 		arg.IsDefault,
+		arg.ParentID,
+		arg.Capacity,
 		arg.ID,
 	)
 	var i Location
@@ -275,6 +525,9 @@ func (q *Queries) UpdateLocation(ctx context.Context, arg UpdateLocationParams)
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsLoan,
+		&i.ParentID,
+		&i.Capacity,
 	)
 	return i, err
 }
@@ -283,7 +536,7 @@ const updateLocationName = `-- name: UpdateLocationName :one
 UPDATE locations
 SET name = ?
 WHERE id = ?
-RETURNING id, name, description, is_default, created_at, updated_at
+RETURNING id, name, description, is_default, created_at, updated_at, is_loan, parent_id, capacity
 `
 
 type UpdateLocationNameParams struct {
@@ -301,6 +554,9 @@ func (q *Queries) UpdateLocationName(ctx context.Context, arg UpdateLocationName
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsLoan,
+		&i.ParentID,
+		&i.Capacity,
 	)
 	return i, err
 }
@@ -22,12 +22,66 @@ type ApiToken struct {
 }
 
 type Artist struct {
+	ID             int64
+	Name           string
+	CreatedAt      sql.NullTime
+	UpdatedAt      sql.NullTime
+	SortName       string
+	SortNameManual bool
+}
+
+type ArtistAlias struct {
+	ID        int64
+	ArtistID  int64
+	Name      string
+	CreatedAt sql.NullTime
+}
+
+type Borrower struct {
 	ID        int64
 	Name      string
+	Contact   sql.NullString
+	Notes     sql.NullString
 	CreatedAt sql.NullTime
 	UpdatedAt sql.NullTime
 }
 
+type ConditionHistory struct {
+	ID                  int64
+	RecordID            int64
+	MediaGrade          sql.NullString
+	SleeveGrade         sql.NullString
+	PreviousMediaGrade  sql.NullString
+	PreviousSleeveGrade sql.NullString
+	Note                sql.NullString
+	UserID              sql.NullString
+	GradedAt            time.Time
+}
+
+type DismissedDuplicate struct {
+	RecordID    int64
+	DuplicateID int64
+	DismissedAt sql.NullTime
+}
+
+type Grade struct {
+	Code string
+	Name string
+	Rank int64
+}
+
+type Loan struct {
+	ID         int64
+	RecordID   int64
+	BorrowerID int64
+	LentAt     time.Time
+	DueAt      sql.NullTime
+	ReturnedAt sql.NullTime
+	Notes      sql.NullString
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+}
+
 type Location struct {
 	ID          int64
 	Name        string
@@ -35,6 +89,27 @@ type Location struct {
 	IsDefault   sql.NullBool
 	CreatedAt   sql.NullTime
 	UpdatedAt   sql.NullTime
+	IsLoan      bool
+	ParentID    sql.NullInt64
+	Capacity    sql.NullInt64
+}
+
+type MetadataCache struct {
+	Provider  string
+	ReleaseID string
+	Data      string
+	FetchedAt sql.NullTime
+}
+
+type Play struct {
+	ID        int64
+	RecordID  int64
+	UserID    sql.NullString
+	PlayedAt  time.Time
+	Side      sql.NullString
+	Notes     sql.NullString
+	CreatedAt sql.NullTime
+	UpdatedAt sql.NullTime
 }
 
 type Record struct {
@@ -46,12 +121,83 @@ type Record struct {
 	CurrentLocationID sql.NullInt64
 	HomeLocationID    sql.NullInt64
 	CatalogNumber     sql.NullString
-	Condition         sql.NullString
 	Notes             sql.NullString
 	LastPlayedAt      sql.NullTime
 	PlayCount         sql.NullInt64
 	CreatedAt         sql.NullTime
 	UpdatedAt         sql.NullTime
+	MediaGrade        sql.NullString
+	SleeveGrade       sql.NullString
+	Barcode           sql.NullString
+	ShelfPosition     sql.NullInt64
+}
+
+type RecordArtist struct {
+	RecordID  int64
+	ArtistID  int64
+	Role      string
+	Position  int64
+	CreatedAt sql.NullTime
+}
+
+type RecordImage struct {
+	ID          int64
+	RecordID    int64
+	Kind        string
+	ContentType string
+	Width       int64
+	Height      int64
+	SizeBytes   int64
+	Checksum    string
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+}
+
+type RecordMovement struct {
+	ID             int64
+	RecordID       int64
+	FromLocationID sql.NullInt64
+	ToLocationID   sql.NullInt64
+	Note           sql.NullString
+	UserID         sql.NullString
+	MovedAt        sql.NullTime
+}
+
+type RecordPurchase struct {
+	RecordID    int64
+	PriceCents  sql.NullInt64
+	Currency    string
+	PurchasedAt sql.NullTime
+	Seller      sql.NullString
+	Shop        sql.NullString
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+}
+
+type RecordTag struct {
+	RecordID  int64
+	TagID     int64
+	CreatedAt sql.NullTime
+}
+
+type RecordValue struct {
+	ID          int64
+	RecordID    int64
+	ValuedAt    time.Time
+	AmountCents int64
+	Currency    string
+	Source      sql.NullString
+	CreatedAt   sql.NullTime
+}
+
+type SearchIndex struct {
+	EntityType    string
+	EntityID      string
+	Title         string
+	AlbumTitle    string
+	CatalogNumber string
+	Notes         string
+	ArtistName    string
 }
 
 type Session struct {
@@ -65,6 +211,26 @@ type Session struct {
 	UpdatedAt sql.NullTime
 }
 
+type Tag struct {
+	ID        int64
+	Name      string
+	Kind      string
+	CreatedAt sql.NullTime
+	UpdatedAt sql.NullTime
+}
+
+type Track struct {
+	ID              int64
+	RecordID        int64
+	Side            sql.NullString
+	Position        string
+	Title           string
+	DurationSeconds sql.NullInt64
+	ArtistID        sql.NullInt64
+	CreatedAt       sql.NullTime
+	UpdatedAt       sql.NullTime
+}
+
 type User struct {
 	ID            string
 	Email         string
@@ -77,3 +243,19 @@ type User struct {
 	CreatedAt     sql.NullTime
 	UpdatedAt     sql.NullTime
 }
+
+type Want struct {
+	ID            int64
+	ArtistName    string
+	Title         string
+	Pressing      sql.NullString
+	CatalogNumber sql.NullString
+	Barcode       sql.NullString
+	MaxPriceCents sql.NullInt64
+	Priority      int64
+	Notes         sql.NullString
+	RecordID      sql.NullInt64
+	AcquiredAt    sql.NullTime
+	CreatedAt     sql.NullTime
+	UpdatedAt     sql.NullTime
+}
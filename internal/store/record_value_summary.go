@@ -0,0 +1,119 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Groupings accepted by ValueSummary; ValueByTotal is the whole collection
+const (
+	ValueByTotal    = ""
+	ValueByArtist   = "artist"
+	ValueByDecade   = "decade"
+	ValueByLocation = "location"
+)
+
+// valueGroupColumns maps groupings to the column expression grouped on.
+// Keys are never interpolated directly so user input can't reach the SQL.
+var valueGroupColumns = map[string]string{
+	ValueByTotal:    "NULL",
+	ValueByArtist:   "a.name",
+	ValueByDecade:   "CAST((r.release_year / 10) * 10 AS TEXT) || 's'",
+	ValueByLocation: "cl.path",
+}
+
+// valueGroupOrders maps groupings to the expression groups are ordered by,
+// where that isn't the grouped column: artists go by their sort name
+var valueGroupOrders = map[string]string{
+	ValueByArtist: "MIN(a.sort_name)",
+}
+
+// ValueSummaryRow is what a group of records cost and is worth, in one
+// currency. Records counts those with a price or a value. GainCents is the
+// value less the price of the records that have both in the currency, so
+// records that were never valued don't count as a loss. Group is null for
+// the total, and for records without an artist, year or location.
+type ValueSummaryRow struct {
+	Group      sql.NullString
+	Currency   string
+	Records    int64
+	PaidCents  int64
+	ValueCents int64
+	GainCents  int64
+}
+
+// valueSummary adds up the purchase prices and latest value estimates. Each
+// record contributes a row for what was paid, one for its current value and
+// one for the difference when both are in the same currency.
+const valueSummary = `WITH latest AS (
+    SELECT v.record_id, v.amount_cents, v.currency
+    FROM record_values v
+    WHERE v.id = (
+        SELECT v2.id FROM record_values v2
+        WHERE v2.record_id = v.record_id
+        ORDER BY v2.valued_at DESC, v2.id DESC
+        LIMIT 1)
+),
+money AS (
+    SELECT p.record_id, p.currency, p.price_cents AS paid, 0 AS value, 0 AS gain
+    FROM record_purchases p
+    WHERE p.price_cents IS NOT NULL
+    UNION ALL
+    SELECT l.record_id, l.currency, 0, l.amount_cents, 0
+    FROM latest l
+    UNION ALL
+    SELECT l.record_id, l.currency, 0, 0, l.amount_cents - p.price_cents
+    FROM latest l
+    JOIN record_purchases p ON p.record_id = l.record_id AND p.currency = l.currency
+    WHERE p.price_cents IS NOT NULL
+)
+SELECT %s AS grp, m.currency, COUNT(DISTINCT m.record_id),
+       SUM(m.paid), SUM(m.value), SUM(m.gain)
+FROM money m
+JOIN records r ON r.id = m.record_id
+LEFT JOIN artists a ON r.artist_id = a.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+GROUP BY grp, m.currency
+ORDER BY grp IS NULL, %s COLLATE NOCASE, m.currency`
+
+// ValueSummary returns the collection's value grouped by artist, decade or
+// current location, or in total, with a row per group and currency. An
+// unknown grouping is the total.
+func (q *Queries) ValueSummary(ctx context.Context, by string) ([]ValueSummaryRow, error) {
+	column, ok := valueGroupColumns[by]
+	if !ok {
+		by, column = ValueByTotal, valueGroupColumns[ValueByTotal]
+	}
+	order := cmp.Or(valueGroupOrders[by], "grp")
+
+	rows, err := q.db.QueryContext(ctx, fmt.Sprintf(valueSummary, column, order))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ValueSummaryRow
+	for rows.Next() {
+		var i ValueSummaryRow
+		if err := rows.Scan(
+			&i.Group,
+			&i.Currency,
+			&i.Records,
+			&i.PaidCents,
+			&i.ValueCents,
+			&i.GainCents,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
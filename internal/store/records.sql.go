@@ -8,6 +8,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const countRecords = `-- name: CountRecords :one
@@ -22,10 +23,10 @@ func (q *Queries) CountRecords(ctx context.Context) (int64, error) {
 }
 
 const countRecordsByArtist = `-- name: CountRecordsByArtist :one
-SELECT COUNT(*) FROM records WHERE artist_id = ?
+SELECT COUNT(DISTINCT record_id) FROM record_artists WHERE artist_id = ?
 `
 
-func (q *Queries) CountRecordsByArtist(ctx context.Context, artistID sql.NullInt64) (int64, error) {
+func (q *Queries) CountRecordsByArtist(ctx context.Context, artistID int64) (int64, error) {
 	row := q.db.QueryRowContext(ctx, countRecordsByArtist, artistID)
 	var count int64
 	err := row.Scan(&count)
@@ -47,13 +48,13 @@ const createRecord = `-- name: CreateRecord :one
 INSERT INTO records (
     title, artist_id, album_title, release_year, 
     current_location_id, home_location_id, catalog_number, 
-    condition, notes, play_count
+    media_grade, sleeve_grade, notes, play_count, barcode
 )
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING id, title, artist_id, album_title, release_year, 
           current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at
+          notes, last_played_at, play_count, 
+          created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 `
 
 type CreateRecordParams struct {
@@ -64,9 +65,11 @@ type CreateRecordParams struct {
 	CurrentLocationID sql.NullInt64
 	HomeLocationID    sql.NullInt64
 	CatalogNumber     sql.NullString
-	Condition         sql.NullString
+	MediaGrade        sql.NullString
+	SleeveGrade       sql.NullString
 	Notes             sql.NullString
 	PlayCount         sql.NullInt64
+	Barcode           sql.NullString
 }
 
 func (q *Queries) CreateRecord(ctx context.Context, arg CreateRecordParams) (Record, error) {
@@ -78,9 +81,11 @@ func (q *Queries) CreateRecord(ctx context.Context, arg CreateRecordParams) (Rec
 		arg.CurrentLocationID,
 		arg.HomeLocationID,
 		arg.CatalogNumber,
-		arg.Condition,
+		arg.MediaGrade,
+		arg.SleeveGrade,
 		arg.Notes,
 		arg.PlayCount,
+		arg.Barcode,
 	)
 	var i Record
 	err := row.Scan(
@@ -92,12 +97,15 @@ func (q *Queries) CreateRecord(ctx context.Context, arg CreateRecordParams) (Rec
 		&i.CurrentLocationID,
 		&i.HomeLocationID,
 		&i.CatalogNumber,
-		&i.Condition,
 		&i.Notes,
 		&i.LastPlayedAt,
 		&i.PlayCount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MediaGrade,
+		&i.SleeveGrade,
+		&i.Barcode,
+		&i.ShelfPosition,
 	)
 	return i, err
 }
@@ -112,26 +120,80 @@ func (q *Queries) DeleteRecord(ctx context.Context, id int64) error {
 	return err
 }
 
+const findMatchingRecord = `-- name: FindMatchingRecord :one
+SELECT id FROM records
+WHERE title = ? COLLATE NOCASE
+  AND artist_id IS ?
+  AND COALESCE(catalog_number, '') = ? COLLATE NOCASE
+ORDER BY id
+LIMIT 1
+`
+
+type FindMatchingRecordParams struct {
+	Title         string
+	ArtistID      sql.NullInt64
+	CatalogNumber string
+}
+
+// The first record with the title (ignoring case), artist and catalog number,
+// for imports to spot records already in the collection
+func (q *Queries) FindMatchingRecord(ctx context.Context, arg FindMatchingRecordParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, findMatchingRecord, arg.Title, arg.ArtistID, arg.CatalogNumber)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
 const getMostPlayedRecords = `-- name: GetMostPlayedRecords :many
-SELECT id, title, artist_id, album_title, release_year, 
-       current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
-FROM records
-WHERE play_count > 0
-ORDER BY play_count DESC
+SELECT r.id, r.title, r.artist_id, r.album_title, r.release_year, 
+       r.current_location_id, r.home_location_id, r.catalog_number, 
+       r.notes, r.last_played_at, r.play_count, 
+       r.created_at, r.updated_at, r.media_grade, r.sleeve_grade, r.barcode, r.shelf_position,
+       COUNT(p.id) AS window_play_count
+FROM records r
+JOIN plays p ON p.record_id = r.id
+WHERE p.played_at >= ? AND p.played_at < ?
+GROUP BY r.id
+ORDER BY window_play_count DESC, MAX(p.played_at) DESC
 LIMIT ?
 `
 
-func (q *Queries) GetMostPlayedRecords(ctx context.Context, limit int64) ([]Record, error) {
-	rows, err := q.db.QueryContext(ctx, getMostPlayedRecords, limit)
+type GetMostPlayedRecordsParams struct {
+	Since time.Time
+	Until time.Time
+	Limit int64
+}
+
+type GetMostPlayedRecordsRow struct {
+	ID                int64
+	Title             string
+	ArtistID          sql.NullInt64
+	AlbumTitle        sql.NullString
+	ReleaseYear       sql.NullInt64
+	CurrentLocationID sql.NullInt64
+	HomeLocationID    sql.NullInt64
+	CatalogNumber     sql.NullString
+	Notes             sql.NullString
+	LastPlayedAt      sql.NullTime
+	PlayCount         sql.NullInt64
+	CreatedAt         sql.NullTime
+	UpdatedAt         sql.NullTime
+	MediaGrade        sql.NullString
+	SleeveGrade       sql.NullString
+	Barcode           sql.NullString
+	ShelfPosition     sql.NullInt64
+	WindowPlayCount   int64
+}
+
+func (q *Queries) GetMostPlayedRecords(ctx context.Context, arg GetMostPlayedRecordsParams) ([]GetMostPlayedRecordsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getMostPlayedRecords, arg.Since, arg.Until, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Record
+	var items []GetMostPlayedRecordsRow
 	for rows.Next() {
-		var i Record
+		var i GetMostPlayedRecordsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Title,
@@ -141,12 +203,16 @@ func (q *Queries) GetMostPlayedRecords(ctx context.Context, limit int64) ([]Reco
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
+			&i.WindowPlayCount,
 		); err != nil {
 			return nil, err
 		}
@@ -162,18 +228,26 @@ func (q *Queries) GetMostPlayedRecords(ctx context.Context, limit int64) ([]Reco
 }
 
 const getRecentlyPlayedRecords = `-- name: GetRecentlyPlayedRecords :many
-SELECT id, title, artist_id, album_title, release_year, 
-       current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
-FROM records
-WHERE last_played_at IS NOT NULL
-ORDER BY last_played_at DESC
+SELECT r.id, r.title, r.artist_id, r.album_title, r.release_year, 
+       r.current_location_id, r.home_location_id, r.catalog_number, 
+       r.notes, r.last_played_at, r.play_count, 
+       r.created_at, r.updated_at, r.media_grade, r.sleeve_grade, r.barcode, r.shelf_position
+FROM records r
+JOIN plays p ON p.record_id = r.id
+WHERE p.played_at >= ? AND p.played_at < ?
+GROUP BY r.id
+ORDER BY MAX(p.played_at) DESC
 LIMIT ?
 `
 
-func (q *Queries) GetRecentlyPlayedRecords(ctx context.Context, limit int64) ([]Record, error) {
-	rows, err := q.db.QueryContext(ctx, getRecentlyPlayedRecords, limit)
+type GetRecentlyPlayedRecordsParams struct {
+	Since time.Time
+	Until time.Time
+	Limit int64
+}
+
+func (q *Queries) GetRecentlyPlayedRecords(ctx context.Context, arg GetRecentlyPlayedRecordsParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentlyPlayedRecords, arg.Since, arg.Until, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -190,12 +264,15 @@ func (q *Queries) GetRecentlyPlayedRecords(ctx context.Context, limit int64) ([]
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
 		); err != nil {
 			return nil, err
 		}
@@ -213,8 +290,8 @@ func (q *Queries) GetRecentlyPlayedRecords(ctx context.Context, limit int64) ([]
 const getRecord = `-- name: GetRecord :one
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 WHERE id = ?
 `
@@ -231,27 +308,31 @@ func (q *Queries) GetRecord(ctx context.Context, id int64) (Record, error) {
 		&i.CurrentLocationID,
 		&i.HomeLocationID,
 		&i.CatalogNumber,
-		&i.Condition,
 		&i.Notes,
 		&i.LastPlayedAt,
 		&i.PlayCount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MediaGrade,
+		&i.SleeveGrade,
+		&i.Barcode,
+		&i.ShelfPosition,
 	)
 	return i, err
 }
 
 const getRecordWithDetails = `-- name: GetRecordWithDetails :one
 SELECT r.id, r.title, r.album_title, r.release_year, 
-       r.catalog_number, r.condition, r.notes, 
+       r.catalog_number, r.media_grade, r.sleeve_grade, r.notes, 
        r.last_played_at, r.play_count, r.created_at, r.updated_at,
        a.id as artist_id, a.name as artist_name,
-       cl.id as current_location_id, cl.name as current_location_name,
-       hl.id as home_location_id, hl.name as home_location_name
+       cl.id as current_location_id, cl.path as current_location_name,
+       hl.id as home_location_id, hl.path as home_location_name,
+       r.barcode
 FROM records r
 LEFT JOIN artists a ON r.artist_id = a.id
-LEFT JOIN locations cl ON r.current_location_id = cl.id
-LEFT JOIN locations hl ON r.home_location_id = hl.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+LEFT JOIN location_paths hl ON r.home_location_id = hl.id
 WHERE r.id = ?
 `
 
@@ -261,7 +342,8 @@ type GetRecordWithDetailsRow struct {
 	AlbumTitle          sql.NullString
 	ReleaseYear         sql.NullInt64
 	CatalogNumber       sql.NullString
-	Condition           sql.NullString
+	MediaGrade          sql.NullString
+	SleeveGrade         sql.NullString
 	Notes               sql.NullString
 	LastPlayedAt        sql.NullTime
 	PlayCount           sql.NullInt64
@@ -273,6 +355,7 @@ type GetRecordWithDetailsRow struct {
 	CurrentLocationName sql.NullString
 	HomeLocationID      sql.NullInt64
 	HomeLocationName    sql.NullString
+	Barcode             sql.NullString
 }
 
 func (q *Queries) GetRecordWithDetails(ctx context.Context, id int64) (GetRecordWithDetailsRow, error) {
@@ -284,7 +367,8 @@ func (q *Queries) GetRecordWithDetails(ctx context.Context, id int64) (GetRecord
 		&i.AlbumTitle,
 		&i.ReleaseYear,
 		&i.CatalogNumber,
-		&i.Condition,
+		&i.MediaGrade,
+		&i.SleeveGrade,
 		&i.Notes,
 		&i.LastPlayedAt,
 		&i.PlayCount,
@@ -296,6 +380,7 @@ func (q *Queries) GetRecordWithDetails(ctx context.Context, id int64) (GetRecord
 		&i.CurrentLocationName,
 		&i.HomeLocationID,
 		&i.HomeLocationName,
+		&i.Barcode,
 	)
 	return i, err
 }
@@ -303,14 +388,14 @@ func (q *Queries) GetRecordWithDetails(ctx context.Context, id int64) (GetRecord
 const getRecordsByArtist = `-- name: GetRecordsByArtist :many
 SELECT r.id, r.title, r.artist_id, r.album_title, r.release_year, 
        r.current_location_id, r.home_location_id, r.catalog_number, 
-       r.condition, r.notes, r.last_played_at, r.play_count, 
-       r.created_at, r.updated_at
+       r.notes, r.last_played_at, r.play_count, 
+       r.created_at, r.updated_at, r.media_grade, r.sleeve_grade, r.barcode, r.shelf_position
 FROM records r
-WHERE r.artist_id = ?
+WHERE r.id IN (SELECT record_id FROM record_artists WHERE artist_id = ?)
 ORDER BY r.title ASC
 `
 
-func (q *Queries) GetRecordsByArtist(ctx context.Context, artistID sql.NullInt64) ([]Record, error) {
+func (q *Queries) GetRecordsByArtist(ctx context.Context, artistID int64) ([]Record, error) {
 	rows, err := q.db.QueryContext(ctx, getRecordsByArtist, artistID)
 	if err != nil {
 		return nil, err
@@ -328,12 +413,15 @@ func (q *Queries) GetRecordsByArtist(ctx context.Context, artistID sql.NullInt64
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
 		); err != nil {
 			return nil, err
 		}
@@ -348,18 +436,18 @@ func (q *Queries) GetRecordsByArtist(ctx context.Context, artistID sql.NullInt64
 	return items, nil
 }
 
-const getRecordsByCondition = `-- name: GetRecordsByCondition :many
-SELECT id, title, artist_id, album_title, release_year, 
-       current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
-FROM records
-WHERE condition = ?
-ORDER BY title ASC
+const getRecordsByLocation = `-- name: GetRecordsByLocation :many
+SELECT r.id, r.title, r.artist_id, r.album_title, r.release_year, 
+       r.current_location_id, r.home_location_id, r.catalog_number, 
+       r.notes, r.last_played_at, r.play_count, 
+       r.created_at, r.updated_at, r.media_grade, r.sleeve_grade, r.barcode, r.shelf_position
+FROM records r
+WHERE r.current_location_id = ?
+ORDER BY r.title ASC
 `
 
-func (q *Queries) GetRecordsByCondition(ctx context.Context, condition sql.NullString) ([]Record, error) {
-	rows, err := q.db.QueryContext(ctx, getRecordsByCondition, condition)
+func (q *Queries) GetRecordsByLocation(ctx context.Context, currentLocationID sql.NullInt64) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, getRecordsByLocation, currentLocationID)
 	if err != nil {
 		return nil, err
 	}
@@ -376,12 +464,15 @@ func (q *Queries) GetRecordsByCondition(ctx context.Context, condition sql.NullS
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
 		); err != nil {
 			return nil, err
 		}
@@ -396,18 +487,18 @@ func (q *Queries) GetRecordsByCondition(ctx context.Context, condition sql.NullS
 	return items, nil
 }
 
-const getRecordsByLocation = `-- name: GetRecordsByLocation :many
-SELECT r.id, r.title, r.artist_id, r.album_title, r.release_year, 
-       r.current_location_id, r.home_location_id, r.catalog_number, 
-       r.condition, r.notes, r.last_played_at, r.play_count, 
-       r.created_at, r.updated_at
-FROM records r
-WHERE r.current_location_id = ?
-ORDER BY r.title ASC
+const getRecordsByMediaGrade = `-- name: GetRecordsByMediaGrade :many
+SELECT id, title, artist_id, album_title, release_year, 
+       current_location_id, home_location_id, catalog_number, 
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
+FROM records
+WHERE media_grade = ?
+ORDER BY title ASC
 `
 
-func (q *Queries) GetRecordsByLocation(ctx context.Context, currentLocationID sql.NullInt64) ([]Record, error) {
-	rows, err := q.db.QueryContext(ctx, getRecordsByLocation, currentLocationID)
+func (q *Queries) GetRecordsByMediaGrade(ctx context.Context, mediaGrade sql.NullString) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, getRecordsByMediaGrade, mediaGrade)
 	if err != nil {
 		return nil, err
 	}
@@ -424,12 +515,15 @@ func (q *Queries) GetRecordsByLocation(ctx context.Context, currentLocationID sq
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
 		); err != nil {
 			return nil, err
 		}
@@ -447,8 +541,8 @@ func (q *Queries) GetRecordsByLocation(ctx context.Context, currentLocationID sq
 const getRecordsByReleaseYear = `-- name: GetRecordsByReleaseYear :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 WHERE release_year = ?
 ORDER BY title ASC
@@ -472,12 +566,15 @@ func (q *Queries) GetRecordsByReleaseYear(ctx context.Context, releaseYear sql.N
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
 		); err != nil {
 			return nil, err
 		}
@@ -492,11 +589,105 @@ func (q *Queries) GetRecordsByReleaseYear(ctx context.Context, releaseYear sql.N
 	return items, nil
 }
 
+const listDuplicateCandidates = `-- name: ListDuplicateCandidates :many
+SELECT r.id, r.title, r.album_title, r.release_year, r.catalog_number,
+       r.barcode, r.media_grade, r.sleeve_grade, r.notes, r.play_count,
+       r.created_at, a.name AS artist_name
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id
+ORDER BY r.id
+`
+
+type ListDuplicateCandidatesRow struct {
+	ID            int64
+	Title         string
+	AlbumTitle    sql.NullString
+	ReleaseYear   sql.NullInt64
+	CatalogNumber sql.NullString
+	Barcode       sql.NullString
+	MediaGrade    sql.NullString
+	SleeveGrade   sql.NullString
+	Notes         sql.NullString
+	PlayCount     sql.NullInt64
+	CreatedAt     sql.NullTime
+	ArtistName    sql.NullString
+}
+
+// Every record with what the duplicate finder compares and the review page
+// shows
+func (q *Queries) ListDuplicateCandidates(ctx context.Context) ([]ListDuplicateCandidatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDuplicateCandidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDuplicateCandidatesRow
+	for rows.Next() {
+		var i ListDuplicateCandidatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.AlbumTitle,
+			&i.ReleaseYear,
+			&i.CatalogNumber,
+			&i.Barcode,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Notes,
+			&i.PlayCount,
+			&i.CreatedAt,
+			&i.ArtistName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecordIDsByTitle = `-- name: ListRecordIDsByTitle :many
+SELECT id FROM records
+WHERE title = ? COLLATE NOCASE
+ORDER BY id
+LIMIT 2
+`
+
+// Up to two records with the title (ignoring case), for imports that have
+// nothing else to match on to tell whether it's unambiguous
+func (q *Queries) ListRecordIDsByTitle(ctx context.Context, title string) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listRecordIDsByTitle, title)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listRecords = `-- name: ListRecords :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 ORDER BY title ASC
 `
@@ -519,12 +710,143 @@ func (q *Queries) ListRecords(ctx context.Context) ([]Record, error) {
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecordsAwayFromHome = `-- name: ListRecordsAwayFromHome :many
+SELECT r.id, r.title, a.name AS artist_name,
+       r.current_location_id, cl.path AS current_location_name,
+       CAST(COALESCE(c.is_loan, 0) AS BOOLEAN) AS on_loan,
+       r.home_location_id, hl.path AS home_location_name,
+       m.moved_at
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id
+LEFT JOIN locations c ON r.current_location_id = c.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+JOIN location_paths hl ON r.home_location_id = hl.id
+LEFT JOIN record_movements m ON m.id = (
+    SELECT id FROM record_movements
+    WHERE record_id = r.id
+    ORDER BY moved_at DESC, id DESC
+    LIMIT 1)
+WHERE r.current_location_id IS NOT r.home_location_id
+ORDER BY hl.path, COALESCE(a.sort_name, '') COLLATE NOCASE, r.title COLLATE NOCASE, r.id
+`
+
+type ListRecordsAwayFromHomeRow struct {
+	ID                  int64
+	Title               string
+	ArtistName          sql.NullString
+	CurrentLocationID   sql.NullInt64
+	CurrentLocationName sql.NullString
+	OnLoan              bool
+	HomeLocationID      sql.NullInt64
+	HomeLocationName    string
+	MovedAt             sql.NullTime
+}
+
+// Records with a home location that are somewhere else, or nowhere, grouped
+// by home so they can be put away in one trip. MovedAt is when the record
+// last moved, if that's been logged.
+func (q *Queries) ListRecordsAwayFromHome(ctx context.Context) ([]ListRecordsAwayFromHomeRow, error) {
+	rows, err := q.db.QueryContext(ctx, listRecordsAwayFromHome)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRecordsAwayFromHomeRow
+	for rows.Next() {
+		var i ListRecordsAwayFromHomeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.ArtistName,
+			&i.CurrentLocationID,
+			&i.CurrentLocationName,
+			&i.OnLoan,
+			&i.HomeLocationID,
+			&i.HomeLocationName,
+			&i.MovedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecordsByBarcode = `-- name: ListRecordsByBarcode :many
+SELECT r.id, r.title, r.album_title, r.release_year, r.catalog_number,
+       r.media_grade, r.sleeve_grade,
+       a.name AS artist_name,
+       r.current_location_id, cl.path AS current_location_name
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+WHERE r.barcode = ?
+ORDER BY r.id
+`
+
+type ListRecordsByBarcodeRow struct {
+	ID                  int64
+	Title               string
+	AlbumTitle          sql.NullString
+	ReleaseYear         sql.NullInt64
+	CatalogNumber       sql.NullString
+	MediaGrade          sql.NullString
+	SleeveGrade         sql.NullString
+	ArtistName          sql.NullString
+	CurrentLocationID   sql.NullInt64
+	CurrentLocationName sql.NullString
+}
+
+// Every copy with the barcode, and where it is
+func (q *Queries) ListRecordsByBarcode(ctx context.Context, barcode sql.NullString) ([]ListRecordsByBarcodeRow, error) {
+	rows, err := q.db.QueryContext(ctx, listRecordsByBarcode, barcode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRecordsByBarcodeRow
+	for rows.Next() {
+		var i ListRecordsByBarcodeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.AlbumTitle,
+			&i.ReleaseYear,
+			&i.CatalogNumber,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.ArtistName,
+			&i.CurrentLocationID,
+			&i.CurrentLocationName,
 		); err != nil {
 			return nil, err
 		}
@@ -541,15 +863,19 @@ func (q *Queries) ListRecords(ctx context.Context) ([]Record, error) {
 
 const listRecordsWithDetails = `-- name: ListRecordsWithDetails :many
 SELECT r.id, r.title, r.album_title, r.release_year, 
-       r.catalog_number, r.condition, r.notes, 
+       r.catalog_number, r.barcode, r.media_grade, r.sleeve_grade, r.notes, 
        r.last_played_at, r.play_count, r.created_at, r.updated_at,
        a.id as artist_id, a.name as artist_name,
-       cl.id as current_location_id, cl.name as current_location_name,
-       hl.id as home_location_id, hl.name as home_location_name
+       cl.id as current_location_id, cl.path as current_location_name,
+       hl.id as home_location_id, hl.path as home_location_name,
+       (SELECT COUNT(*) FROM tracks t WHERE t.record_id = r.id) AS track_count,
+       (SELECT CAST(COALESCE(SUM(t.duration_seconds), 0) AS INTEGER) FROM tracks t WHERE t.record_id = r.id) AS running_time,
+       fi.checksum AS cover_checksum
 FROM records r
 LEFT JOIN artists a ON r.artist_id = a.id
-LEFT JOIN locations cl ON r.current_location_id = cl.id
-LEFT JOIN locations hl ON r.home_location_id = hl.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+LEFT JOIN location_paths hl ON r.home_location_id = hl.id
+LEFT JOIN record_images fi ON fi.record_id = r.id AND fi.kind = 'front'
 ORDER BY r.title ASC
 `
 
@@ -559,7 +885,9 @@ type ListRecordsWithDetailsRow struct {
 	AlbumTitle          sql.NullString
 	ReleaseYear         sql.NullInt64
 	CatalogNumber       sql.NullString
-	Condition           sql.NullString
+	Barcode             sql.NullString
+	MediaGrade          sql.NullString
+	SleeveGrade         sql.NullString
 	Notes               sql.NullString
 	LastPlayedAt        sql.NullTime
 	PlayCount           sql.NullInt64
@@ -571,6 +899,9 @@ type ListRecordsWithDetailsRow struct {
 	CurrentLocationName sql.NullString
 	HomeLocationID      sql.NullInt64
 	HomeLocationName    sql.NullString
+	TrackCount          int64
+	RunningTime         int64
+	CoverChecksum       sql.NullString
 }
 
 func (q *Queries) ListRecordsWithDetails(ctx context.Context) ([]ListRecordsWithDetailsRow, error) {
@@ -588,7 +919,9 @@ func (q *Queries) ListRecordsWithDetails(ctx context.Context) ([]ListRecordsWith
 			&i.AlbumTitle,
 			&i.ReleaseYear,
 			&i.CatalogNumber,
-			&i.Condition,
+			&i.Barcode,
+			&i.MediaGrade,
+			&i.SleeveGrade,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
@@ -600,6 +933,9 @@ func (q *Queries) ListRecordsWithDetails(ctx context.Context) ([]ListRecordsWith
 			&i.CurrentLocationName,
 			&i.HomeLocationID,
 			&i.HomeLocationName,
+			&i.TrackCount,
+			&i.RunningTime,
+			&i.CoverChecksum,
 		); err != nil {
 			return nil, err
 		}
@@ -617,8 +953,8 @@ func (q *Queries) ListRecordsWithDetails(ctx context.Context) ([]ListRecordsWith
 const listRecordsWithPagination = `-- name: ListRecordsWithPagination :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 ORDER BY title ASC
 LIMIT ? OFFSET ?
@@ -647,12 +983,15 @@ func (q *Queries) ListRecordsWithPagination(ctx context.Context, arg ListRecords
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
 		); err != nil {
 			return nil, err
 		}
@@ -667,43 +1006,11 @@ func (q *Queries) ListRecordsWithPagination(ctx context.Context, arg ListRecords
 	return items, nil
 }
 
-const recordPlayback = `-- name: RecordPlayback :one
-UPDATE records
-SET last_played_at = CURRENT_TIMESTAMP, play_count = play_count + 1
-WHERE id = ?
-RETURNING id, title, artist_id, album_title, release_year, 
-          current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at
-`
-
-func (q *Queries) RecordPlayback(ctx context.Context, id int64) (Record, error) {
-	row := q.db.QueryRowContext(ctx, recordPlayback, id)
-	var i Record
-	err := row.Scan(
-		&i.ID,
-		&i.Title,
-		&i.ArtistID,
-		&i.AlbumTitle,
-		&i.ReleaseYear,
-		&i.CurrentLocationID,
-		&i.HomeLocationID,
-		&i.CatalogNumber,
-		&i.Condition,
-		&i.Notes,
-		&i.LastPlayedAt,
-		&i.PlayCount,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
-}
-
 const searchRecordsByAlbum = `-- name: SearchRecordsByAlbum :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 WHERE album_title LIKE '%' || ? || '%'
 ORDER BY album_title ASC
@@ -727,12 +1034,15 @@ func (q *Queries) SearchRecordsByAlbum(ctx context.Context, dollar_1 sql.NullStr
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
 		); err != nil {
 			return nil, err
 		}
@@ -750,8 +1060,8 @@ func (q *Queries) SearchRecordsByAlbum(ctx context.Context, dollar_1 sql.NullStr
 const searchRecordsByTitle = `-- name: SearchRecordsByTitle :many
 SELECT id, title, artist_id, album_title, release_year, 
        current_location_id, home_location_id, catalog_number, 
-       condition, notes, last_played_at, play_count, 
-       created_at, updated_at
+       notes, last_played_at, play_count, 
+       created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 FROM records
 WHERE title LIKE '%' || ? || '%'
 ORDER BY title ASC
@@ -775,12 +1085,15 @@ func (q *Queries) SearchRecordsByTitle(ctx context.Context, dollar_1 sql.NullStr
 			&i.CurrentLocationID,
 			&i.HomeLocationID,
 			&i.CatalogNumber,
-			&i.Condition,
 			&i.Notes,
 			&i.LastPlayedAt,
 			&i.PlayCount,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Barcode,
+			&i.ShelfPosition,
 		); err != nil {
 			return nil, err
 		}
@@ -799,12 +1112,12 @@ const updateRecord = `-- name: UpdateRecord :one
 UPDATE records
 SET title = ?, artist_id = ?, album_title = ?, release_year = ?,
     current_location_id = ?, home_location_id = ?, catalog_number = ?,
-    condition = ?, notes = ?
+    media_grade = ?, sleeve_grade = ?, notes = ?, barcode = ?
 WHERE id = ?
 RETURNING id, title, artist_id, album_title, release_year, 
           current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at
+          notes, last_played_at, play_count, 
+          created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 `
 
 type UpdateRecordParams struct {
@@ -815,8 +1128,10 @@ type UpdateRecordParams struct {
 	CurrentLocationID sql.NullInt64
 	HomeLocationID    sql.NullInt64
 	CatalogNumber     sql.NullString
-	Condition         sql.NullString
+	MediaGrade        sql.NullString
+	SleeveGrade       sql.NullString
 	Notes             sql.NullString
+	Barcode           sql.NullString
 	ID                int64
 }
 
@@ -829,8 +1144,10 @@ func (q *Queries) UpdateRecord(ctx context.Context, arg UpdateRecordParams) (Rec
 		arg.CurrentLocationID,
 		arg.HomeLocationID,
 		arg.CatalogNumber,
-		arg.Condition,
+		arg.MediaGrade,
+		arg.SleeveGrade,
 		arg.Notes,
+		arg.Barcode,
 		arg.ID,
 	)
 	var i Record
@@ -843,33 +1160,37 @@ func (q *Queries) UpdateRecord(ctx context.Context, arg UpdateRecordParams) (Rec
 		&i.CurrentLocationID,
 		&i.HomeLocationID,
 		&i.CatalogNumber,
-		&i.Condition,
 		&i.Notes,
 		&i.LastPlayedAt,
 		&i.PlayCount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MediaGrade,
+		&i.SleeveGrade,
+		&i.Barcode,
+		&i.ShelfPosition,
 	)
 	return i, err
 }
 
 const updateRecordCondition = `-- name: UpdateRecordCondition :one
 UPDATE records
-SET condition = ?
+SET media_grade = ?, sleeve_grade = ?
 WHERE id = ?
 RETURNING id, title, artist_id, album_title, release_year, 
           current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at
+          notes, last_played_at, play_count, 
+          created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 `
 
 type UpdateRecordConditionParams struct {
-	Condition sql.NullString
-	ID        int64
+	MediaGrade  sql.NullString
+	SleeveGrade sql.NullString
+	ID          int64
 }
 
 func (q *Queries) UpdateRecordCondition(ctx context.Context, arg UpdateRecordConditionParams) (Record, error) {
-	row := q.db.QueryRowContext(ctx, updateRecordCondition, arg.Condition, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateRecordCondition, arg.MediaGrade, arg.SleeveGrade, arg.ID)
 	var i Record
 	err := row.Scan(
 		&i.ID,
@@ -880,24 +1201,27 @@ func (q *Queries) UpdateRecordCondition(ctx context.Context, arg UpdateRecordCon
 		&i.CurrentLocationID,
 		&i.HomeLocationID,
 		&i.CatalogNumber,
-		&i.Condition,
 		&i.Notes,
 		&i.LastPlayedAt,
 		&i.PlayCount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MediaGrade,
+		&i.SleeveGrade,
+		&i.Barcode,
+		&i.ShelfPosition,
 	)
 	return i, err
 }
 
 const updateRecordLocation = `-- name: UpdateRecordLocation :one
 UPDATE records
-SET current_location_id = ?
+SET current_location_id = ?, shelf_position = NULL
 WHERE id = ?
 RETURNING id, title, artist_id, album_title, release_year, 
           current_location_id, home_location_id, catalog_number, 
-          condition, notes, last_played_at, play_count, 
-          created_at, updated_at
+          notes, last_played_at, play_count, 
+          created_at, updated_at, media_grade, sleeve_grade, barcode, shelf_position
 `
 
 type UpdateRecordLocationParams struct {
@@ -905,6 +1229,7 @@ type UpdateRecordLocationParams struct {
 	ID                int64
 }
 
+// Moves a record. It has no shelf position in the new location until it's given one.
 func (q *Queries) UpdateRecordLocation(ctx context.Context, arg UpdateRecordLocationParams) (Record, error) {
 	row := q.db.QueryRowContext(ctx, updateRecordLocation, arg.CurrentLocationID, arg.ID)
 	var i Record
@@ -917,12 +1242,15 @@ func (q *Queries) UpdateRecordLocation(ctx context.Context, arg UpdateRecordLoca
 		&i.CurrentLocationID,
 		&i.HomeLocationID,
 		&i.CatalogNumber,
-		&i.Condition,
 		&i.Notes,
 		&i.LastPlayedAt,
 		&i.PlayCount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MediaGrade,
+		&i.SleeveGrade,
+		&i.Barcode,
+		&i.ShelfPosition,
 	)
 	return i, err
 }
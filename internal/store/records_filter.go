@@ -0,0 +1,293 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Sort keys accepted by RecordFilter.Sort
+const (
+	RecordSortTitle        = "title"
+	RecordSortArtist       = "artist"
+	RecordSortYear         = "year"
+	RecordSortPlayCount    = "play_count"
+	RecordSortLastPlayedAt = "last_played_at"
+	RecordSortCreatedAt    = "created_at"
+	RecordSortMediaGrade   = "media_grade"
+	RecordSortSleeveGrade  = "sleeve_grade"
+	RecordSortRunningTime  = "running_time"
+)
+
+// recordSortColumns maps sort keys to the column expression used in ORDER BY.
+// Keys are never interpolated directly so user input can't reach the SQL.
+var recordSortColumns = map[string]string{
+	RecordSortTitle:        "r.title",
+	RecordSortArtist:       "a.sort_name",
+	RecordSortYear:         "r.release_year",
+	RecordSortPlayCount:    "r.play_count",
+	RecordSortLastPlayedAt: "r.last_played_at",
+	RecordSortCreatedAt:    "r.created_at",
+	RecordSortMediaGrade:   "(SELECT rank FROM grades WHERE code = r.media_grade)",
+	RecordSortSleeveGrade:  "(SELECT rank FROM grades WHERE code = r.sleeve_grade)",
+	RecordSortRunningTime:  "running_time",
+}
+
+// recordDetailsFrom is the shared SELECT/FROM for the filtered record listing.
+// The column order must match ListRecordsWithDetailsRow.
+const recordDetailsFrom = `SELECT r.id, r.title, r.album_title, r.release_year,
+       r.catalog_number, r.barcode, r.media_grade, r.sleeve_grade, r.notes,
+       r.last_played_at, r.play_count, r.created_at, r.updated_at,
+       a.id as artist_id, a.name as artist_name,
+       cl.id as current_location_id, cl.path as current_location_name,
+       hl.id as home_location_id, hl.path as home_location_name,
+       (SELECT COUNT(*) FROM tracks t WHERE t.record_id = r.id) AS track_count,
+       (SELECT CAST(COALESCE(SUM(t.duration_seconds), 0) AS INTEGER) FROM tracks t WHERE t.record_id = r.id) AS running_time,
+       fi.checksum AS cover_checksum
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id
+LEFT JOIN location_paths cl ON r.current_location_id = cl.id
+LEFT JOIN location_paths hl ON r.home_location_id = hl.id
+LEFT JOIN record_images fi ON fi.record_id = r.id AND fi.kind = 'front'`
+
+// RecordFilter describes an arbitrary combination of record filters, a sort
+// order and a page window. Zero values mean "no filter". The location filters
+// also match records in any location inside the given one.
+type RecordFilter struct {
+	// ArtistID matches records crediting the artist in any role
+	ArtistID       sql.NullInt64
+	LocationID     sql.NullInt64
+	HomeLocationID sql.NullInt64
+	MediaGrade     sql.NullString
+	SleeveGrade    sql.NullString
+	// MinMediaGrade and MinSleeveGrade match the given grade or better
+	MinMediaGrade  sql.NullString
+	MinSleeveGrade sql.NullString
+	YearFrom       sql.NullInt64
+	YearTo         sql.NullInt64
+	Played         sql.NullBool
+	CreatedSince   sql.NullTime
+	Query          string
+	// AnyTagIDs matches records with at least one of the tags, AllTagIDs
+	// records with every one of them
+	AnyTagIDs []int64
+	AllTagIDs []int64
+
+	Sort string
+	Desc bool
+
+	// Limit <= 0 returns every matching record
+	Limit  int64
+	Offset int64
+}
+
+// where builds the WHERE clause and its arguments for the filter
+func (f RecordFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if f.ArtistID.Valid {
+		conds = append(conds, "r.id IN (SELECT record_id FROM record_artists WHERE artist_id = ?)")
+		args = append(args, f.ArtistID.Int64)
+	}
+	if f.LocationID.Valid {
+		conds = append(conds, "r.current_location_id IN (SELECT location_id FROM location_closure WHERE ancestor_id = ?)")
+		args = append(args, f.LocationID.Int64)
+	}
+	if f.HomeLocationID.Valid {
+		conds = append(conds, "r.home_location_id IN (SELECT location_id FROM location_closure WHERE ancestor_id = ?)")
+		args = append(args, f.HomeLocationID.Int64)
+	}
+	if f.MediaGrade.Valid {
+		conds = append(conds, "r.media_grade = ?")
+		args = append(args, f.MediaGrade.String)
+	}
+	if f.SleeveGrade.Valid {
+		conds = append(conds, "r.sleeve_grade = ?")
+		args = append(args, f.SleeveGrade.String)
+	}
+	if f.MinMediaGrade.Valid {
+		conds = append(conds, gradeAtLeast("r.media_grade"))
+		args = append(args, f.MinMediaGrade.String)
+	}
+	if f.MinSleeveGrade.Valid {
+		conds = append(conds, gradeAtLeast("r.sleeve_grade"))
+		args = append(args, f.MinSleeveGrade.String)
+	}
+	if f.YearFrom.Valid {
+		conds = append(conds, "r.release_year >= ?")
+		args = append(args, f.YearFrom.Int64)
+	}
+	if f.YearTo.Valid {
+		conds = append(conds, "r.release_year <= ?")
+		args = append(args, f.YearTo.Int64)
+	}
+	if f.Played.Valid {
+		if f.Played.Bool {
+			conds = append(conds, "r.play_count > 0")
+		} else {
+			conds = append(conds, "(r.play_count IS NULL OR r.play_count = 0)")
+		}
+	}
+	if f.CreatedSince.Valid {
+		// created_at is stored by CURRENT_TIMESTAMP as UTC text
+		conds = append(conds, "r.created_at >= ?")
+		args = append(args, f.CreatedSince.Time.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if ids := uniqueIDs(f.AnyTagIDs); len(ids) > 0 {
+		conds = append(conds, fmt.Sprintf("r.id IN (SELECT record_id FROM record_tags WHERE tag_id IN (%s))", placeholders(len(ids))))
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	if ids := uniqueIDs(f.AllTagIDs); len(ids) > 0 {
+		conds = append(conds, fmt.Sprintf(`r.id IN (
+    SELECT record_id FROM record_tags WHERE tag_id IN (%s)
+    GROUP BY record_id HAVING COUNT(*) = ?)`, placeholders(len(ids))))
+		for _, id := range ids {
+			args = append(args, id)
+		}
+		args = append(args, len(ids))
+	}
+	if match := FTSQuery(f.Query); match != "" {
+		// Free text goes through the full-text index (title, album, catalog
+		// number, notes and artist name)
+		conds = append(conds, `r.id IN (
+    SELECT entity_id FROM search_index
+    WHERE search_index MATCH ? AND entity_type = 'record')`)
+		args = append(args, match)
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return "\nWHERE " + strings.Join(conds, "\n  AND "), args
+}
+
+// uniqueIDs drops duplicate ids, keeping the first of each
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	var unique []int64
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// placeholders returns n comma-separated "?" for an IN list
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// gradeAtLeast compares a grade column against a grade code by rank.
+// Ungraded records never match.
+func gradeAtLeast(column string) string {
+	return fmt.Sprintf(`(SELECT rank FROM grades WHERE code = %s) >= (SELECT rank FROM grades WHERE code = ?)`, column)
+}
+
+// orderBy builds the ORDER BY clause. NULLs always sort last and r.id breaks
+// ties so pages are stable.
+func (f RecordFilter) orderBy() string {
+	column, ok := recordSortColumns[f.Sort]
+	if !ok {
+		column = recordSortColumns[RecordSortTitle]
+	}
+
+	direction := "ASC"
+	if f.Desc {
+		direction = "DESC"
+	}
+
+	return fmt.Sprintf("\nORDER BY %s %s NULLS LAST, r.id %s", column, direction, direction)
+}
+
+// IsValidRecordSort reports whether key is a supported sort key
+func IsValidRecordSort(key string) bool {
+	_, ok := recordSortColumns[key]
+	return ok
+}
+
+// FilterRecords returns the page of records matching the filter, with artist
+// and location names joined in
+func (q *Queries) FilterRecords(ctx context.Context, f RecordFilter) ([]ListRecordsWithDetailsRow, error) {
+	var items []ListRecordsWithDetailsRow
+	if err := q.EachFilteredRecord(ctx, f, func(i ListRecordsWithDetailsRow) error {
+		items = append(items, i)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// EachFilteredRecord calls fn with each record matching the filter in turn,
+// reading rows as it goes so the whole listing is never held in memory. An
+// error from fn stops the iteration and is returned.
+func (q *Queries) EachFilteredRecord(ctx context.Context, f RecordFilter, fn func(ListRecordsWithDetailsRow) error) error {
+	where, args := f.where()
+	query := recordDetailsFrom + where + f.orderBy()
+	if f.Limit > 0 {
+		query += "\nLIMIT ? OFFSET ?"
+		args = append(args, f.Limit, f.Offset)
+	}
+
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i ListRecordsWithDetailsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.AlbumTitle,
+			&i.ReleaseYear,
+			&i.CatalogNumber,
+			&i.Barcode,
+			&i.MediaGrade,
+			&i.SleeveGrade,
+			&i.Notes,
+			&i.LastPlayedAt,
+			&i.PlayCount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ArtistID,
+			&i.ArtistName,
+			&i.CurrentLocationID,
+			&i.CurrentLocationName,
+			&i.HomeLocationID,
+			&i.HomeLocationName,
+			&i.TrackCount,
+			&i.RunningTime,
+			&i.CoverChecksum,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// CountFilteredRecords returns the total number of records matching the
+// filter, ignoring Limit and Offset
+func (q *Queries) CountFilteredRecords(ctx context.Context, f RecordFilter) (int64, error) {
+	where, args := f.where()
+	query := `SELECT COUNT(*)
+FROM records r
+LEFT JOIN artists a ON r.artist_id = a.id` + where
+
+	row := q.db.QueryRowContext(ctx, query, args...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"strings"
+)
+
+// Search entity types stored in search_index.entity_type
+const (
+	SearchEntityRecord = "record"
+	SearchEntityArtist = "artist"
+)
+
+// Highlight markers wrapped around matched terms by SearchCollection. They
+// are control characters so callers can escape the text for HTML first and
+// then swap the markers for tags.
+const (
+	SearchMatchStart = "\x02"
+	SearchMatchEnd   = "\x03"
+)
+
+// searchCollection ranks with bm25, weighting title and artist matches above
+// album, catalog number and notes matches. Column order: entity_type,
+// entity_id, title, album_title, catalog_number, notes, artist_name.
+const searchCollection = `SELECT entity_type, entity_id,
+       COALESCE(highlight(search_index, 2, char(2), char(3)), '') AS title,
+       COALESCE(highlight(search_index, 6, char(2), char(3)), '') AS artist_name,
+       COALESCE(snippet(search_index, -1, char(2), char(3), '…', 12), '') AS snippet,
+       bm25(search_index, 0.0, 0.0, 10.0, 5.0, 3.0, 1.0, 8.0) AS rank
+FROM search_index
+WHERE search_index MATCH ?
+ORDER BY rank
+LIMIT ?`
+
+type SearchCollectionParams struct {
+	Query string
+	Limit int64
+}
+
+type SearchCollectionRow struct {
+	EntityType string
+	EntityID   int64
+	Title      string
+	ArtistName string
+	Snippet    string
+	Rank       float64
+}
+
+// SearchCollection runs a ranked full-text search across records and artists.
+// Query is free text from the user; it is converted with FTSQuery first.
+func (q *Queries) SearchCollection(ctx context.Context, arg SearchCollectionParams) ([]SearchCollectionRow, error) {
+	match := FTSQuery(arg.Query)
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, err := q.db.QueryContext(ctx, searchCollection, match, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchCollectionRow
+	for rows.Next() {
+		var i SearchCollectionRow
+		if err := rows.Scan(
+			&i.EntityType,
+			&i.EntityID,
+			&i.Title,
+			&i.ArtistName,
+			&i.Snippet,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FTSQuery turns free text into an FTS5 MATCH expression. Every word becomes
+// a quoted prefix term, so FTS5 operators and punctuation typed by the user
+// are matched literally instead of being parsed as query syntax. It returns
+// an empty string when there is nothing to search for.
+func FTSQuery(input string) string {
+	var terms []string
+	for _, word := range strings.Fields(input) {
+		word = strings.Trim(word, `"*`)
+		if word == "" {
+			continue
+		}
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}